@@ -0,0 +1,69 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cache
+
+import (
+	hd "github.com/MakeNowJust/heredoc"
+	"github.com/spf13/cobra"
+)
+
+var CacheCmd *cobra.Command
+
+func init() {
+	CacheCmd = NewCacheCmd()
+	CacheCmd.AddCommand(cacheListCmd())
+	CacheCmd.AddCommand(cachePruneCmd())
+	CacheCmd.AddCommand(cacheClearCmd())
+}
+
+func NewCacheCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "cache",
+		Short: "Manage the persistent cache",
+
+		Long: hd.Doc(`
+			Manage the persistent cache
+
+			When enabled, content such as git and OCI policy sources is kept in a
+			persistent cache across invocations. Sources pinned to a git commit or an
+			image digest are served from the cache without network access, other
+			sources are served from the cache while the cached entry is fresh.
+
+			The manifests and blobs fetched by digest from OCI registries, e.g. by the
+			ec.oci.* rego functions, are kept in the images area of the cache
+			directory, regardless of EC_PERSISTENT_CACHE, unless EC_CACHE is set to "0".
+			Those never need refreshing, but like the other cache areas the images area
+			is kept within EC_CACHE_MAX_SIZE, and is pruned and cleared by "ec cache
+			prune" and "ec cache clear".
+
+			The results of image validations are kept in the cache when using the
+			--result-cache flag of "ec validate image", regardless of
+			EC_PERSISTENT_CACHE, and are reused while fresh for the same image, policy
+			and effective day.
+
+			The cache is configured using the following environment variables:
+
+			  EC_PERSISTENT_CACHE  set to "1" to enable the persistent cache
+			  EC_CACHE             set to "0" to turn all caching off, including the
+			                       persistent cache
+			  EC_CACHE_DIR         the cache directory, defaults to $XDG_CACHE_HOME/ec
+			  EC_CACHE_TTL         how long entries are considered fresh, defaults to 24h
+			  EC_CACHE_MAX_SIZE    the maximum size of each cache area, defaults to 1Gi,
+			                       least recently used entries are evicted first
+		`),
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Define the `ec cache clear` command
+package cache
+
+import (
+	hd "github.com/MakeNowJust/heredoc"
+	"github.com/spf13/cobra"
+
+	"github.com/enterprise-contract/ec-cli/internal/cache"
+	"github.com/enterprise-contract/ec-cli/internal/utils"
+)
+
+func cacheClearCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "clear",
+		Short: "Remove all entries from the persistent cache",
+
+		Example: hd.Doc(`
+			Remove all cache entries:
+
+			  ec cache clear
+		`),
+
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := cache.ConfigFromEnv()
+			if err != nil {
+				return err
+			}
+
+			stores, err := cache.Stores(utils.FS(cmd.Context()), c)
+			if err != nil {
+				return err
+			}
+
+			for _, s := range stores {
+				if err := s.Clear(); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+	}
+
+	return cmd
+}
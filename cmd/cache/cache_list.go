@@ -0,0 +1,127 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Define the `ec cache list` command
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	hd "github.com/MakeNowJust/heredoc"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/enterprise-contract/ec-cli/internal/cache"
+	"github.com/enterprise-contract/ec-cli/internal/utils"
+)
+
+type listedEntry struct {
+	Store string `json:"store"`
+	cache.Entry
+}
+
+func cacheListCmd() *cobra.Command {
+	var outputFormat string
+
+	validFormats := []string{"text", "json"}
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the entries in the persistent cache",
+
+		Long: hd.Doc(`
+			List the entries in the persistent cache.
+
+			Entries are listed per cache area, least recently used first.
+		`),
+
+		Example: hd.Doc(`
+			List the cache entries:
+
+			  ec cache list
+
+			List the cache entries in JSON format:
+
+			  ec cache list --output json
+		`),
+
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !slices.Contains(validFormats, outputFormat) {
+				return fmt.Errorf("invalid value for --output '%s'. accepted values: %s", outputFormat, strings.Join(validFormats, ", "))
+			}
+
+			c, err := cache.ConfigFromEnv()
+			if err != nil {
+				return err
+			}
+
+			stores, err := cache.Stores(utils.FS(cmd.Context()), c)
+			if err != nil {
+				return err
+			}
+
+			listed := []listedEntry{}
+			for _, s := range stores {
+				entries, err := s.Entries()
+				if err != nil {
+					return err
+				}
+
+				for _, e := range entries {
+					listed = append(listed, listedEntry{Store: s.Name(), Entry: e})
+				}
+			}
+
+			out := cmd.OutOrStdout()
+			if outputFormat == "json" {
+				return json.NewEncoder(out).Encode(listed)
+			}
+
+			w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "STORE\tKEY\tSIZE\tCREATED\tACCESSED\tREFS")
+			for _, l := range listed {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+					l.Store,
+					shortKey(l.Key),
+					resource.NewQuantity(l.Size, resource.BinarySI).String(),
+					l.Created.Format(time.RFC3339),
+					l.Accessed.Format(time.RFC3339),
+					strings.Join(l.Refs, ", "))
+			}
+
+			return w.Flush()
+		},
+	}
+
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "text", fmt.Sprintf("output format. one of: %s", strings.Join(validFormats, ", ")))
+
+	return cmd
+}
+
+// shortKey abbreviates the key for display purposes
+func shortKey(key string) string {
+	if len(key) > 12 {
+		return key[:12]
+	}
+
+	return key
+}
@@ -0,0 +1,77 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Define the `ec cache prune` command
+package cache
+
+import (
+	"fmt"
+
+	hd "github.com/MakeNowJust/heredoc"
+	"github.com/spf13/cobra"
+
+	"github.com/enterprise-contract/ec-cli/internal/cache"
+	"github.com/enterprise-contract/ec-cli/internal/utils"
+)
+
+func cachePruneCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Remove stale entries from the persistent cache",
+
+		Long: hd.Doc(`
+			Remove stale entries from the persistent cache.
+
+			Entries that have not been used within the time set by EC_CACHE_TTL are
+			removed. Then the least recently used entries are removed until each cache
+			area is within the size set by EC_CACHE_MAX_SIZE.
+		`),
+
+		Example: hd.Doc(`
+			Remove entries not used in the last hour:
+
+			  EC_CACHE_TTL=1h ec cache prune
+		`),
+
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := cache.ConfigFromEnv()
+			if err != nil {
+				return err
+			}
+
+			stores, err := cache.Stores(utils.FS(cmd.Context()), c)
+			if err != nil {
+				return err
+			}
+
+			for _, s := range stores {
+				removed, err := s.Prune()
+				if err != nil {
+					return err
+				}
+
+				for _, e := range removed {
+					fmt.Fprintf(cmd.OutOrStdout(), "Removed %s/%s\n", s.Name(), e.Key)
+				}
+			}
+
+			return nil
+		},
+	}
+
+	return cmd
+}
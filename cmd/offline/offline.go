@@ -0,0 +1,45 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package offline
+
+import (
+	hd "github.com/MakeNowJust/heredoc"
+	"github.com/spf13/cobra"
+)
+
+var OfflineCmd *cobra.Command
+
+func init() {
+	OfflineCmd = NewOfflineCmd()
+	OfflineCmd.AddCommand(offlineExportCmd())
+}
+
+func NewOfflineCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "offline",
+		Short: "Prepare for validation without network access",
+
+		Long: hd.Doc(`
+			Prepare for validation without network access
+
+			Images, their signatures and attestations, and policy bundles can be exported
+			into an OCI image layout on disk. The layout can then be used to validate the
+			images in an air-gapped environment by using the --offline-layout flag of the
+			"ec validate image" command.
+		`),
+	}
+}
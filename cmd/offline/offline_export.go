@@ -0,0 +1,186 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Define the `ec offline export` command
+package offline
+
+import (
+	"fmt"
+	"strings"
+
+	hd "github.com/MakeNowJust/heredoc"
+	goci "github.com/conforma/go-gather/gather/oci"
+	"github.com/google/go-containerregistry/pkg/name"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/enterprise-contract/ec-cli/internal/applicationsnapshot"
+	"github.com/enterprise-contract/ec-cli/internal/offline"
+	"github.com/enterprise-contract/ec-cli/internal/policy"
+	validate_utils "github.com/enterprise-contract/ec-cli/internal/validate"
+)
+
+func offlineExportCmd() *cobra.Command {
+	data := struct {
+		imageRef            string
+		images              string
+		snapshot            string
+		policyConfiguration string
+		artifacts           []string
+		layout              string
+	}{}
+
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export images and policies into an OCI image layout",
+
+		Long: hd.Doc(`
+			Export images and policies into an OCI image layout
+
+			Each image is exported together with its signatures, attestations and SBOMs
+			stored using the cosign tag scheme. For image indexes, the same is exported
+			for each image within the index. Policy and data sources from the policy
+			configuration that are stored in an OCI registry are exported as well. Policy
+			and data sources from git repositories are not exported, consider pinning
+			them and using the persistent cache, see "ec cache".
+
+			Any other image the policy rules access, for example base images or Tekton
+			task bundles, can be exported using the --artifact flag.
+
+			When the layout already exists, the exported content is added to it. Content
+			exported from the same image reference is replaced.
+		`),
+
+		Example: hd.Doc(`
+			Export all images from an ApplicationSnapshot Spec file along with the policy
+			bundles from the policy configuration:
+
+			  ec offline export --images my-app.yaml --policy policy.yaml --layout my-app-layout
+
+			Validate the images using the exported layout:
+
+			  ec validate image --images my-app.yaml --policy policy.yaml --offline-layout my-app-layout
+
+			Export a single image and a task bundle:
+
+			  ec offline export --image registry/name:tag --artifact registry/task-bundle:tag \
+			    --layout my-app-layout
+		`),
+
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			spec, err := applicationsnapshot.DetermineInputSpec(ctx, applicationsnapshot.Input{
+				Image:    data.imageRef,
+				Snapshot: data.snapshot,
+				Images:   data.images,
+			})
+			if err != nil {
+				return err
+			}
+
+			var bundles []string
+			if data.policyConfiguration != "" {
+				policyConfiguration, err := validate_utils.GetPolicyConfig(ctx, data.policyConfiguration)
+				if err != nil {
+					return err
+				}
+
+				p, err := policy.NewInertPolicy(ctx, policyConfiguration)
+				if err != nil {
+					return err
+				}
+
+				for _, s := range p.Spec().Sources {
+					for _, u := range append(s.Policy, s.Data...) {
+						if (&goci.OCIGatherer{}).Matcher(u) {
+							bundles = append(bundles, ociReference(u))
+						} else {
+							log.Warnf("Not exporting the %s source, only sources stored in an OCI registry can be exported", u)
+						}
+					}
+				}
+			}
+
+			exporter, err := offline.NewExporter(ctx, data.layout)
+			if err != nil {
+				return err
+			}
+
+			for _, c := range spec.Components {
+				ref, err := name.ParseReference(c.ContainerImage)
+				if err != nil {
+					return fmt.Errorf("unable to parse container image %s: %w", c.ContainerImage, err)
+				}
+
+				if err := exporter.Image(ref); err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "Exported %s\n", ref)
+			}
+
+			for _, a := range append(bundles, data.artifacts...) {
+				ref, err := name.ParseReference(a)
+				if err != nil {
+					return fmt.Errorf("unable to parse reference %s: %w", a, err)
+				}
+
+				if err := exporter.Artifact(ref); err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "Exported %s\n", ref)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&data.imageRef, "image", "i", data.imageRef, "OCI image reference")
+
+	cmd.Flags().StringVar(&data.images, "images", data.images,
+		"path to ApplicationSnapshot Spec JSON file or JSON representation of an ApplicationSnapshot Spec")
+
+	cmd.Flags().StringVar(&data.snapshot, "snapshot", "", hd.Doc(`
+		Provide the AppStudio Snapshot as a source of the images to export, as inline
+		JSON of the "spec" or a reference to a Kubernetes object [<namespace>/]<name>`))
+
+	cmd.Flags().StringVarP(&data.policyConfiguration, "policy", "p", data.policyConfiguration, hd.Doc(`
+		Policy configuration, its policy and data sources stored in an OCI registry are
+		exported. See "ec validate image --help" for the supported formats.`))
+
+	cmd.Flags().StringArrayVar(&data.artifacts, "artifact", []string{}, hd.Doc(`
+		Reference of an additional image to export as is, e.g. a base image or a Tekton
+		task bundle. Multiple values are allowed.`))
+
+	cmd.Flags().StringVarP(&data.layout, "layout", "l", data.layout,
+		"Directory of the OCI image layout to export into, created if it doesn't exist")
+
+	if err := cmd.MarkFlagRequired("layout"); err != nil {
+		panic(err)
+	}
+
+	return cmd
+}
+
+// ociReference returns the image reference of an OCI policy or data source
+func ociReference(source string) string {
+	for _, prefix := range []string{"oci::", "oci://"} {
+		source = strings.TrimPrefix(source, prefix)
+	}
+
+	return source
+}
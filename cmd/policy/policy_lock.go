@@ -0,0 +1,135 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Define the `ec policy lock` command
+package policy
+
+import (
+	"fmt"
+
+	hd "github.com/MakeNowJust/heredoc"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+
+	ecpolicy "github.com/enterprise-contract/ec-cli/internal/policy"
+	"github.com/enterprise-contract/ec-cli/internal/utils"
+	validate_utils "github.com/enterprise-contract/ec-cli/internal/validate"
+)
+
+func policyLockCmd() *cobra.Command {
+	var (
+		policyConfiguration string
+		outputFile          string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "lock --policy <policy-configuration>",
+		Short: "Lock the policy sources to immutable references",
+
+		Long: hd.Doc(`
+			Lock the policy sources to immutable references.
+
+			The policy configuration, when fetched from a git repository or other
+			remote location, and the policy and data sources of each source group are
+			resolved to immutable references, i.e. git commit SHAs and OCI image
+			digests, and written to a lockfile. Sources that can't be resolved to an
+			immutable reference, e.g. https URLs or local files, can't be locked.
+
+			The lockfile can be used with the --lockfile flag of "ec validate image"
+			and "ec validate input" to validate with exactly the same policy. The
+			lockfile also records the digests of the policy and of each source group, so
+			the validation fails if the policy configuration, its sources, the rule data
+			or any other setting of the policy have changed since the lockfile was
+			written. A remote policy configuration not recorded in the lockfile is
+			rejected. Run this command again to update the lockfile.
+		`),
+
+		Example: hd.Doc(`
+			Lock the sources of the policy in a local file and write the lockfile:
+
+			  ec policy lock --policy policy.yaml --output-file policy.lock.yaml
+
+			Lock the policy configuration in a git repository and its sources:
+
+			  ec policy lock --policy github.com/user/repo//default?ref=main
+
+			Validate an image using the locked policy:
+
+			  ec validate image --image registry/name:tag --public-key key.pub \
+			    --policy policy.yaml --lockfile policy.lock.yaml
+		`),
+
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			config, pinned, err := validate_utils.GetPinnedPolicyConfig(ctx, policyConfiguration)
+			if err != nil {
+				return err
+			}
+
+			p, err := ecpolicy.NewInertPolicy(ctx, config)
+			if err != nil {
+				return err
+			}
+
+			lock, err := ecpolicy.Lock(ctx, p.Spec())
+			if err != nil {
+				return err
+			}
+
+			if pinned != "" {
+				configuration, err := ecpolicy.NewLockedSource(policyConfiguration, pinned)
+				if err != nil {
+					return err
+				}
+				lock.Configuration = &configuration
+			}
+
+			out, err := yaml.Marshal(lock)
+			if err != nil {
+				return err
+			}
+
+			if outputFile == "" {
+				_, err = cmd.OutOrStdout().Write(out)
+				return err
+			}
+
+			if err := afero.WriteFile(utils.FS(ctx), outputFile, out, 0644); err != nil {
+				return fmt.Errorf("writing the lockfile to %q: %w", outputFile, err)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&policyConfiguration, "policy", "p", policyConfiguration, hd.Doc(`
+		Policy configuration as:
+		  * Kubernetes reference ([<namespace>/]<name>)
+		  * file (policy.yaml)
+		  * git reference (github.com/user/repo//default?ref=main), or
+		  * inline JSON ('{sources: {...}}')`))
+	cmd.Flags().StringVarP(&outputFile, "output-file", "o", outputFile,
+		"write the lockfile to the given file instead of the standard output")
+
+	if err := cmd.MarkFlagRequired("policy"); err != nil {
+		panic(err)
+	}
+
+	return cmd
+}
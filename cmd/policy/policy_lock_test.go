@@ -0,0 +1,120 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package policy
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	gitMetadata "github.com/conforma/go-gather/gather/git"
+	ociMetadata "github.com/conforma/go-gather/gather/oci"
+	"github.com/conforma/go-gather/metadata"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/enterprise-contract/ec-cli/cmd/root"
+	"github.com/enterprise-contract/ec-cli/internal/policy/source"
+	"github.com/enterprise-contract/ec-cli/internal/utils"
+)
+
+type mockDownloader struct {
+	mock.Mock
+}
+
+func (m *mockDownloader) Download(_ context.Context, dest string, sourceUrl string, showMsg bool) (metadata.Metadata, error) {
+	args := m.Called(dest, sourceUrl, showMsg)
+
+	return args.Get(0).(metadata.Metadata), args.Error(1)
+}
+
+func TestPolicyLock(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	ctx := utils.WithFS(context.Background(), fs)
+
+	dl := mockDownloader{}
+	dl.On("Download", mock.Anything, "github.com/org/cmd-policy", false).Return(&gitMetadata.GitMetadata{LatestCommit: "3b1e6ad9bd1e1f7e5cf0e6e1a8d6c4d0e2b9a7f1"}, nil)
+	dl.On("Download", mock.Anything, "registry.io/cmd/data:latest", false).Return(&ociMetadata.OCIMetadata{Digest: "sha256:def456"}, nil)
+	ctx = context.WithValue(ctx, source.DownloaderFuncKey, &dl)
+
+	require.NoError(t, afero.WriteFile(fs, "/policy.yaml", []byte(`sources:
+- name: release
+  policy:
+  - github.com/org/cmd-policy
+  data:
+  - registry.io/cmd/data:latest
+`), 0644))
+	expected := `digest: sha256:44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a
+sources:
+- data:
+  - pinned: oci::registry.io/cmd/data:latest@sha256:def456
+    url: registry.io/cmd/data:latest
+  digest: sha256:8f496319171331b8fa92ba6d0919e6ef3c1d5bbfb8e079b90acbb9f681ce4b15
+  name: release
+  policy:
+  - pinned: git::github.com/org/cmd-policy?ref=3b1e6ad9bd1e1f7e5cf0e6e1a8d6c4d0e2b9a7f1
+    url: github.com/org/cmd-policy
+`
+
+	t.Run("standard output", func(t *testing.T) {
+		cmd := setUpCobra(policyLockCmd())
+		cmd.SetContext(ctx)
+		out := bytes.Buffer{}
+		cmd.SetOut(&out)
+		cmd.SetArgs([]string{"policy", "lock", "--policy", "/policy.yaml"})
+
+		require.NoError(t, cmd.Execute())
+		assert.Equal(t, expected, out.String())
+	})
+
+	t.Run("output file", func(t *testing.T) {
+		cmd := setUpCobra(policyLockCmd())
+		cmd.SetContext(ctx)
+		out := bytes.Buffer{}
+		cmd.SetOut(&out)
+		cmd.SetArgs([]string{"policy", "lock", "--policy", "/policy.yaml", "--output-file", "/policy.lock.yaml"})
+
+		require.NoError(t, cmd.Execute())
+		assert.Empty(t, out.String())
+
+		written, err := afero.ReadFile(fs, "/policy.lock.yaml")
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(written))
+	})
+}
+
+func TestPolicyLockRequiresPolicy(t *testing.T) {
+	cmd := setUpCobra(policyLockCmd())
+	cmd.SetContext(utils.WithFS(context.Background(), afero.NewMemMapFs()))
+	cmd.SetArgs([]string{"policy", "lock"})
+
+	err := cmd.Execute()
+	assert.ErrorContains(t, err, `required flag(s) "policy" not set`)
+}
+
+func setUpCobra(command *cobra.Command) *cobra.Command {
+	policyCmd := NewPolicyCmd()
+	policyCmd.AddCommand(command)
+	cmd := root.NewRootCmd()
+	cmd.AddCommand(policyCmd)
+	return cmd
+}
@@ -0,0 +1,123 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Define the `ec report diff` command
+package report
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	hd "github.com/MakeNowJust/heredoc"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+
+	"github.com/enterprise-contract/ec-cli/internal/applicationsnapshot"
+	"github.com/enterprise-contract/ec-cli/internal/format"
+	"github.com/enterprise-contract/ec-cli/internal/report/diff"
+	"github.com/enterprise-contract/ec-cli/internal/utils"
+)
+
+func reportDiffCmd() *cobra.Command {
+	data := struct {
+		output     []string
+		noColor    bool
+		forceColor bool
+	}{}
+
+	cmd := &cobra.Command{
+		Use:   "diff <old report> <new report>",
+		Short: "Compare the reports of two validation runs",
+
+		Long: hd.Doc(`
+			Compare the reports of two validation runs
+
+			The reports need to be produced by "ec validate image" in the json or yaml
+			format. Components are matched by their name and image reference, and the
+			results of the components are matched by their rule code and term. The
+			differences reported are:
+
+			  * the violations introduced in the new report,
+			  * the violations of the old report resolved in the new report, and
+			  * the warnings of the old report that are violations in the new report,
+			    usually because the effective_on date of the rule has passed.
+
+			A component missing from one of the reports is considered to have no
+			results in that report.
+
+			The command exits with a non-zero status when the new report introduces
+			violations, including warnings that became violations.
+		`),
+
+		Example: hd.Doc(`
+			Compare the validation before and after updating the policy:
+
+			  ec validate image --images my-app.yaml --policy policy.yaml --output json=before.json
+			  ec validate image --images my-app.yaml --policy updated-policy.yaml --output json=after.json
+			  ec report diff before.json after.json
+
+			Write the differences in markdown format to a file:
+
+			  ec report diff before.json after.json --output markdown=diff.md
+		`),
+
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fs := utils.FS(cmd.Context())
+
+			reports := make([]applicationsnapshot.Report, 0, len(args))
+			for _, path := range args {
+				data, err := afero.ReadFile(fs, path)
+				if err != nil {
+					return fmt.Errorf("unable to read the report %s: %w", path, err)
+				}
+
+				r, err := diff.ReadReport(data)
+				if err != nil {
+					return fmt.Errorf("%s: %w", path, err)
+				}
+				reports = append(reports, r)
+			}
+
+			d := diff.Compare(reports[0], reports[1])
+
+			p := format.NewTargetParser(diff.Text, format.Options{}, cmd.OutOrStdout(), fs)
+			utils.SetColorEnabled(data.noColor, data.forceColor)
+			if err := d.WriteAll(data.output, p); err != nil {
+				return err
+			}
+
+			if d.Regressed() {
+				return errors.New("the new report introduces violations")
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringSliceVarP(&data.output, "output", "o", data.output, hd.Doc(`
+		Write output to a file in a specific format, e.g. json=/tmp/diff.json. Use empty
+		string path for stdout, e.g. json. May be used multiple times. Possible formats are:
+		`+strings.Join(diff.OutputFormats, ", ")+`.
+	`))
+
+	cmd.Flags().BoolVar(&data.noColor, "no-color", data.noColor, "Disable color when using text output even when the current terminal supports it")
+
+	cmd.Flags().BoolVar(&data.forceColor, "color", data.forceColor, "Enable color when using text output even when the current terminal does not support it")
+
+	return cmd
+}
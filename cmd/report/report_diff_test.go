@@ -0,0 +1,101 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package report
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/enterprise-contract/ec-cli/cmd/root"
+	"github.com/enterprise-contract/ec-cli/internal/utils"
+)
+
+const before = `{
+	"success": true,
+	"components": [
+		{"name": "a", "containerImage": "registry.io/a@sha256:1", "success": true}
+	]
+}`
+
+const after = `{
+	"success": false,
+	"components": [
+		{
+			"name": "a",
+			"containerImage": "registry.io/a@sha256:1",
+			"violations": [{"msg": "Missing signature", "metadata": {"code": "signature.missing"}}],
+			"success": false
+		}
+	]
+}`
+
+func setUpCobra(command *cobra.Command) *cobra.Command {
+	reportCmd := NewReportCmd()
+	reportCmd.AddCommand(command)
+	cmd := root.NewRootCmd()
+	cmd.AddCommand(reportCmd)
+	return cmd
+}
+
+func run(t *testing.T, args ...string) (string, error) {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "before.json", []byte(before), 0644))
+	require.NoError(t, afero.WriteFile(fs, "after.json", []byte(after), 0644))
+
+	cmd := setUpCobra(reportDiffCmd())
+	cmd.SetContext(utils.WithFS(context.Background(), fs))
+	cmd.SetArgs(append([]string{"report", "diff"}, args...))
+
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+
+	err := cmd.Execute()
+
+	return out.String(), err
+}
+
+func TestReportDiffRegression(t *testing.T) {
+	out, err := run(t, "before.json", "after.json", "--output", "json")
+	assert.EqualError(t, err, "the new report introduces violations")
+	assert.JSONEq(t, `{
+		"introduced": [
+			{"component": "a", "containerImage": "registry.io/a@sha256:1", "code": "signature.missing", "msg": "Missing signature"}
+		],
+		"resolved": [],
+		"promoted": []
+	}`, out)
+}
+
+func TestReportDiffNoRegression(t *testing.T) {
+	out, err := run(t, "after.json", "before.json")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "Regression: false")
+	assert.Contains(t, out, "Resolved violations:")
+}
+
+func TestReportDiffMissingReport(t *testing.T) {
+	_, err := run(t, "before.json", "missing.json")
+	assert.ErrorContains(t, err, "unable to read the report missing.json")
+}
@@ -22,15 +22,21 @@ import (
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 
+	"github.com/enterprise-contract/ec-cli/cmd/cache"
 	"github.com/enterprise-contract/ec-cli/cmd/fetch"
 	"github.com/enterprise-contract/ec-cli/cmd/initialize"
 	"github.com/enterprise-contract/ec-cli/cmd/inspect"
+	"github.com/enterprise-contract/ec-cli/cmd/offline"
 	"github.com/enterprise-contract/ec-cli/cmd/opa"
+	"github.com/enterprise-contract/ec-cli/cmd/policy"
+	"github.com/enterprise-contract/ec-cli/cmd/report"
 	"github.com/enterprise-contract/ec-cli/cmd/root"
+	"github.com/enterprise-contract/ec-cli/cmd/serve"
 	"github.com/enterprise-contract/ec-cli/cmd/sigstore"
 	"github.com/enterprise-contract/ec-cli/cmd/test"
 	"github.com/enterprise-contract/ec-cli/cmd/track"
 	"github.com/enterprise-contract/ec-cli/cmd/validate"
+	"github.com/enterprise-contract/ec-cli/cmd/verify"
 	"github.com/enterprise-contract/ec-cli/cmd/version"
 	"github.com/enterprise-contract/ec-cli/internal/utils"
 )
@@ -56,11 +62,17 @@ func init() {
 }
 
 func AddCommandsTo(cmd *cobra.Command) {
+	cmd.AddCommand(cache.CacheCmd)
 	cmd.AddCommand(fetch.FetchCmd)
 	cmd.AddCommand(initialize.InitCmd)
 	cmd.AddCommand(inspect.InspectCmd)
+	cmd.AddCommand(offline.OfflineCmd)
+	cmd.AddCommand(policy.PolicyCmd)
+	cmd.AddCommand(report.ReportCmd)
+	cmd.AddCommand(serve.ServeCmd)
 	cmd.AddCommand(track.TrackCmd)
 	cmd.AddCommand(validate.ValidateCmd)
+	cmd.AddCommand(verify.VerifyCmd)
 	cmd.AddCommand(version.VersionCmd)
 	cmd.AddCommand(opa.OPACmd)
 	cmd.AddCommand(sigstore.SigstoreCmd)
@@ -34,6 +34,7 @@ import (
 	"github.com/enterprise-contract/ec-cli/internal/kubernetes"
 	"github.com/enterprise-contract/ec-cli/internal/logging"
 	"github.com/enterprise-contract/ec-cli/internal/tracing"
+	"github.com/enterprise-contract/ec-cli/internal/utils/oci"
 	"github.com/enterprise-contract/ec-cli/internal/version"
 )
 
@@ -139,6 +140,8 @@ func NewRootCmd() *cobra.Command {
 						_ = tracefile.Close() // ignore errors
 						cmd.PrintErrf("Wrote performance trace to: %s\n", tracefile.Name())
 					}
+					stats := oci.Stats()
+					cmd.PrintErrf("OCI cache: %d memory hits, %d disk hits, %d misses\n", stats.MemoryHits, stats.DiskHits, stats.Misses)
 				}
 
 				// perform resource cleanup
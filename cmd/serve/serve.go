@@ -0,0 +1,45 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package serve
+
+import (
+	hd "github.com/MakeNowJust/heredoc"
+	"github.com/spf13/cobra"
+
+	"github.com/enterprise-contract/ec-cli/internal/image"
+)
+
+var ServeCmd *cobra.Command
+
+func init() {
+	ServeCmd = NewServeCmd()
+	ServeCmd.AddCommand(serveAdmissionCmd(image.ValidateImage))
+}
+
+func NewServeCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "serve",
+		Short: "Run a server that validates images on request",
+
+		Long: hd.Doc(`
+			Run a server that validates images on request
+
+			The server validates images the same way as the "ec validate image" command,
+			on requests from other services, for instance from the Kubernetes API server.
+		`),
+	}
+}
@@ -0,0 +1,264 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Define the `ec serve admission` command
+package serve
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	hd "github.com/MakeNowJust/heredoc"
+	app "github.com/konflux-ci/application-api/api/v1alpha1"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/enterprise-contract/ec-cli/internal/admission"
+	"github.com/enterprise-contract/ec-cli/internal/evaluator"
+	"github.com/enterprise-contract/ec-cli/internal/output"
+	"github.com/enterprise-contract/ec-cli/internal/policy"
+	"github.com/enterprise-contract/ec-cli/internal/policy/source"
+	"github.com/enterprise-contract/ec-cli/internal/utils"
+	validate_utils "github.com/enterprise-contract/ec-cli/internal/validate"
+)
+
+type imageValidationFunc func(context.Context, app.SnapshotComponent, *app.SnapshotSpec, policy.Policy, []evaluator.Evaluator, bool) (*output.Output, error)
+
+var newConftestEvaluator = evaluator.NewConftestEvaluator
+var newOPAEvaluator = evaluator.NewOPAEvaluator
+
+// serve runs the HTTPS server until the context is done
+var serve = func(ctx context.Context, server *http.Server, certFile, keyFile string) error {
+	errs := make(chan error, 1)
+	go func() {
+		errs <- server.ListenAndServeTLS(certFile, keyFile)
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+		shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		return server.Shutdown(shutdown)
+	}
+}
+
+func serveAdmissionCmd(validate imageValidationFunc) *cobra.Command {
+	data := struct {
+		address                     string
+		cacheTTL                    time.Duration
+		certificateIdentity         string
+		certificateIdentityRegExp   string
+		certificateOIDCIssuer       string
+		certificateOIDCIssuerRegExp string
+		ignoreRekor                 bool
+		policyConfiguration         string
+		publicKey                   string
+		rekorURL                    string
+		tlsCertFile                 string
+		tlsKeyFile                  string
+	}{
+		address:  ":8443",
+		cacheTTL: 10 * time.Minute,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "admission",
+		Short: "Run a Kubernetes validating admission webhook server",
+
+		Long: hd.Doc(`
+			Run a Kubernetes validating admission webhook server
+
+			The server handles the AdmissionReview requests sent by the Kubernetes API
+			server to a ValidatingAdmissionWebhook on the /validate path. The images used
+			by the containers, init containers and ephemeral containers of Pods, and of
+			the Pod templates of Deployments, ReplicaSets, StatefulSets, DaemonSets, Jobs,
+			CronJobs, ReplicationControllers and PodTemplates are validated with the
+			provided policy, the same way as with the "ec validate image" command. The
+			object is admitted only if all of its images conform to the policy, otherwise
+			it is denied with the violations found. Objects of other kinds are admitted.
+
+			The policy is loaded, and the policy sources are fetched, for each validation,
+			so changes to the policy are picked up by new validations. The result of the
+			validation of each image digest is kept for the duration given by --cache-ttl.
+
+			The /healthz path can be used for the liveness and readiness probes.
+		`),
+
+		Example: hd.Doc(`
+			Validate the images of the admitted workloads using the policy defined in the
+			EnterpriseContractPolicy custom resource named "default" in the
+			enterprise-contract-service Kubernetes namespace:
+
+			  ec serve admission --policy enterprise-contract-service/default \
+			    --tls-cert-file /etc/webhook/tls.crt --tls-key-file /etc/webhook/tls.key
+		`),
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			validator := func(ctx context.Context, ref string) ([]string, error) {
+				policyConfiguration, err := validate_utils.GetPolicyConfig(ctx, data.policyConfiguration)
+				if err != nil {
+					return nil, err
+				}
+
+				p, _, err := policy.PreProcessPolicy(ctx, policy.Options{
+					EffectiveTime: policy.Now,
+					Identity: cosign.Identity{
+						Issuer:        data.certificateOIDCIssuer,
+						IssuerRegExp:  data.certificateOIDCIssuerRegExp,
+						Subject:       data.certificateIdentity,
+						SubjectRegExp: data.certificateIdentityRegExp,
+					},
+					IgnoreRekor: data.ignoreRekor,
+					PolicyRef:   policyConfiguration,
+					PublicKey:   data.publicKey,
+					RekorURL:    data.rekorURL,
+				})
+				if err != nil {
+					return nil, err
+				}
+
+				evaluators, err := newEvaluators(ctx, p)
+				for _, e := range evaluators {
+					defer e.Destroy()
+				}
+				if err != nil {
+					return nil, err
+				}
+
+				component := app.SnapshotComponent{Name: ref, ContainerImage: ref}
+				out, err := validate(ctx, component, &app.SnapshotSpec{Components: []app.SnapshotComponent{component}}, p, evaluators, false)
+				if err != nil {
+					return nil, err
+				}
+
+				violations := []string{}
+				for _, v := range out.Violations() {
+					violations = append(violations, v.Message)
+				}
+
+				return violations, nil
+			}
+
+			mux := http.NewServeMux()
+			mux.Handle("/validate", admission.NewHandler(validator, data.cacheTTL))
+			mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+
+			server := &http.Server{
+				Addr:              data.address,
+				Handler:           mux,
+				ReadHeaderTimeout: 10 * time.Second,
+				// the requests carry the values set on the command context, e.g.
+				// the file system or the clients
+				BaseContext: func(net.Listener) context.Context {
+					return ctx
+				},
+			}
+
+			log.Infof("Serving admission requests on %s", data.address)
+			if err := serve(ctx, server, data.tlsCertFile, data.tlsKeyFile); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&data.policyConfiguration, "policy", "p", data.policyConfiguration, hd.Doc(`
+		Policy configuration as:
+		  * Kubernetes reference ([<namespace>/]<name>)
+		  * file (policy.yaml)
+		  * git reference (github.com/user/repo//default?ref=main), or
+		  * inline JSON ('{sources: {...}, identity: {...}}')`))
+
+	cmd.Flags().StringVarP(&data.publicKey, "public-key", "k", data.publicKey,
+		"path to the public key. Overrides publicKey from EnterpriseContractPolicy")
+
+	cmd.Flags().StringVarP(&data.rekorURL, "rekor-url", "r", data.rekorURL,
+		"Rekor URL. Overrides rekorURL from EnterpriseContractPolicy")
+
+	cmd.Flags().BoolVar(&data.ignoreRekor, "ignore-rekor", data.ignoreRekor,
+		"Skip Rekor transparency log checks during validation.")
+
+	cmd.Flags().StringVar(&data.certificateIdentity, "certificate-identity", data.certificateIdentity,
+		"URL of the certificate identity for keyless verification")
+
+	cmd.Flags().StringVar(&data.certificateIdentityRegExp, "certificate-identity-regexp", data.certificateIdentityRegExp,
+		"Regular expression for the URL of the certificate identity for keyless verification")
+
+	cmd.Flags().StringVar(&data.certificateOIDCIssuer, "certificate-oidc-issuer", data.certificateOIDCIssuer,
+		"URL of the certificate OIDC issuer for keyless verification")
+
+	cmd.Flags().StringVar(&data.certificateOIDCIssuerRegExp, "certificate-oidc-issuer-regexp", data.certificateOIDCIssuerRegExp,
+		"Regular expresssion for the URL of the certificate OIDC issuer for keyless verification")
+
+	cmd.Flags().StringVar(&data.address, "address", data.address,
+		"address, in the host:port form, to listen on")
+
+	cmd.Flags().StringVar(&data.tlsCertFile, "tls-cert-file", data.tlsCertFile,
+		"path to the PEM encoded TLS certificate of the server, including any intermediate certificates")
+
+	cmd.Flags().StringVar(&data.tlsKeyFile, "tls-key-file", data.tlsKeyFile,
+		"path to the PEM encoded private key of the TLS certificate")
+
+	cmd.Flags().DurationVar(&data.cacheTTL, "cache-ttl", data.cacheTTL, hd.Doc(`
+		How long to keep the result of the validation of an image digest. Use 0 to
+		validate images on every request.`))
+
+	for _, f := range []string{"policy", "tls-cert-file", "tls-key-file"} {
+		if err := cmd.MarkFlagRequired(f); err != nil {
+			panic(err)
+		}
+	}
+
+	return cmd
+}
+
+// newEvaluators returns an evaluator for each of the policy source groups
+func newEvaluators(ctx context.Context, p policy.Policy) ([]evaluator.Evaluator, error) {
+	evaluators := []evaluator.Evaluator{}
+	for _, sourceGroup := range p.Spec().Sources {
+		policySources := source.PolicySourcesFrom(sourceGroup)
+
+		var c evaluator.Evaluator
+		var err error
+		if utils.IsOpaEnabled() {
+			c, err = newOPAEvaluator(ctx, policySources, p, sourceGroup)
+		} else {
+			c, err = newConftestEvaluator(ctx, policySources, p, sourceGroup)
+		}
+		if err != nil {
+			return evaluators, err
+		}
+
+		evaluators = append(evaluators, c)
+	}
+
+	return evaluators, nil
+}
@@ -0,0 +1,179 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package serve
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/enterprise-contract/ec-cli/cmd/root"
+	"github.com/enterprise-contract/ec-cli/internal/image"
+	"github.com/enterprise-contract/ec-cli/internal/utils"
+)
+
+func setUpCobra(command *cobra.Command) *cobra.Command {
+	serveCmd := NewServeCmd()
+	serveCmd.AddCommand(command)
+	cmd := root.NewRootCmd()
+	cmd.AddCommand(serveCmd)
+	return cmd
+}
+
+// fakeAPIServer serves the EnterpriseContractPolicy named ns/policy and
+// returns the path to a kubeconfig file pointing to it
+func fakeAPIServer(t *testing.T) string {
+	policy := map[string]any{
+		"apiVersion": "appstudio.redhat.com/v1alpha1",
+		"kind":       "EnterpriseContractPolicy",
+		"metadata":   map[string]any{"name": "policy", "namespace": "ns"},
+		"spec":       map[string]any{"publicKey": utils.TestPublicKey},
+	}
+
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/apis/appstudio.redhat.com/v1alpha1/namespaces/ns/enterprisecontractpolicies/policy" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(policy)
+	}))
+	t.Cleanup(api.Close)
+
+	kubeconfig := filepath.Join(t.TempDir(), "kubeconfig")
+	require.NoError(t, os.WriteFile(kubeconfig, []byte(fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- name: fake
+  cluster:
+    server: %s
+contexts:
+- name: fake
+  context:
+    cluster: fake
+    namespace: ns
+current-context: fake
+`, api.URL)), 0600))
+
+	return kubeconfig
+}
+
+func TestServeAdmission(t *testing.T) {
+	r := httptest.NewServer(registry.New())
+	t.Cleanup(r.Close)
+
+	img, err := random.Image(512, 1)
+	require.NoError(t, err)
+	ref, err := name.ParseReference(fmt.Sprintf("%s/app:latest", r.Listener.Addr()))
+	require.NoError(t, err)
+	require.NoError(t, remote.Write(ref, img))
+
+	kubeconfig := fakeAPIServer(t)
+
+	var responses []*admissionv1.AdmissionResponse
+	original := serve
+	t.Cleanup(func() {
+		serve = original
+	})
+	serve = func(ctx context.Context, server *http.Server, certFile, keyFile string) error {
+		assert.Equal(t, "127.0.0.1:8443", server.Addr)
+		assert.Equal(t, "tls.crt", certFile)
+		assert.Equal(t, "tls.key", keyFile)
+
+		rec := httptest.NewRecorder()
+		server.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil).WithContext(ctx))
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		for _, object := range []string{
+			fmt.Sprintf(`{"kind": "Deployment", "metadata": {"name": "app"}, "spec": {"template": {"spec": {"containers": [{"name": "app", "image": %q}]}}}}`, ref),
+			`{"kind": "ConfigMap", "metadata": {"name": "config"}}`,
+		} {
+			review, err := json.Marshal(admissionv1.AdmissionReview{
+				TypeMeta: metav1.TypeMeta{APIVersion: "admission.k8s.io/v1", Kind: "AdmissionReview"},
+				Request: &admissionv1.AdmissionRequest{
+					UID:    "uid",
+					Object: runtime.RawExtension{Raw: []byte(object)},
+				},
+			})
+			require.NoError(t, err)
+
+			rec := httptest.NewRecorder()
+			server.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/validate", bytes.NewReader(review)).WithContext(ctx))
+			require.Equal(t, http.StatusOK, rec.Code)
+
+			var got admissionv1.AdmissionReview
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+			responses = append(responses, got.Response)
+		}
+
+		return http.ErrServerClosed
+	}
+
+	cmd := setUpCobra(serveAdmissionCmd(image.ValidateImage))
+	cmd.SetContext(context.Background())
+	cmd.SetArgs([]string{
+		"serve",
+		"admission",
+		"--kubeconfig", kubeconfig,
+		"--policy", "ns/policy",
+		"--ignore-rekor",
+		"--address", "127.0.0.1:8443",
+		"--tls-cert-file", "tls.crt",
+		"--tls-key-file", "tls.key",
+	})
+
+	require.NoError(t, cmd.Execute())
+
+	require.Len(t, responses, 2)
+
+	// the image is not signed
+	assert.False(t, responses[0].Allowed)
+	assert.Contains(t, responses[0].Result.Message, fmt.Sprintf("image %s: Image signature check failed", ref))
+
+	// nothing to validate
+	assert.True(t, responses[1].Allowed)
+}
+
+func TestServeAdmissionRequiredFlags(t *testing.T) {
+	cmd := setUpCobra(serveAdmissionCmd(image.ValidateImage))
+	cmd.SetContext(context.Background())
+	cmd.SetArgs([]string{"serve", "admission"})
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, `required flag(s) "policy", "tls-cert-file", "tls-key-file" not set`)
+}
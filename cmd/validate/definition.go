@@ -0,0 +1,261 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package validate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/trace"
+	"sort"
+	"strings"
+
+	hd "github.com/MakeNowJust/heredoc"
+	ecc "github.com/enterprise-contract/enterprise-contract-controller/api/v1alpha1"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/enterprise-contract/ec-cli/internal/format"
+	"github.com/enterprise-contract/ec-cli/internal/input"
+	"github.com/enterprise-contract/ec-cli/internal/output"
+	"github.com/enterprise-contract/ec-cli/internal/policy"
+	"github.com/enterprise-contract/ec-cli/internal/policy/source"
+	"github.com/enterprise-contract/ec-cli/internal/utils"
+)
+
+type DefinitionValidationFunc func(context.Context, string, []source.PolicySource, []string, bool) (*output.Output, error)
+
+func validateDefinitionCmd(validate DefinitionValidationFunc) *cobra.Command {
+	data := struct {
+		data       []string
+		filePaths  []string
+		info       bool
+		namespaces []string
+		output     []string
+		policies   []string
+		policy     policy.Policy
+		sources    []source.PolicySource
+		strict     bool
+		workers    int
+	}{
+		strict:  true,
+		workers: 5,
+	}
+	cmd := &cobra.Command{
+		Use:   "definition",
+		Short: "Validate definition file conformance with the provided policies",
+		Long: hd.Doc(`
+			Validate conformance of Tekton Pipeline and Task definition files with the provided policies
+
+			For each file, validation is performed to determine if the definition conforms to
+			the rego policies from the provided policy sources. The rules from all namespaces
+			are evaluated, unless namespaces are provided with the --namespace flag. The report
+			has the same structure as the report of the "ec validate input" command.
+			`),
+		Example: hd.Doc(`
+			Validate a Pipeline definition against the rules in the pipeline namespaces of
+			the policy sources from a git repository
+
+			  ec validate definition --file pipeline.yaml \
+			    --policy git::https://github.com/enterprise-contract/ec-policies//policy/lib \
+			    --policy git::https://github.com/enterprise-contract/ec-policies//policy/pipeline \
+			    --namespace pipeline.basic --namespace pipeline.required_tasks
+
+			Validate all definitions in a directory, providing additional data to the rules
+
+			  ec validate definition --file .tekton/ --policy oci::quay.io/org/policy:latest \
+			    --data git::https://github.com/org/data.git//data
+`),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			spec := ecc.EnterpriseContractPolicySpec{
+				Sources: []ecc.Source{
+					{
+						Policy: data.policies,
+						Data:   data.data,
+					},
+				},
+			}
+
+			p, err := policy.NewOfflinePolicy(cmd.Context(), policy.Now)
+			if err != nil {
+				return err
+			}
+
+			data.policy = p.WithSpec(spec)
+			data.sources = source.PolicySourcesFrom(spec.Sources[0])
+
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if trace.IsEnabled() {
+				ctx, task := trace.NewTask(cmd.Context(), "ec:validate-definitions")
+				cmd.SetContext(ctx)
+				defer task.End()
+			}
+
+			type result struct {
+				err   error
+				input input.Input
+			}
+
+			showSuccesses, _ := cmd.Flags().GetBool("show-successes")
+
+			// Set numWorkers to the value from our flag. The default is 5.
+			numWorkers := data.workers
+
+			jobs := make(chan string, len(data.filePaths))
+			results := make(chan result, len(data.filePaths))
+
+			// worker function processes one file path at a time.
+			worker := func(id int, jobs <-chan string, results chan<- result) {
+				log.Debugf("Starting worker %d", id)
+				for fpath := range jobs {
+					ctx := cmd.Context()
+					var task *trace.Task
+					if trace.IsEnabled() {
+						ctx, task = trace.NewTask(ctx, "ec:validate-definition")
+						trace.Logf(ctx, "", "workerID=%d, file=%s", id, fpath)
+					}
+
+					out, err := validate(ctx, fpath, data.sources, data.namespaces, data.info)
+					res := result{
+						err: err,
+						input: input.Input{
+							FilePath: fpath,
+							Success:  err == nil,
+						},
+					}
+
+					if err == nil {
+						res.input.Violations = out.Violations()
+						res.input.Warnings = out.Warnings()
+
+						successes := out.Successes()
+						res.input.SuccessCount = len(successes)
+						if showSuccesses {
+							res.input.Successes = successes
+						}
+						res.input.Success = (len(res.input.Violations) == 0)
+					}
+
+					if task != nil {
+						task.End()
+					}
+					results <- res
+				}
+				log.Debugf("Done with worker %d", id)
+			}
+
+			// Start the worker pool
+			for i := 0; i < numWorkers; i++ {
+				go worker(i, jobs, results)
+			}
+
+			// Push all jobs (file paths) to the jobs channel
+			for _, f := range data.filePaths {
+				jobs <- f
+			}
+			close(jobs)
+
+			var inputs []input.Input
+			var allErrors error = nil
+
+			// Collect all results
+			for i := 0; i < len(data.filePaths); i++ {
+				r := <-results
+				if r.err != nil {
+					e := fmt.Errorf("error validating file %s: %w", r.input.FilePath, r.err)
+					allErrors = errors.Join(allErrors, e)
+				} else {
+					inputs = append(inputs, r.input)
+				}
+			}
+			close(results)
+
+			if allErrors != nil {
+				return allErrors
+			}
+
+			// Sort inputs for consistent output
+			sort.Slice(inputs, func(i, j int) bool {
+				return inputs[i].FilePath > inputs[j].FilePath
+			})
+
+			report, err := input.NewReport(inputs, data.policy, nil)
+			if err != nil {
+				return err
+			}
+
+			p := format.NewTargetParser(input.JSON, format.Options{ShowSuccesses: showSuccesses}, cmd.OutOrStdout(), utils.FS(cmd.Context()))
+			if err := report.WriteAll(data.output, p); err != nil {
+				return err
+			}
+
+			if data.strict && !report.Success {
+				return errors.New("success criteria not met")
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringSliceVarP(&data.filePaths, "file", "f", data.filePaths, hd.Doc(`
+		path to a definition YAML/JSON file, a directory of definition files, or an inline
+		YAML/JSON definition (required)`))
+
+	cmd.Flags().StringSliceVarP(&data.policies, "policy", "p", data.policies, hd.Doc(`
+		Policy source reference, e.g. git::https://github.com/org/repo//policy or
+		oci::quay.io/org/policy:tag. May be used multiple times (required)`))
+
+	cmd.Flags().StringSliceVar(&data.data, "data", data.data, hd.Doc(`
+		Data source reference, e.g. git::https://github.com/org/repo//data. May be used
+		multiple times`))
+
+	cmd.Flags().StringSliceVar(&data.namespaces, "namespace", data.namespaces, hd.Doc(`
+		Namespace of the policy rules to evaluate, e.g. pipeline.basic. May be used multiple
+		times. The rules from all namespaces are evaluated when not provided`))
+
+	validOutputFormats := input.OutputFormats
+	cmd.Flags().StringSliceVarP(&data.output, "output", "o", data.output, hd.Doc(`
+		Write output to a file in a specific format, e.g. yaml=/tmp/output.yaml. Use empty string
+		path for stdout, e.g. yaml. May be used multiple times. Possible formats are:
+		`+strings.Join(validOutputFormats, ", ")+`. In following format and file path
+		additional options can be provided in key=value form following the question
+		mark (?) sign, for example: --output text=output.txt?show-successes=false
+	`))
+
+	cmd.Flags().BoolVarP(&data.strict, "strict", "s", data.strict,
+		"Return non-zero status on non-successful validation")
+
+	cmd.Flags().BoolVar(&data.info, "info", data.info, hd.Doc(`
+		Include additional information on the failures. For instance for policy
+		violations, include the title and the description of the failed policy
+		rule.`))
+
+	cmd.Flags().IntVar(&data.workers, "workers", data.workers, hd.Doc(`
+		Number of workers to use for validation. Defaults to 5.`))
+
+	if err := cmd.MarkFlagRequired("file"); err != nil {
+		panic(err)
+	}
+
+	if err := cmd.MarkFlagRequired("policy"); err != nil {
+		panic(err)
+	}
+
+	return cmd
+}
@@ -0,0 +1,179 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package validate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/enterprise-contract/ec-cli/internal/evaluator"
+	"github.com/enterprise-contract/ec-cli/internal/output"
+	"github.com/enterprise-contract/ec-cli/internal/policy/source"
+	"github.com/enterprise-contract/ec-cli/internal/utils"
+)
+
+func setUpValidateDefinitionCmd(validate DefinitionValidationFunc) (*cobra.Command, *bytes.Buffer) {
+	cmd := validateDefinitionCmd(validate)
+
+	ctx := utils.WithFS(context.Background(), afero.NewMemMapFs())
+	cmd.SetContext(ctx)
+
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+
+	return cmd, &out
+}
+
+func Test_ValidateDefinitionCmd_Success(t *testing.T) {
+	var gotSources []source.PolicySource
+	var gotNamespaces []string
+	var gotFiles []string
+	validate := func(_ context.Context, fpath string, sources []source.PolicySource, namespaces []string, _ bool) (*output.Output, error) {
+		gotSources = sources
+		gotNamespaces = namespaces
+		gotFiles = append(gotFiles, fpath)
+		return &output.Output{
+			PolicyCheck: []evaluator.Outcome{
+				{
+					Warnings:  []evaluator.Result{{Message: "Careful"}},
+					Successes: []evaluator.Result{{Message: "Pass"}},
+				},
+			},
+		}, nil
+	}
+
+	cmd, buf := setUpValidateDefinitionCmd(validate)
+	cmd.SetArgs([]string{
+		"--file", "/pipeline.yaml",
+		"--file", "/task.yaml",
+		"--policy", "git::https://github.com/org/policy//policy/lib",
+		"--policy", "git::https://github.com/org/policy//policy/pipeline",
+		"--data", "git::https://github.com/org/data//data",
+		"--namespace", "pipeline.basic",
+		"--workers", "1",
+	})
+
+	require.NoError(t, cmd.Execute())
+
+	assert.ElementsMatch(t, []string{"/pipeline.yaml", "/task.yaml"}, gotFiles)
+	assert.Equal(t, []string{"pipeline.basic"}, gotNamespaces)
+	assert.Equal(t, []source.PolicySource{
+		&source.PolicyUrl{Url: "git::https://github.com/org/policy//policy/lib", Kind: source.PolicyKind},
+		&source.PolicyUrl{Url: "git::https://github.com/org/policy//policy/pipeline", Kind: source.PolicyKind},
+		&source.PolicyUrl{Url: "git::https://github.com/org/data//data", Kind: source.DataKind},
+	}, gotSources)
+
+	var report struct {
+		Success   bool `json:"success"`
+		FilePaths []struct {
+			FilePath string             `json:"filepath"`
+			Success  bool               `json:"success"`
+			Warnings []evaluator.Result `json:"warnings"`
+		} `json:"filepaths"`
+		Policy struct {
+			Sources []struct {
+				Policy []string `json:"policy"`
+				Data   []string `json:"data"`
+			} `json:"sources"`
+		} `json:"policy"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &report))
+
+	assert.True(t, report.Success)
+	require.Len(t, report.FilePaths, 2)
+	assert.Equal(t, "/task.yaml", report.FilePaths[0].FilePath)
+	assert.Equal(t, "/pipeline.yaml", report.FilePaths[1].FilePath)
+	assert.Equal(t, []evaluator.Result{{Message: "Careful"}}, report.FilePaths[0].Warnings)
+	require.Len(t, report.Policy.Sources, 1)
+	assert.Equal(t, []string{"git::https://github.com/org/policy//policy/lib", "git::https://github.com/org/policy//policy/pipeline"}, report.Policy.Sources[0].Policy)
+	assert.Equal(t, []string{"git::https://github.com/org/data//data"}, report.Policy.Sources[0].Data)
+}
+
+func Test_ValidateDefinitionCmd_Failure(t *testing.T) {
+	validate := func(_ context.Context, _ string, _ []source.PolicySource, _ []string, _ bool) (*output.Output, error) {
+		return nil, errors.New("validation failed")
+	}
+
+	cmd, _ := setUpValidateDefinitionCmd(validate)
+	cmd.SetArgs([]string{
+		"--file", "/pipeline.yaml",
+		"--policy", "registry.io/policy:latest",
+	})
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, "error validating file /pipeline.yaml: validation failed")
+}
+
+func Test_ValidateDefinitionCmd_Strict(t *testing.T) {
+	validate := func(_ context.Context, _ string, _ []source.PolicySource, _ []string, _ bool) (*output.Output, error) {
+		return &output.Output{
+			PolicyCheck: []evaluator.Outcome{
+				{
+					Failures: []evaluator.Result{{Message: "Some violation"}},
+				},
+			},
+		}, nil
+	}
+
+	cases := []struct {
+		name   string
+		strict string
+		err    string
+	}{
+		{name: "strict", strict: "true", err: "success criteria not met"},
+		{name: "non strict", strict: "false"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cmd, buf := setUpValidateDefinitionCmd(validate)
+			cmd.SetArgs([]string{
+				"--file", "/pipeline.yaml",
+				"--policy", "registry.io/policy:latest",
+				"--strict=" + c.strict,
+				"--output", "yaml",
+			})
+
+			err := cmd.Execute()
+			if c.err != "" {
+				assert.EqualError(t, err, c.err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Contains(t, buf.String(), "msg: Some violation")
+		})
+	}
+}
+
+func Test_ValidateDefinitionCmd_RequiredFlags(t *testing.T) {
+	cmd, _ := setUpValidateDefinitionCmd(nil)
+	cmd.SetArgs([]string{})
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, `required flag(s) "file", "policy" not set`)
+}
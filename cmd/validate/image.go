@@ -24,8 +24,10 @@ import (
 	"runtime/trace"
 	"sort"
 	"strings"
+	"time"
 
 	hd "github.com/MakeNowJust/heredoc"
+	"github.com/google/go-containerregistry/pkg/name"
 	app "github.com/konflux-ci/application-api/api/v1alpha1"
 	"github.com/sigstore/cosign/v2/pkg/cosign"
 	log "github.com/sirupsen/logrus"
@@ -33,13 +35,18 @@ import (
 	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 
 	"github.com/enterprise-contract/ec-cli/internal/applicationsnapshot"
+	"github.com/enterprise-contract/ec-cli/internal/cache"
 	"github.com/enterprise-contract/ec-cli/internal/evaluator"
+	"github.com/enterprise-contract/ec-cli/internal/forecast"
 	"github.com/enterprise-contract/ec-cli/internal/format"
+	"github.com/enterprise-contract/ec-cli/internal/image"
+	"github.com/enterprise-contract/ec-cli/internal/offline"
 	"github.com/enterprise-contract/ec-cli/internal/output"
 	"github.com/enterprise-contract/ec-cli/internal/policy"
 	"github.com/enterprise-contract/ec-cli/internal/policy/source"
 	"github.com/enterprise-contract/ec-cli/internal/utils"
 	validate_utils "github.com/enterprise-contract/ec-cli/internal/validate"
+	"github.com/enterprise-contract/ec-cli/internal/vsa"
 )
 
 type imageValidationFunc func(context.Context, app.SnapshotComponent, *app.SnapshotSpec, policy.Policy, []evaluator.Evaluator, bool) (*output.Output, error)
@@ -56,6 +63,7 @@ func validateImageCmd(validate imageValidationFunc) *cobra.Command {
 		effectiveTime               string
 		extraRuleData               []string
 		filePath                    string // Deprecated: images replaced this
+		forecast                    time.Duration
 		imageRef                    string
 		info                        bool
 		input                       string // Deprecated: images replaced this
@@ -63,9 +71,11 @@ func validateImageCmd(validate imageValidationFunc) *cobra.Command {
 		output                      []string
 		outputFile                  string
 		policy                      policy.Policy
+		lockfile                    string
 		policyConfiguration         string
 		publicKey                   string
 		rekorURL                    string
+		trustedRoot                 string
 		snapshot                    string
 		spec                        *app.SnapshotSpec
 		strict                      bool
@@ -73,9 +83,17 @@ func validateImageCmd(validate imageValidationFunc) *cobra.Command {
 		noColor                     bool
 		forceColor                  bool
 		workers                     int
+		offlineLayout               string
+		vsaSigning                  vsa.Options
+		vsaAttach                   bool
+		vsaFile                     string
+		baseImageDepth              int
+		verifyBaseImages            bool
+		resultCache                 bool
 	}{
-		strict:  true,
-		workers: 5,
+		strict:         true,
+		workers:        5,
+		baseImageDepth: image.DefaultBaseImageDepth,
 	}
 
 	validOutputFormats := applicationsnapshot.OutputFormats
@@ -99,6 +117,11 @@ func validateImageCmd(validate imageValidationFunc) *cobra.Command {
 			to the "cosign verify-attestation" command. This stage temporarily stores the
 			attestations for usage in the next stage.
 
+			In both stages, signatures and attestations attached to the image as OCI
+			referrers, including Sigstore bundles and DSSE envelopes, are verified in
+			addition to those found using the cosign tag scheme. The OCI referrers API
+			is used when the registry supports it, otherwise the referrers tag schema.
+
 			The final stage verifies the attestations conform to rego policies defined in
 			the EnterpriseContractPolicy.
 
@@ -170,6 +193,19 @@ func validateImageCmd(validate imageValidationFunc) *cobra.Command {
 
 			  ec validate image --image registry/name:tag --output yaml --output appstudio=<path>
 
+			Write output in SARIF format to a file, to be uploaded to a code scanning tool
+
+			  ec validate image --image registry/name:tag --output sarif=<path>
+
+			Write an HTML report of the validation of all images to a file
+
+			  ec validate image --images my-app.yaml --output html=report.html
+
+			Show which policy rules are going to start failing within the next 30 days,
+			and when, in addition to the current validation results
+
+			  ec validate image --image registry/name:tag --output text --forecast 720h
+
 
 			Validate a single image with keyless workflow.
 
@@ -184,6 +220,18 @@ func validateImageCmd(validate imageValidationFunc) *cobra.Command {
 			    --certificate-identity-regexp '^https://github\.com' \
 			    --certificate-oidc-issuer-regexp 'githubusercontent' \
 			    --rekor-url 'https://rekor.sigstore.dev'
+
+			Validate images without network access using an OCI image layout created by
+			the "ec offline export" command.
+
+			  ec validate image --images my-app.yaml --policy policy.yaml \
+			    --offline-layout my-app-layout
+
+			Sign the Verification Summary Attestation (VSA) with a key and attach it to
+			each validated image:
+
+			  ec validate image --images my-app.yaml --policy policy.yaml \
+			    --vsa-signing-key cosign.key --vsa-attach
 		`),
 
 		PreRunE: func(cmd *cobra.Command, args []string) (allErrors error) {
@@ -195,6 +243,26 @@ func validateImageCmd(validate imageValidationFunc) *cobra.Command {
 				cmd.SetContext(ctx)
 			}
 
+			if data.offlineLayout != "" {
+				var err error
+				if ctx, err = offline.WithLayout(ctx, data.offlineLayout); err != nil {
+					return err
+				}
+				cmd.SetContext(ctx)
+			}
+
+			if data.baseImageDepth < 0 {
+				allErrors = errors.Join(allErrors, errors.New("the --base-image-depth must not be negative"))
+			} else {
+				ctx = image.WithBaseImageDepth(ctx, data.baseImageDepth)
+				ctx = image.WithVerifyBaseImages(ctx, data.verifyBaseImages)
+				cmd.SetContext(ctx)
+			}
+
+			if (data.vsaAttach || data.vsaFile != "") != data.vsaSigning.Enabled() {
+				allErrors = errors.Join(allErrors, errors.New("--vsa-signing-key or --vsa-keyless must be used together with --vsa-attach or --vsa-file"))
+			}
+
 			if s, err := applicationsnapshot.DetermineInputSpec(ctx, applicationsnapshot.Input{
 				File:     data.filePath,
 				JSON:     data.input,
@@ -207,7 +275,17 @@ func validateImageCmd(validate imageValidationFunc) *cobra.Command {
 				data.spec = s
 			}
 
-			policyConfiguration, err := validate_utils.GetPolicyConfig(ctx, data.policyConfiguration)
+			var lock *policy.Lockfile
+			if data.lockfile != "" {
+				l, err := policy.ReadLockfile(utils.FS(ctx), data.lockfile)
+				if err != nil {
+					allErrors = errors.Join(allErrors, err)
+					return
+				}
+				lock = l
+			}
+
+			policyConfiguration, err := validate_utils.GetLockedPolicyConfig(ctx, data.policyConfiguration, lock)
 			if err != nil {
 				allErrors = errors.Join(allErrors, err)
 				return
@@ -223,9 +301,11 @@ func validateImageCmd(validate imageValidationFunc) *cobra.Command {
 					SubjectRegExp: data.certificateIdentityRegExp,
 				},
 				IgnoreRekor: data.ignoreRekor,
+				Lockfile:    lock,
 				PolicyRef:   data.policyConfiguration,
 				PublicKey:   data.publicKey,
 				RekorURL:    data.rekorURL,
+				TrustedRoot: data.trustedRoot,
 			}
 
 			// We're not currently using the policyCache returned from PreProcessPolicy, but we could
@@ -309,34 +389,78 @@ func validateImageCmd(validate imageValidationFunc) *cobra.Command {
 				policyInput []byte
 			}
 
+			if data.forecast < 0 {
+				return errors.New("the --forecast duration must not be negative")
+			}
+
 			appComponents := data.spec.Components
-			evaluators := []evaluator.Evaluator{}
 
-			// Return an evaluator for each of these
-			for _, sourceGroup := range data.policy.Spec().Sources {
-				// Todo: Make each fetch run concurrently
-				log.Debugf("Fetching policy source group '%s'", sourceGroup.Name)
-				policySources := source.PolicySourcesFrom(sourceGroup)
+			// Return an evaluator for each of the policy source groups
+			newEvaluators := func(p evaluator.ConfigProvider) ([]evaluator.Evaluator, error) {
+				evaluators := []evaluator.Evaluator{}
+				for _, sourceGroup := range data.policy.Spec().Sources {
+					// Todo: Make each fetch run concurrently
+					log.Debugf("Fetching policy source group '%s'", sourceGroup.Name)
+					policySources := source.PolicySourcesFrom(sourceGroup)
+
+					for _, policySource := range policySources {
+						log.Debugf("policySource: %#v", policySource)
+					}
+
+					var c evaluator.Evaluator
+					var err error
+					if utils.IsOpaEnabled() {
+						c, err = newOPAEvaluator(cmd.Context(), policySources, p, sourceGroup)
+					} else {
+						c, err = newConftestEvaluator(cmd.Context(), policySources, p, sourceGroup)
+					}
+
+					if err != nil {
+						log.Debug("Failed to initialize the policy evaluator!")
+						return evaluators, err
+					}
 
-				for _, policySource := range policySources {
-					log.Debugf("policySource: %#v", policySource)
+					evaluators = append(evaluators, c)
 				}
 
-				var c evaluator.Evaluator
-				var err error
-				if utils.IsOpaEnabled() {
-					c, err = newOPAEvaluator()
-				} else {
-					c, err = newConftestEvaluator(cmd.Context(), policySources, data.policy, sourceGroup)
+				return evaluators, nil
+			}
+
+			evaluators, err := newEvaluators(data.policy)
+			for _, e := range evaluators {
+				defer e.Destroy()
+			}
+			if err != nil {
+				return err
+			}
+
+			// When forecasting, the policy input of each component is also
+			// evaluated by evaluators using the effective time at the end of
+			// the forecast
+			var forecastEvaluators []evaluator.Evaluator
+			forecastFrom := data.policy.EffectiveTime()
+			forecastUntil := forecastFrom.Add(data.forecast)
+			if data.forecast > 0 {
+				forecastEvaluators, err = newEvaluators(forecast.At(data.policy, forecastUntil))
+				for _, e := range forecastEvaluators {
+					defer e.Destroy()
+				}
+				if err != nil {
+					return err
 				}
+			}
 
+			if data.resultCache {
+				c, err := cache.ConfigFromEnv()
 				if err != nil {
-					log.Debug("Failed to initialize the conftest evaluator!")
 					return err
 				}
 
-				evaluators = append(evaluators, c)
-				defer c.Destroy()
+				r, err := image.NewResultCache(utils.FS(cmd.Context()), c, data.policy)
+				if err != nil {
+					return err
+				}
+				cmd.SetContext(image.WithResultCache(cmd.Context(), r))
 			}
 
 			showSuccesses, _ := cmd.Flags().GetBool("show-successes")
@@ -386,6 +510,16 @@ func validateImageCmd(validate imageValidationFunc) *cobra.Command {
 						}
 						res.component.ContainerImage = out.ImageURL
 						res.policyInput = out.PolicyInput
+
+						if len(forecastEvaluators) > 0 && out.PolicyInput != nil {
+							future, expirations, ferr := forecast.Evaluate(ctx, out.PolicyInput, out.ImageURL, forecastEvaluators, forecastFrom, forecastUntil)
+							if ferr != nil {
+								// forecasting is advisory, it doesn't change the outcome of the validation
+								log.Warnf("Unable to forecast the policy results of %s: %v", out.ImageURL, ferr)
+							} else {
+								res.component.Forecast = forecast.Compare(res.component.Violations, future, forecastFrom, forecastUntil, expirations)
+							}
+						}
 					}
 					res.component.Success = err == nil && len(res.component.Violations) == 0
 
@@ -447,12 +581,22 @@ func validateImageCmd(validate imageValidationFunc) *cobra.Command {
 			if err != nil {
 				return err
 			}
+			if data.forecast > 0 {
+				until := forecastUntil.UTC()
+				report.ForecastUntil = &until
+			}
 			p := format.NewTargetParser(applicationsnapshot.JSON, format.Options{ShowSuccesses: showSuccesses}, cmd.OutOrStdout(), utils.FS(cmd.Context()))
 			utils.SetColorEnabled(data.noColor, data.forceColor)
 			if err := report.WriteAll(data.output, p); err != nil {
 				return err
 			}
 
+			if data.vsaSigning.Enabled() {
+				if err := publishVSA(cmd.Context(), report, data.vsaSigning, data.vsaAttach, data.vsaFile); err != nil {
+					return err
+				}
+			}
+
 			if data.strict && !report.Success {
 				return errors.New("success criteria not met")
 			}
@@ -468,6 +612,11 @@ func validateImageCmd(validate imageValidationFunc) *cobra.Command {
 		  * git reference (github.com/user/repo//default?ref=main), or
 		  * inline JSON ('{sources: {...}, identity: {...}}')")`))
 
+	cmd.Flags().StringVar(&data.lockfile, "lockfile", data.lockfile, hd.Doc(`
+		Use the immutable references of the policy configuration and the policy and
+		data sources from the given lockfile, see "ec policy lock". Fails if the policy
+		has drifted from the lockfile.`))
+
 	cmd.Flags().StringVarP(&data.imageRef, "image", "i", data.imageRef, "OCI image reference")
 
 	cmd.Flags().StringVarP(&data.publicKey, "public-key", "k", data.publicKey,
@@ -479,6 +628,13 @@ func validateImageCmd(validate imageValidationFunc) *cobra.Command {
 	cmd.Flags().BoolVar(&data.ignoreRekor, "ignore-rekor", data.ignoreRekor,
 		"Skip Rekor transparency log checks during validation.")
 
+	cmd.Flags().StringVar(&data.trustedRoot, "trusted-root", data.trustedRoot, hd.Doc(`
+		path to a Sigstore trusted_root.json file with the certificate authorities,
+		transparency logs and timestamping authorities to verify signatures with,
+		instead of fetching them using TUF. Sigstore bundles attached to the image
+		are verified fully offline using it.
+	`))
+
 	cmd.Flags().StringVar(&data.certificateIdentity, "certificate-identity", data.certificateIdentity,
 		"URL of the certificate identity for keyless verification")
 
@@ -523,6 +679,13 @@ func validateImageCmd(validate imageValidationFunc) *cobra.Command {
 		a RFC3339 formatted value, e.g. 2022-11-18T00:00:00Z.
 	`))
 
+	cmd.Flags().DurationVar(&data.forecast, "forecast", data.forecast, hd.Doc(`
+		Also evaluate the policy rules at the effective time advanced by the given
+		duration, e.g. 720h, and report per component the rules that are going to
+		start failing by then, and when, based on the effective_on date of the rules
+		and the expires_on date of the trusted tasks.
+	`))
+
 	cmd.Flags().StringSliceVar(&data.extraRuleData, "extra-rule-data", data.extraRuleData, hd.Doc(`
 		Extra data to be provided to the Rego policy evaluator. Use format 'key=value'. May be used multiple times.
 	`))
@@ -545,6 +708,59 @@ func validateImageCmd(validate imageValidationFunc) *cobra.Command {
 	cmd.Flags().IntVar(&data.workers, "workers", data.workers, hd.Doc(`
 		Number of workers to use for validation. Defaults to 5.`))
 
+	cmd.Flags().StringVar(&data.offlineLayout, "offline-layout", data.offlineLayout, hd.Doc(`
+		Resolve images, their signatures and attestations, and policy and data sources
+		stored in OCI registries from the OCI image layout in the given directory instead
+		of from the remote registries. See "ec offline export".`))
+
+	cmd.Flags().IntVar(&data.baseImageDepth, "base-image-depth", data.baseImageDepth, hd.Doc(`
+		Number of base images, following the chain of base images of each image, to
+		include with their config in the policy input under image.parent. Zero excludes
+		the base images.`))
+
+	cmd.Flags().BoolVar(&data.verifyBaseImages, "verify-base-images", data.verifyBaseImages, hd.Doc(`
+		Verify the signatures and attestations of the base images with the same public
+		key or keyless identity as the images, and include the verified signatures and
+		attestations in the policy input under image.parent. Failing to verify those is
+		reported as a warning and does not fail the validation.`))
+
+	cmd.Flags().BoolVar(&data.resultCache, "result-cache", data.resultCache, hd.Doc(`
+		Reuse the results of previous validations of the same image digest, with the
+		same policy, ec version and effective day, unless new signatures or attestations
+		were attached to the image since. The results are stored in the cache directory,
+		see "ec cache".`))
+
+	cmd.Flags().StringVar(&data.vsaSigning.KeyRef, "vsa-signing-key", data.vsaSigning.KeyRef, hd.Doc(`
+		Sign the Verification Summary Attestation (VSA) with the given private key, a path
+		to a file or a KMS URI. The password of the key is read from the COSIGN_PASSWORD
+		environment variable.`))
+
+	cmd.Flags().BoolVar(&data.vsaSigning.Keyless, "vsa-keyless", data.vsaSigning.Keyless, hd.Doc(`
+		Sign the Verification Summary Attestation (VSA) using a short-lived certificate
+		issued by Fulcio for the OIDC identity of the caller. The signature is always
+		recorded in Rekor, see --vsa-rekor-url.`))
+
+	cmd.Flags().StringVar(&data.vsaSigning.FulcioURL, "vsa-fulcio-url", data.vsaSigning.FulcioURL,
+		"URL of the Fulcio instance used with --vsa-keyless, defaults to the public Sigstore instance")
+
+	cmd.Flags().StringVar(&data.vsaSigning.IDToken, "vsa-identity-token", data.vsaSigning.IDToken, hd.Doc(`
+		OIDC identity token presented to Fulcio with --vsa-keyless. When not provided an
+		ambient token, e.g. from GitHub Actions, or the interactive flow is used.`))
+
+	cmd.Flags().StringVar(&data.vsaSigning.RekorURL, "vsa-rekor-url", data.vsaSigning.RekorURL, hd.Doc(`
+		URL of the Rekor instance to record the signed VSA in. When not provided, VSAs
+		signed with --vsa-keyless are recorded in the public Sigstore instance and VSAs
+		signed with --vsa-signing-key are not recorded.`))
+
+	cmd.Flags().BoolVar(&data.vsaAttach, "vsa-attach", data.vsaAttach, hd.Doc(`
+		Attach the signed VSA of each component to the component image as a cosign
+		attestation, replacing any VSA previously attached.`))
+
+	cmd.Flags().StringVar(&data.vsaFile, "vsa-file", data.vsaFile, hd.Doc(`
+		Write the signed VSA of all components to the given file as a DSSE envelope.`))
+
+	cmd.MarkFlagsMutuallyExclusive("vsa-signing-key", "vsa-keyless")
+
 	if len(data.input) > 0 || len(data.filePath) > 0 || len(data.images) > 0 {
 		if err := cmd.MarkFlagRequired("image"); err != nil {
 			panic(err)
@@ -554,6 +770,47 @@ func validateImageCmd(validate imageValidationFunc) *cobra.Command {
 	return cmd
 }
 
+// publishVSA signs the Verification Summary Attestation and attaches it to
+// each component image, and/or writes it to a file
+func publishVSA(ctx context.Context, report applicationsnapshot.Report, opts vsa.Options, attach bool, file string) error {
+	signer, err := vsa.NewSigner(ctx, opts)
+	if err != nil {
+		return err
+	}
+	defer signer.Close()
+
+	if attach {
+		for _, c := range report.Components {
+			statement, err := applicationsnapshot.NewComponentVSA(report, c)
+			if err != nil {
+				return err
+			}
+
+			ref, err := name.NewDigest(c.ContainerImage)
+			if err != nil {
+				return err
+			}
+
+			if err := signer.Attest(ctx, ref, statement); err != nil {
+				return err
+			}
+		}
+	}
+
+	if file != "" {
+		statement, err := applicationsnapshot.NewSnapshotVSA(report)
+		if err != nil {
+			return err
+		}
+
+		if err := signer.WriteFile(ctx, utils.FS(ctx), file, statement); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // find if the slice contains "value" output
 func containsOutput(data []string, value string) bool {
 	for _, item := range data {
@@ -29,12 +29,14 @@ import (
 
 	hd "github.com/MakeNowJust/heredoc"
 	ociMetadata "github.com/conforma/go-gather/gather/oci"
+	ecc "github.com/enterprise-contract/enterprise-contract-controller/api/v1alpha1"
 	"github.com/gkampitakis/go-snaps/snaps"
 	app "github.com/konflux-ci/application-api/api/v1alpha1"
 	"github.com/sigstore/cosign/v2/pkg/cosign"
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 
 	"github.com/enterprise-contract/ec-cli/internal/applicationsnapshot"
 	"github.com/enterprise-contract/ec-cli/internal/evaluator"
@@ -615,6 +617,90 @@ func Test_ValidateImageCommandJSONPolicyFile(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func Test_ValidateImageCommandLockfile(t *testing.T) {
+	pinned := "oci::registry/locked-policy:latest@sha256:da54bca5477bf4e3449bc37de1822888fa0fbb8d89c640218cb31b987374d357"
+
+	cases := []struct {
+		name     string
+		lockfile string
+		err      string
+	}{
+		{
+			name: "locked",
+			lockfile: `digest: sha256:44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a
+sources:
+- digest: sha256:fe2c5f9a2a0e2a86e7da26a01090c4e74ef5cdbc93e5d915ab36b3a2be73c0fa
+  policy:
+  - url: registry/locked-policy:latest
+    pinned: ` + pinned + `
+`,
+		},
+		{
+			name: "drifted",
+			lockfile: `digest: sha256:44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a
+sources:
+- digest: sha256:fe2c5f9a2a0e2a86e7da26a01090c4e74ef5cdbc93e5d915ab36b3a2be73c0fa
+  policy:
+  - url: registry/locked-policy:v1
+    pinned: ` + pinned + `
+`,
+			err: "the policy has drifted from the lockfile",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			validateImageCmd := validateImageCmd(happyValidator())
+			cmd := setUpCobra(validateImageCmd)
+
+			client := fake.FakeClient{}
+			commonMockClient(&client)
+			fs := afero.NewMemMapFs()
+			ctx := utils.WithFS(context.Background(), fs)
+			ctx = oci.WithClient(ctx, &client)
+
+			// only the pinned URL from the lockfile is fetched
+			mdl := MockDownloader{}
+			mdl.On("Download", mock.Anything, pinned, false).Return(&ociMetadata.OCIMetadata{Digest: "sha256:da54bca5477bf4e3449bc37de1822888fa0fbb8d89c640218cb31b987374d357"}, nil)
+			ctx = context.WithValue(ctx, source.DownloaderFuncKey, &mdl)
+
+			cmd.SetContext(ctx)
+
+			require.NoError(t, afero.WriteFile(fs, "/policy.yaml", []byte(`sources:
+- policy:
+  - registry/locked-policy:latest
+`), 0644))
+			require.NoError(t, afero.WriteFile(fs, "/policy.lock.yaml", []byte(c.lockfile), 0644))
+
+			args := append(rootArgs, []string{
+				"--image",
+				"registry/image:tag",
+				"--public-key",
+				utils.TestPublicKey,
+				"--policy",
+				"/policy.yaml",
+				"--lockfile",
+				"/policy.lock.yaml",
+			}...)
+			cmd.SetArgs(args)
+
+			var out bytes.Buffer
+			cmd.SetOut(&out)
+
+			utils.SetTestRekorPublicKey(t)
+
+			err := cmd.Execute()
+			if c.err != "" {
+				assert.ErrorContains(t, err, c.err)
+				return
+			}
+
+			assert.NoError(t, err)
+			mdl.AssertExpectations(t)
+		})
+	}
+}
+
 func Test_ValidateImageCommandExtraData(t *testing.T) {
 	validateImageCmd := validateImageCmd(happyValidator())
 	cmd := setUpCobra(validateImageCmd)
@@ -1137,6 +1223,238 @@ func Test_WarningOutput(t *testing.T) {
 	  }`, effectiveTimeTest, utils.TestPublicKeyJSON, utils.TestPublicKeyJSON), out.String())
 }
 
+func Test_ForecastOutput(t *testing.T) {
+	notYetEffective := evaluator.Result{
+		Message: "not yet effective",
+		Metadata: map[string]interface{}{
+			"code":         "policy.future",
+			"effective_on": "2024-05-15T00:00:00Z",
+		},
+	}
+
+	validate := func(_ context.Context, component app.SnapshotComponent, _ *app.SnapshotSpec, _ policy.Policy, _ []evaluator.Evaluator, _ bool) (*output.Output, error) {
+		return &output.Output{
+			ImageSignatureCheck: output.VerificationStatus{
+				Passed: true,
+			},
+			ImageAccessibleCheck: output.VerificationStatus{
+				Passed: true,
+			},
+			AttestationSignatureCheck: output.VerificationStatus{
+				Passed: true,
+			},
+			PolicyCheck: []evaluator.Outcome{
+				{
+					Warnings: []evaluator.Result{notYetEffective},
+				},
+			},
+			ImageURL:    component.ContainerImage,
+			PolicyInput: []byte(`{}`),
+		}, nil
+	}
+
+	// evaluators are created for the current effective time and for the
+	// effective time at the end of the forecast
+	effectiveTimes := []time.Time{}
+	newConftestEvaluator = func(_ context.Context, _ []source.PolicySource, p evaluator.ConfigProvider, _ ecc.Source) (evaluator.Evaluator, error) {
+		effectiveTimes = append(effectiveTimes, p.EffectiveTime())
+
+		e := &mockEvaluator{}
+		e.On("Evaluate", mock.Anything, mock.Anything).Return([]evaluator.Outcome{
+			{
+				Failures: []evaluator.Result{notYetEffective},
+			},
+		}, nil)
+		e.On("Destroy").Return()
+
+		return e, nil
+	}
+	t.Cleanup(func() {
+		newConftestEvaluator = evaluator.NewConftestEvaluator
+	})
+
+	validateImageCmd := validateImageCmd(validate)
+	cmd := setUpCobra(validateImageCmd)
+
+	client := fake.FakeClient{}
+	commonMockClient(&client)
+	ctx := utils.WithFS(context.Background(), afero.NewMemMapFs())
+	ctx = oci.WithClient(ctx, &client)
+
+	mdl := MockDownloader{}
+	mdl.On("Download", mock.Anything, "registry/policy:latest", false).Return(&ociMetadata.OCIMetadata{Digest: "sha256:da54bca5477bf4e3449bc37de1822888fa0fbb8d89c640218cb31b987374d357"}, nil)
+	ctx = context.WithValue(ctx, source.DownloaderFuncKey, &mdl)
+
+	cmd.SetContext(ctx)
+
+	cmd.SetArgs(append(rootArgs, []string{
+		"--image",
+		"registry/image:tag",
+		"--policy",
+		fmt.Sprintf(`{"publicKey": %s, "sources": [{"policy": ["registry/policy:latest"]}]}`, utils.TestPublicKeyJSON),
+		"--effective-time",
+		"2024-05-01T00:00:00Z",
+		"--forecast",
+		"720h",
+	}...))
+
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+
+	utils.SetTestRekorPublicKey(t)
+
+	err := cmd.Execute()
+	assert.NoError(t, err)
+
+	assert.Equal(t, []time.Time{
+		time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 5, 31, 0, 0, 0, 0, time.UTC),
+	}, effectiveTimes)
+
+	var report struct {
+		ForecastUntil string `json:"forecast-until"`
+		Components    []struct {
+			Forecast []map[string]string `json:"forecast"`
+		} `json:"components"`
+	}
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &report))
+
+	assert.Equal(t, "2024-05-31T00:00:00Z", report.ForecastUntil)
+	assert.Len(t, report.Components, 1)
+	assert.Equal(t, []map[string]string{
+		{
+			"code":   "policy.future",
+			"msg":    "not yet effective",
+			"date":   "2024-05-15T00:00:00Z",
+			"reason": "effective_on",
+		},
+	}, report.Components[0].Forecast)
+}
+
+func Test_ForecastErrorIsAdvisory(t *testing.T) {
+	validate := func(_ context.Context, component app.SnapshotComponent, _ *app.SnapshotSpec, _ policy.Policy, _ []evaluator.Evaluator, _ bool) (*output.Output, error) {
+		return &output.Output{
+			ImageSignatureCheck: output.VerificationStatus{
+				Passed: true,
+			},
+			ImageAccessibleCheck: output.VerificationStatus{
+				Passed: true,
+			},
+			AttestationSignatureCheck: output.VerificationStatus{
+				Passed: true,
+			},
+			ImageURL:    component.ContainerImage,
+			PolicyInput: []byte(`{}`),
+		}, nil
+	}
+
+	newConftestEvaluator = func(_ context.Context, _ []source.PolicySource, _ evaluator.ConfigProvider, _ ecc.Source) (evaluator.Evaluator, error) {
+		e := &mockEvaluator{}
+		e.On("Evaluate", mock.Anything, mock.Anything).Return([]evaluator.Outcome{}, errors.New("expected"))
+		e.On("Destroy").Return()
+
+		return e, nil
+	}
+	t.Cleanup(func() {
+		newConftestEvaluator = evaluator.NewConftestEvaluator
+	})
+
+	validateImageCmd := validateImageCmd(validate)
+	cmd := setUpCobra(validateImageCmd)
+
+	client := fake.FakeClient{}
+	commonMockClient(&client)
+	ctx := utils.WithFS(context.Background(), afero.NewMemMapFs())
+	ctx = oci.WithClient(ctx, &client)
+
+	mdl := MockDownloader{}
+	mdl.On("Download", mock.Anything, "registry/policy:latest", false).Return(&ociMetadata.OCIMetadata{Digest: "sha256:da54bca5477bf4e3449bc37de1822888fa0fbb8d89c640218cb31b987374d357"}, nil)
+	ctx = context.WithValue(ctx, source.DownloaderFuncKey, &mdl)
+
+	cmd.SetContext(ctx)
+
+	cmd.SetArgs(append(rootArgs, []string{
+		"--image",
+		"registry/image:tag",
+		"--policy",
+		fmt.Sprintf(`{"publicKey": %s, "sources": [{"policy": ["registry/policy:latest"]}]}`, utils.TestPublicKeyJSON),
+		"--forecast",
+		"720h",
+	}...))
+
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+
+	utils.SetTestRekorPublicKey(t)
+
+	err := cmd.Execute()
+	assert.NoError(t, err)
+
+	var report struct {
+		Success    bool `json:"success"`
+		Components []struct {
+			Success  bool              `json:"success"`
+			Forecast []json.RawMessage `json:"forecast"`
+		} `json:"components"`
+	}
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &report))
+
+	assert.True(t, report.Success)
+	assert.Len(t, report.Components, 1)
+	assert.True(t, report.Components[0].Success)
+	assert.Empty(t, report.Components[0].Forecast)
+}
+
+func Test_NegativeForecast(t *testing.T) {
+	validateImageCmd := validateImageCmd(happyValidator())
+	cmd := setUpCobra(validateImageCmd)
+
+	client := fake.FakeClient{}
+	commonMockClient(&client)
+	ctx := utils.WithFS(context.Background(), afero.NewMemMapFs())
+	ctx = oci.WithClient(ctx, &client)
+	cmd.SetContext(ctx)
+
+	cmd.SetArgs(append(rootArgs, []string{
+		"--image",
+		"registry/image:tag",
+		"--policy",
+		fmt.Sprintf(`{"publicKey": %s}`, utils.TestPublicKeyJSON),
+		"--forecast",
+		"-1h",
+	}...))
+
+	utils.SetTestRekorPublicKey(t)
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, "the --forecast duration must not be negative")
+}
+
+func Test_NegativeBaseImageDepth(t *testing.T) {
+	validateImageCmd := validateImageCmd(happyValidator())
+	cmd := setUpCobra(validateImageCmd)
+
+	client := fake.FakeClient{}
+	commonMockClient(&client)
+	ctx := utils.WithFS(context.Background(), afero.NewMemMapFs())
+	ctx = oci.WithClient(ctx, &client)
+	cmd.SetContext(ctx)
+
+	cmd.SetArgs(append(rootArgs, []string{
+		"--image",
+		"registry/image:tag",
+		"--policy",
+		fmt.Sprintf(`{"publicKey": %s}`, utils.TestPublicKeyJSON),
+		"--base-image-depth",
+		"-1",
+	}...))
+
+	utils.SetTestRekorPublicKey(t)
+
+	err := cmd.Execute()
+	assert.ErrorContains(t, err, "the --base-image-depth must not be negative")
+}
+
 func Test_FailureImageAccessibilityNonStrict(t *testing.T) {
 	validate := func(_ context.Context, component app.SnapshotComponent, _ *app.SnapshotSpec, _ policy.Policy, _ []evaluator.Evaluator, _ bool) (*output.Output, error) {
 		return &output.Output{
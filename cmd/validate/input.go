@@ -28,7 +28,6 @@ import (
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 
-	"github.com/enterprise-contract/ec-cli/internal/applicationsnapshot"
 	"github.com/enterprise-contract/ec-cli/internal/format"
 	"github.com/enterprise-contract/ec-cli/internal/input"
 	"github.com/enterprise-contract/ec-cli/internal/output"
@@ -44,6 +43,7 @@ func validateInputCmd(validate InputValidationFunc) *cobra.Command {
 		effectiveTime       string
 		filePaths           []string
 		info                bool
+		lockfile            string
 		namespaces          []string
 		output              []string
 		policy              policy.Policy
@@ -89,18 +89,39 @@ func validateInputCmd(validate InputValidationFunc) *cobra.Command {
 		PreRunE: func(cmd *cobra.Command, args []string) (allErrors error) {
 			ctx := cmd.Context()
 
-			policyConfiguration, err := validate_utils.GetPolicyConfig(ctx, data.policyConfiguration)
+			var lock *policy.Lockfile
+			if data.lockfile != "" {
+				l, err := policy.ReadLockfile(utils.FS(ctx), data.lockfile)
+				if err != nil {
+					allErrors = errors.Join(allErrors, err)
+					return
+				}
+				lock = l
+			}
+
+			policyConfiguration, err := validate_utils.GetLockedPolicyConfig(ctx, data.policyConfiguration, lock)
 			if err != nil {
 				allErrors = errors.Join(allErrors, err)
 				return
 			}
 			data.policyConfiguration = policyConfiguration
 
-			if p, err := policy.NewInputPolicy(cmd.Context(), data.policyConfiguration, data.effectiveTime); err != nil {
+			p, err := policy.NewInputPolicy(cmd.Context(), data.policyConfiguration, data.effectiveTime)
+			if err != nil {
 				allErrors = errors.Join(allErrors, err)
-			} else {
-				data.policy = p
+				return
 			}
+
+			if lock != nil {
+				spec, err := lock.Apply(p.Spec())
+				if err != nil {
+					allErrors = errors.Join(allErrors, err)
+					return
+				}
+				p = p.WithSpec(spec)
+			}
+
+			data.policy = p
 			return
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -228,7 +249,12 @@ func validateInputCmd(validate InputValidationFunc) *cobra.Command {
 		* git reference (github.com/user/repo//default?ref=main), or
 		* inline JSON ('{sources: {...}}')")`))
 
-	validOutputFormats := applicationsnapshot.OutputFormats
+	cmd.Flags().StringVar(&data.lockfile, "lockfile", data.lockfile, hd.Doc(`
+		Use the immutable references of the policy configuration and the policy and
+		data sources from the given lockfile, see "ec policy lock". Fails if the policy
+		has drifted from the lockfile.`))
+
+	validOutputFormats := input.OutputFormats
 	cmd.Flags().StringSliceVarP(&data.output, "output", "o", data.output, hd.Doc(`
 		Write output to a file in a specific format, e.g. yaml=/tmp/output.yaml. Use empty string
 		path for stdout, e.g. yaml. May be used multiple times. Possible formats are:
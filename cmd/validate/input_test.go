@@ -307,3 +307,67 @@ func Test_ValidateInputCmd_EmptyPolicyFile(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "file /policy.yaml is empty")
 }
+
+func Test_ValidateInputCmd_Lockfile(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "/file.yaml", []byte("some: data"), 0644))
+	require.NoError(t, afero.WriteFile(fs, "/policy.yaml", []byte(`sources:
+- name: release
+  policy:
+  - github.com/org/policy
+`), 0644))
+	require.NoError(t, afero.WriteFile(fs, "/policy.lock.yaml", []byte(`digest: sha256:44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a
+sources:
+- name: release
+  digest: sha256:0b41be78b8a71105b6a7315ea6b759a376cf978d770b5d24323fcc90f9daec2c
+  policy:
+  - url: github.com/org/policy
+    pinned: git::github.com/org/policy?ref=3b1e6ad9bd1e1f7e5cf0e6e1a8d6c4d0e2b9a7f1
+`), 0644))
+	require.NoError(t, afero.WriteFile(fs, "/drifted.lock.yaml", []byte(`sources:
+- name: release
+  policy:
+  - url: github.com/org/other
+    pinned: git::github.com/org/other?ref=3b1e6ad9bd1e1f7e5cf0e6e1a8d6c4d0e2b9a7f1
+`), 0644))
+
+	var sources []string
+	validate := func(_ context.Context, _ string, p policy.Policy, _ bool) (*output.Output, error) {
+		sources = p.Spec().Sources[0].Policy
+		return &output.Output{}, nil
+	}
+
+	cmd, _ := setUpValidateInputCmd(validate, fs)
+	cmd.SetArgs([]string{
+		"input",
+		"--file", "/file.yaml",
+		"--policy", "/policy.yaml",
+		"--lockfile", "/policy.lock.yaml",
+	})
+
+	require.NoError(t, cmd.Execute())
+	assert.Equal(t, []string{"git::github.com/org/policy?ref=3b1e6ad9bd1e1f7e5cf0e6e1a8d6c4d0e2b9a7f1"}, sources)
+
+	cmd, _ = setUpValidateInputCmd(validate, fs)
+	cmd.SetArgs([]string{
+		"input",
+		"--file", "/file.yaml",
+		"--policy", "/policy.yaml",
+		"--lockfile", "/drifted.lock.yaml",
+	})
+
+	err := cmd.Execute()
+	assert.ErrorContains(t, err, "the policy has drifted from the lockfile")
+
+	// a remote policy configuration not in the lockfile is not fetched
+	cmd, _ = setUpValidateInputCmd(validate, fs)
+	cmd.SetArgs([]string{
+		"input",
+		"--file", "/file.yaml",
+		"--policy", "github.com/org/config//policy?ref=main",
+		"--lockfile", "/policy.lock.yaml",
+	})
+
+	err = cmd.Execute()
+	assert.EqualError(t, err, `the policy configuration "github.com/org/config//policy?ref=main" has drifted from the lockfile, the lockfile has no remote policy configuration`)
+}
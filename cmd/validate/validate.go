@@ -19,6 +19,7 @@ package validate
 import (
 	"github.com/spf13/cobra"
 
+	"github.com/enterprise-contract/ec-cli/internal/definition"
 	"github.com/enterprise-contract/ec-cli/internal/image"
 	"github.com/enterprise-contract/ec-cli/internal/input"
 	"github.com/enterprise-contract/ec-cli/internal/policy"
@@ -32,6 +33,7 @@ func init() {
 }
 
 func init() {
+	ValidateCmd.AddCommand(validateDefinitionCmd(definition.ValidateDefinition))
 	ValidateCmd.AddCommand(validateImageCmd(image.ValidateImage))
 	ValidateCmd.AddCommand(validateInputCmd(input.ValidateInput))
 	ValidateCmd.AddCommand(ValidatePolicyCmd(policy.ValidatePolicy))
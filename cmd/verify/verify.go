@@ -0,0 +1,46 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package verify
+
+import (
+	hd "github.com/MakeNowJust/heredoc"
+	"github.com/spf13/cobra"
+
+	"github.com/enterprise-contract/ec-cli/internal/vsa"
+)
+
+var VerifyCmd *cobra.Command
+
+func init() {
+	VerifyCmd = NewVerifyCmd()
+	VerifyCmd.AddCommand(verifyVSACmd(vsa.Verify))
+}
+
+func NewVerifyCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "verify",
+		Short: "Verify the results of previous validations",
+
+		Long: hd.Doc(`
+			Verify the results of previous validations
+
+			The Verification Summary Attestations (VSA) produced by "ec validate image"
+			can be verified to establish that an image was validated successfully without
+			evaluating the policy again.
+		`),
+	}
+}
@@ -0,0 +1,231 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Define the `ec verify vsa` command
+package verify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	hd "github.com/MakeNowJust/heredoc"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	"github.com/spf13/cobra"
+
+	"github.com/enterprise-contract/ec-cli/internal/applicationsnapshot"
+	"github.com/enterprise-contract/ec-cli/internal/policy"
+	validate_utils "github.com/enterprise-contract/ec-cli/internal/validate"
+	"github.com/enterprise-contract/ec-cli/internal/vsa"
+)
+
+type vsaVerificationFunc func(context.Context, name.Reference, *cosign.CheckOpts, vsa.Expectations) (*applicationsnapshot.ProvenanceStatementVSA, error)
+
+func verifyVSACmd(verify vsaVerificationFunc) *cobra.Command {
+	data := struct {
+		certificateIdentity         string
+		certificateIdentityRegExp   string
+		certificateOIDCIssuer       string
+		certificateOIDCIssuerRegExp string
+		imageRef                    string
+		ignoreRekor                 bool
+		maxAge                      time.Duration
+		policyConfiguration         string
+		policyDigest                string
+		publicKey                   string
+		rekorURL                    string
+	}{
+		maxAge: 24 * time.Hour,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "vsa",
+		Short: "Verify the Verification Summary Attestation (VSA) of an image",
+
+		Long: hd.Doc(`
+			Verify the Verification Summary Attestation (VSA) of an image
+
+			The VSA attached to the image by "ec validate image --vsa-attach" is fetched
+			and its signature is verified with the provided public key, or with the
+			provided certificate identity for VSAs signed keyless. The VSA is accepted
+			when:
+
+			  * it describes the image, i.e. one of its subjects has the digest of the image,
+			  * it records a successful validation,
+			  * the policy recorded in it matches the expected policy, when the --policy or
+			    the --policy-digest flag is provided, and
+			  * its effective time is within --max-age of the current time, and not in
+			    the future.
+
+			The policy matches the expected policy when both have the same sources, with
+			the same configuration and rule data. The source references recorded in the
+			VSA are pinned to the digest or the commit the sources were fetched at, the
+			pinning is disregarded in the comparison unless the expected source is pinned
+			too. Pinning replaces the git reference of a source, so an expected source
+			referencing a git branch or tag never matches. The policy digest is the SHA-256
+			digest of the policy recorded in the VSA, and it is printed on a successful
+			verification.
+
+			VSAs signed with a key are only recorded in the Rekor transparency log when
+			--vsa-rekor-url is provided when signing, so the transparency log entry of a
+			VSA verified with --public-key is only checked when --rekor-url is provided.
+			The transparency log entry of a VSA signed keyless is always checked, unless
+			--ignore-rekor is provided.
+		`),
+
+		Example: hd.Doc(`
+			Verify the VSA of an image signed with a key, produced with the policy from a
+			local file within the last 24 hours:
+
+			  ec verify vsa --image registry/name:tag --public-key vsa.pub --policy policy.yaml
+
+			Verify the VSA of an image signed with a key and recorded in a Rekor instance
+			with "ec validate image --vsa-rekor-url":
+
+			  ec verify vsa --image registry/name:tag --public-key vsa.pub \
+			    --rekor-url https://rekor.example.com
+
+			Verify the VSA of an image signed keyless, produced within the last hour:
+
+			  ec verify vsa --image registry/name:tag \
+			    --certificate-identity 'https://github.com/user/repo/.github/workflows/push.yaml@refs/heads/main' \
+			    --certificate-oidc-issuer 'https://token.actions.githubusercontent.com' \
+			    --max-age 1h
+
+			Verify the VSA of an image produced with a policy of a known digest:
+
+			  ec verify vsa --image registry/name:tag --public-key vsa.pub \
+			    --policy-digest sha256:<digest>
+		`),
+
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			ref, err := name.ParseReference(data.imageRef)
+			if err != nil {
+				return fmt.Errorf("unable to parse image reference %s: %w", data.imageRef, err)
+			}
+
+			// VSAs signed with a key are not recorded in Rekor unless a Rekor URL
+			// was provided when signing, see vsa.Options
+			ignoreRekor := data.ignoreRekor || (data.publicKey != "" && data.rekorURL == "")
+
+			// the VSA signer is not related to the signer of the image, so the public
+			// key and the identity are never taken from the expected policy
+			signer, err := policy.NewPolicy(ctx, policy.Options{
+				EffectiveTime: policy.Now,
+				Identity: cosign.Identity{
+					Issuer:        data.certificateOIDCIssuer,
+					IssuerRegExp:  data.certificateOIDCIssuerRegExp,
+					Subject:       data.certificateIdentity,
+					SubjectRegExp: data.certificateIdentityRegExp,
+				},
+				IgnoreRekor: ignoreRekor,
+				PublicKey:   data.publicKey,
+				RekorURL:    data.rekorURL,
+			})
+			if err != nil {
+				return err
+			}
+
+			opts, err := signer.CheckOpts()
+			if err != nil {
+				return err
+			}
+
+			expect := vsa.Expectations{
+				PolicyDigest: data.policyDigest,
+				MaxAge:       data.maxAge,
+			}
+
+			if data.policyConfiguration != "" {
+				policyConfiguration, err := validate_utils.GetPolicyConfig(ctx, data.policyConfiguration)
+				if err != nil {
+					return err
+				}
+
+				p, err := policy.NewInertPolicy(ctx, policyConfiguration)
+				if err != nil {
+					return err
+				}
+
+				spec := p.Spec()
+				expect.Policy = &spec
+			}
+
+			verified, err := verify(ctx, ref, opts, expect)
+			if err != nil {
+				return errors.Join(fmt.Errorf("the VSA of %s was not accepted", ref), err)
+			}
+
+			digest, err := vsa.PolicyDigest(verified.Predicate.Policy)
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			fmt.Fprintf(out, "Success: the VSA of %s was verified\n", ref)
+			fmt.Fprintf(out, "Policy digest: %s\n", digest)
+			fmt.Fprintf(out, "Effective time: %s\n", verified.Predicate.EffectiveTime.Format(time.RFC3339))
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&data.imageRef, "image", "i", data.imageRef, "OCI image reference")
+
+	cmd.Flags().StringVarP(&data.publicKey, "public-key", "k", data.publicKey,
+		"path to the public key the VSA was signed with")
+
+	cmd.Flags().StringVarP(&data.rekorURL, "rekor-url", "r", data.rekorURL, hd.Doc(`
+		Rekor URL. The transparency log entry of a VSA signed with a key is only checked
+		when provided.`))
+
+	cmd.Flags().BoolVar(&data.ignoreRekor, "ignore-rekor", data.ignoreRekor,
+		"Skip Rekor transparency log checks during verification.")
+
+	cmd.Flags().StringVar(&data.certificateIdentity, "certificate-identity", data.certificateIdentity,
+		"URL of the certificate identity for keyless verification")
+
+	cmd.Flags().StringVar(&data.certificateIdentityRegExp, "certificate-identity-regexp", data.certificateIdentityRegExp,
+		"Regular expression for the URL of the certificate identity for keyless verification")
+
+	cmd.Flags().StringVar(&data.certificateOIDCIssuer, "certificate-oidc-issuer", data.certificateOIDCIssuer,
+		"URL of the certificate OIDC issuer for keyless verification")
+
+	cmd.Flags().StringVar(&data.certificateOIDCIssuerRegExp, "certificate-oidc-issuer-regexp", data.certificateOIDCIssuerRegExp,
+		"Regular expression for the URL of the certificate OIDC issuer for keyless verification")
+
+	cmd.Flags().StringVarP(&data.policyConfiguration, "policy", "p", data.policyConfiguration, hd.Doc(`
+		Expected policy configuration the image was validated with. See "ec validate image
+		--help" for the supported formats.`))
+
+	cmd.Flags().StringVar(&data.policyDigest, "policy-digest", data.policyDigest,
+		"Expected digest of the policy the image was validated with, in the form sha256:<hex>")
+
+	cmd.Flags().DurationVar(&data.maxAge, "max-age", data.maxAge, hd.Doc(`
+		Maximum age of the effective time of the validation recorded in the VSA. Use 0 to
+		accept a VSA of any age.`))
+
+	if err := cmd.MarkFlagRequired("image"); err != nil {
+		panic(err)
+	}
+
+	return cmd
+}
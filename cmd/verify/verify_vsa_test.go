@@ -0,0 +1,209 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package verify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	ecc "github.com/enterprise-contract/enterprise-contract-controller/api/v1alpha1"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	app "github.com/konflux-ci/application-api/api/v1alpha1"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/enterprise-contract/ec-cli/cmd/root"
+	"github.com/enterprise-contract/ec-cli/internal/applicationsnapshot"
+	"github.com/enterprise-contract/ec-cli/internal/utils"
+	"github.com/enterprise-contract/ec-cli/internal/vsa"
+)
+
+func setUpCobra(command *cobra.Command) *cobra.Command {
+	verifyCmd := NewVerifyCmd()
+	verifyCmd.AddCommand(command)
+	cmd := root.NewRootCmd()
+	cmd.AddCommand(verifyCmd)
+	return cmd
+}
+
+func TestVerifyVSACommand(t *testing.T) {
+	effectiveTime := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	recorded := ecc.EnterpriseContractPolicySpec{
+		Sources: []ecc.Source{{Policy: []string{"oci::registry.io/policy@sha256:abc"}}},
+	}
+	digest, err := vsa.PolicyDigest(recorded)
+	require.NoError(t, err)
+
+	var gotRef name.Reference
+	var gotOpts *cosign.CheckOpts
+	var gotExpect vsa.Expectations
+	cmd := setUpCobra(verifyVSACmd(func(_ context.Context, ref name.Reference, opts *cosign.CheckOpts, expect vsa.Expectations) (*applicationsnapshot.ProvenanceStatementVSA, error) {
+		gotRef, gotOpts, gotExpect = ref, opts, expect
+		return &applicationsnapshot.ProvenanceStatementVSA{
+			Predicate: applicationsnapshot.Report{Policy: recorded, EffectiveTime: effectiveTime},
+		}, nil
+	}))
+
+	cmd.SetContext(utils.WithFS(context.Background(), afero.NewMemMapFs()))
+	cmd.SetArgs([]string{
+		"verify",
+		"vsa",
+		"--image",
+		"registry.io/repository/image:tag",
+		"--public-key",
+		utils.TestPublicKey,
+		"--ignore-rekor",
+		"--policy",
+		`{"sources": [{"policy": ["oci::registry.io/policy"]}]}`,
+		"--policy-digest",
+		digest,
+		"--max-age",
+		"1h",
+	})
+
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+
+	require.NoError(t, cmd.Execute())
+
+	assert.Equal(t, "registry.io/repository/image:tag", gotRef.String())
+	assert.NotNil(t, gotOpts.SigVerifier)
+	assert.True(t, gotOpts.IgnoreTlog)
+	assert.Equal(t, vsa.Expectations{
+		Policy: &ecc.EnterpriseContractPolicySpec{
+			Sources: []ecc.Source{{Policy: []string{"oci::registry.io/policy"}}},
+		},
+		PolicyDigest: digest,
+		MaxAge:       time.Hour,
+	}, gotExpect)
+
+	assert.Equal(t, fmt.Sprintf(`Success: the VSA of registry.io/repository/image:tag was verified
+Policy digest: %s
+Effective time: 2026-01-02T03:04:05Z
+`, digest), out.String())
+}
+
+// TestVerifyVSACommandSignedWithKey checks that a VSA signed with a key using
+// the default options, i.e. not recorded in Rekor, is verified with the default
+// options of the command
+func TestVerifyVSACommandSignedWithKey(t *testing.T) {
+	ctx := context.Background()
+
+	password := []byte("s3cr3t")
+	t.Setenv("COSIGN_PASSWORD", string(password))
+	keys, err := cosign.GenerateKeyPair(func(bool) ([]byte, error) {
+		return password, nil
+	})
+	require.NoError(t, err)
+	keyPath := path.Join(t.TempDir(), "cosign.key")
+	require.NoError(t, os.WriteFile(keyPath, keys.PrivateBytes, 0600))
+
+	r := httptest.NewServer(registry.New())
+	t.Cleanup(r.Close)
+	u, err := url.Parse(r.URL)
+	require.NoError(t, err)
+
+	img, err := random.Image(512, 1)
+	require.NoError(t, err)
+	imgDigest, err := img.Digest()
+	require.NoError(t, err)
+	tag, err := name.NewTag(u.Host + "/repository/image:tag")
+	require.NoError(t, err)
+	require.NoError(t, remote.Write(tag, img))
+	ref := tag.Context().Digest(imgDigest.String())
+
+	statement, err := applicationsnapshot.NewComponentVSA(applicationsnapshot.Report{
+		EffectiveTime: time.Now(),
+	}, applicationsnapshot.Component{
+		SnapshotComponent: app.SnapshotComponent{Name: "component", ContainerImage: ref.String()},
+		Success:           true,
+	})
+	require.NoError(t, err)
+
+	signer, err := vsa.NewSigner(ctx, vsa.Options{KeyRef: keyPath})
+	require.NoError(t, err)
+	t.Cleanup(signer.Close)
+	require.NoError(t, signer.Attest(ctx, ref, statement))
+
+	cmd := setUpCobra(verifyVSACmd(vsa.Verify))
+	cmd.SetContext(utils.WithFS(ctx, afero.NewMemMapFs()))
+	cmd.SetArgs([]string{
+		"verify",
+		"vsa",
+		"--image",
+		tag.String(),
+		"--public-key",
+		string(keys.PublicBytes),
+	})
+
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+
+	require.NoError(t, cmd.Execute())
+	assert.Contains(t, out.String(), fmt.Sprintf("Success: the VSA of %s was verified\n", tag))
+}
+
+func TestVerifyVSACommandRejected(t *testing.T) {
+	cmd := setUpCobra(verifyVSACmd(func(context.Context, name.Reference, *cosign.CheckOpts, vsa.Expectations) (*applicationsnapshot.ProvenanceStatementVSA, error) {
+		return nil, errors.New("the VSA records a failed validation")
+	}))
+
+	cmd.SetContext(utils.WithFS(context.Background(), afero.NewMemMapFs()))
+	cmd.SetArgs([]string{
+		"verify",
+		"vsa",
+		"--image",
+		"registry.io/repository/image:tag",
+		"--public-key",
+		utils.TestPublicKey,
+		"--ignore-rekor",
+	})
+	cmd.SetOut(&bytes.Buffer{})
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, "the VSA of registry.io/repository/image:tag was not accepted\nthe VSA records a failed validation")
+}
+
+func TestVerifyVSACommandNoSigner(t *testing.T) {
+	cmd := setUpCobra(verifyVSACmd(func(context.Context, name.Reference, *cosign.CheckOpts, vsa.Expectations) (*applicationsnapshot.ProvenanceStatementVSA, error) {
+		t.Fatal("unexpected verification")
+		return nil, nil
+	}))
+
+	cmd.SetContext(utils.WithFS(context.Background(), afero.NewMemMapFs()))
+	cmd.SetArgs([]string{"verify", "vsa", "--image", "registry.io/repository/image:tag"})
+	cmd.SetOut(&bytes.Buffer{})
+
+	err := cmd.Execute()
+	assert.ErrorContains(t, err, "certificate identity must be provided for keyless workflow")
+}
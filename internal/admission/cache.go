@@ -0,0 +1,81 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package admission
+
+import (
+	"sync"
+	"time"
+)
+
+type cachedResult struct {
+	violations []string
+	expires    time.Time
+}
+
+// resultCache holds the violations found for each image digest for a limited
+// time. Nothing is cached when the TTL is not positive.
+type resultCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	results map[string]cachedResult
+	now     func() time.Time
+}
+
+func newResultCache(ttl time.Duration) *resultCache {
+	return &resultCache{
+		ttl:     ttl,
+		results: map[string]cachedResult{},
+		now:     time.Now,
+	}
+}
+
+func (c *resultCache) get(digest string) ([]string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	r, ok := c.results[digest]
+	if !ok {
+		return nil, false
+	}
+
+	if !c.now().Before(r.expires) {
+		delete(c.results, digest)
+		return nil, false
+	}
+
+	return r.violations, true
+}
+
+func (c *resultCache) put(digest string, violations []string) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	// drop the expired results so the cache does not grow unbounded with
+	// images that are no longer admitted
+	for d, r := range c.results {
+		if !now.Before(r.expires) {
+			delete(c.results, d)
+		}
+	}
+
+	c.results[digest] = cachedResult{violations: violations, expires: now.Add(c.ttl)}
+}
@@ -0,0 +1,62 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package admission
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResultCache(t *testing.T) {
+	now := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	c := newResultCache(time.Minute)
+	c.now = func() time.Time { return now }
+
+	_, ok := c.get("registry.io/app@sha256:1")
+	assert.False(t, ok)
+
+	c.put("registry.io/app@sha256:1", []string{"violation"})
+	violations, ok := c.get("registry.io/app@sha256:1")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"violation"}, violations)
+
+	now = now.Add(30 * time.Second)
+	c.put("registry.io/app@sha256:2", []string{})
+
+	now = now.Add(30 * time.Second)
+	_, ok = c.get("registry.io/app@sha256:1")
+	assert.False(t, ok, "expired")
+	_, ok = c.get("registry.io/app@sha256:2")
+	assert.True(t, ok)
+
+	// expired entries are pruned when adding new ones
+	now = now.Add(time.Minute)
+	c.put("registry.io/app@sha256:3", nil)
+	assert.Len(t, c.results, 1)
+}
+
+func TestResultCacheDisabled(t *testing.T) {
+	c := newResultCache(0)
+
+	c.put("registry.io/app@sha256:1", []string{"violation"})
+	_, ok := c.get("registry.io/app@sha256:1")
+	assert.False(t, ok)
+}
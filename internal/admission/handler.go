@@ -0,0 +1,177 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package admission implements a Kubernetes validating admission webhook that
+// admits only the workloads whose images conform to the policy.
+package admission
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sync/singleflight"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/enterprise-contract/ec-cli/internal/utils/oci"
+)
+
+// maximum size of the AdmissionReview request body, the API server limits
+// the size of the objects to a few megabytes
+const maxRequestSize = 10 << 20
+
+// Validator validates the image, given as a reference pinned to its digest,
+// and returns the messages of the policy violations found
+type Validator func(ctx context.Context, ref string) ([]string, error)
+
+// Handler handles the AdmissionReview requests of a validating admission
+// webhook, denying the objects that use images that do not conform to the
+// policy
+type Handler struct {
+	validate Validator
+	cache    *resultCache
+	// pending validations, so concurrent requests for the same image digest
+	// share a single validation
+	pending singleflight.Group
+}
+
+// NewHandler returns a Handler that validates images with the given
+// validator, keeping the result of the validation of each image digest for
+// the given time
+func NewHandler(validate Validator, ttl time.Duration) *Handler {
+	return &Handler{
+		validate: validate,
+		cache:    newResultCache(ttl),
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "only POST is supported", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var review admissionv1.AdmissionReview
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize)).Decode(&review); err != nil {
+		http.Error(w, fmt.Sprintf("unable to decode the AdmissionReview: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if review.Request == nil {
+		http.Error(w, "the AdmissionReview does not contain a request", http.StatusBadRequest)
+		return
+	}
+
+	review.Response = h.Review(r.Context(), review.Request)
+	review.Request = nil
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(review); err != nil {
+		log.Warnf("Unable to write the AdmissionReview response: %v", err)
+	}
+}
+
+// Review returns the response to the admission request, allowing the object
+// only if all of its images conform to the policy
+func (h *Handler) Review(ctx context.Context, req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	response := &admissionv1.AdmissionResponse{UID: req.UID}
+
+	images, err := Images(req.Object.Raw)
+	if err != nil {
+		response.Result = &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Code:    http.StatusBadRequest,
+			Reason:  metav1.StatusReasonBadRequest,
+			Message: err.Error(),
+		}
+		return response
+	}
+
+	var denials []string
+	for _, image := range images {
+		violations, err := h.check(ctx, image)
+		if err != nil {
+			denials = append(denials, fmt.Sprintf("image %s could not be validated: %v", image, err))
+			continue
+		}
+
+		for _, v := range violations {
+			denials = append(denials, fmt.Sprintf("image %s: %s", image, v))
+		}
+	}
+
+	if len(denials) == 0 {
+		response.Allowed = true
+		return response
+	}
+
+	log.Infof("Denied %s %s/%s: %s", req.Kind.Kind, req.Namespace, req.Name, strings.Join(denials, "; "))
+	response.Result = &metav1.Status{
+		Status:  metav1.StatusFailure,
+		Code:    http.StatusForbidden,
+		Reason:  metav1.StatusReasonForbidden,
+		Message: strings.Join(denials, "; "),
+	}
+
+	return response
+}
+
+// check returns the violations of the image, validating the image only when
+// there is no result for its digest in the cache
+func (h *Handler) check(ctx context.Context, image string) ([]string, error) {
+	ref, err := name.ParseReference(image)
+	if err != nil {
+		return nil, err
+	}
+
+	digest, err := oci.NewClient(ctx).ResolveDigest(ref)
+	if err != nil {
+		return nil, fmt.Errorf("resolving the digest: %w", err)
+	}
+
+	pinned := ref.Context().Digest(digest).String()
+	if violations, ok := h.cache.get(pinned); ok {
+		log.Debugf("Using the cached validation result of %s", pinned)
+		return violations, nil
+	}
+
+	v, err, _ := h.pending.Do(pinned, func() (any, error) {
+		// another request might have completed the validation meanwhile
+		if violations, ok := h.cache.get(pinned); ok {
+			return violations, nil
+		}
+
+		violations, err := h.validate(ctx, pinned)
+		if err != nil {
+			return nil, err
+		}
+
+		h.cache.put(pinned, violations)
+
+		return violations, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return v.([]string), nil
+}
@@ -0,0 +1,258 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package admission
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/enterprise-contract/ec-cli/internal/utils/oci"
+	"github.com/enterprise-contract/ec-cli/internal/utils/oci/fake"
+)
+
+const (
+	goodDigest = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+	badDigest  = "sha256:2222222222222222222222222222222222222222222222222222222222222222"
+)
+
+func pod(images ...string) []byte {
+	containers := []map[string]string{}
+	for _, i := range images {
+		containers = append(containers, map[string]string{"name": "c", "image": i})
+	}
+
+	raw, err := json.Marshal(map[string]any{
+		"kind":     "Pod",
+		"metadata": map[string]any{"name": "pod"},
+		"spec":     map[string]any{"containers": containers},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return raw
+}
+
+func request(object []byte) *admissionv1.AdmissionRequest {
+	return &admissionv1.AdmissionRequest{
+		UID:       "uid-1",
+		Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+		Namespace: "ns",
+		Name:      "pod",
+		Object:    runtime.RawExtension{Raw: object},
+	}
+}
+
+func testContext() context.Context {
+	client := fake.FakeClient{}
+	client.On("ResolveDigest", name.MustParseReference("registry.io/good:1")).Return(goodDigest, nil)
+	client.On("ResolveDigest", name.MustParseReference("registry.io/bad:1")).Return(badDigest, nil)
+	client.On("ResolveDigest", mock.Anything).Return("", errors.New("not found"))
+
+	return oci.WithClient(context.Background(), &client)
+}
+
+type countingValidator struct {
+	calls atomic.Int32
+}
+
+func (v *countingValidator) validate(_ context.Context, ref string) ([]string, error) {
+	v.calls.Add(1)
+	switch ref {
+	case "registry.io/good@" + goodDigest:
+		return []string{}, nil
+	case "registry.io/bad@" + badDigest:
+		return []string{"first violation", "second violation"}, nil
+	default:
+		return nil, errors.New("unexpected image")
+	}
+}
+
+func TestReview(t *testing.T) {
+	cases := []struct {
+		name    string
+		object  []byte
+		allowed bool
+		code    int32
+		message string
+	}{
+		{name: "no object", allowed: true},
+		{name: "no containers", object: pod(), allowed: true},
+		{name: "conforming image", object: pod("registry.io/good:1"), allowed: true},
+		{
+			name:    "non conforming image",
+			object:  pod("registry.io/good:1", "registry.io/bad:1"),
+			code:    http.StatusForbidden,
+			message: "image registry.io/bad:1: first violation; image registry.io/bad:1: second violation",
+		},
+		{
+			name:    "unresolvable image",
+			object:  pod("registry.io/missing:1"),
+			code:    http.StatusForbidden,
+			message: "image registry.io/missing:1 could not be validated: resolving the digest: not found",
+		},
+		{
+			name:    "invalid image reference",
+			object:  pod("registry.io/INVALID"),
+			code:    http.StatusForbidden,
+			message: "image registry.io/INVALID could not be validated: could not parse reference: registry.io/INVALID",
+		},
+		{
+			name:    "malformed object",
+			object:  []byte(`{`),
+			code:    http.StatusBadRequest,
+			message: "unable to parse the object: unexpected end of JSON input",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			v := countingValidator{}
+			h := NewHandler(v.validate, time.Minute)
+
+			response := h.Review(testContext(), request(c.object))
+
+			assert.Equal(t, "uid-1", string(response.UID))
+			assert.Equal(t, c.allowed, response.Allowed)
+			if c.allowed {
+				assert.Nil(t, response.Result)
+			} else {
+				require.NotNil(t, response.Result)
+				assert.Equal(t, c.code, response.Result.Code)
+				assert.Equal(t, c.message, response.Result.Message)
+			}
+		})
+	}
+}
+
+func TestReviewCache(t *testing.T) {
+	ctx := testContext()
+
+	v := countingValidator{}
+	h := NewHandler(v.validate, time.Minute)
+
+	for i := 0; i < 3; i++ {
+		assert.False(t, h.Review(ctx, request(pod("registry.io/bad:1"))).Allowed)
+		assert.True(t, h.Review(ctx, request(pod("registry.io/good:1"))).Allowed)
+	}
+	assert.Equal(t, int32(2), v.calls.Load(), "each digest is validated once")
+
+	v = countingValidator{}
+	h = NewHandler(v.validate, 0)
+	for i := 0; i < 3; i++ {
+		assert.True(t, h.Review(ctx, request(pod("registry.io/good:1"))).Allowed)
+	}
+	assert.Equal(t, int32(3), v.calls.Load(), "no caching")
+}
+
+func TestReviewConcurrent(t *testing.T) {
+	ctx := testContext()
+
+	release := make(chan struct{})
+	var calls atomic.Int32
+	h := NewHandler(func(_ context.Context, _ string) ([]string, error) {
+		calls.Add(1)
+		<-release
+		return []string{}, nil
+	}, time.Minute)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.True(t, h.Review(ctx, request(pod("registry.io/good:1"))).Allowed)
+		}()
+	}
+
+	// give the requests a chance to wait on the same validation
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestServeHTTP(t *testing.T) {
+	v := countingValidator{}
+	h := NewHandler(v.validate, time.Minute)
+
+	review := admissionv1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{APIVersion: "admission.k8s.io/v1", Kind: "AdmissionReview"},
+		Request:  request(pod("registry.io/bad:1")),
+	}
+	body, err := json.Marshal(review)
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/validate", bytes.NewReader(body)).WithContext(testContext())
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var got admissionv1.AdmissionReview
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	assert.Equal(t, "admission.k8s.io/v1", got.APIVersion)
+	assert.Equal(t, "AdmissionReview", got.Kind)
+	assert.Nil(t, got.Request)
+	require.NotNil(t, got.Response)
+	assert.Equal(t, "uid-1", string(got.Response.UID))
+	assert.False(t, got.Response.Allowed)
+	assert.Equal(t, "image registry.io/bad:1: first violation; image registry.io/bad:1: second violation", got.Response.Result.Message)
+}
+
+func TestServeHTTPErrors(t *testing.T) {
+	h := NewHandler(nil, time.Minute)
+
+	cases := []struct {
+		name   string
+		method string
+		body   string
+		code   int
+	}{
+		{name: "method", method: http.MethodGet, code: http.StatusMethodNotAllowed},
+		{name: "malformed", method: http.MethodPost, body: "{", code: http.StatusBadRequest},
+		{name: "no request", method: http.MethodPost, body: `{"apiVersion": "admission.k8s.io/v1", "kind": "AdmissionReview"}`, code: http.StatusBadRequest},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(c.method, "/validate", bytes.NewBufferString(c.body)))
+			assert.Equal(t, c.code, rec.Code)
+		})
+	}
+}
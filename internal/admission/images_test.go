@@ -0,0 +1,108 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package admission
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImages(t *testing.T) {
+	podSpec := `{
+		"initContainers": [{"name": "init", "image": "registry.io/init:1"}],
+		"containers": [
+			{"name": "app", "image": "registry.io/app:1"},
+			{"name": "sidecar", "image": "registry.io/sidecar:1"},
+			{"name": "again", "image": "registry.io/app:1"}
+		],
+		"ephemeralContainers": [{"name": "debug", "image": "registry.io/debug:1"}]
+	}`
+	all := []string{"registry.io/init:1", "registry.io/app:1", "registry.io/sidecar:1", "registry.io/debug:1"}
+
+	cases := []struct {
+		name     string
+		object   string
+		expected []string
+		err      string
+	}{
+		{name: "no object"},
+		{
+			name:     "pod",
+			object:   `{"kind": "Pod", "metadata": {"name": "p"}, "spec": ` + podSpec + `}`,
+			expected: all,
+		},
+		{
+			name:     "deployment",
+			object:   `{"kind": "Deployment", "spec": {"template": {"spec": ` + podSpec + `}}}`,
+			expected: all,
+		},
+		{
+			name:     "job",
+			object:   `{"kind": "Job", "spec": {"template": {"spec": {"containers": [{"name": "job", "image": "registry.io/job:1"}]}}}}`,
+			expected: []string{"registry.io/job:1"},
+		},
+		{
+			name:     "cron job",
+			object:   `{"kind": "CronJob", "spec": {"jobTemplate": {"spec": {"template": {"spec": {"containers": [{"name": "cron", "image": "registry.io/cron:1"}]}}}}}}`,
+			expected: []string{"registry.io/cron:1"},
+		},
+		{
+			name:     "pod template",
+			object:   `{"kind": "PodTemplate", "template": {"spec": {"containers": [{"name": "t", "image": "registry.io/t:1"}]}}}`,
+			expected: []string{"registry.io/t:1"},
+		},
+		{
+			name:   "unsupported kind",
+			object: `{"kind": "ConfigMap", "data": {"image": "registry.io/not:1"}}`,
+		},
+		{
+			name:   "no pod spec",
+			object: `{"kind": "Deployment", "spec": {}}`,
+		},
+		{
+			name:   "malformed object",
+			object: `{"kind":`,
+			err:    "unable to parse the object",
+		},
+		{
+			name:   "malformed pod spec",
+			object: `{"kind": "Pod", "metadata": {"name": "p"}, "spec": {"containers": "nope"}}`,
+			err:    `unable to read the pod spec of Pod "p"`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			images, err := Images([]byte(c.object))
+			if c.err != "" {
+				require.ErrorContains(t, err, c.err)
+				return
+			}
+
+			require.NoError(t, err)
+			if len(c.expected) == 0 {
+				assert.Empty(t, images)
+			} else {
+				assert.Equal(t, c.expected, images)
+			}
+		})
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"encoding/json"
 
 	"github.com/in-toto/in-toto-golang/in_toto"
+	v1 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v1"
 
 	"github.com/enterprise-contract/ec-cli/internal/attestation"
 	"github.com/enterprise-contract/ec-cli/internal/signature"
@@ -31,12 +32,21 @@ type SLSAProvenance interface {
 	PredicateBuildType() string
 }
 
+type SLSAProvenanceV1 interface {
+	SLSAProvenance
+	BuildDefinition() v1.ProvenanceBuildDefinition
+	RunDetails() v1.ProvenanceRunDetails
+}
+
 type AttestationResult struct {
-	Type               string                      `json:"type,omitempty"`
-	PredicateType      string                      `json:"predicateType,omitempty"`
-	PredicateBuildType string                      `json:"predicateBuildType,omitempty"`
-	Signatures         []signature.EntitySignature `json:"signatures,omitempty"`
-	Statement          []byte                      `json:"-"`
+	Type               string                        `json:"type,omitempty"`
+	PredicateType      string                        `json:"predicateType,omitempty"`
+	PredicateBuildType string                        `json:"predicateBuildType,omitempty"`
+	BuildDefinition    *v1.ProvenanceBuildDefinition `json:"buildDefinition,omitempty"`
+	RunDetails         *v1.ProvenanceRunDetails      `json:"runDetails,omitempty"`
+	SBOM               *attestation.SBOMSummary      `json:"sbom,omitempty"`
+	Signatures         []signature.EntitySignature   `json:"signatures,omitempty"`
+	Statement          []byte                        `json:"-"`
 }
 
 func NewAttestationResult(att attestation.Attestation) AttestationResult {
@@ -49,6 +59,16 @@ func NewAttestationResult(att attestation.Attestation) AttestationResult {
 		attResult.PredicateBuildType = value.PredicateBuildType()
 
 	}
+	if value, ok := att.(SLSAProvenanceV1); ok {
+		buildDefinition := value.BuildDefinition()
+		runDetails := value.RunDetails()
+		attResult.BuildDefinition = &buildDefinition
+		attResult.RunDetails = &runDetails
+	}
+	if value, ok := att.(attestation.SBOM); ok {
+		summary := value.SBOMSummary()
+		attResult.SBOM = &summary
+	}
 	return attResult
 }
 
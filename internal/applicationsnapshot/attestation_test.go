@@ -24,9 +24,11 @@ import (
 
 	"github.com/gkampitakis/go-snaps/snaps"
 	"github.com/in-toto/in-toto-golang/in_toto"
+	v1 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v1"
 	app "github.com/konflux-ci/application-api/api/v1alpha1"
 	"github.com/stretchr/testify/assert"
 
+	"github.com/enterprise-contract/ec-cli/internal/attestation"
 	"github.com/enterprise-contract/ec-cli/internal/evaluator"
 	"github.com/enterprise-contract/ec-cli/internal/signature"
 )
@@ -250,3 +252,92 @@ func TestNewAttestationResultWithSLSAProvenance(t *testing.T) {
 	assert.Len(t, result.Signatures, 1)
 	assert.Equal(t, "slsa-build-type", result.PredicateBuildType, "expected PredicateBuildType to be set for SLSAProvenance attestation")
 }
+
+type slsaProvenanceV1 struct {
+	slsaProvenance
+	predicate v1.ProvenancePredicate
+}
+
+// BuildDefinition implements SLSAProvenanceV1
+func (s slsaProvenanceV1) BuildDefinition() v1.ProvenanceBuildDefinition {
+	return s.predicate.BuildDefinition
+}
+
+// RunDetails implements SLSAProvenanceV1
+func (s slsaProvenanceV1) RunDetails() v1.ProvenanceRunDetails {
+	return s.predicate.RunDetails
+}
+
+func TestNewAttestationResultWithSLSAProvenanceV1(t *testing.T) {
+	s := slsaProvenanceV1{
+		slsaProvenance: slsaProvenance{
+			data:       []byte("some slsa data"),
+			signatures: []signature.EntitySignature{{KeyID: "key-slsa"}},
+		},
+		predicate: v1.ProvenancePredicate{
+			BuildDefinition: v1.ProvenanceBuildDefinition{
+				BuildType:          "slsa-build-type",
+				ExternalParameters: map[string]any{"key": "value"},
+			},
+			RunDetails: v1.ProvenanceRunDetails{
+				Builder: v1.Builder{ID: "https://builder.example"},
+			},
+		},
+	}
+
+	result := NewAttestationResult(s) // s implements SLSAProvenanceV1
+
+	assert.Equal(t, "slsa-build-type", result.PredicateBuildType)
+	assert.Equal(t, &s.predicate.BuildDefinition, result.BuildDefinition)
+	assert.Equal(t, &s.predicate.RunDetails, result.RunDetails)
+
+	j, err := json.Marshal(result)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"type": "slsa-type",
+		"predicateType": "slsa-predicate-type",
+		"predicateBuildType": "slsa-build-type",
+		"buildDefinition": {"buildType": "slsa-build-type", "externalParameters": {"key": "value"}},
+		"runDetails": {"builder": {"id": "https://builder.example"}, "metadata": {}},
+		"signatures": [{"keyid": "key-slsa", "sig": ""}]
+	}`, string(j))
+}
+
+func TestNewAttestationResultWithSLSAProvenanceOmitsV1Fields(t *testing.T) {
+	result := NewAttestationResult(slsaProvenance{})
+
+	assert.Nil(t, result.BuildDefinition)
+	assert.Nil(t, result.RunDetails)
+}
+
+type sbom struct {
+	provenance
+	summary attestation.SBOMSummary
+}
+
+// SBOMSummary implements SBOM
+func (s sbom) SBOMSummary() attestation.SBOMSummary {
+	return s.summary
+}
+
+func TestNewAttestationResultWithSBOM(t *testing.T) {
+	s := sbom{
+		provenance: provenance{signatures: []signature.EntitySignature{{KeyID: "key1"}}},
+		summary:    attestation.SBOMSummary{Format: "SPDX", Version: "2.3", PackageCount: 42},
+	}
+
+	result := NewAttestationResult(s) // s implements SBOM
+
+	assert.Equal(t, &s.summary, result.SBOM)
+	assert.Nil(t, result.BuildDefinition)
+	assert.Empty(t, result.PredicateBuildType)
+
+	j, err := json.Marshal(result)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"type": "generic-provenance-type",
+		"predicateType": "generic-predicate-type",
+		"sbom": {"format": "SPDX", "version": "2.3", "packageCount": 42},
+		"signatures": [{"keyid": "key1", "sig": ""}]
+	}`, string(j))
+}
@@ -0,0 +1,156 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package applicationsnapshot
+
+import (
+	"embed"
+	"sort"
+	"time"
+
+	"github.com/enterprise-contract/ec-cli/internal/evaluator"
+	"github.com/enterprise-contract/ec-cli/internal/signature"
+	"github.com/enterprise-contract/ec-cli/internal/utils"
+)
+
+//go:embed templates/html/*.tmpl
+var htmlfs embed.FS
+
+// htmlReport is the input of the HTML report template, the components are
+// grouped by their status
+type htmlReport struct {
+	Report      *Report
+	TestReport  TestReport
+	Created     string
+	Groups      []htmlGroup
+	Codes       []string
+	Collections []string
+}
+
+type htmlGroup struct {
+	Status     string
+	Title      string
+	Components []htmlComponent
+}
+
+type htmlComponent struct {
+	Name           string
+	ContainerImage string
+	Success        bool
+	Violations     []htmlResult
+	Warnings       []htmlResult
+	Successes      []htmlResult
+	SuccessCount   int
+	Signatures     []signature.EntitySignature
+	Attestations   []AttestationResult
+}
+
+type htmlResult struct {
+	Code             string
+	Message          string
+	Title            string
+	Description      string
+	Solution         string
+	DocumentationURL string
+	Term             string
+	Collections      []string
+}
+
+func generateHTMLReport(r *Report) ([]byte, error) {
+	codes := map[string]bool{}
+	collections := map[string]bool{}
+
+	results := func(rs []evaluator.Result) []htmlResult {
+		out := make([]htmlResult, 0, len(rs))
+		for _, res := range rs {
+			h := htmlResult{
+				Code:             evaluator.ExtractStringFromMetadata(res, "code"),
+				Message:          res.Message,
+				Title:            evaluator.ExtractStringFromMetadata(res, "title"),
+				Description:      evaluator.ExtractStringFromMetadata(res, "description"),
+				Solution:         evaluator.ExtractStringFromMetadata(res, "solution"),
+				DocumentationURL: evaluator.ExtractStringFromMetadata(res, "documentation_url"),
+				Term:             evaluator.ExtractStringFromMetadata(res, "term"),
+				Collections:      evaluator.ExtractStringsFromMetadata(res, "collections"),
+			}
+
+			if h.Code != "" {
+				codes[h.Code] = true
+			}
+			for _, c := range h.Collections {
+				collections[c] = true
+			}
+
+			out = append(out, h)
+		}
+		return out
+	}
+
+	failed := htmlGroup{Status: "failed", Title: "Failed"}
+	warned := htmlGroup{Status: "warning", Title: "Passed with warnings"}
+	passed := htmlGroup{Status: "passed", Title: "Passed"}
+
+	for _, c := range r.Components {
+		h := htmlComponent{
+			Name:           c.Name,
+			ContainerImage: c.ContainerImage,
+			Success:        c.Success,
+			Violations:     results(c.Violations),
+			Warnings:       results(c.Warnings),
+			SuccessCount:   c.SuccessCount,
+			Signatures:     c.Signatures,
+			Attestations:   c.Attestations,
+		}
+		if r.ShowSuccesses {
+			h.Successes = results(c.Successes)
+		}
+
+		switch {
+		case !c.Success:
+			failed.Components = append(failed.Components, h)
+		case len(c.Warnings) > 0:
+			warned.Components = append(warned.Components, h)
+		default:
+			passed.Components = append(passed.Components, h)
+		}
+	}
+
+	input := htmlReport{
+		Report:      r,
+		TestReport:  r.toAppstudioReport(),
+		Created:     r.created.UTC().Format(time.RFC3339),
+		Codes:       sortedKeys(codes),
+		Collections: sortedKeys(collections),
+	}
+
+	for _, g := range []htmlGroup{failed, warned, passed} {
+		if len(g.Components) > 0 {
+			input.Groups = append(input.Groups, g)
+		}
+	}
+
+	return utils.RenderHTMLFromTemplatesWithGlob(input, "html_report.tmpl", []string{"templates/html/*.tmpl"}, htmlfs)
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,131 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package applicationsnapshot
+
+import (
+	"strings"
+	"testing"
+
+	app "github.com/konflux-ci/application-api/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/enterprise-contract/ec-cli/internal/evaluator"
+	"github.com/enterprise-contract/ec-cli/internal/signature"
+)
+
+func htmlTestReport() Report {
+	return Report{
+		Snapshot: "my-app",
+		Components: []Component{
+			{
+				SnapshotComponent: app.SnapshotComponent{Name: "passing", ContainerImage: "registry.io/repository/passing:tag"},
+				Success:           true,
+				SuccessCount:      1,
+				Successes: []evaluator.Result{
+					{Message: "Pass", Metadata: map[string]any{"code": "tasks.required"}},
+				},
+			},
+			{
+				SnapshotComponent: app.SnapshotComponent{Name: "failing", ContainerImage: "registry.io/repository/failing:tag"},
+				Success:           false,
+				Violations: []evaluator.Result{
+					{
+						Message: "Missing <signature>",
+						Metadata: map[string]any{
+							"code":              "signature.missing",
+							"title":             "Signature is present",
+							"description":       "The image needs to be signed.",
+							"solution":          "Sign the image.",
+							"documentation_url": "https://conforma.dev/docs/policy/signature.html",
+							"collections":       []any{"minimal", "redhat"},
+						},
+					},
+				},
+				Signatures: []signature.EntitySignature{
+					{KeyID: "key-1", Metadata: map[string]string{"issuer": "https://issuer.dev"}},
+				},
+				Attestations: []AttestationResult{
+					{Type: "https://in-toto.io/Statement/v0.1", PredicateType: "https://slsa.dev/provenance/v0.2", PredicateBuildType: "tekton.dev/v1beta1/TaskRun"},
+				},
+			},
+			{
+				SnapshotComponent: app.SnapshotComponent{Name: "warning", ContainerImage: "registry.io/repository/warning:tag"},
+				Success:           true,
+				Warnings: []evaluator.Result{
+					{Message: "Deprecated task", Metadata: map[string]any{"code": "tasks.deprecated", "collections": []string{"redhat"}}},
+				},
+			},
+		},
+	}
+}
+
+func TestHTMLReport(t *testing.T) {
+	r := htmlTestReport()
+
+	data, err := r.toFormat(HTML)
+	require.NoError(t, err)
+	html := string(data)
+
+	assert.True(t, strings.HasPrefix(html, "<!DOCTYPE html>"))
+	assert.Contains(t, html, "<title>Conforma report for my-app</title>")
+
+	// the groups are ordered by status
+	failed := strings.Index(html, "<h2>Failed (1)</h2>")
+	warned := strings.Index(html, "<h2>Passed with warnings (1)</h2>")
+	passed := strings.Index(html, "<h2>Passed (1)</h2>")
+	assert.True(t, failed > 0 && failed < warned && warned < passed, "unexpected order of groups")
+
+	// values are escaped
+	assert.Contains(t, html, "signature.missing</span>: Missing &lt;signature&gt;")
+	assert.NotContains(t, html, "<signature>")
+
+	assert.Contains(t, html, `<div class="result violation" data-code="signature.missing" data-collections="minimal redhat">`)
+	assert.Contains(t, html, `<div class="result warning" data-code="tasks.deprecated" data-collections="redhat">`)
+	assert.Contains(t, html, "<dt>Solution</dt><dd>Sign the image.</dd>")
+	assert.Contains(t, html, `<a href="https://conforma.dev/docs/policy/signature.html">`)
+	assert.Contains(t, html, `<span class="collection">minimal</span><span class="collection">redhat</span>`)
+
+	// filter options
+	assert.Contains(t, html, `<option value="signature.missing">signature.missing</option>`)
+	assert.Contains(t, html, `<option value="minimal">minimal</option>`)
+
+	// signatures and attestations
+	assert.Contains(t, html, "<td>key-1</td>")
+	assert.Contains(t, html, "issuer: https://issuer.dev")
+	assert.Contains(t, html, "<td>https://slsa.dev/provenance/v0.2</td>")
+
+	// successes are shown only when requested
+	assert.NotContains(t, html, `data-code="tasks.required"`)
+
+	r.ShowSuccesses = true
+	data, err = r.toFormat(HTML)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `<div class="result success" data-code="tasks.required" data-collections="">`)
+	assert.Contains(t, string(data), `<option value="tasks.required">tasks.required</option>`)
+}
+
+func TestHTMLReportEmpty(t *testing.T) {
+	r := Report{}
+
+	data, err := r.toFormat(HTML)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), `id="code-filter"`)
+	assert.NotContains(t, string(data), "<h2>")
+}
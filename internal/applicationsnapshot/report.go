@@ -30,6 +30,7 @@ import (
 	"sigs.k8s.io/yaml"
 
 	"github.com/enterprise-contract/ec-cli/internal/evaluator"
+	"github.com/enterprise-contract/ec-cli/internal/forecast"
 	"github.com/enterprise-contract/ec-cli/internal/format"
 	"github.com/enterprise-contract/ec-cli/internal/policy"
 	"github.com/enterprise-contract/ec-cli/internal/signature"
@@ -46,6 +47,7 @@ type Component struct {
 	SuccessCount int                         `json:"-"`
 	Signatures   []signature.EntitySignature `json:"signatures,omitempty"`
 	Attestations []AttestationResult         `json:"attestations,omitempty"`
+	Forecast     []forecast.Upcoming         `json:"forecast,omitempty"`
 }
 
 type Report struct {
@@ -60,6 +62,7 @@ type Report struct {
 	EffectiveTime time.Time                        `json:"effective-time"`
 	PolicyInput   [][]byte                         `json:"-"`
 	ShowSuccesses bool                             `json:"-"`
+	ForecastUntil *time.Time                       `json:"forecast-until,omitempty"`
 }
 
 type summary struct {
@@ -106,6 +109,8 @@ const (
 	Attestation     = "attestation"
 	PolicyInput     = "policy-input"
 	VSA             = "vsa"
+	SARIF           = "sarif"
+	HTML            = "html"
 	// Deprecated old version of appstudio. Remove some day.
 	HACBS = "hacbs"
 )
@@ -121,6 +126,8 @@ var OutputFormats = []string{
 	Attestation,
 	PolicyInput,
 	VSA,
+	SARIF,
+	HTML,
 }
 
 // WriteReport returns a new instance of Report representing the state of
@@ -212,6 +219,10 @@ func (r *Report) toFormat(format string) (data []byte, err error) {
 		data = bytes.Join(r.PolicyInput, []byte("\n"))
 	case VSA:
 		data, err = r.toVSA()
+	case SARIF:
+		data, err = json.Marshal(r.toSARIF())
+	case HTML:
+		data, err = generateHTMLReport(r)
 	default:
 		return nil, fmt.Errorf("%q is not a valid report format", format)
 	}
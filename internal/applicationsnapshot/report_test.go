@@ -34,6 +34,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/enterprise-contract/ec-cli/internal/evaluator"
+	"github.com/enterprise-contract/ec-cli/internal/forecast"
 	"github.com/enterprise-contract/ec-cli/internal/format"
 	"github.com/enterprise-contract/ec-cli/internal/policy"
 	"github.com/enterprise-contract/ec-cli/internal/utils"
@@ -883,6 +884,9 @@ func Test_TextReport(t *testing.T) {
 		},
 	}
 
+	until := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	effectiveOn := time.Date(2024, 5, 15, 0, 0, 0, 0, time.UTC)
+
 	cases := []struct {
 		name   string
 		report Report
@@ -921,6 +925,45 @@ func Test_TextReport(t *testing.T) {
 				},
 			},
 		}},
+		{"forecast", Report{
+			ForecastUntil: &until,
+			Components: []Component{
+				{
+					SnapshotComponent: app.SnapshotComponent{
+						ContainerImage: "registry.io/repository/component-1:tag",
+					},
+					Warnings: warnings,
+					Forecast: []forecast.Upcoming{
+						{
+							Code:    "warning-1",
+							Message: "Warning 1 message",
+							Date:    &effectiveOn,
+							Reason:  forecast.EffectiveOn,
+						},
+						{
+							Code:    "trusted_task.trusted",
+							Term:    "buildah",
+							Message: "Task buildah uses an untrusted reference",
+						},
+					},
+				},
+				{
+					SnapshotComponent: app.SnapshotComponent{
+						ContainerImage: "registry.io/repository/component-2:tag",
+					},
+				},
+			},
+		}},
+		{"empty forecast", Report{
+			ForecastUntil: &until,
+			Components: []Component{
+				{
+					SnapshotComponent: app.SnapshotComponent{
+						ContainerImage: "registry.io/repository/component-1:tag",
+					},
+				},
+			},
+		}},
 	}
 
 	for _, c := range cases {
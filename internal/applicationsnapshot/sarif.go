@@ -0,0 +1,42 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package applicationsnapshot
+
+import (
+	"github.com/enterprise-contract/ec-cli/internal/report/sarif"
+)
+
+// toSARIF returns a version of the report in SARIF format, with the image of
+// each component as the location of its results
+func (r *Report) toSARIF() sarif.Log {
+	targets := make([]sarif.Target, 0, len(r.Components))
+	for _, c := range r.Components {
+		t := sarif.Target{
+			Location:   c.ContainerImage,
+			Violations: c.Violations,
+			Warnings:   c.Warnings,
+		}
+
+		if r.ShowSuccesses {
+			t.Successes = c.Successes
+		}
+
+		targets = append(targets, t)
+	}
+
+	return sarif.NewLog(r.EcVersion, targets)
+}
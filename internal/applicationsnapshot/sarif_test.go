@@ -0,0 +1,72 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package applicationsnapshot
+
+import (
+	"testing"
+
+	app "github.com/konflux-ci/application-api/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/enterprise-contract/ec-cli/internal/evaluator"
+)
+
+func TestToSARIF(t *testing.T) {
+	report := Report{
+		EcVersion: "v1.0.0",
+		Components: []Component{
+			{
+				SnapshotComponent: app.SnapshotComponent{
+					Name:           "Name",
+					ContainerImage: "registry.io/repository/image:tag",
+				},
+				Violations: []evaluator.Result{{Message: "violation", Metadata: map[string]any{"code": "a.violation"}}},
+				Warnings:   []evaluator.Result{{Message: "warning", Metadata: map[string]any{"code": "a.warning"}}},
+				Successes:  []evaluator.Result{{Message: "success", Metadata: map[string]any{"code": "a.success"}}},
+			},
+		},
+	}
+
+	log := report.toSARIF()
+	require.Len(t, log.Runs, 1)
+	assert.Equal(t, "v1.0.0", log.Runs[0].Tool.Driver.Version)
+
+	results := log.Runs[0].Results
+	require.Len(t, results, 2)
+	for _, r := range results {
+		require.Len(t, r.Locations, 1)
+		assert.Equal(t, "registry.io/repository/image:tag", r.Locations[0].PhysicalLocation.ArtifactLocation.URI)
+	}
+	assert.Equal(t, "a.violation", results[0].RuleID)
+	assert.Equal(t, "error", results[0].Level)
+	assert.Equal(t, "a.warning", results[1].RuleID)
+	assert.Equal(t, "warning", results[1].Level)
+
+	report.ShowSuccesses = true
+	log = report.toSARIF()
+	results = log.Runs[0].Results
+	require.Len(t, results, 3)
+	assert.Equal(t, "a.success", results[2].RuleID)
+	assert.Equal(t, "pass", results[2].Kind)
+
+	data, err := report.toFormat(SARIF)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"version":"2.1.0"`)
+}
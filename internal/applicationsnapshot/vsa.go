@@ -17,6 +17,10 @@
 package applicationsnapshot
 
 import (
+	"fmt"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
 	"github.com/in-toto/in-toto-golang/in_toto"
 )
 
@@ -47,6 +51,67 @@ func NewVSA(report Report) (ProvenanceStatementVSA, error) {
 	}, nil
 }
 
+// NewComponentVSA returns the VSA of a single component of the report. The
+// subject of the VSA is the image of the component, and the predicate is the
+// report holding only that component, so that the VSA can be attached to the
+// image it describes.
+func NewComponentVSA(report Report, component Component) (ProvenanceStatementVSA, error) {
+	subject, err := imageSubject(component)
+	if err != nil {
+		return ProvenanceStatementVSA{}, err
+	}
+
+	report.Components = []Component{component}
+	report.Success = component.Success
+
+	return ProvenanceStatementVSA{
+		StatementHeader: in_toto.StatementHeader{
+			Type:          StatmentVSA,
+			PredicateType: PredicateVSAProvenance,
+			Subject:       []in_toto.Subject{subject},
+		},
+		Predicate: report,
+	}, nil
+}
+
+// NewSnapshotVSA returns the VSA of the whole report with the images of all
+// the components as its subjects.
+func NewSnapshotVSA(report Report) (ProvenanceStatementVSA, error) {
+	subjects := make([]in_toto.Subject, 0, len(report.Components))
+	for _, c := range report.Components {
+		subject, err := imageSubject(c)
+		if err != nil {
+			return ProvenanceStatementVSA{}, err
+		}
+		subjects = append(subjects, subject)
+	}
+
+	return ProvenanceStatementVSA{
+		StatementHeader: in_toto.StatementHeader{
+			Type:          StatmentVSA,
+			PredicateType: PredicateVSAProvenance,
+			Subject:       subjects,
+		},
+		Predicate: report,
+	}, nil
+}
+
+// imageSubject returns the in-toto subject for the image of the component,
+// the image reference is expected to be pinned to a digest
+func imageSubject(component Component) (in_toto.Subject, error) {
+	ref, err := name.NewDigest(component.ContainerImage)
+	if err != nil {
+		return in_toto.Subject{}, fmt.Errorf("the image of component %q is not pinned to a digest: %w", component.Name, err)
+	}
+
+	algorithm, digest, _ := strings.Cut(ref.DigestStr(), ":")
+
+	return in_toto.Subject{
+		Name:   ref.Context().Name(),
+		Digest: map[string]string{algorithm: digest},
+	}, nil
+}
+
 func getSubjects(report Report) ([]in_toto.Subject, error) {
 	statements, err := report.attestations()
 	if err != nil {
@@ -114,6 +114,71 @@ func TestSubjects(t *testing.T) {
 	assert.Equal(t, expected, subjects)
 }
 
+func TestNewComponentVSA(t *testing.T) {
+	digest := "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	passing := Component{
+		SnapshotComponent: app.SnapshotComponent{Name: "component1", ContainerImage: "registry.io/repository/image1@" + digest},
+		Success:           true,
+	}
+	failing := Component{
+		SnapshotComponent: app.SnapshotComponent{Name: "component2", ContainerImage: "registry.io/repository/image2@" + digest},
+		Success:           false,
+	}
+	report := Report{Success: false, Components: []Component{passing, failing}}
+
+	vsa, err := NewComponentVSA(report, passing)
+	assert.NoError(t, err)
+	assert.Equal(t, StatmentVSA, vsa.Type)
+	assert.Equal(t, PredicateVSAProvenance, vsa.PredicateType)
+	assert.Equal(t, []in_toto.Subject{
+		{
+			Name:   "registry.io/repository/image1",
+			Digest: map[string]string{"sha256": "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"},
+		},
+	}, vsa.Subject)
+	assert.True(t, vsa.Predicate.Success)
+	assert.Equal(t, []Component{passing}, vsa.Predicate.Components)
+
+	// the given report is left intact
+	assert.Len(t, report.Components, 2)
+
+	_, err = NewComponentVSA(report, Component{
+		SnapshotComponent: app.SnapshotComponent{Name: "unpinned", ContainerImage: "registry.io/repository/image:latest"},
+	})
+	assert.ErrorContains(t, err, `the image of component "unpinned" is not pinned to a digest`)
+}
+
+func TestNewSnapshotVSA(t *testing.T) {
+	digest := "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	report := Report{
+		Success: true,
+		Components: []Component{
+			{SnapshotComponent: app.SnapshotComponent{Name: "component1", ContainerImage: "registry.io/repository/image1@" + digest}},
+			{SnapshotComponent: app.SnapshotComponent{Name: "component2", ContainerImage: "registry.io/repository/image2@" + digest}},
+		},
+	}
+
+	vsa, err := NewSnapshotVSA(report)
+	assert.NoError(t, err)
+	assert.Equal(t, report, vsa.Predicate)
+	assert.Equal(t, []in_toto.Subject{
+		{
+			Name:   "registry.io/repository/image1",
+			Digest: map[string]string{"sha256": "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"},
+		},
+		{
+			Name:   "registry.io/repository/image2",
+			Digest: map[string]string{"sha256": "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"},
+		},
+	}, vsa.Subject)
+
+	report.Components = append(report.Components, Component{
+		SnapshotComponent: app.SnapshotComponent{Name: "unpinned", ContainerImage: "registry.io/repository/image3"},
+	})
+	_, err = NewSnapshotVSA(report)
+	assert.ErrorContains(t, err, `the image of component "unpinned" is not pinned to a digest`)
+}
+
 func toJson(policy any) string {
 	newInline, err := json.Marshal(policy)
 	if err != nil {
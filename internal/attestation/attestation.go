@@ -128,17 +128,71 @@ func ProvenanceFromSignature(sig oci.Signature) (Attestation, error) {
 		return nil, err
 	}
 
+	p, err := parseProvenance(embedded)
+	if err != nil {
+		return nil, err
+	}
+
+	p.signatures, err = createEntitySignatures(sig, payload)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create signed entity: %w", err)
+	}
+
+	return p, nil
+}
+
+func parseProvenance(embedded []byte) (provenance, error) {
 	var statement in_toto.Statement
 	if err := json.Unmarshal(embedded, &statement); err != nil {
-		return nil, fmt.Errorf("malformed attestation data: %w", err)
+		return provenance{}, fmt.Errorf("malformed attestation data: %w", err)
 	}
 
-	signatures, err := createEntitySignatures(sig, payload)
+	return provenance{statement: statement, data: embedded}, nil
+}
+
+// FromStatement returns the attestation of the given in-toto statement with
+// the given signatures, typed by its predicate type the same way as the
+// attestations parsed from OCI layers. It is meant for restoring attestations
+// from their statement and signatures, e.g. when cached.
+func FromStatement(statement []byte, signatures []signature.EntitySignature) (Attestation, error) {
+	p, err := parseProvenance(statement)
 	if err != nil {
-		return nil, fmt.Errorf("cannot create signed entity: %w", err)
+		return nil, err
+	}
+
+	switch p.PredicateType() {
+	case PredicateSLSAProvenance:
+		sp, err := parseSLSAProvenance(statement)
+		if err != nil {
+			return nil, err
+		}
+		sp.signatures = signatures
+		return sp, nil
+	case PredicateSLSAProvenanceV1:
+		sp, err := parseSLSAProvenanceV1(statement)
+		if err != nil {
+			return nil, err
+		}
+		sp.signatures = signatures
+		return sp, nil
+	case PredicateSpdxDocument:
+		s, err := parseSBOM(statement, PredicateSpdxDocument, spdxSummary)
+		if err != nil {
+			return nil, err
+		}
+		s.signatures = signatures
+		return s, nil
+	case PredicateCycloneDXDocument:
+		s, err := parseSBOM(statement, PredicateCycloneDXDocument, cyclonedxSummary)
+		if err != nil {
+			return nil, err
+		}
+		s.signatures = signatures
+		return s, nil
 	}
 
-	return provenance{statement: statement, data: embedded, signatures: signatures}, nil
+	p.signatures = signatures
+	return p, nil
 }
 
 type provenance struct {
@@ -125,3 +125,74 @@ func TestProvenanceFromSignature(t *testing.T) {
 		})
 	}
 }
+
+func TestFromStatement(t *testing.T) {
+	signatures := []signature.EntitySignature{{KeyID: "key-id", Signature: "sig"}}
+
+	cases := []struct {
+		name          string
+		statement     string
+		predicateType string
+		check         func(*testing.T, Attestation)
+		err           string
+	}{
+		{
+			name:          "untyped",
+			statement:     `{"_type": "https://in-toto.io/Statement/v0.1", "predicateType": "https://example.io/Other/v1", "predicate": {}}`,
+			predicateType: "https://example.io/Other/v1",
+			check: func(t *testing.T, a Attestation) {
+				assert.IsType(t, provenance{}, a)
+			},
+		},
+		{
+			name:          "SLSA v0.2",
+			statement:     `{"_type": "https://in-toto.io/Statement/v0.1", "predicateType": "https://slsa.dev/provenance/v0.2", "predicate": {"buildType": "https://my.build.type"}}`,
+			predicateType: PredicateSLSAProvenance,
+			check: func(t *testing.T, a Attestation) {
+				assert.Equal(t, "https://my.build.type", a.(slsaProvenance).PredicateBuildType())
+			},
+		},
+		{
+			name:          "SLSA v1",
+			statement:     `{"_type": "https://in-toto.io/Statement/v1", "predicateType": "https://slsa.dev/provenance/v1", "predicate": {"buildDefinition": {"buildType": "https://my.build.type"}}}`,
+			predicateType: PredicateSLSAProvenanceV1,
+			check: func(t *testing.T, a Attestation) {
+				assert.Equal(t, "https://my.build.type", a.(slsaProvenanceV1).PredicateBuildType())
+			},
+		},
+		{
+			name:          "SPDX",
+			statement:     `{"_type": "https://in-toto.io/Statement/v0.1", "predicateType": "https://spdx.dev/Document", "predicate": {"spdxVersion": "SPDX-2.3", "packages": [{}, {}]}}`,
+			predicateType: PredicateSpdxDocument,
+			check: func(t *testing.T, a Attestation) {
+				assert.Equal(t, SBOMSummary{Format: SBOMFormatSPDX, Version: "2.3", PackageCount: 2}, a.(sbom).SBOMSummary())
+			},
+		},
+		{
+			name:      "malformed",
+			statement: `{`,
+			err:       "malformed attestation data: unexpected end of JSON input",
+		},
+		{
+			name:      "malformed SLSA v0.2",
+			statement: `{"_type": "https://in-toto.io/Statement/v1", "predicateType": "https://slsa.dev/provenance/v0.2", "predicate": {}}`,
+			err:       "unsupported attestation type: https://in-toto.io/Statement/v1",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			a, err := FromStatement([]byte(c.statement), signatures)
+			if c.err != "" {
+				assert.EqualError(t, err, c.err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, c.predicateType, a.PredicateType())
+			assert.Equal(t, []byte(c.statement), a.Statement())
+			assert.Equal(t, signatures, a.Signatures())
+			c.check(t, a)
+		})
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package attestation
+
+import (
+	"encoding/json"
+
+	"github.com/in-toto/in-toto-golang/in_toto"
+	"github.com/sigstore/cosign/v2/pkg/oci"
+)
+
+const (
+	PredicateCycloneDXDocument = in_toto.PredicateCycloneDX
+)
+
+// CycloneDXSBOMFromSignature parses the CycloneDX SBOM attestation from the
+// provided OCI layer. Expects that the layer contains DSSE JSON with the
+// embedded CycloneDX BOM as the predicate.
+func CycloneDXSBOMFromSignature(sig oci.Signature) (Attestation, error) {
+	return sbomFromSignature(sig, PredicateCycloneDXDocument, cyclonedxSummary)
+}
+
+func cyclonedxSummary(predicate json.RawMessage) SBOMSummary {
+	summary := SBOMSummary{Format: SBOMFormatCycloneDX}
+
+	var bom map[string]json.RawMessage
+	if err := json.Unmarshal(predicate, &bom); err != nil {
+		return summary
+	}
+
+	field(bom, "specVersion", &summary.Version)
+	summary.PackageCount = countComponents(bom)
+
+	return summary
+}
+
+// countComponents counts the components of the BOM, including the components
+// nested within other components
+func countComponents(parent map[string]json.RawMessage) int {
+	var components []map[string]json.RawMessage
+	field(parent, "components", &components)
+
+	count := len(components)
+	for _, c := range components {
+		count += countComponents(c)
+	}
+
+	return count
+}
@@ -0,0 +1,159 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package attestation
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/in-toto/in-toto-golang/in_toto"
+	"github.com/sigstore/cosign/v2/pkg/oci"
+
+	"github.com/enterprise-contract/ec-cli/internal/signature"
+)
+
+const (
+	SBOMFormatSPDX      = "SPDX"
+	SBOMFormatCycloneDX = "CycloneDX"
+)
+
+// SBOMSummary holds the format, the version of the format and the number of
+// packages of an SBOM
+type SBOMSummary struct {
+	Format       string `json:"format"`
+	Version      string `json:"version,omitempty"`
+	PackageCount int    `json:"packageCount"`
+}
+
+// SBOM is an attestation holding an SBOM, e.g. an SPDX or a CycloneDX SBOM
+type SBOM interface {
+	Attestation
+	SBOMSummary() SBOMSummary
+}
+
+// sbomStatement is an in-toto statement with the SBOM kept as is, the SBOM is
+// only summarized so a malformed SBOM can still be reported by the syntax
+// validation
+type sbomStatement struct {
+	in_toto.StatementHeader
+	Predicate json.RawMessage `json:"predicate"`
+}
+
+// sbomFromSignature parses the SBOM attestation of the given predicate type
+// from the provided OCI layer, summarizing the SBOM using the provided
+// function. Expects that the layer contains DSSE JSON with the embedded
+// attestation.
+func sbomFromSignature(sig oci.Signature, predicateType string, summarize func(json.RawMessage) SBOMSummary) (Attestation, error) {
+	payload, err := payloadFromSig(sig)
+	if err != nil {
+		return nil, err
+	}
+
+	embedded, err := decodedPayload(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := parseSBOM(embedded, predicateType, summarize)
+	if err != nil {
+		return nil, err
+	}
+
+	s.signatures, err = createEntitySignatures(sig, payload)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create signed entity: %w", err)
+	}
+
+	return s, nil
+}
+
+func parseSBOM(embedded []byte, predicateType string, summarize func(json.RawMessage) SBOMSummary) (sbom, error) {
+	var statement sbomStatement
+	if err := json.Unmarshal(embedded, &statement); err != nil {
+		return sbom{}, fmt.Errorf("malformed attestation data: %w", err)
+	}
+
+	if statement.Type != in_toto.StatementInTotoV01 && statement.Type != statementInTotoV1 {
+		return sbom{}, fmt.Errorf("unsupported attestation type: %s", statement.Type)
+	}
+
+	if statement.PredicateType != predicateType {
+		return sbom{}, fmt.Errorf("unsupported attestation predicate type: %s", statement.PredicateType)
+	}
+
+	return sbom{
+		header:  statement.StatementHeader,
+		summary: summarize(statement.Predicate),
+		data:    embedded,
+	}, nil
+}
+
+type sbom struct {
+	header     in_toto.StatementHeader
+	summary    SBOMSummary
+	data       []byte
+	signatures []signature.EntitySignature
+}
+
+func (s sbom) Type() string {
+	return s.header.Type
+}
+
+func (s sbom) PredicateType() string {
+	return s.header.PredicateType
+}
+
+// This returns the raw json of the statement
+func (s sbom) Statement() []byte {
+	return s.data
+}
+
+func (s sbom) Signatures() []signature.EntitySignature {
+	return s.signatures
+}
+
+func (s sbom) Subject() []in_toto.Subject {
+	return s.header.Subject
+}
+
+func (s sbom) SBOMSummary() SBOMSummary {
+	return s.summary
+}
+
+func (s sbom) MarshalJSON() ([]byte, error) {
+	val := struct {
+		Type          string                      `json:"type"`
+		PredicateType string                      `json:"predicateType"`
+		SBOM          SBOMSummary                 `json:"sbom"`
+		Signatures    []signature.EntitySignature `json:"signatures"`
+	}{
+		Type:          s.header.Type,
+		PredicateType: s.header.PredicateType,
+		SBOM:          s.summary,
+		Signatures:    s.signatures,
+	}
+
+	return json.Marshal(val)
+}
+
+// field unmarshals the named field of the JSON object into v, leaving v as is
+// if the field is missing or of an unexpected type
+func field(object map[string]json.RawMessage, name string, v any) {
+	if raw, ok := object[name]; ok {
+		_ = json.Unmarshal(raw, v)
+	}
+}
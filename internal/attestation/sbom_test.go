@@ -0,0 +1,151 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package attestation
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/sigstore/cosign/v2/pkg/oci"
+	ct "github.com/sigstore/cosign/v2/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func sbomSignature(statement string) mockSignature {
+	sig := mockSignature{&mock.Mock{}}
+	sig.On("MediaType").Return(types.MediaType(ct.DssePayloadType), nil)
+	sig.On("Uncompressed").Return(buffy(
+		fmt.Sprintf(`{"payload": "%s", "signatures": [{"keyid": "key-id-1", "sig": "sig-1"}]}`, encode(statement)),
+	), nil)
+	sig.On("Base64Signature").Return("", nil)
+	sig.On("Cert").Return(&x509.Certificate{}, nil)
+	sig.On("Chain").Return([]*x509.Certificate{}, nil)
+
+	return sig
+}
+
+func TestSBOMFromSignature(t *testing.T) {
+	cases := []struct {
+		name      string
+		parse     func(oci.Signature) (Attestation, error)
+		statement string
+		expected  SBOMSummary
+		err       string
+	}{
+		{
+			name:  "SPDX",
+			parse: SPDXSBOMFromSignature,
+			statement: `{
+				"_type": "https://in-toto.io/Statement/v0.1",
+				"predicateType": "https://spdx.dev/Document",
+				"predicate": {"spdxVersion": "SPDX-2.3", "packages": [{"name": "a"}, {"name": "b"}]}
+			}`,
+			expected: SBOMSummary{Format: "SPDX", Version: "2.3", PackageCount: 2},
+		},
+		{
+			name:  "CycloneDX",
+			parse: CycloneDXSBOMFromSignature,
+			statement: `{
+				"_type": "https://in-toto.io/Statement/v1",
+				"predicateType": "https://cyclonedx.org/bom",
+				"predicate": {
+					"bomFormat": "CycloneDX",
+					"specVersion": "1.6",
+					"components": [{"name": "a", "components": [{"name": "a1"}, {"name": "a2"}]}, {"name": "b"}]
+				}
+			}`,
+			expected: SBOMSummary{Format: "CycloneDX", Version: "1.6", PackageCount: 4},
+		},
+		{
+			name:  "malformed SPDX",
+			parse: SPDXSBOMFromSignature,
+			statement: `{
+				"_type": "https://in-toto.io/Statement/v0.1",
+				"predicateType": "https://spdx.dev/Document",
+				"predicate": {"spdxVersion": 2.3, "packages": {}}
+			}`,
+			expected: SBOMSummary{Format: "SPDX"},
+		},
+		{
+			name:  "malformed CycloneDX",
+			parse: CycloneDXSBOMFromSignature,
+			statement: `{
+				"_type": "https://in-toto.io/Statement/v0.1",
+				"predicateType": "https://cyclonedx.org/bom",
+				"predicate": "bom"
+			}`,
+			expected: SBOMSummary{Format: "CycloneDX"},
+		},
+		{
+			name:  "unexpected predicate type",
+			parse: SPDXSBOMFromSignature,
+			statement: `{
+				"_type": "https://in-toto.io/Statement/v0.1",
+				"predicateType": "https://cyclonedx.org/bom"
+			}`,
+			err: "unsupported attestation predicate type: https://cyclonedx.org/bom",
+		},
+		{
+			name:      "unexpected statement type",
+			parse:     CycloneDXSBOMFromSignature,
+			statement: `{"_type": "kaboom"}`,
+			err:       "unsupported attestation type: kaboom",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			att, err := c.parse(sbomSignature(c.statement))
+			if c.err != "" {
+				assert.Nil(t, att)
+				assert.EqualError(t, err, c.err)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.JSONEq(t, c.statement, string(att.Statement()))
+			assert.Len(t, att.Signatures(), 1)
+
+			s, ok := att.(sbom)
+			require.True(t, ok)
+			assert.Equal(t, c.expected, s.SBOMSummary())
+
+			j, err := json.Marshal(att)
+			require.NoError(t, err)
+			assert.JSONEq(t, fmt.Sprintf(`{
+				"type": %q,
+				"predicateType": %q,
+				"sbom": %s,
+				"signatures": [{"keyid": "key-id-1", "sig": "sig-1"}]
+			}`, att.Type(), att.PredicateType(), toJSON(t, c.expected)), string(j))
+		})
+	}
+}
+
+func toJSON(t *testing.T, v any) string {
+	j, err := json.Marshal(v)
+	require.NoError(t, err)
+
+	return string(j)
+}
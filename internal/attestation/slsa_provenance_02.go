@@ -46,25 +46,34 @@ func SLSAProvenanceFromSignature(sig oci.Signature) (Attestation, error) {
 		return nil, err
 	}
 
+	a, err := parseSLSAProvenance(embedded)
+	if err != nil {
+		return nil, err
+	}
+
+	a.signatures, err = createEntitySignatures(sig, payload)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create signed entity: %w", err)
+	}
+
+	return a, nil
+}
+
+func parseSLSAProvenance(embedded []byte) (slsaProvenance, error) {
 	var statement in_toto.ProvenanceStatementSLSA02
 	if err := json.Unmarshal(embedded, &statement); err != nil {
-		return nil, fmt.Errorf("malformed attestation data: %w", err)
+		return slsaProvenance{}, fmt.Errorf("malformed attestation data: %w", err)
 	}
 
 	if statement.Type != in_toto.StatementInTotoV01 {
-		return nil, fmt.Errorf("unsupported attestation type: %s", statement.Type)
+		return slsaProvenance{}, fmt.Errorf("unsupported attestation type: %s", statement.Type)
 	}
 
 	if statement.PredicateType != v02.PredicateSLSAProvenance {
-		return nil, fmt.Errorf("unsupported attestation predicate type: %s", statement.PredicateType)
-	}
-
-	signatures, err := createEntitySignatures(sig, payload)
-	if err != nil {
-		return nil, fmt.Errorf("cannot create signed entity: %w", err)
+		return slsaProvenance{}, fmt.Errorf("unsupported attestation predicate type: %s", statement.PredicateType)
 	}
 
-	return slsaProvenance{statement: statement, data: embedded, signatures: signatures}, nil
+	return slsaProvenance{statement: statement, data: embedded}, nil
 }
 
 type slsaProvenance struct {
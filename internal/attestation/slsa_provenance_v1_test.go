@@ -0,0 +1,148 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package attestation
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/gkampitakis/go-snaps/snaps"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	v1 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v1"
+	ct "github.com/sigstore/cosign/v2/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/enterprise-contract/ec-cli/internal/signature"
+)
+
+const slsaProvenanceV1Statement = `{
+	"_type": "https://in-toto.io/Statement/v1",
+	"predicateType": "https://slsa.dev/provenance/v1",
+	"subject": [{"name": "registry.io/repository/image", "digest": {"sha256": "abc"}}],
+	"predicate": {
+		"buildDefinition": {
+			"buildType": "https://tekton.dev/chains/v2/slsa",
+			"externalParameters": {"runSpec": {"pipelineRef": {"name": "docker-build"}}},
+			"resolvedDependencies": [{"uri": "git+https://github.com/org/repo.git", "digest": {"sha1": "abc"}}]
+		},
+		"runDetails": {
+			"builder": {"id": "https://tekton.dev/chains/v2"},
+			"metadata": {"invocationID": "run-1"}
+		}
+	}
+}`
+
+func TestSLSAProvenanceV1FromSignatureNilSignature(t *testing.T) {
+	sp, err := SLSAProvenanceV1FromSignature(nil)
+	assert.ErrorContains(t, err, "no attestation found")
+	assert.Nil(t, sp)
+}
+
+func TestSLSAProvenanceV1FromSignature(t *testing.T) {
+	cases := []struct {
+		name string
+		data string
+		err  error
+	}{
+		{
+			name: "empty payload JSON",
+			data: "{}",
+			err:  errors.New("unsupported attestation type: "),
+		},
+		{
+			name: "unexpected predicate type",
+			data: `{
+				"_type": "https://in-toto.io/Statement/v1",
+				"predicateType": "https://slsa.dev/provenance/v0.2"
+			}`,
+			err: errors.New("unsupported attestation predicate type: https://slsa.dev/provenance/v0.2"),
+		},
+		{
+			name: "in-toto statement v0.1",
+			data: `{
+				"_type": "https://in-toto.io/Statement/v0.1",
+				"predicateType": "https://slsa.dev/provenance/v1",
+				"predicate": {"buildDefinition": {"buildType": "https://my.build.type"}}
+			}`,
+		},
+		{
+			name: "in-toto statement v1",
+			data: slsaProvenanceV1Statement,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			sig := mockSignature{&mock.Mock{}}
+			sig.On("MediaType").Return(types.MediaType(ct.DssePayloadType), nil)
+			sig.On("Uncompressed").Return(buffy(
+				fmt.Sprintf(`{"payload": "%s", "signatures": [{"keyid": "key-id-1", "sig": "sig-1"}]}`, encode(c.data)),
+			), nil)
+			sig.On("Base64Signature").Return("", nil)
+			sig.On("Cert").Return(&x509.Certificate{}, nil)
+			sig.On("Chain").Return([]*x509.Certificate{}, nil)
+
+			sp, err := SLSAProvenanceV1FromSignature(sig)
+			if c.err != nil {
+				require.Nil(t, sp)
+				assert.EqualError(t, err, c.err.Error())
+				return
+			}
+			require.NoError(t, err)
+
+			assert.JSONEq(t, c.data, string(sp.Statement()))
+			assert.Equal(t, v1.PredicateSLSAProvenance, sp.PredicateType())
+			assert.Len(t, sp.Signatures(), 1)
+		})
+	}
+}
+
+func TestSLSAProvenanceV1Predicate(t *testing.T) {
+	sig := mockSignature{&mock.Mock{}}
+	sig.On("MediaType").Return(types.MediaType(ct.DssePayloadType), nil)
+	sig.On("Uncompressed").Return(buffy(
+		fmt.Sprintf(`{"payload": "%s", "signatures": [{"keyid": "ignored-1", "sig": "ignored-1"}]}`, encode(slsaProvenanceV1Statement)),
+	), nil)
+	sig.On("Base64Signature").Return("sig-from-cert", nil)
+	sig.On("Cert").Return(signature.ParseChainguardReleaseCert(), nil)
+	sig.On("Chain").Return(signature.ParseSigstoreChainCert(), nil)
+
+	att, err := SLSAProvenanceV1FromSignature(sig)
+	require.NoError(t, err)
+
+	sp, ok := att.(slsaProvenanceV1)
+	require.True(t, ok)
+
+	assert.Equal(t, "https://in-toto.io/Statement/v1", sp.Type())
+	assert.Equal(t, "https://tekton.dev/chains/v2/slsa", sp.PredicateBuildType())
+	assert.Equal(t, map[string]any{"runSpec": map[string]any{"pipelineRef": map[string]any{"name": "docker-build"}}}, sp.BuildDefinition().ExternalParameters)
+	assert.Equal(t, []v1.ResourceDescriptor{{URI: "git+https://github.com/org/repo.git", Digest: map[string]string{"sha1": "abc"}}}, sp.BuildDefinition().ResolvedDependencies)
+	assert.Equal(t, "https://tekton.dev/chains/v2", sp.RunDetails().Builder.ID)
+	assert.Equal(t, "run-1", sp.RunDetails().BuildMetadata.InvocationID)
+
+	j, err := json.Marshal(att)
+	require.NoError(t, err)
+
+	snaps.MatchJSON(t, j)
+}
@@ -16,9 +16,39 @@
 
 package attestation
 
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/sigstore/cosign/v2/pkg/oci"
+)
+
 const (
 	PredicateSpdxDocument = "https://spdx.dev/Document"
 )
 
-// Todo: More code here in future probably, e.g. for unmarshaling an SPDX
-// document to confirm it is formatted correctly
+// SPDXSBOMFromSignature parses the SPDX SBOM attestation from the provided OCI
+// layer. Expects that the layer contains DSSE JSON with the embedded SPDX
+// document as the predicate.
+func SPDXSBOMFromSignature(sig oci.Signature) (Attestation, error) {
+	return sbomFromSignature(sig, PredicateSpdxDocument, spdxSummary)
+}
+
+func spdxSummary(predicate json.RawMessage) SBOMSummary {
+	summary := SBOMSummary{Format: SBOMFormatSPDX}
+
+	var document map[string]json.RawMessage
+	if err := json.Unmarshal(predicate, &document); err != nil {
+		return summary
+	}
+
+	var version string
+	field(document, "spdxVersion", &version)
+	summary.Version = strings.TrimPrefix(version, "SPDX-")
+
+	var packages []json.RawMessage
+	field(document, "packages", &packages)
+	summary.PackageCount = len(packages)
+
+	return summary
+}
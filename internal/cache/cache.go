@@ -0,0 +1,113 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package cache implements a persistent, content-addressed, on-disk cache
+// that survives across invocations of the CLI. Content is kept in stores,
+// directories within the cache directory, each holding entries keyed by a
+// digest of the content they hold.
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const (
+	// DefaultTTL is the default time after which unused, or unpinned, cache
+	// entries are considered stale
+	DefaultTTL = 24 * time.Hour
+	// DefaultMaxSize is the default maximum size of a store in bytes
+	DefaultMaxSize int64 = 1 << 30 // 1GiB
+)
+
+// ErrNoDir is returned when the cache directory is needed but could not be
+// determined, e.g. when neither EC_CACHE_DIR nor HOME is set
+var ErrNoDir = errors.New("unable to determine the cache directory, set EC_CACHE_DIR")
+
+// Config holds the configuration of the cache, see ConfigFromEnv.
+type Config struct {
+	Enabled bool
+	Dir     string
+	TTL     time.Duration
+	MaxSize int64
+}
+
+// ConfigFromEnv returns the cache configuration from the environment:
+//   - EC_PERSISTENT_CACHE, set to a true value, e.g. "1" or "true", to enable
+//     the persistent cache
+//   - EC_CACHE, set to a false value, e.g. "0" or "false", to turn all caching
+//     off, including the persistent cache
+//   - EC_CACHE_DIR, the cache directory, defaults to $XDG_CACHE_HOME/ec
+//   - EC_CACHE_TTL, the time entries are considered fresh, e.g. "12h"
+//   - EC_CACHE_MAX_SIZE, the maximum size of a store, e.g. "500Mi" or "2G"
+//
+// When the cache directory can't be determined, the persistent cache is turned
+// off and Dir is left empty.
+func ConfigFromEnv() (Config, error) {
+	c := Config{
+		TTL:     DefaultTTL,
+		MaxSize: DefaultMaxSize,
+	}
+
+	if v := os.Getenv("EC_PERSISTENT_CACHE"); v != "" {
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			return c, fmt.Errorf("invalid EC_PERSISTENT_CACHE value %q: %w", v, err)
+		}
+		c.Enabled = enabled
+	}
+
+	// same as for the image cache, a value parsed as false turns caching off
+	if v, err := strconv.ParseBool(os.Getenv("EC_CACHE")); err == nil && !v {
+		c.Enabled = false
+	}
+
+	if v := os.Getenv("EC_CACHE_DIR"); v != "" {
+		c.Dir = v
+	} else if dir, err := os.UserCacheDir(); err == nil {
+		c.Dir = filepath.Join(dir, "ec")
+	} else {
+		if c.Enabled {
+			log.Warnf("Persistent cache turned off: %v", ErrNoDir)
+		}
+		c.Enabled = false
+	}
+
+	if v := os.Getenv("EC_CACHE_TTL"); v != "" {
+		ttl, err := time.ParseDuration(v)
+		if err != nil {
+			return c, fmt.Errorf("invalid EC_CACHE_TTL value %q: %w", v, err)
+		}
+		c.TTL = ttl
+	}
+
+	if v := os.Getenv("EC_CACHE_MAX_SIZE"); v != "" {
+		q, err := resource.ParseQuantity(v)
+		if err != nil {
+			return c, fmt.Errorf("invalid EC_CACHE_MAX_SIZE value %q: %w", v, err)
+		}
+		c.MaxSize = q.Value()
+	}
+
+	return c, nil
+}
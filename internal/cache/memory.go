@@ -0,0 +1,121 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cache
+
+import (
+	"container/list"
+	"sync"
+)
+
+// Memory is an in-memory cache holding values up to a maximum total size,
+// evicting the least recently used values first. It is safe for concurrent
+// use, e.g. by the workers validating components or by the requests of a
+// long running server.
+type Memory[K comparable, V any] struct {
+	mu      sync.Mutex
+	maxSize int64
+	size    int64
+	sizeOf  func(V) int64
+	order   *list.List
+	items   map[K]*list.Element
+}
+
+type memoryItem[K comparable, V any] struct {
+	key   K
+	value V
+	size  int64
+}
+
+// NewMemory returns a Memory cache holding values with a total size of up to
+// maxSize, the size of each value is given by sizeOf. When sizeOf is nil each
+// value has the size of 1, i.e. maxSize is the maximum number of values.
+func NewMemory[K comparable, V any](maxSize int64, sizeOf func(V) int64) *Memory[K, V] {
+	if sizeOf == nil {
+		sizeOf = func(V) int64 { return 1 }
+	}
+
+	return &Memory[K, V]{
+		maxSize: maxSize,
+		sizeOf:  sizeOf,
+		order:   list.New(),
+		items:   map[K]*list.Element{},
+	}
+}
+
+// Get returns the value for the key, and marks it as recently used
+func (m *Memory[K, V]) Get(key K) (V, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.items[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+
+	m.order.MoveToFront(e)
+
+	return e.Value.(*memoryItem[K, V]).value, true
+}
+
+// Add adds the value for the key, evicting the least recently used values
+// if needed to stay within the maximum size. Values larger than the maximum
+// size are not added.
+func (m *Memory[K, V]) Add(key K, value V) {
+	size := m.sizeOf(value)
+	if size > m.maxSize {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if e, ok := m.items[key]; ok {
+		m.remove(e)
+	}
+
+	m.items[key] = m.order.PushFront(&memoryItem[K, V]{key: key, value: value, size: size})
+	m.size += size
+
+	for m.size > m.maxSize {
+		m.remove(m.order.Back())
+	}
+}
+
+// Len returns the number of values in the cache
+func (m *Memory[K, V]) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.order.Len()
+}
+
+// Clear removes all values from the cache
+func (m *Memory[K, V]) Clear() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.order.Init()
+	m.items = map[K]*list.Element{}
+	m.size = 0
+}
+
+func (m *Memory[K, V]) remove(e *list.Element) {
+	item := m.order.Remove(e).(*memoryItem[K, V])
+	delete(m.items, item.key)
+	m.size -= item.size
+}
@@ -0,0 +1,71 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package cache
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemory(t *testing.T) {
+	m := NewMemory[string, int](2, nil)
+
+	m.Add("a", 1)
+	m.Add("b", 2)
+	assert.Equal(t, 2, m.Len())
+
+	// marks a as recently used, so b is evicted next
+	v, ok := m.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, v)
+
+	m.Add("c", 3)
+	assert.Equal(t, 2, m.Len())
+	_, ok = m.Get("b")
+	assert.False(t, ok)
+	_, ok = m.Get("a")
+	assert.True(t, ok)
+
+	// replacing a value doesn't grow the cache
+	m.Add("c", 4)
+	assert.Equal(t, 2, m.Len())
+	v, _ = m.Get("c")
+	assert.Equal(t, 4, v)
+
+	m.Clear()
+	assert.Equal(t, 0, m.Len())
+}
+
+func TestMemorySize(t *testing.T) {
+	m := NewMemory[string, []byte](10, func(b []byte) int64 { return int64(len(b)) })
+
+	m.Add("a", make([]byte, 4))
+	m.Add("b", make([]byte, 4))
+	m.Add("c", make([]byte, 4))
+	assert.Equal(t, 2, m.Len())
+	_, ok := m.Get("a")
+	assert.False(t, ok)
+
+	// values larger than the cache are not added
+	m.Add("d", make([]byte, 11))
+	_, ok = m.Get("d")
+	assert.False(t, ok)
+	assert.Equal(t, 2, m.Len())
+}
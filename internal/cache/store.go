@@ -0,0 +1,454 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+)
+
+const (
+	entryFile  = "entry.json"
+	contentDir = "content"
+)
+
+// TempPrefix is the prefix of the files and directories being written within a
+// store, those are not entries of the store
+const TempPrefix = ".tmp-"
+
+// Entry describes a single item held in a Store.
+type Entry struct {
+	// Key is the content address of the entry
+	Key string `json:"key"`
+	// Refs are the references, e.g. URLs, the entry can be looked up by
+	Refs []string `json:"refs"`
+	// Info holds any additional information about the entry
+	Info map[string]string `json:"info,omitempty"`
+	// Size is the total size, in bytes, of the entry content
+	Size int64 `json:"size"`
+	// Created is the time the entry was added to the store
+	Created time.Time `json:"created"`
+	// Accessed is the time the entry was last looked up
+	Accessed time.Time `json:"accessed"`
+}
+
+// Store is a named area of the cache directory holding entries of the same
+// kind, for example downloaded policy sources. Files directly within the store
+// directory, e.g. the blobs of the image cache, are entries too, addressed by
+// their file name, with their modification time as the time they were created
+// and last accessed.
+type Store struct {
+	fs      afero.Fs
+	name    string
+	dir     string
+	ttl     time.Duration
+	maxSize int64
+	now     func() time.Time
+	// mu is shared by all stores of the same directory, see storeLock
+	mu *sync.Mutex
+}
+
+// storeLocks holds a mutex for each store directory, so that stores created
+// independently, e.g. for each policy download, don't prune and add entries
+// concurrently within the process
+var storeLocks sync.Map
+
+func storeLock(dir string) *sync.Mutex {
+	mu, _ := storeLocks.LoadOrStore(dir, &sync.Mutex{})
+	return mu.(*sync.Mutex)
+}
+
+// NewStore returns the Store with the given name within the cache directory
+func NewStore(fs afero.Fs, c Config, name string) *Store {
+	dir := filepath.Join(c.Dir, name)
+	return &Store{
+		fs:      fs,
+		name:    name,
+		dir:     dir,
+		ttl:     c.TTL,
+		maxSize: c.MaxSize,
+		now:     time.Now,
+		mu:      storeLock(dir),
+	}
+}
+
+// Stores returns all stores present within the cache directory
+func Stores(fs afero.Fs, c Config) ([]*Store, error) {
+	if c.Dir == "" {
+		return nil, ErrNoDir
+	}
+
+	infos, err := afero.ReadDir(fs, c.Dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var stores []*Store
+	for _, i := range infos {
+		if i.IsDir() {
+			stores = append(stores, NewStore(fs, c, i.Name()))
+		}
+	}
+
+	return stores, nil
+}
+
+// Key returns the content address for the given parts
+func Key(parts ...string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(strings.Join(parts, "\x00"))))
+}
+
+// Name returns the name of the store
+func (s *Store) Name() string {
+	return s.name
+}
+
+// ContentPath returns the path to the content of the entry
+func (s *Store) ContentPath(e Entry) string {
+	return filepath.Join(s.dir, e.Key, contentDir)
+}
+
+// Restore copies the content of the entry to the dest directory
+func (s *Store) Restore(e Entry, dest string) error {
+	_, err := Copy(s.fs, s.ContentPath(e), dest)
+	return err
+}
+
+// Fresh returns true if the entry was created within the TTL of the store
+func (s *Store) Fresh(e Entry) bool {
+	return s.now().Sub(e.Created) < s.ttl
+}
+
+// Lookup finds the most recently created entry that can be referenced by the
+// given reference, and marks it as accessed.
+func (s *Store) Lookup(ref string) (Entry, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := s.entries()
+	if err != nil {
+		return Entry{}, false, err
+	}
+
+	var found *Entry
+	for i := range entries {
+		if !slices.Contains(entries[i].Refs, ref) {
+			continue
+		}
+
+		if found == nil || entries[i].Created.After(found.Created) {
+			found = &entries[i]
+		}
+	}
+
+	if found == nil {
+		return Entry{}, false, nil
+	}
+
+	found.Accessed = s.now()
+	if err := s.writeEntry(*found); err != nil {
+		return Entry{}, false, err
+	}
+
+	return *found, true, nil
+}
+
+// Get finds the entry with the given key, and marks it as accessed. Unlike
+// Lookup, it doesn't need to read all entries of the store.
+func (s *Store) Get(key string) (Entry, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, err := s.readEntry(key)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return Entry{}, false, nil
+		}
+		return Entry{}, false, err
+	}
+
+	e.Accessed = s.now()
+	if err := s.writeEntry(e); err != nil {
+		return Entry{}, false, err
+	}
+
+	return e, true, nil
+}
+
+// Put copies the content from the src directory into the store under the given
+// key. If an entry with the same key already exists the references and info
+// are merged into it and the content is kept as is.
+func (s *Store) Put(key string, refs []string, info map[string]string, src string) (Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+
+	if existing, err := s.readEntry(key); err == nil {
+		for _, r := range refs {
+			if !slices.Contains(existing.Refs, r) {
+				existing.Refs = append(existing.Refs, r)
+			}
+		}
+		if existing.Info == nil {
+			existing.Info = map[string]string{}
+		}
+		for k, v := range info {
+			existing.Info[k] = v
+		}
+		existing.Created = now
+		existing.Accessed = now
+
+		return existing, s.writeEntry(existing)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return Entry{}, err
+	}
+
+	if err := s.fs.MkdirAll(s.dir, 0755); err != nil {
+		return Entry{}, err
+	}
+
+	tmp, err := afero.TempDir(s.fs, s.dir, TempPrefix)
+	if err != nil {
+		return Entry{}, err
+	}
+	defer func() {
+		_ = s.fs.RemoveAll(tmp)
+	}()
+
+	size, err := Copy(s.fs, src, filepath.Join(tmp, contentDir))
+	if err != nil {
+		return Entry{}, err
+	}
+
+	e := Entry{
+		Key:      key,
+		Refs:     refs,
+		Info:     info,
+		Size:     size,
+		Created:  now,
+		Accessed: now,
+	}
+
+	if err := writeJSON(s.fs, filepath.Join(tmp, entryFile), e); err != nil {
+		return Entry{}, err
+	}
+
+	if err := s.fs.Rename(tmp, filepath.Join(s.dir, key)); err != nil {
+		// another process might have stored the same content concurrently
+		if _, rerr := s.readEntry(key); rerr == nil {
+			return e, nil
+		}
+		return Entry{}, err
+	}
+
+	log.Debugf("Stored %q in the %s cache as %s", refs, s.name, key)
+
+	return e, nil
+}
+
+// Entries returns all entries in the store, least recently accessed first
+func (s *Store) Entries() ([]Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.entries()
+}
+
+// Prune removes the entries that have not been accessed within the TTL of the
+// store, and then the least recently accessed entries until the total size of
+// the store is within its maximum size. The removed entries are returned.
+func (s *Store) Prune() ([]Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := s.entries()
+	if err != nil {
+		return nil, err
+	}
+
+	var total int64
+	for _, e := range entries {
+		total += e.Size
+	}
+
+	var removed []Entry
+	for _, e := range entries {
+		if s.now().Sub(e.Accessed) < s.ttl && total <= s.maxSize {
+			continue
+		}
+
+		if err := s.fs.RemoveAll(filepath.Join(s.dir, e.Key)); err != nil {
+			return removed, err
+		}
+		total -= e.Size
+		removed = append(removed, e)
+		log.Debugf("Pruned %s from the %s cache", e.Key, s.name)
+	}
+
+	return removed, nil
+}
+
+// Clear removes all entries from the store
+func (s *Store) Clear() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.fs.RemoveAll(s.dir)
+}
+
+func (s *Store) entries() ([]Entry, error) {
+	infos, err := afero.ReadDir(s.fs, s.dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	entries := make([]Entry, 0, len(infos))
+	for _, i := range infos {
+		if strings.HasPrefix(i.Name(), TempPrefix) {
+			continue
+		}
+
+		if i.Mode().IsRegular() {
+			entries = append(entries, Entry{
+				Key:      i.Name(),
+				Size:     i.Size(),
+				Created:  i.ModTime(),
+				Accessed: i.ModTime(),
+			})
+			continue
+		}
+
+		if !i.IsDir() {
+			continue
+		}
+
+		e, err := s.readEntry(i.Name())
+		if err != nil {
+			// ignore any malformed entry, it will be overwritten or
+			// removed eventually
+			log.Debugf("Ignoring malformed cache entry %s: %v", i.Name(), err)
+			continue
+		}
+		entries = append(entries, e)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Accessed.Before(entries[j].Accessed)
+	})
+
+	return entries, nil
+}
+
+func (s *Store) readEntry(key string) (Entry, error) {
+	var e Entry
+	b, err := afero.ReadFile(s.fs, filepath.Join(s.dir, key, entryFile))
+	if err != nil {
+		return e, err
+	}
+
+	if err := json.Unmarshal(b, &e); err != nil {
+		return e, err
+	}
+
+	return e, nil
+}
+
+func (s *Store) writeEntry(e Entry) error {
+	return writeJSON(s.fs, filepath.Join(s.dir, e.Key, entryFile), e)
+}
+
+func writeJSON(fs afero.Fs, path string, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return afero.WriteFile(fs, path, b, 0644)
+}
+
+// Copy recursively copies the src file or directory to dst, returning the
+// total number of bytes copied
+func Copy(fs afero.Fs, src, dst string) (int64, error) {
+	var size int64
+	err := afero.Walk(fs, src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		if info.IsDir() {
+			return fs.MkdirAll(target, 0755)
+		}
+
+		if !info.Mode().IsRegular() {
+			// skip symlinks and other special files
+			return nil
+		}
+
+		n, err := copyFile(fs, path, target)
+		size += n
+
+		return err
+	})
+
+	return size, err
+}
+
+func copyFile(fs afero.Fs, src, dst string) (int64, error) {
+	in, err := fs.Open(src)
+	if err != nil {
+		return 0, err
+	}
+	defer in.Close()
+
+	if err := fs.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return 0, err
+	}
+
+	out, err := fs.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer out.Close()
+
+	return io.Copy(out, in)
+}
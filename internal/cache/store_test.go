@@ -0,0 +1,273 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package cache
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testStore(t *testing.T, fs afero.Fs, ttl time.Duration, maxSize int64) (*Store, *time.Time) {
+	t.Helper()
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := NewStore(fs, Config{Dir: "/cache", TTL: ttl, MaxSize: maxSize}, "test")
+	s.now = func() time.Time { return now }
+
+	return s, &now
+}
+
+func writeContent(t *testing.T, fs afero.Fs, dir string, content string) {
+	t.Helper()
+
+	require.NoError(t, afero.WriteFile(fs, filepath.Join(dir, "nested", "file.txt"), []byte(content), 0644))
+}
+
+func TestPutLookupRestore(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	s, _ := testStore(t, fs, time.Hour, DefaultMaxSize)
+
+	writeContent(t, fs, "/src", "hello")
+
+	e, err := s.Put(Key("a"), []string{"ref-a"}, map[string]string{"k": "v"}, "/src")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), e.Size)
+
+	found, ok, err := s.Lookup("ref-a")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, e.Key, found.Key)
+	assert.Equal(t, map[string]string{"k": "v"}, found.Info)
+	assert.True(t, s.Fresh(found))
+
+	require.NoError(t, s.Restore(found, "/dest"))
+	b, err := afero.ReadFile(fs, "/dest/nested/file.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(b))
+
+	_, ok, err = s.Lookup("ref-b")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestGet(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	s, now := testStore(t, fs, time.Hour, DefaultMaxSize)
+
+	writeContent(t, fs, "/src", "hello")
+
+	e, err := s.Put(Key("a"), []string{"ref-a"}, nil, "/src")
+	require.NoError(t, err)
+
+	*now = now.Add(time.Minute)
+	found, ok, err := s.Get(e.Key)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, e.Key, found.Key)
+	assert.Equal(t, *now, found.Accessed)
+
+	_, ok, err = s.Get(Key("b"))
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestPutMergesRefs(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	s, _ := testStore(t, fs, time.Hour, DefaultMaxSize)
+
+	writeContent(t, fs, "/src", "hello")
+
+	_, err := s.Put(Key("a"), []string{"ref-1"}, nil, "/src")
+	require.NoError(t, err)
+	_, err = s.Put(Key("a"), []string{"ref-2"}, map[string]string{"k": "v"}, "/src")
+	require.NoError(t, err)
+
+	entries, err := s.Entries()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, []string{"ref-1", "ref-2"}, entries[0].Refs)
+	assert.Equal(t, map[string]string{"k": "v"}, entries[0].Info)
+}
+
+func TestLookupPicksNewest(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	s, now := testStore(t, fs, time.Hour, DefaultMaxSize)
+
+	writeContent(t, fs, "/src", "hello")
+
+	_, err := s.Put(Key("old"), []string{"ref"}, nil, "/src")
+	require.NoError(t, err)
+
+	*now = now.Add(2 * time.Hour)
+	newer, err := s.Put(Key("new"), []string{"ref"}, nil, "/src")
+	require.NoError(t, err)
+
+	found, ok, err := s.Lookup("ref")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, newer.Key, found.Key)
+}
+
+func TestFresh(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	s, now := testStore(t, fs, time.Hour, DefaultMaxSize)
+
+	writeContent(t, fs, "/src", "hello")
+
+	e, err := s.Put(Key("a"), []string{"ref"}, nil, "/src")
+	require.NoError(t, err)
+	assert.True(t, s.Fresh(e))
+
+	*now = now.Add(time.Hour)
+	assert.False(t, s.Fresh(e))
+}
+
+func TestPrune(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	s, now := testStore(t, fs, time.Hour, 10)
+
+	writeContent(t, fs, "/src", "12345")
+
+	expired, err := s.Put(Key("expired"), []string{"expired"}, nil, "/src")
+	require.NoError(t, err)
+
+	*now = now.Add(2 * time.Hour)
+	lru, err := s.Put(Key("lru"), []string{"lru"}, nil, "/src")
+	require.NoError(t, err)
+
+	*now = now.Add(time.Minute)
+	_, err = s.Put(Key("recent1"), []string{"recent1"}, nil, "/src")
+	require.NoError(t, err)
+
+	*now = now.Add(time.Minute)
+	_, err = s.Put(Key("recent2"), []string{"recent2"}, nil, "/src")
+	require.NoError(t, err)
+
+	removed, err := s.Prune()
+	require.NoError(t, err)
+	assert.Equal(t, []string{expired.Key, lru.Key}, []string{removed[0].Key, removed[1].Key})
+
+	entries, err := s.Entries()
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, []string{"recent1"}, entries[0].Refs)
+	assert.Equal(t, []string{"recent2"}, entries[1].Refs)
+}
+
+func TestPruneFiles(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	s, now := testStore(t, fs, time.Hour, 10)
+
+	write := func(name string, modified time.Time) {
+		path := filepath.Join("/cache", "test", name)
+		require.NoError(t, afero.WriteFile(fs, path, []byte("12345"), 0644))
+		require.NoError(t, fs.Chtimes(path, modified, modified))
+	}
+	write("sha256:expired", now.Add(-2*time.Hour))
+	write("sha256:lru", now.Add(-2*time.Minute))
+	write("sha256:recent1", now.Add(-time.Minute))
+	write("sha256:recent2", *now)
+
+	removed, err := s.Prune()
+	require.NoError(t, err)
+	require.Len(t, removed, 2)
+	assert.Equal(t, []string{"sha256:expired", "sha256:lru"}, []string{removed[0].Key, removed[1].Key})
+
+	entries, err := s.Entries()
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "sha256:recent1", entries[0].Key)
+	assert.Equal(t, int64(5), entries[0].Size)
+	assert.True(t, entries[0].Accessed.Equal(now.Add(-time.Minute)))
+	assert.Equal(t, "sha256:recent2", entries[1].Key)
+}
+
+func TestClearAndStores(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	s, _ := testStore(t, fs, time.Hour, DefaultMaxSize)
+
+	stores, err := Stores(fs, Config{Dir: "/cache"})
+	require.NoError(t, err)
+	assert.Empty(t, stores)
+
+	writeContent(t, fs, "/src", "hello")
+	_, err = s.Put(Key("a"), []string{"ref"}, nil, "/src")
+	require.NoError(t, err)
+
+	stores, err = Stores(fs, Config{Dir: "/cache"})
+	require.NoError(t, err)
+	require.Len(t, stores, 1)
+	assert.Equal(t, "test", stores[0].Name())
+
+	require.NoError(t, s.Clear())
+
+	entries, err := s.Entries()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("EC_PERSISTENT_CACHE", "1")
+	t.Setenv("EC_CACHE", "")
+	t.Setenv("EC_CACHE_DIR", "/some/dir")
+	t.Setenv("EC_CACHE_TTL", "2h")
+	t.Setenv("EC_CACHE_MAX_SIZE", "1Mi")
+
+	c, err := ConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, Config{Enabled: true, Dir: "/some/dir", TTL: 2 * time.Hour, MaxSize: 1 << 20}, c)
+
+	t.Setenv("EC_CACHE", "false")
+	c, err = ConfigFromEnv()
+	require.NoError(t, err)
+	assert.False(t, c.Enabled)
+
+	t.Setenv("EC_CACHE_TTL", "bogus")
+	_, err = ConfigFromEnv()
+	assert.ErrorContains(t, err, "invalid EC_CACHE_TTL value")
+}
+
+func TestConfigFromEnvWithoutCacheDir(t *testing.T) {
+	t.Setenv("EC_PERSISTENT_CACHE", "1")
+	t.Setenv("EC_CACHE", "")
+	t.Setenv("EC_CACHE_DIR", "")
+	t.Setenv("XDG_CACHE_HOME", "")
+	t.Setenv("HOME", "")
+
+	c, err := ConfigFromEnv()
+	require.NoError(t, err)
+	assert.False(t, c.Enabled)
+	assert.Empty(t, c.Dir)
+
+	_, err = Stores(afero.NewMemMapFs(), c)
+	assert.ErrorIs(t, err, ErrNoDir)
+}
+
+func TestStoresShareLock(t *testing.T) {
+	c := Config{Dir: "/cache"}
+	fs := afero.NewMemMapFs()
+
+	assert.Same(t, NewStore(fs, c, "a").mu, NewStore(fs, c, "a").mu)
+	assert.NotSame(t, NewStore(fs, c, "a").mu, NewStore(fs, c, "b").mu)
+}
@@ -0,0 +1,70 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package definition
+
+import (
+	"context"
+	"fmt"
+	"runtime/trace"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/enterprise-contract/ec-cli/internal/evaluation_target/definition"
+	"github.com/enterprise-contract/ec-cli/internal/evaluator"
+	"github.com/enterprise-contract/ec-cli/internal/input"
+	"github.com/enterprise-contract/ec-cli/internal/output"
+	"github.com/enterprise-contract/ec-cli/internal/policy/source"
+)
+
+var definitionFile = definition.NewDefinition
+
+// ValidateDefinition evaluates the Tekton Pipeline or Task definitions found at
+// fpath, a file, a directory or an inline JSON or YAML string, against the
+// policy rules from the policy sources within the given namespaces, or within
+// all namespaces if none are given
+func ValidateDefinition(ctx context.Context, fpath string, sources []source.PolicySource, namespace []string, detailed bool) (*output.Output, error) {
+	if trace.IsEnabled() {
+		region := trace.StartRegion(ctx, "ec:validate-definition")
+		defer region.End()
+		trace.Logf(ctx, "", "file=%q", fpath)
+	}
+
+	log.Debugf("Current definition filePath: %q", fpath)
+	definitionFiles, err := input.DetectInput(ctx, fpath)
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := definitionFile(ctx, definitionFiles, sources, namespace)
+	if err != nil {
+		log.Debug("Failed to create definition!")
+		return nil, err
+	}
+	defer d.Evaluator.Destroy()
+
+	results, err := d.Evaluator.Evaluate(ctx, evaluator.EvaluationTarget{Inputs: definitionFiles})
+	if err != nil {
+		return nil, fmt.Errorf("evaluating policy: %w", err)
+	}
+
+	log.Debug("Conftest policy check complete")
+
+	out := output.Output{Detailed: detailed}
+	out.SetPolicyCheck(results)
+
+	return &out, nil
+}
@@ -0,0 +1,144 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package definition
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/enterprise-contract/ec-cli/internal/evaluation_target/definition"
+	"github.com/enterprise-contract/ec-cli/internal/evaluator"
+	"github.com/enterprise-contract/ec-cli/internal/output"
+	"github.com/enterprise-contract/ec-cli/internal/policy/source"
+	"github.com/enterprise-contract/ec-cli/internal/utils"
+)
+
+type mockEvaluator struct {
+	outcomes  []evaluator.Outcome
+	err       error
+	destroyed *bool
+}
+
+func (e mockEvaluator) Evaluate(ctx context.Context, target evaluator.EvaluationTarget) ([]evaluator.Outcome, error) {
+	return e.outcomes, e.err
+}
+
+func (e mockEvaluator) Destroy() {
+	*e.destroyed = true
+}
+
+func (e mockEvaluator) CapabilitiesPath() string {
+	return ""
+}
+
+func Test_ValidateDefinition(t *testing.T) {
+	emptyDir := "/empty"
+	nonEmptyDir := "/nonEmpty"
+	validFile := filepath.Join(nonEmptyDir, "pipeline.yaml")
+	badPath := "bad"
+
+	violation := evaluator.Result{Message: "violation", Metadata: map[string]any{"code": "pipeline.a"}}
+
+	tests := []struct {
+		name      string
+		fpath     string
+		outcomes  []evaluator.Outcome
+		evalErr   error
+		err       error
+		output    *output.Output
+		destroyed bool
+	}{
+		{
+			name:      "validation succeeds",
+			fpath:     validFile,
+			output:    &output.Output{},
+			destroyed: true,
+		},
+		{
+			name:     "validation finds violations",
+			fpath:    validFile,
+			outcomes: []evaluator.Outcome{{Failures: []evaluator.Result{violation}}},
+			output: &output.Output{
+				PolicyCheck: []evaluator.Outcome{{Failures: []evaluator.Result{violation}}},
+				ExitCode:    1,
+			},
+			destroyed: true,
+		},
+		{
+			name:  "validation fails on empty directory",
+			fpath: emptyDir,
+			err:   fmt.Errorf("the directory %v contained no files", emptyDir),
+		},
+		{
+			name:  "validation fails on bad path",
+			fpath: badPath,
+			err:   fmt.Errorf("unable to parse the provided input file: %v", badPath),
+		},
+		{
+			name:      "valid file, but evaluator fails",
+			fpath:     validFile,
+			evalErr:   errors.New("Evaluator error"),
+			err:       fmt.Errorf("evaluating policy: %w", errors.New("Evaluator error")),
+			destroyed: true,
+		},
+		{
+			name:      "validation succeeds with yaml input",
+			fpath:     "kind: Task",
+			output:    &output.Output{},
+			destroyed: true,
+		},
+	}
+
+	appFS := afero.NewMemMapFs()
+	assert.NoError(t, appFS.MkdirAll(emptyDir, 0777))
+	assert.NoError(t, appFS.MkdirAll(nonEmptyDir, 0777))
+	assert.NoError(t, afero.WriteFile(appFS, validFile, []byte("kind: Pipeline"), 0777))
+	ctx := utils.WithFS(context.Background(), appFS)
+
+	sources := []source.PolicySource{&source.PolicyUrl{Url: "registry.io/policy:latest", Kind: source.PolicyKind}}
+	namespaces := []string{"pipeline.main"}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			destroyed := false
+			definitionFile = func(_ context.Context, fpath []string, s []source.PolicySource, n []string) (*definition.Definition, error) {
+				assert.Equal(t, sources, s)
+				assert.Equal(t, namespaces, n)
+				return &definition.Definition{
+					Fpath:     fpath,
+					Evaluator: mockEvaluator{outcomes: tt.outcomes, err: tt.evalErr, destroyed: &destroyed},
+				}, nil
+			}
+			t.Cleanup(func() {
+				definitionFile = definition.NewDefinition
+			})
+
+			output, err := ValidateDefinition(ctx, tt.fpath, sources, namespaces, false)
+			assert.Equal(t, tt.err, err)
+			assert.Equal(t, tt.output, output)
+			assert.Equal(t, tt.destroyed, destroyed)
+		})
+	}
+}
@@ -19,6 +19,7 @@ package downloader
 import (
 	"context"
 	"fmt"
+	nethttp "net/http"
 	"regexp"
 	"strings"
 	"sync"
@@ -81,6 +82,13 @@ var _initialize = func() {
 
 var initialize = sync.OnceFunc(_initialize)
 
+// UseOCITransport replaces the transport used to download sources from OCI
+// registries, e.g. to serve them from an OCI layout when running offline
+func UseOCITransport(t nethttp.RoundTripper) {
+	initialize()
+	goci.Transport = t
+}
+
 // WithDownloadImpl replaces the downloadImpl implementation used
 func WithDownloadImpl(ctx context.Context, d downloadImpl) context.Context {
 	return context.WithValue(ctx, downloadImplKey, d)
@@ -22,16 +22,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"os"
-	"path"
 	"runtime/trace"
 
 	"github.com/google/go-containerregistry/pkg/name"
 	app "github.com/konflux-ci/application-api/api/v1alpha1"
 	"github.com/santhosh-tekuri/jsonschema/v5"
 	"github.com/sigstore/cosign/v2/pkg/cosign"
+	"github.com/sigstore/sigstore-go/pkg/root"
 	log "github.com/sirupsen/logrus"
-	"github.com/spf13/afero"
 
 	"github.com/enterprise-contract/ec-cli/internal/attestation"
 	"github.com/enterprise-contract/ec-cli/internal/evaluator"
@@ -39,28 +37,46 @@ import (
 	"github.com/enterprise-contract/ec-cli/internal/fetchers/oci/files"
 	"github.com/enterprise-contract/ec-cli/internal/policy"
 	"github.com/enterprise-contract/ec-cli/internal/signature"
-	"github.com/enterprise-contract/ec-cli/internal/utils"
 	"github.com/enterprise-contract/ec-cli/internal/utils/oci"
 	"github.com/enterprise-contract/ec-cli/pkg/schema"
 )
 
 var attestationSchemas = map[string]*jsonschema.Schema{
 	"https://slsa.dev/provenance/v0.2": schema.SLSA_Provenance_v0_2,
+	"https://slsa.dev/provenance/v1":   schema.SLSA_Provenance_v1,
+}
+
+// sbomSchemas holds the JSON schemas the predicates of SBOM attestations are
+// validated against, by SBOM format and version. The schema keyed by the empty
+// version is used for SBOMs that don't declare a version, SBOMs of versions
+// without a schema are not validated.
+var sbomSchemas = map[string]map[string]*jsonschema.Schema{
+	attestation.SBOMFormatSPDX: {
+		"":    schema.SPDX_v2_3,
+		"2.3": schema.SPDX_v2_3,
+	},
+	attestation.SBOMFormatCycloneDX: {
+		"":    schema.CycloneDX_v1_6,
+		"1.5": schema.CycloneDX_v1_5,
+		"1.6": schema.CycloneDX_v1_6,
+	},
 }
 
 // ApplicationSnapshotImage represents the structure needed to evaluate an Application Snapshot Image
 type ApplicationSnapshotImage struct {
-	reference        name.Reference
-	checkOpts        cosign.CheckOpts
-	signatures       []signature.EntitySignature
-	configJSON       json.RawMessage
-	parentConfigJSON json.RawMessage
-	parentRef        name.Reference
-	attestations     []attestation.Attestation
-	Evaluators       []evaluator.Evaluator
-	files            map[string]json.RawMessage
-	component        app.SnapshotComponent
-	snapshot         app.SnapshotSpec
+	reference       name.Reference
+	checkOpts       cosign.CheckOpts
+	signatures      []signature.EntitySignature
+	configJSON      json.RawMessage
+	baseImages      []baseImage
+	attestations    []attestation.Attestation
+	Evaluators      []evaluator.Evaluator
+	files           map[string]json.RawMessage
+	fileExtractors  []files.Extractor
+	component       app.SnapshotComponent
+	snapshot        app.SnapshotSpec
+	referrers       *referrers
+	trustedMaterial root.TrustedMaterial
 }
 
 // NewApplicationSnapshotImage returns an ApplicationSnapshotImage struct with reference, checkOpts, and evaluator ready to use.
@@ -69,10 +85,19 @@ func NewApplicationSnapshotImage(ctx context.Context, component app.SnapshotComp
 	if err != nil {
 		return nil, err
 	}
+	// Files the policy sources declare in their rule data to be extracted
+	// from the image in addition to the OLM manifests
+	fileExtractors, err := files.ExtractorsFromSources(p.Spec().Sources)
+	if err != nil {
+		return nil, err
+	}
+
 	a := &ApplicationSnapshotImage{
-		checkOpts: *opts,
-		component: component,
-		snapshot:  snap,
+		checkOpts:       *opts,
+		component:       component,
+		snapshot:        snap,
+		trustedMaterial: p.TrustedMaterial(),
+		fileExtractors:  fileExtractors,
 	}
 
 	if err := a.SetImageURL(component.ContainerImage); err != nil {
@@ -112,6 +137,7 @@ func (a *ApplicationSnapshotImage) SetImageURL(url string) error {
 	// Reset internal state relevant to the image
 	a.attestations = []attestation.Attestation{}
 	a.signatures = []signature.EntitySignature{}
+	a.referrers = nil
 
 	return nil
 }
@@ -122,32 +148,33 @@ func (a *ApplicationSnapshotImage) FetchImageConfig(ctx context.Context) error {
 	return err
 }
 
-func (a *ApplicationSnapshotImage) FetchParentImageConfig(ctx context.Context) error {
-	var err error
-	a.parentRef, err = config.FetchParentImage(ctx, a.reference)
-	if err != nil {
-		return err
-	}
-	a.parentConfigJSON, err = config.FetchImageConfig(ctx, a.parentRef)
-	return err
-}
-
 func (a *ApplicationSnapshotImage) FetchImageFiles(ctx context.Context) error {
 	var err error
-	extractors := []files.Extractor{files.OLMManifest{}}
+	extractors := append([]files.Extractor{files.OLMManifest{}}, a.fileExtractors...)
 	a.files, err = files.ImageFiles(ctx, a.reference, extractors)
 	return err
 }
 
 // ValidateImageSignature executes the cosign.VerifyImageSignature method on the ApplicationSnapshotImage image ref.
+// Signatures attached to the image as referrers are verified as well, the check
+// passes if any of the signatures verifies.
 func (a *ApplicationSnapshotImage) ValidateImageSignature(ctx context.Context) error {
 	// Set the ClaimVerifier on a shallow *copy* of CheckOpts to avoid unexpected side-effects
 	opts := a.checkOpts
 	opts.ClaimVerifier = cosign.SimpleClaimVerifier
 	signatures, _, err := oci.NewClient(ctx).VerifyImageSignatures(a.reference, &opts)
+
+	referred, rerr := a.referrerSignatures(ctx, &opts)
+	if err != nil && len(referred) == 0 {
+		return errors.Join(err, rerr)
+	}
 	if err != nil {
-		return err
+		log.Debugf("Using the signatures attached as referrers to %s: %v", a.reference, err)
 	}
+	if rerr != nil {
+		log.Debugf("Ignoring the signatures attached as referrers to %s that failed to verify: %v", a.reference, rerr)
+	}
+	signatures = append(signatures, referred...)
 
 	for _, s := range signatures {
 		es, err := signature.NewEntitySignature(s)
@@ -161,15 +188,26 @@ func (a *ApplicationSnapshotImage) ValidateImageSignature(ctx context.Context) e
 }
 
 // ValidateAttestationSignature executes the cosign.VerifyImageAttestations method
+// and verifies the attestations attached to the image as referrers, either as
+// Sigstore bundles or as DSSE envelopes.
 func (a *ApplicationSnapshotImage) ValidateAttestationSignature(ctx context.Context) error {
 	// Set the ClaimVerifier on a shallow *copy* of CheckOpts to avoid unexpected side-effects
 	opts := a.checkOpts
 	opts.ClaimVerifier = cosign.IntotoSubjectClaimVerifier
 
 	layers, _, err := oci.NewClient(ctx).VerifyImageAttestations(a.reference, &opts)
+
+	referred, rerr := a.referrerAttestations(ctx, &opts)
+	if err != nil && len(referred) == 0 {
+		return errors.Join(err, rerr)
+	}
 	if err != nil {
-		return err
+		log.Debugf("Using the attestations attached as referrers to %s: %v", a.reference, err)
 	}
+	if rerr != nil {
+		log.Debugf("Ignoring the attestations attached as referrers to %s that failed to verify: %v", a.reference, rerr)
+	}
+	layers = append(layers, referred...)
 
 	// Extract the signatures from the attestations here in order to also validate that
 	// the signatures do exist in the expected format.
@@ -192,13 +230,26 @@ func (a *ApplicationSnapshotImage) ValidateAttestationSignature(ctx context.Cont
 			}
 			a.attestations = append(a.attestations, sp)
 
+		case attestation.PredicateSLSAProvenanceV1:
+			sp, err := attestation.SLSAProvenanceV1FromSignature(sig)
+			if err != nil {
+				return fmt.Errorf("unable to parse as SLSA v1.0: %w", err)
+			}
+			a.attestations = append(a.attestations, sp)
+
 		case attestation.PredicateSpdxDocument:
-			// It's an SPDX format SBOM
-			// Todo maybe: We could unmarshal it into a suitable SPDX struct
-			// similar to how it's done for SLSA above
-			a.attestations = append(a.attestations, att)
+			sbom, err := attestation.SPDXSBOMFromSignature(sig)
+			if err != nil {
+				return fmt.Errorf("unable to parse as SPDX SBOM: %w", err)
+			}
+			a.attestations = append(a.attestations, sbom)
 
-		// Todo: CycloneDX format SBOM
+		case attestation.PredicateCycloneDXDocument:
+			sbom, err := attestation.CycloneDXSBOMFromSignature(sig)
+			if err != nil {
+				return fmt.Errorf("unable to parse as CycloneDX SBOM: %w", err)
+			}
+			a.attestations = append(a.attestations, sbom)
 
 		default:
 			// It's some other kind of attestation
@@ -226,7 +277,15 @@ func (a ApplicationSnapshotImage) ValidateAttestationSyntax(ctx context.Context)
 	var validationErr error
 	for _, sp := range a.attestations {
 		pt := sp.PredicateType()
-		if schema, ok := attestationSchemas[pt]; ok {
+		if sbom, ok := sp.(attestation.SBOM); ok {
+			if err := validateSBOMSyntax(sbom); err != nil {
+				if _, ok = err.(*jsonschema.ValidationError); !ok {
+					return err
+				}
+
+				validationErr = errors.Join(validationErr, err)
+			}
+		} else if schema, ok := attestationSchemas[pt]; ok {
 			// Found a validator for this predicate type so let's use it
 			log.Debugf("Attempting to validate an attestation with predicateType %s", pt)
 
@@ -260,6 +319,37 @@ func (a ApplicationSnapshotImage) ValidateAttestationSyntax(ctx context.Context)
 	return fmt.Errorf("attestation syntax validation failed: %s", validationErr.Error())
 }
 
+// validateSBOMSyntax validates the SBOM, i.e. the predicate of the SBOM
+// attestation, against the JSON schema of its format and version
+func validateSBOMSyntax(sbom attestation.SBOM) error {
+	summary := sbom.SBOMSummary()
+
+	schema := sbomSchemas[summary.Format][summary.Version]
+	if schema == nil {
+		log.Debugf("No schema validation found for %s %s SBOM", summary.Format, summary.Version)
+		return nil
+	}
+	log.Debugf("Attempting to validate a %s %s SBOM against the %s schema", summary.Format, summary.Version, schema.Location)
+
+	var statement struct {
+		Predicate any `json:"predicate"`
+	}
+	if err := json.Unmarshal(sbom.Statement(), &statement); err != nil {
+		return fmt.Errorf("unable to decode attestation data from attestation image: %w", err)
+	}
+
+	if err := schema.Validate(statement.Predicate); err != nil {
+		if _, ok := err.(*jsonschema.ValidationError); !ok {
+			// Error while trying to validate
+			return fmt.Errorf("unable to validate attestation data from attestation image: %w", err)
+		}
+		return err
+	}
+	log.Debugf("SBOM was validated successfully against the %s schema", schema.Location)
+
+	return nil
+}
+
 // Attestations returns the value of the attestations field of the ApplicationSnapshotImage struct
 func (a *ApplicationSnapshotImage) Attestations() []attestation.Attestation {
 	return a.attestations
@@ -329,12 +419,13 @@ func (a attestationData) MarshalJSON() ([]byte, error) {
 }
 
 type image struct {
-	Ref        string                      `json:"ref"`
-	Signatures []signature.EntitySignature `json:"signatures,omitempty"`
-	Config     json.RawMessage             `json:"config,omitempty"`
-	Parent     any                         `json:"parent,omitempty"`
-	Files      map[string]json.RawMessage  `json:"files,omitempty"`
-	Source     any                         `json:"source,omitempty"`
+	Ref          string                      `json:"ref"`
+	Signatures   []signature.EntitySignature `json:"signatures,omitempty"`
+	Attestations []attestationData           `json:"attestations,omitempty"`
+	Config       json.RawMessage             `json:"config,omitempty"`
+	Parent       any                         `json:"parent,omitempty"`
+	Files        map[string]json.RawMessage  `json:"files,omitempty"`
+	Source       any                         `json:"source,omitempty"`
 }
 
 type Input struct {
@@ -343,20 +434,25 @@ type Input struct {
 	AppSnapshot  app.SnapshotSpec  `json:"snapshot"`
 }
 
-// WriteInputFile writes the JSON from the attestations to input.json in a random temp dir
-func (a *ApplicationSnapshotImage) WriteInputFile(ctx context.Context) (string, []byte, error) {
-	log.Debugf("Attempting to write %d attestations to input file", len(a.attestations))
-
-	var attestations []attestationData
-	for _, a := range a.attestations {
-		attestations = append(attestations, attestationData{
+func inputAttestations(attestations []attestation.Attestation) []attestationData {
+	var data []attestationData
+	for _, a := range attestations {
+		data = append(data, attestationData{
 			Statement:  a.Statement(),
 			Signatures: a.Signatures(),
 		})
 	}
 
+	return data
+}
+
+// PolicyInput returns the JSON encoded input for the policy evaluation with
+// the image, its attestations and the application snapshot
+func (a *ApplicationSnapshotImage) PolicyInput(_ context.Context) ([]byte, error) {
+	log.Debugf("Preparing policy input with %d attestations", len(a.attestations))
+
 	input := Input{
-		Attestations: attestations,
+		Attestations: inputAttestations(a.attestations),
 		Image: image{
 			Ref:        a.reference.String(),
 			Signatures: a.signatures,
@@ -367,38 +463,24 @@ func (a *ApplicationSnapshotImage) WriteInputFile(ctx context.Context) (string,
 		AppSnapshot: a.snapshot,
 	}
 
-	if a.parentRef != nil {
-		input.Image.Parent = image{
-			Ref:    a.parentRef.String(),
-			Config: a.parentConfigJSON,
+	// The chain of base images is nested, the base image of each image is
+	// its parent
+	for i := len(a.baseImages) - 1; i >= 0; i-- {
+		b := a.baseImages[i]
+		parent := image{
+			Ref:          b.ref.String(),
+			Signatures:   b.signatures,
+			Attestations: inputAttestations(b.attestations),
+			Config:       b.configJSON,
+			Parent:       input.Image.Parent,
 		}
+		input.Image.Parent = parent
 	}
 
-	fs := utils.FS(ctx)
-	inputDir, err := afero.TempDir(fs, "", "ecp_input.")
-	if err != nil {
-		log.Debug("Problem making temp dir!")
-		return "", nil, err
-	}
-	log.Debugf("Created dir %s", inputDir)
-	inputJSONPath := path.Join(inputDir, "input.json")
-
-	f, err := fs.OpenFile(inputJSONPath, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0644)
-	if err != nil {
-		log.Debugf("Problem creating file in %s", inputDir)
-		return "", nil, err
-	}
-	defer f.Close()
-
 	inputJSON, err := json.Marshal(input)
 	if err != nil {
-		return "", nil, fmt.Errorf("input to JSON: %w", err)
-	}
-
-	if _, err := f.Write(inputJSON); err != nil {
-		return "", nil, fmt.Errorf("write input to file: %w", err)
+		return nil, fmt.Errorf("input to JSON: %w", err)
 	}
 
-	log.Debugf("Done preparing input file:\n%s", inputJSONPath)
-	return inputJSONPath, inputJSON, nil
+	return inputJSON, nil
 }
@@ -24,10 +24,12 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"regexp"
 	"strings"
 	"testing"
 
+	ecc "github.com/enterprise-contract/enterprise-contract-controller/api/v1alpha1"
 	"github.com/gkampitakis/go-snaps/snaps"
 	"github.com/google/go-containerregistry/pkg/crane"
 	"github.com/google/go-containerregistry/pkg/name"
@@ -37,6 +39,7 @@ import (
 	"github.com/in-toto/in-toto-golang/in_toto"
 	"github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/common"
 	v02 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v0.2"
+	slsav1 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v1"
 	app "github.com/konflux-ci/application-api/api/v1alpha1"
 	"github.com/secure-systems-lab/go-securesystemslib/dsse"
 	"github.com/sigstore/cosign/v2/pkg/cosign"
@@ -48,6 +51,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 
 	"github.com/enterprise-contract/ec-cli/internal/attestation"
 	"github.com/enterprise-contract/ec-cli/internal/policy"
@@ -117,7 +121,7 @@ func createSimpleAttestation(statement *in_toto.ProvenanceStatementSLSA02, o ...
 	return a
 }
 
-func TestWriteInputFile(t *testing.T) {
+func TestPolicyInput(t *testing.T) {
 	cases := []struct {
 		name     string
 		snapshot ApplicationSnapshotImage
@@ -169,9 +173,36 @@ func TestWriteInputFile(t *testing.T) {
 		{
 			name: "parent image config",
 			snapshot: ApplicationSnapshotImage{
-				reference:        name.MustParseReference("registry.io/repository/image:tag"),
-				parentConfigJSON: json.RawMessage(`{"Labels":{"io.k8s.display-name":"Base Image"}}`),
-				parentRef:        name.MustParseReference("registry.io/repository/image/parent:tag"),
+				reference: name.MustParseReference("registry.io/repository/image:tag"),
+				baseImages: []baseImage{
+					{
+						ref:        name.MustParseReference("registry.io/repository/image/parent:tag"),
+						configJSON: json.RawMessage(`{"Labels":{"io.k8s.display-name":"Base Image"}}`),
+					},
+				},
+			},
+		},
+		{
+			name: "base image chain",
+			snapshot: ApplicationSnapshotImage{
+				reference: name.MustParseReference("registry.io/repository/image:tag"),
+				baseImages: []baseImage{
+					{
+						ref:          name.MustParseReference("registry.io/repository/image/parent:tag"),
+						configJSON:   json.RawMessage(`{"Labels":{"io.k8s.display-name":"Base Image"}}`),
+						attestations: []attestation.Attestation{createSimpleAttestation(nil)},
+					},
+					{
+						ref:        name.MustParseReference("registry.io/repository/image/grandparent:tag"),
+						configJSON: json.RawMessage(`{"Labels":{"io.k8s.display-name":"Root Image"}}`),
+						signatures: []signature.EntitySignature{
+							{
+								KeyID:     "keyId",
+								Signature: "signature",
+							},
+						},
+					},
+				},
 			},
 		},
 		{
@@ -225,25 +256,15 @@ func TestWriteInputFile(t *testing.T) {
 					},
 				},
 			}
-			inputPath, inputJSON, err := tt.snapshot.WriteInputFile(ctx)
+			inputJSON, err := tt.snapshot.PolicyInput(ctx)
 
 			assert.NoError(t, err)
-			assert.NotEmpty(t, inputPath)
-			assert.Regexp(t, `/ecp_input.\d+/input.json`, inputPath)
-			fileExists, err := afero.Exists(fs, inputPath)
-			assert.NoError(t, err)
-			assert.True(t, fileExists)
-
-			bytes, err := afero.ReadFile(fs, inputPath)
-			assert.NoError(t, err)
-			snaps.MatchJSON(t, bytes)
-
-			assert.JSONEq(t, string(inputJSON), string(bytes))
+			snaps.MatchJSON(t, inputJSON)
 		})
 	}
 }
 
-func TestWriteInputFileMultipleAttestations(t *testing.T) {
+func TestPolicyInputMultipleAttestations(t *testing.T) {
 	att := createSimpleAttestation(nil)
 	snapshot := app.SnapshotSpec{
 		Components: []app.SnapshotComponent{
@@ -263,20 +284,10 @@ func TestWriteInputFileMultipleAttestations(t *testing.T) {
 
 	fs := afero.NewMemMapFs()
 	ctx := utils.WithFS(context.Background(), fs)
-	inputPath, inputJSON, err := a.WriteInputFile(ctx)
+	inputJSON, err := a.PolicyInput(ctx)
 
 	assert.NoError(t, err)
-	assert.NotEmpty(t, inputPath)
-	assert.Regexp(t, `/ecp_input.\d+/input.json`, inputPath)
-	fileExists, err := afero.Exists(fs, inputPath)
-	assert.NoError(t, err)
-	assert.True(t, fileExists)
-
-	bytes, err := afero.ReadFile(fs, inputPath)
-	assert.NoError(t, err)
-	snaps.MatchJSON(t, bytes)
-
-	assert.JSONEq(t, string(inputJSON), string(bytes))
+	snaps.MatchJSON(t, inputJSON)
 }
 
 func TestNewApplicationSnapshotImage(t *testing.T) {
@@ -427,6 +438,8 @@ func TestValidateImageSignatureClaims(t *testing.T) {
 	ctx := o.WithClient(context.Background(), &c)
 
 	c.On("VerifyImageSignatures", ref, mock.Anything).Return([]oci.Signature{}, false, nil)
+	c.On("ResolveDigest", ref).Return("sha256:dabbad00", nil)
+	c.On("Referrers", mock.Anything).Return([]v1.Descriptor{}, nil)
 
 	err := a.ValidateImageSignature(ctx)
 	require.NoError(t, err)
@@ -548,6 +561,8 @@ func TestValidateAttestationSignatureClaims(t *testing.T) {
 	ctx := o.WithClient(context.Background(), &c)
 
 	c.On("VerifyImageAttestations", ref, mock.Anything).Return([]oci.Signature{}, false, nil)
+	c.On("ResolveDigest", ref).Return("sha256:dabbad00", nil)
+	c.On("Referrers", mock.Anything).Return([]v1.Descriptor{}, nil)
 
 	err := a.ValidateAttestationSignature(ctx)
 	require.NoError(t, err)
@@ -676,6 +691,8 @@ func TestValidateImageSignatureWithCertificates(t *testing.T) {
 	require.NoError(t, err)
 
 	c.On("VerifyImageSignatures", ref, mock.Anything).Return([]oci.Signature{sig}, false, nil)
+	c.On("ResolveDigest", ref).Return("sha256:dabbad00", nil)
+	c.On("Referrers", mock.Anything).Return([]v1.Descriptor{}, nil)
 
 	err = a.ValidateImageSignature(ctx)
 	require.NoError(t, err)
@@ -712,21 +729,6 @@ func TestFetchImageConfig(t *testing.T) {
 	require.Equal(t, string(a.configJSON), `{"Labels":{"io.k8s.display-name":"Test Image"}}`)
 }
 
-func TestFetchParentImageConfig(t *testing.T) {
-	url := utils.WithDigest("registry.local/test-image")
-	ctx := context.Background()
-	ctx = fake.WithTestImageConfig(ctx, url)
-
-	ref, err := name.ParseReference(url)
-	require.NoError(t, err)
-	a := ApplicationSnapshotImage{reference: ref}
-
-	err = a.FetchParentImageConfig(ctx)
-	require.NoError(t, err)
-
-	require.Equal(t, string(a.parentConfigJSON), `{"Labels":{"io.k8s.display-name":"Base Image"}}`)
-}
-
 func TestFetchImageFiles(t *testing.T) {
 	ref := name.MustParseReference("registry.io/repository/image:tag")
 	a := ApplicationSnapshotImage{reference: ref}
@@ -756,3 +758,261 @@ kind: ClusterServiceVersion`),
 		"manifests/csv.yaml": json.RawMessage(`{"apiVersion":"operators.coreos.com/v1alpha1","kind":"ClusterServiceVersion"}`),
 	}, a.files)
 }
+
+func TestFetchImageFilesFromRuleData(t *testing.T) {
+	ctx := context.Background()
+	ref := name.MustParseReference("registry.io/repository/image:tag")
+
+	image, err := crane.Image(map[string][]byte{
+		"manifests/csv.yaml":             []byte(`kind: ClusterServiceVersion`),
+		"etc/containers/registries.conf": []byte(`unqualified-search-registries = ["registry.io"]`),
+		"etc/os-release":                 []byte(`ID=rhel`),
+	})
+	require.NoError(t, err)
+	image, err = mutate.Config(image, v1.Config{
+		Labels: map[string]string{
+			"operators.operatorframework.io.bundle.manifests.v1": "manifests/",
+		},
+	})
+	require.NoError(t, err)
+
+	client := fake.FakeClient{}
+	client.On("Image", ref, mock.Anything).Return(image, nil)
+
+	ctx = o.WithClient(ctx, &client)
+
+	p, err := policy.NewOfflinePolicy(ctx, policy.Now)
+	require.NoError(t, err)
+	p = p.WithSpec(ecc.EnterpriseContractPolicySpec{
+		Sources: []ecc.Source{
+			{
+				RuleData: &extv1.JSON{Raw: []byte(`{"image_files": {"files": [{"glob": "etc/containers/*.conf", "format": "toml"}]}}`)},
+			},
+		},
+	})
+
+	a, err := NewApplicationSnapshotImage(ctx, app.SnapshotComponent{ContainerImage: ref.String()}, p, app.SnapshotSpec{})
+	require.NoError(t, err)
+
+	err = a.FetchImageFiles(ctx)
+	require.NoError(t, err)
+
+	require.Equal(t, map[string]json.RawMessage{
+		"manifests/csv.yaml":             json.RawMessage(`{"kind":"ClusterServiceVersion"}`),
+		"etc/containers/registries.conf": json.RawMessage(`{"unqualified-search-registries":["registry.io"]}`),
+	}, a.files)
+}
+
+func TestNewApplicationSnapshotImageInvalidImageFiles(t *testing.T) {
+	ctx := context.Background()
+
+	p, err := policy.NewOfflinePolicy(ctx, policy.Now)
+	require.NoError(t, err)
+	p = p.WithSpec(ecc.EnterpriseContractPolicySpec{
+		Sources: []ecc.Source{
+			{
+				Name:     "release",
+				RuleData: &extv1.JSON{Raw: []byte(`{"image_files": {"max_file_size": "big"}}`)},
+			},
+		},
+	})
+
+	_, err = NewApplicationSnapshotImage(ctx, app.SnapshotComponent{ContainerImage: "registry.io/repository/image:tag"}, p, app.SnapshotSpec{})
+	require.ErrorContains(t, err, `invalid max_file_size value "big" of source "release"`)
+}
+
+func TestSLSAProvenanceV1Attestation(t *testing.T) {
+	ref := name.MustParseReference("registry.io/repository/image:tag")
+
+	statement := func(builderID string) oci.Signature {
+		s, err := json.Marshal(in_toto.ProvenanceStatementSLSA1{
+			StatementHeader: in_toto.StatementHeader{
+				Type:          "https://in-toto.io/Statement/v1",
+				PredicateType: slsav1.PredicateSLSAProvenance,
+				Subject: []in_toto.Subject{
+					{Name: "registry.io/repository/image", Digest: common.DigestSet{"sha256": "dabbad00"}},
+				},
+			},
+			Predicate: slsav1.ProvenancePredicate{
+				BuildDefinition: slsav1.ProvenanceBuildDefinition{
+					BuildType:          "https://tekton.dev/chains/v2/slsa",
+					ExternalParameters: map[string]any{},
+				},
+				RunDetails: slsav1.ProvenanceRunDetails{
+					Builder: slsav1.Builder{ID: builderID},
+				},
+			},
+		})
+		require.NoError(t, err)
+
+		envelope, err := json.Marshal(dsse.Envelope{
+			PayloadType: "application/vnd.in-toto+json",
+			Payload:     base64.StdEncoding.EncodeToString(s),
+			Signatures:  []dsse.Signature{{KeyID: "key-id", Sig: "sig"}},
+		})
+		require.NoError(t, err)
+
+		sig, err := static.NewAttestation(envelope, static.WithLayerMediaType(cosignTypes.DssePayloadType))
+		require.NoError(t, err)
+
+		return sig
+	}
+
+	cases := []struct {
+		name      string
+		builderID string
+		err       string
+	}{
+		{
+			name:      "valid",
+			builderID: "https://tekton.dev/chains/v2",
+		},
+		{
+			name:      "invalid",
+			builderID: "invalid",
+			err:       "attestation syntax validation failed: jsonschema: '/predicate/runDetails/builder/id' does not validate with https://slsa.dev/provenance/v1#/properties/predicate/properties/runDetails/properties/builder/properties/id/format: 'invalid' is not valid 'uri'",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			client := fake.FakeClient{}
+			client.On("VerifyImageAttestations", ref, mock.Anything).Return([]oci.Signature{statement(c.builderID)}, true, nil)
+			client.On("ResolveDigest", ref).Return("sha256:dabbad00", nil)
+			client.On("Referrers", mock.Anything).Return([]v1.Descriptor{}, nil)
+			ctx := o.WithClient(context.Background(), &client)
+
+			a := ApplicationSnapshotImage{reference: ref}
+			require.NoError(t, a.ValidateAttestationSignature(ctx))
+			require.Len(t, a.attestations, 1)
+
+			sp, ok := a.attestations[0].(interface {
+				BuildDefinition() slsav1.ProvenanceBuildDefinition
+				RunDetails() slsav1.ProvenanceRunDetails
+			})
+			require.True(t, ok, "expecting a typed SLSA Provenance v1.0 attestation")
+			assert.Equal(t, "https://tekton.dev/chains/v2/slsa", sp.BuildDefinition().BuildType)
+			assert.Equal(t, c.builderID, sp.RunDetails().Builder.ID)
+
+			err := a.ValidateAttestationSyntax(ctx)
+			if c.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, c.err)
+			}
+		})
+	}
+}
+
+func TestSBOMSyntaxValidation(t *testing.T) {
+	ref := name.MustParseReference("registry.io/repository/image:tag")
+
+	sbom := func(predicateType, predicate string) oci.Signature {
+		s := fmt.Sprintf(`{
+			"_type": "https://in-toto.io/Statement/v0.1",
+			"predicateType": %q,
+			"subject": [{"name": "registry.io/repository/image", "digest": {"sha256": "dabbad00"}}],
+			"predicate": %s
+		}`, predicateType, predicate)
+
+		envelope, err := json.Marshal(dsse.Envelope{
+			PayloadType: "application/vnd.in-toto+json",
+			Payload:     base64.StdEncoding.EncodeToString([]byte(s)),
+			Signatures:  []dsse.Signature{{KeyID: "key-id", Sig: "sig"}},
+		})
+		require.NoError(t, err)
+
+		sig, err := static.NewAttestation(envelope, static.WithLayerMediaType(cosignTypes.DssePayloadType))
+		require.NoError(t, err)
+
+		return sig
+	}
+
+	validSPDX := `{
+		"spdxVersion": "SPDX-2.3",
+		"dataLicense": "CC0-1.0",
+		"SPDXID": "SPDXRef-DOCUMENT",
+		"name": "image",
+		"creationInfo": {"created": "2024-05-21T12:16:52Z", "creators": ["Tool: syft"]},
+		"packages": [{"SPDXID": "SPDXRef-Package", "name": "openssl", "downloadLocation": "NOASSERTION"}]
+	}`
+
+	cases := []struct {
+		name          string
+		predicateType string
+		predicate     string
+		err           *regexp.Regexp
+	}{
+		{
+			name:          "valid SPDX",
+			predicateType: attestation.PredicateSpdxDocument,
+			predicate:     validSPDX,
+		},
+		{
+			name:          "invalid SPDX",
+			predicateType: attestation.PredicateSpdxDocument,
+			predicate:     `{"spdxVersion": "SPDX-2.3", "SPDXID": "SPDXRef-DOCUMENT", "dataLicense": "CC0-1.0", "name": "image", "creationInfo": {"created": "2024-05-21T12:16:52Z", "creators": ["Tool: syft"]}, "packages": [{"name": "openssl"}]}`,
+			err:           regexp.MustCompile(`^attestation syntax validation failed: jsonschema: '/packages/0' does not validate with http://spdx.org/rdf/terms/2.3#/properties/packages/items/required: missing properties: 'SPDXID', 'downloadLocation'$`),
+		},
+		{
+			name:          "SPDX without a version",
+			predicateType: attestation.PredicateSpdxDocument,
+			predicate:     `{"packages": []}`,
+			err:           regexp.MustCompile(`^attestation syntax validation failed: jsonschema: '' does not validate with http://spdx.org/rdf/terms/2.3#/required: missing properties: .*$`),
+		},
+		{
+			name:          "SPDX of a version without a schema",
+			predicateType: attestation.PredicateSpdxDocument,
+			predicate:     `{"spdxVersion": "SPDX-2.2", "packages": [{"name": "openssl"}]}`,
+		},
+		{
+			name:          "valid CycloneDX 1.5",
+			predicateType: attestation.PredicateCycloneDXDocument,
+			predicate:     `{"bomFormat": "CycloneDX", "specVersion": "1.5", "version": 1, "components": [{"type": "library", "name": "openssl"}]}`,
+		},
+		{
+			name:          "invalid CycloneDX 1.5",
+			predicateType: attestation.PredicateCycloneDXDocument,
+			predicate:     `{"bomFormat": "CycloneDX", "specVersion": "1.5", "version": 1, "components": [{"type": "cryptographic-asset", "name": "openssl"}]}`,
+			err:           regexp.MustCompile(`^attestation syntax validation failed: jsonschema: '/components/0/type' does not validate with http://cyclonedx.org/schema/bom-1.5.schema.json#/properties/components/items/\$ref/properties/type/enum: value must be one of .*$`),
+		},
+		{
+			name:          "valid CycloneDX 1.6",
+			predicateType: attestation.PredicateCycloneDXDocument,
+			predicate:     `{"bomFormat": "CycloneDX", "specVersion": "1.6", "components": [{"type": "cryptographic-asset", "name": "openssl"}]}`,
+		},
+		{
+			name:          "CycloneDX of a version without a schema",
+			predicateType: attestation.PredicateCycloneDXDocument,
+			predicate:     `{"bomFormat": "CycloneDX", "specVersion": "1.4", "components": [{"type": "unknown"}]}`,
+		},
+		{
+			name:          "CycloneDX without a version",
+			predicateType: attestation.PredicateCycloneDXDocument,
+			predicate:     `{"bomFormat": "CycloneDX"}`,
+			err:           regexp.MustCompile(`^attestation syntax validation failed: jsonschema: '' does not validate with http://cyclonedx.org/schema/bom-1.6.schema.json#/required: missing properties: 'specVersion'$`),
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			client := fake.FakeClient{}
+			client.On("VerifyImageAttestations", ref, mock.Anything).Return([]oci.Signature{sbom(c.predicateType, c.predicate)}, true, nil)
+			client.On("ResolveDigest", ref).Return("sha256:dabbad00", nil)
+			client.On("Referrers", mock.Anything).Return([]v1.Descriptor{}, nil)
+			ctx := o.WithClient(context.Background(), &client)
+
+			a := ApplicationSnapshotImage{reference: ref}
+			require.NoError(t, a.ValidateAttestationSignature(ctx))
+			require.Len(t, a.attestations, 1)
+
+			err := a.ValidateAttestationSyntax(ctx)
+			if c.err == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+				assert.Regexp(t, c.err, err.Error())
+			}
+		})
+	}
+}
@@ -0,0 +1,280 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package application_snapshot_image
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime/trace"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/enterprise-contract/ec-cli/internal/attestation"
+	"github.com/enterprise-contract/ec-cli/internal/fetchers/oci/config"
+	"github.com/enterprise-contract/ec-cli/internal/signature"
+)
+
+// ociMaterialPrefixes are the URI prefixes of the provenance materials that
+// refer to container images
+var ociMaterialPrefixes = []string{"oci://", "docker://"}
+
+// tektonDefinitionNames are the names Tekton Chains gives to the resolved
+// dependencies holding the definitions of the pipeline and its tasks, these
+// are not base images even though they're stored in OCI registries
+var tektonDefinitionNames = map[string]bool{
+	"pipeline":     true,
+	"pipelineTask": true,
+	"task":         true,
+}
+
+// baseImage is an image from the chain of base images of the image being
+// validated, with its verified signatures and attestations when those are
+// verified
+type baseImage struct {
+	ref          name.Reference
+	configJSON   json.RawMessage
+	signatures   []signature.EntitySignature
+	attestations []attestation.Attestation
+}
+
+// FetchBaseImages resolves the chain of base images of the image, up to depth
+// base images. The base image of each image is determined from its base image
+// annotations or labels, or from the materials of its verified SLSA Provenance
+// attestations. Only the config of each base image is fetched unless verify is
+// set, in which case the signatures and attestations of each base image are
+// verified with the same options as the image itself, failing to verify those
+// does not end the chain. Must be invoked after
+// [ValidateAttestationSignature] for the attestations of the image to be
+// considered.
+func (a *ApplicationSnapshotImage) FetchBaseImages(ctx context.Context, depth int, verify bool) error {
+	if trace.IsEnabled() {
+		region := trace.StartRegion(ctx, "ec:fetch-base-images")
+		defer region.End()
+		trace.Logf(ctx, "", "image=%q depth=%d verify=%t", a.reference, depth, verify)
+	}
+
+	a.baseImages = nil
+
+	seen := map[string]bool{a.reference.Identifier(): true}
+	ref, attestations := a.reference, a.attestations
+	for len(a.baseImages) < depth {
+		parent, err := resolveBaseImage(ctx, ref, attestations)
+		if err != nil {
+			if len(a.baseImages) == 0 {
+				return err
+			}
+			log.Debugf("No base image found for %s, ending the chain: %v", ref, err)
+			return nil
+		}
+
+		if seen[parent.Identifier()] {
+			log.Debugf("Base image %s of %s is already in the chain, ending the chain", parent, ref)
+			return nil
+		}
+		seen[parent.Identifier()] = true
+
+		base := a.fetchBaseImage(ctx, parent, verify)
+		a.baseImages = append(a.baseImages, base)
+		ref, attestations = base.ref, base.attestations
+	}
+
+	return nil
+}
+
+// fetchBaseImage fetches the config of the given base image, and verifies its
+// signatures and attestations if verify is set
+func (a *ApplicationSnapshotImage) fetchBaseImage(ctx context.Context, ref name.Digest, verify bool) baseImage {
+	log.Debugf("Fetching base image %s", ref)
+
+	b := ApplicationSnapshotImage{
+		reference:       ref,
+		checkOpts:       a.checkOpts,
+		trustedMaterial: a.trustedMaterial,
+	}
+
+	if err := b.FetchImageConfig(ctx); err != nil {
+		log.Debugf("Unable to fetch the config of base image %s: %v", ref, err)
+	}
+
+	if verify {
+		if err := b.ValidateImageSignature(ctx); err != nil {
+			log.Warnf("Unable to verify the signatures of base image %s: %v", ref, err)
+		}
+
+		if err := b.ValidateAttestationSignature(ctx); err != nil {
+			log.Warnf("Unable to verify the attestations of base image %s: %v", ref, err)
+		}
+	}
+
+	return baseImage{
+		ref:          ref,
+		configJSON:   b.configJSON,
+		signatures:   b.signatures,
+		attestations: b.attestations,
+	}
+}
+
+// resolveBaseImage determines the base image of the given image, preferring
+// the base image annotations or labels of the image over the materials of its
+// provenance attestations
+func resolveBaseImage(ctx context.Context, ref name.Reference, attestations []attestation.Attestation) (name.Digest, error) {
+	parent, err := config.FetchParentImage(ctx, ref)
+	if err == nil {
+		if digest, ok := parent.(name.Digest); ok {
+			return digest, nil
+		}
+	}
+
+	digest, perr := baseImageFromProvenance(ref, attestations)
+	if perr != nil {
+		return name.Digest{}, errors.Join(err, perr)
+	}
+
+	return digest, nil
+}
+
+type provenanceMaterial struct {
+	URI    string            `json:"uri"`
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+type provenanceMaterials struct {
+	Predicate struct {
+		// SLSA Provenance v0.2
+		Materials []provenanceMaterial `json:"materials"`
+		// SLSA Provenance v1.0
+		BuildDefinition struct {
+			ResolvedDependencies []provenanceMaterial `json:"resolvedDependencies"`
+		} `json:"buildDefinition"`
+	} `json:"predicate"`
+}
+
+// baseImageFromProvenance determines the base image of the given image from
+// the materials of its SLSA Provenance attestations. Only materials with an OCI
+// URI and a sha256 digest are considered, other than the Tekton bundles the
+// pipeline and its tasks were resolved from. The base image is determined only
+// if a single such material remains.
+func baseImageFromProvenance(ref name.Reference, attestations []attestation.Attestation) (name.Digest, error) {
+	candidates := map[string]name.Digest{}
+	for _, att := range attestations {
+		switch att.PredicateType() {
+		case attestation.PredicateSLSAProvenance, attestation.PredicateSLSAProvenanceV1:
+		default:
+			continue
+		}
+
+		var statement provenanceMaterials
+		if err := json.Unmarshal(att.Statement(), &statement); err != nil {
+			log.Debugf("Unable to decode the provenance of %s: %v", ref, err)
+			continue
+		}
+
+		var raw struct {
+			Predicate any `json:"predicate"`
+		}
+		if err := json.Unmarshal(att.Statement(), &raw); err != nil {
+			continue
+		}
+		bundles := bundleReferences(raw.Predicate, nil)
+
+		materials := append(statement.Predicate.Materials, statement.Predicate.BuildDefinition.ResolvedDependencies...)
+		for _, m := range materials {
+			if tektonDefinitionNames[m.Name] {
+				continue
+			}
+
+			digest, ok := materialImage(m)
+			if !ok || digest.Identifier() == ref.Identifier() || isBundle(digest, bundles) {
+				continue
+			}
+
+			candidates[digest.String()] = digest
+		}
+	}
+
+	switch len(candidates) {
+	case 0:
+		return name.Digest{}, fmt.Errorf("no base image found in the provenance of %s", ref)
+	case 1:
+		for _, digest := range candidates {
+			return digest, nil
+		}
+	}
+
+	return name.Digest{}, fmt.Errorf("unable to determine the base image of %s, found %d candidates in its provenance", ref, len(candidates))
+}
+
+// materialImage returns the digest reference of the image the provenance
+// material refers to, if it refers to an image
+func materialImage(m provenanceMaterial) (name.Digest, bool) {
+	sha256 := m.Digest["sha256"]
+	if sha256 == "" {
+		return name.Digest{}, false
+	}
+
+	for _, prefix := range ociMaterialPrefixes {
+		if uri, ok := strings.CutPrefix(m.URI, prefix); ok {
+			ref, err := name.ParseReference(uri)
+			if err != nil {
+				return name.Digest{}, false
+			}
+			return ref.Context().Digest("sha256:" + sha256), true
+		}
+	}
+
+	return name.Digest{}, false
+}
+
+// bundleReferences collects the Tekton bundle references within the
+// predicate, either as the value of a bundle key or of a bundle parameter
+func bundleReferences(v any, refs []string) []string {
+	switch v := v.(type) {
+	case map[string]any:
+		if bundle, ok := v["bundle"].(string); ok {
+			refs = append(refs, bundle)
+		}
+		if v["name"] == "bundle" {
+			if bundle, ok := v["value"].(string); ok {
+				refs = append(refs, bundle)
+			}
+		}
+		for _, e := range v {
+			refs = bundleReferences(e, refs)
+		}
+	case []any:
+		for _, e := range v {
+			refs = bundleReferences(e, refs)
+		}
+	}
+
+	return refs
+}
+
+func isBundle(digest name.Digest, bundles []string) bool {
+	for _, b := range bundles {
+		if strings.Contains(b, digest.DigestStr()) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,332 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package application_snapshot_image
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/in-toto/in-toto-golang/in_toto"
+	"github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/common"
+	v02 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v0.2"
+	slsav1 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v1"
+	"github.com/secure-systems-lab/go-securesystemslib/dsse"
+	"github.com/sigstore/cosign/v2/pkg/oci"
+	"github.com/sigstore/cosign/v2/pkg/oci/static"
+	cosignTypes "github.com/sigstore/cosign/v2/pkg/types"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/enterprise-contract/ec-cli/internal/attestation"
+	"github.com/enterprise-contract/ec-cli/internal/utils"
+	o "github.com/enterprise-contract/ec-cli/internal/utils/oci"
+	"github.com/enterprise-contract/ec-cli/internal/utils/oci/fake"
+)
+
+const (
+	imageDigest       = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+	parentDigest      = "sha256:2222222222222222222222222222222222222222222222222222222222222222"
+	grandparentDigest = "sha256:3333333333333333333333333333333333333333333333333333333333333333"
+	bundleDigest      = "sha256:4444444444444444444444444444444444444444444444444444444444444444"
+)
+
+// provenanceWithDependencies returns a SLSA Provenance v1.0 attestation with
+// the given resolved dependencies
+func provenanceWithDependencies(t *testing.T, dependencies ...slsav1.ResourceDescriptor) oci.Signature {
+	s, err := json.Marshal(in_toto.ProvenanceStatementSLSA1{
+		StatementHeader: in_toto.StatementHeader{
+			Type:          "https://in-toto.io/Statement/v1",
+			PredicateType: slsav1.PredicateSLSAProvenance,
+		},
+		Predicate: slsav1.ProvenancePredicate{
+			BuildDefinition: slsav1.ProvenanceBuildDefinition{
+				BuildType:            "https://tekton.dev/chains/v2/slsa",
+				ResolvedDependencies: dependencies,
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	envelope, err := json.Marshal(dsse.Envelope{
+		PayloadType: "application/vnd.in-toto+json",
+		Payload:     base64.StdEncoding.EncodeToString(s),
+		Signatures:  []dsse.Signature{{KeyID: "key-id", Sig: "sig"}},
+	})
+	require.NoError(t, err)
+
+	sig, err := static.NewAttestation(envelope, static.WithLayerMediaType(cosignTypes.DssePayloadType))
+	require.NoError(t, err)
+
+	return sig
+}
+
+func imageWithBaseImage(t *testing.T, base string) v1.Image {
+	image := mutate.Annotations(empty.Image, map[string]string{
+		o.BaseImageNameAnnotation: base,
+	}).(v1.Image)
+	image, err := mutate.Config(image, v1.Config{
+		Labels: map[string]string{"io.k8s.display-name": base},
+	})
+	require.NoError(t, err)
+
+	return image
+}
+
+func TestFetchBaseImages(t *testing.T) {
+	ref := name.MustParseReference("registry.local/image@" + imageDigest)
+	parentRef := name.MustParseReference("registry.local/parent@" + parentDigest)
+	grandparentRef := name.MustParseReference("registry.local/grandparent@" + grandparentDigest)
+
+	// The image has no base image annotations, its base image is recorded in
+	// the materials of its provenance
+	provenance, err := attestation.SLSAProvenanceV1FromSignature(provenanceWithDependencies(t,
+		slsav1.ResourceDescriptor{
+			URI:    "git+https://git.local/repository.git",
+			Digest: common.DigestSet{"sha1": "cafe"},
+		},
+		slsav1.ResourceDescriptor{
+			Name:   "task",
+			URI:    "oci://registry.local/task-bundle",
+			Digest: common.DigestSet{"sha256": strings.TrimPrefix(bundleDigest, "sha256:")},
+		},
+		slsav1.ResourceDescriptor{
+			URI:    "oci://registry.local/parent:latest",
+			Digest: common.DigestSet{"sha256": strings.TrimPrefix(parentDigest, "sha256:")},
+		},
+	))
+	require.NoError(t, err)
+
+	cases := []struct {
+		name         string
+		depth        int
+		verify       bool
+		grandparent  v1.Image
+		attestations []attestation.Attestation
+		expected     []string
+		err          string
+	}{
+		{
+			name:         "disabled",
+			depth:        0,
+			attestations: []attestation.Attestation{provenance},
+		},
+		{
+			name:         "immediate base image",
+			depth:        1,
+			attestations: []attestation.Attestation{provenance},
+			expected:     []string{parentRef.String()},
+		},
+		{
+			name:         "verified immediate base image",
+			depth:        1,
+			verify:       true,
+			attestations: []attestation.Attestation{provenance},
+			expected:     []string{parentRef.String()},
+		},
+		{
+			name:         "whole chain",
+			depth:        5,
+			verify:       true,
+			attestations: []attestation.Attestation{provenance},
+			expected:     []string{parentRef.String(), grandparentRef.String()},
+		},
+		{
+			name:         "cycle",
+			depth:        5,
+			grandparent:  imageWithBaseImage(t, parentRef.String()),
+			attestations: []attestation.Attestation{provenance},
+			expected:     []string{parentRef.String(), grandparentRef.String()},
+		},
+		{
+			name:  "no base image",
+			depth: 1,
+			err:   "no base image found in the provenance",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			grandparent := c.grandparent
+			if grandparent == nil {
+				grandparent = empty.Image
+			}
+
+			client := fake.FakeClient{}
+			client.On("Image", ref, mock.Anything).Return(empty.Image, nil)
+			client.On("Image", parentRef, mock.Anything).Return(imageWithBaseImage(t, grandparentRef.String()), nil)
+			client.On("Image", grandparentRef, mock.Anything).Return(grandparent, nil)
+			client.On("VerifyImageSignatures", mock.Anything, mock.Anything).Return(nil, false, errors.New("no signatures found"))
+			client.On("VerifyImageAttestations", parentRef, mock.Anything).Return([]oci.Signature{provenanceWithDependencies(t)}, true, nil)
+			client.On("VerifyImageAttestations", grandparentRef, mock.Anything).Return(nil, false, errors.New("no attestations found"))
+			client.On("Referrers", mock.Anything).Return(nil, nil)
+			ctx := o.WithClient(context.Background(), &client)
+
+			a := ApplicationSnapshotImage{reference: ref, attestations: c.attestations}
+			err := a.FetchBaseImages(ctx, c.depth, c.verify)
+			if c.err != "" {
+				require.ErrorContains(t, err, c.err)
+				require.Empty(t, a.baseImages)
+				return
+			}
+			require.NoError(t, err)
+
+			refs := []string{}
+			for _, b := range a.baseImages {
+				refs = append(refs, b.ref.String())
+				require.NotEmpty(t, b.configJSON)
+			}
+			require.Equal(t, len(c.expected), len(refs))
+			if len(c.expected) > 0 {
+				require.Equal(t, c.expected, refs)
+				if c.verify {
+					// The verified attestations of the base images are retained
+					require.Len(t, a.baseImages[0].attestations, 1)
+				} else {
+					// Only the config of the base images is fetched
+					require.Empty(t, a.baseImages[0].attestations)
+					client.AssertNotCalled(t, "VerifyImageAttestations", parentRef, mock.Anything)
+				}
+			}
+		})
+	}
+}
+
+func TestFetchBaseImagesFromAnnotations(t *testing.T) {
+	url := utils.WithDigest("registry.local/test-image")
+	ctx := fake.WithTestImageConfig(context.Background(), url)
+
+	ref, err := name.ParseReference(url)
+	require.NoError(t, err)
+	a := ApplicationSnapshotImage{reference: ref}
+
+	err = a.FetchBaseImages(ctx, 1, false)
+	require.NoError(t, err)
+
+	require.Len(t, a.baseImages, 1)
+	require.Equal(t, utils.WithDigest("registry.local/base-image"), a.baseImages[0].ref.String())
+	require.Equal(t, `{"Labels":{"io.k8s.display-name":"Base Image"}}`, string(a.baseImages[0].configJSON))
+}
+
+func TestBaseImageFromProvenance(t *testing.T) {
+	ref := name.MustParseReference("registry.local/image@" + imageDigest)
+
+	material := func(uri, digest string) common.ProvenanceMaterial {
+		return common.ProvenanceMaterial{URI: uri, Digest: common.DigestSet{"sha256": strings.TrimPrefix(digest, "sha256:")}}
+	}
+
+	provenance := func(buildConfig any, materials ...common.ProvenanceMaterial) attestation.Attestation {
+		return createSimpleAttestation(&in_toto.ProvenanceStatementSLSA02{
+			StatementHeader: in_toto.StatementHeader{
+				Type:          in_toto.StatementInTotoV01,
+				PredicateType: v02.PredicateSLSAProvenance,
+			},
+			Predicate: v02.ProvenancePredicate{
+				BuildType:   pipelineRunBuildType,
+				BuildConfig: buildConfig,
+				Materials:   materials,
+			},
+		})
+	}
+
+	cases := []struct {
+		name         string
+		attestations []attestation.Attestation
+		expected     string
+		err          string
+	}{
+		{
+			name: "no attestations",
+			err:  "no base image found in the provenance",
+		},
+		{
+			name: "single image material",
+			attestations: []attestation.Attestation{provenance(nil,
+				common.ProvenanceMaterial{URI: "git+https://git.local/repository.git", Digest: common.DigestSet{"sha1": "cafe"}},
+				material("oci://registry.local/parent", parentDigest),
+			)},
+			expected: "registry.local/parent@" + parentDigest,
+		},
+		{
+			name: "docker scheme",
+			attestations: []attestation.Attestation{provenance(nil,
+				material("docker://registry.local/parent:1.0", parentDigest),
+			)},
+			expected: "registry.local/parent@" + parentDigest,
+		},
+		{
+			name: "bundle key excluded",
+			attestations: []attestation.Attestation{provenance(
+				map[string]any{"tasks": []any{map[string]any{"ref": map[string]any{"bundle": "registry.local/bundle@" + bundleDigest}}}},
+				material("oci://registry.local/bundle", bundleDigest),
+				material("oci://registry.local/parent", parentDigest),
+			)},
+			expected: "registry.local/parent@" + parentDigest,
+		},
+		{
+			name: "bundle parameter excluded",
+			attestations: []attestation.Attestation{provenance(
+				map[string]any{"tasks": []any{map[string]any{"ref": map[string]any{
+					"resolver": "bundles",
+					"params": []any{
+						map[string]any{"name": "bundle", "value": "registry.local/bundle@" + bundleDigest},
+					},
+				}}}},
+				material("oci://registry.local/bundle", bundleDigest),
+				material("oci://registry.local/parent", parentDigest),
+			)},
+			expected: "registry.local/parent@" + parentDigest,
+		},
+		{
+			name: "image itself excluded",
+			attestations: []attestation.Attestation{provenance(nil,
+				material("oci://registry.local/image", imageDigest),
+				material("oci://registry.local/parent", parentDigest),
+			)},
+			expected: "registry.local/parent@" + parentDigest,
+		},
+		{
+			name: "ambiguous",
+			attestations: []attestation.Attestation{provenance(nil,
+				material("oci://registry.local/parent", parentDigest),
+				material("oci://registry.local/grandparent", grandparentDigest),
+			)},
+			err: "found 2 candidates",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			digest, err := baseImageFromProvenance(ref, c.attestations)
+			if c.err != "" {
+				require.ErrorContains(t, err, c.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, c.expected, digest.String())
+		})
+	}
+}
@@ -0,0 +1,464 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package application_snapshot_image
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	ssldsse "github.com/secure-systems-lab/go-securesystemslib/dsse"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	"github.com/sigstore/cosign/v2/pkg/cosign/bundle"
+	"github.com/sigstore/cosign/v2/pkg/oci"
+	"github.com/sigstore/cosign/v2/pkg/oci/static"
+	cosigntypes "github.com/sigstore/cosign/v2/pkg/types"
+	protobundle "github.com/sigstore/protobuf-specs/gen/pb-go/bundle/v1"
+	sgbundle "github.com/sigstore/sigstore-go/pkg/bundle"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/enterprise-contract/ec-cli/internal/signature"
+	ecoci "github.com/enterprise-contract/ec-cli/internal/utils/oci"
+)
+
+const (
+	// cosignSignatureArtifactType is the artifact type of the signatures
+	// cosign attaches as referrers
+	cosignSignatureArtifactType = "application/vnd.dev.cosign.artifact.sig.v1+json"
+	// sigstoreBundleArtifactType is the common prefix of the artifact types
+	// of Sigstore bundles, e.g. application/vnd.dev.sigstore.bundle.v0.3+json
+	sigstoreBundleArtifactType = "application/vnd.dev.sigstore.bundle"
+	// dsseArtifactType is the artifact type of DSSE envelopes
+	dsseArtifactType = "application/vnd.dsse.envelope.v1+json"
+	// inTotoArtifactType is the artifact type of in-toto attestations
+	inTotoArtifactType = "application/vnd.in-toto+json"
+)
+
+// referrers holds the artifacts referring to the image
+type referrers struct {
+	digest      name.Digest
+	descriptors []v1.Descriptor
+}
+
+// signatures adapts a slice of signatures to oci.Signatures, only Get is used
+// by the cosign verification functions
+type signatures struct {
+	oci.Signatures
+	signatures []oci.Signature
+}
+
+func (s signatures) Get() ([]oci.Signature, error) {
+	return s.signatures, nil
+}
+
+// fetchReferrers returns the artifacts referring to the image, discovered
+// through the OCI referrers API or the referrers tag schema. The referrers are
+// fetched once and shared between the image signature and the attestation
+// signature checks. Failing to fetch the referrers is not an error, the image
+// is treated as having no referrers in that case.
+func (a *ApplicationSnapshotImage) fetchReferrers(ctx context.Context) *referrers {
+	if a.referrers != nil {
+		return a.referrers
+	}
+
+	a.referrers = &referrers{}
+
+	client := ecoci.NewClient(ctx)
+
+	digest, ok := a.reference.(name.Digest)
+	if !ok {
+		resolved, err := client.ResolveDigest(a.reference)
+		if err != nil {
+			log.Debugf("Unable to resolve the digest of %s to fetch its referrers: %v", a.reference, err)
+			return a.referrers
+		}
+		digest = a.reference.Context().Digest(resolved)
+	}
+
+	descriptors, err := client.Referrers(digest)
+	if err != nil {
+		log.Debugf("Unable to fetch the referrers of %s: %v", digest, err)
+		return a.referrers
+	}
+
+	a.referrers.digest = digest
+	a.referrers.descriptors = descriptors
+
+	return a.referrers
+}
+
+// referrerSignatures returns the image signatures attached to the image as
+// referrers that verify with the provided options, and the reasons the other
+// signatures failed to verify
+func (a *ApplicationSnapshotImage) referrerSignatures(ctx context.Context, opts *cosign.CheckOpts) ([]oci.Signature, error) {
+	r := a.fetchReferrers(ctx)
+	if len(r.descriptors) == 0 {
+		return nil, nil
+	}
+
+	hash, err := v1.NewHash(r.digest.DigestStr())
+	if err != nil {
+		return nil, err
+	}
+
+	client := ecoci.NewClient(ctx)
+
+	var verified []oci.Signature
+	var errs error
+	for _, d := range r.descriptors {
+		ref := r.digest.Context().Digest(d.Digest.String())
+
+		switch {
+		case d.ArtifactType == cosignSignatureArtifactType:
+			sigs, err := cosignSignatures(client, ref)
+			if err != nil {
+				errs = errors.Join(errs, fmt.Errorf("referrer %s: %w", d.Digest, err))
+				continue
+			}
+
+			for _, sig := range sigs {
+				if _, err := cosign.VerifyImageSignature(ctx, sig, hash, opts); err != nil {
+					errs = errors.Join(errs, fmt.Errorf("referrer %s: %w", d.Digest, err))
+					continue
+				}
+				verified = append(verified, sig)
+			}
+		case strings.HasPrefix(d.ArtifactType, sigstoreBundleArtifactType):
+			sigs, err := a.verifyBundles(ctx, client, ref, hash, opts, false)
+			if err != nil {
+				errs = errors.Join(errs, fmt.Errorf("referrer %s: %w", d.Digest, err))
+			}
+			verified = append(verified, sigs...)
+		}
+	}
+
+	return verified, errs
+}
+
+// referrerAttestations returns the attestations attached to the image as
+// referrers, either within Sigstore bundles or as DSSE envelopes, that verify
+// with the provided options, and the reasons the other attestations failed to
+// verify
+func (a *ApplicationSnapshotImage) referrerAttestations(ctx context.Context, opts *cosign.CheckOpts) ([]oci.Signature, error) {
+	r := a.fetchReferrers(ctx)
+	if len(r.descriptors) == 0 {
+		return nil, nil
+	}
+
+	hash, err := v1.NewHash(r.digest.DigestStr())
+	if err != nil {
+		return nil, err
+	}
+
+	client := ecoci.NewClient(ctx)
+
+	var verified []oci.Signature
+	var errs error
+	for _, d := range r.descriptors {
+		ref := r.digest.Context().Digest(d.Digest.String())
+
+		switch {
+		case strings.HasPrefix(d.ArtifactType, sigstoreBundleArtifactType):
+			atts, err := a.verifyBundles(ctx, client, ref, hash, opts, true)
+			if err != nil {
+				errs = errors.Join(errs, fmt.Errorf("referrer %s: %w", d.Digest, err))
+			}
+			verified = append(verified, atts...)
+		case d.ArtifactType == dsseArtifactType, d.ArtifactType == inTotoArtifactType:
+			atts, err := envelopeAttestations(client, ref)
+			if err != nil {
+				errs = errors.Join(errs, fmt.Errorf("referrer %s: %w", d.Digest, err))
+				continue
+			}
+
+			for _, att := range atts {
+				checked, err := verifyAttestation(ctx, att, hash, opts)
+				if err != nil {
+					errs = errors.Join(errs, fmt.Errorf("referrer %s: %w", d.Digest, err))
+					continue
+				}
+				verified = append(verified, checked...)
+			}
+		}
+	}
+
+	return verified, errs
+}
+
+// verifyBundles verifies the Sigstore bundles held in the layers of an
+// artifact, considering either the bundles holding DSSE envelopes, i.e.
+// attestations, or the bundles holding message signatures. When a trusted root
+// is provided the bundles are verified offline against it. Otherwise the DSSE
+// envelopes are verified in the same way as cosign attestations, and the
+// message signatures are skipped as they can't be verified that way.
+func (a *ApplicationSnapshotImage) verifyBundles(ctx context.Context, client ecoci.Client, ref name.Digest, hash v1.Hash, opts *cosign.CheckOpts, attestations bool) ([]oci.Signature, error) {
+	bundles, err := bundles(client, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	var verified []oci.Signature
+	var errs error
+	for _, b := range bundles {
+		if (b.GetDsseEnvelope() != nil) != attestations {
+			continue
+		}
+
+		if a.trustedMaterial != nil {
+			sig, err := signature.VerifyBundle(b, hash, signature.BundleOptions{
+				TrustedMaterial: a.trustedMaterial,
+				PublicKey:       opts.SigVerifier,
+				Identities:      opts.Identities,
+				IgnoreTlog:      opts.IgnoreTlog,
+				IgnoreSCT:       opts.IgnoreSCT,
+			})
+			if err != nil {
+				errs = errors.Join(errs, err)
+				continue
+			}
+			verified = append(verified, sig)
+			continue
+		}
+
+		if !attestations {
+			log.Debugf("Skipping the Sigstore bundle in %s holding a message signature, a trusted root is needed to verify it", ref)
+			continue
+		}
+
+		att, err := bundleAttestation(b.Bundle)
+		if err != nil {
+			errs = errors.Join(errs, err)
+			continue
+		}
+
+		checked, err := verifyAttestation(ctx, att, hash, opts)
+		if err != nil {
+			errs = errors.Join(errs, err)
+			continue
+		}
+		verified = append(verified, checked...)
+	}
+
+	return verified, errs
+}
+
+func verifyAttestation(ctx context.Context, att oci.Signature, hash v1.Hash, opts *cosign.CheckOpts) ([]oci.Signature, error) {
+	checked, _, err := cosign.VerifyImageAttestation(ctx, signatures{signatures: []oci.Signature{att}}, hash, opts)
+	return checked, err
+}
+
+// cosignSignatures returns the signatures held in the layers of a cosign
+// signature artifact
+func cosignSignatures(client ecoci.Client, ref name.Digest) ([]oci.Signature, error) {
+	img, err := client.Image(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, err := img.Manifest()
+	if err != nil {
+		return nil, err
+	}
+
+	sigs := make([]oci.Signature, 0, len(manifest.Layers))
+	for _, desc := range manifest.Layers {
+		payload, err := layerContent(img, desc.Digest)
+		if err != nil {
+			return nil, err
+		}
+
+		ann := desc.Annotations
+		opts := []static.Option{static.WithLayerMediaType(desc.MediaType), static.WithAnnotations(ann)}
+		if cert := ann[static.CertificateAnnotationKey]; cert != "" {
+			opts = append(opts, static.WithCertChain([]byte(cert), []byte(ann[static.ChainAnnotationKey])))
+		}
+		if b := ann[static.BundleAnnotationKey]; b != "" {
+			var rekorBundle bundle.RekorBundle
+			if err := json.Unmarshal([]byte(b), &rekorBundle); err != nil {
+				return nil, fmt.Errorf("malformed transparency log bundle: %w", err)
+			}
+			opts = append(opts, static.WithBundle(&rekorBundle))
+		}
+		if ts := ann[static.RFC3161TimestampAnnotationKey]; ts != "" {
+			var timestamp bundle.RFC3161Timestamp
+			if err := json.Unmarshal([]byte(ts), &timestamp); err != nil {
+				return nil, fmt.Errorf("malformed RFC3161 timestamp: %w", err)
+			}
+			opts = append(opts, static.WithRFC3161Timestamp(&timestamp))
+		}
+
+		sig, err := static.NewSignature(payload, ann[static.SignatureAnnotationKey], opts...)
+		if err != nil {
+			return nil, err
+		}
+		sigs = append(sigs, sig)
+	}
+
+	return sigs, nil
+}
+
+// envelopeAttestations returns the attestations held as DSSE envelopes in the
+// layers of an artifact
+func envelopeAttestations(client ecoci.Client, ref name.Digest) ([]oci.Signature, error) {
+	img, err := client.Image(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, err := img.Manifest()
+	if err != nil {
+		return nil, err
+	}
+
+	atts := make([]oci.Signature, 0, len(manifest.Layers))
+	for _, desc := range manifest.Layers {
+		envelope, err := layerContent(img, desc.Digest)
+		if err != nil {
+			return nil, err
+		}
+
+		att, err := static.NewAttestation(envelope, static.WithLayerMediaType(cosigntypes.DssePayloadType))
+		if err != nil {
+			return nil, err
+		}
+		atts = append(atts, att)
+	}
+
+	return atts, nil
+}
+
+// bundles returns the Sigstore bundles held in the layers of an artifact
+func bundles(client ecoci.Client, ref name.Digest) ([]*sgbundle.Bundle, error) {
+	img, err := client.Image(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, err := img.Manifest()
+	if err != nil {
+		return nil, err
+	}
+
+	bundles := make([]*sgbundle.Bundle, 0, len(manifest.Layers))
+	for _, desc := range manifest.Layers {
+		data, err := layerContent(img, desc.Digest)
+		if err != nil {
+			return nil, err
+		}
+
+		var b sgbundle.Bundle
+		if err := b.UnmarshalJSON(data); err != nil {
+			return nil, fmt.Errorf("malformed Sigstore bundle: %w", err)
+		}
+		bundles = append(bundles, &b)
+	}
+
+	return bundles, nil
+}
+
+// bundleAttestation converts the DSSE envelope and the verification material
+// of a bundle into the form cosign uses for attestations so it can be verified
+// in the same way
+func bundleAttestation(b *protobundle.Bundle) (oci.Signature, error) {
+	envelope := b.GetDsseEnvelope()
+
+	env := ssldsse.Envelope{
+		PayloadType: envelope.GetPayloadType(),
+		Payload:     base64.StdEncoding.EncodeToString(envelope.GetPayload()),
+	}
+	for _, s := range envelope.GetSignatures() {
+		env.Signatures = append(env.Signatures, ssldsse.Signature{
+			KeyID: s.GetKeyid(),
+			Sig:   base64.StdEncoding.EncodeToString(s.GetSig()),
+		})
+	}
+
+	payload, err := json.Marshal(env)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []static.Option{static.WithLayerMediaType(cosigntypes.DssePayloadType)}
+
+	material := b.GetVerificationMaterial()
+	var certs [][]byte
+	if cert := material.GetCertificate(); cert != nil {
+		certs = append(certs, cert.GetRawBytes())
+	}
+	for _, cert := range material.GetX509CertificateChain().GetCertificates() {
+		certs = append(certs, cert.GetRawBytes())
+	}
+	if len(certs) > 0 {
+		var chain []byte
+		for _, c := range certs[1:] {
+			chain = append(chain, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c})...)
+		}
+		opts = append(opts, static.WithCertChain(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certs[0]}), chain))
+	}
+
+	// cosign verifies a single transparency log entry, the first entry with an
+	// inclusion promise is used
+	for _, entry := range material.GetTlogEntries() {
+		promise := entry.GetInclusionPromise()
+		if promise == nil {
+			continue
+		}
+		opts = append(opts, static.WithBundle(&bundle.RekorBundle{
+			SignedEntryTimestamp: promise.GetSignedEntryTimestamp(),
+			Payload: bundle.RekorPayload{
+				Body:           base64.StdEncoding.EncodeToString(entry.GetCanonicalizedBody()),
+				IntegratedTime: entry.GetIntegratedTime(),
+				LogIndex:       entry.GetLogIndex(),
+				LogID:          hex.EncodeToString(entry.GetLogId().GetKeyId()),
+			},
+		}))
+		break
+	}
+
+	if ts := material.GetTimestampVerificationData().GetRfc3161Timestamps(); len(ts) > 0 {
+		opts = append(opts, static.WithRFC3161Timestamp(&bundle.RFC3161Timestamp{
+			SignedRFC3161Timestamp: ts[0].GetSignedTimestamp(),
+		}))
+	}
+
+	return static.NewAttestation(payload, opts...)
+}
+
+func layerContent(img v1.Image, digest v1.Hash) ([]byte, error) {
+	layer, err := img.LayerByDigest(digest)
+	if err != nil {
+		return nil, err
+	}
+
+	// attached artifacts are not compressed, the compressed stream is the
+	// content as stored in the registry
+	rc, err := layer.Compressed()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	return io.ReadAll(rc)
+}
@@ -0,0 +1,368 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package application_snapshot_image
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	ggcrstatic "github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/in-toto/in-toto-golang/in_toto"
+	ssldsse "github.com/secure-systems-lab/go-securesystemslib/dsse"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	"github.com/sigstore/cosign/v2/pkg/oci/static"
+	cosignTypes "github.com/sigstore/cosign/v2/pkg/types"
+	protobundle "github.com/sigstore/protobuf-specs/gen/pb-go/bundle/v1"
+	protocommon "github.com/sigstore/protobuf-specs/gen/pb-go/common/v1"
+	protodsse "github.com/sigstore/protobuf-specs/gen/pb-go/dsse"
+	"github.com/sigstore/sigstore-go/pkg/root"
+	sigstoresig "github.com/sigstore/sigstore/pkg/signature"
+	sigstoredsse "github.com/sigstore/sigstore/pkg/signature/dsse"
+	"github.com/sigstore/sigstore/pkg/signature/options"
+	"github.com/sigstore/sigstore/pkg/signature/payload"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	o "github.com/enterprise-contract/ec-cli/internal/utils/oci"
+	"github.com/enterprise-contract/ec-cli/internal/utils/oci/fake"
+)
+
+const referrersImageDigest = "sha256:4e388ab32b10dc8dbc7e28144f552830adc74787c1e2c0824032078a79f227fb"
+
+func newSignerVerifier(t *testing.T) sigstoresig.SignerVerifier {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	sv, err := sigstoresig.LoadSignerVerifier(key, crypto.SHA256)
+	require.NoError(t, err)
+
+	return sv
+}
+
+func signatureArtifact(t *testing.T, sv sigstoresig.Signer) v1.Image {
+	p, err := json.Marshal(payload.SimpleContainerImage{
+		Critical: payload.Critical{
+			Image: payload.Image{DockerManifestDigest: referrersImageDigest},
+			Type:  "cosign container image signature",
+		},
+	})
+	require.NoError(t, err)
+
+	sig, err := sv.SignMessage(bytes.NewReader(p))
+	require.NoError(t, err)
+
+	artifact, err := mutate.Append(mutate.ConfigMediaType(empty.Image, cosignSignatureArtifactType), mutate.Addendum{
+		Layer: ggcrstatic.NewLayer(p, cosignTypes.SimpleSigningMediaType),
+		Annotations: map[string]string{
+			static.SignatureAnnotationKey: base64.StdEncoding.EncodeToString(sig),
+		},
+	})
+	require.NoError(t, err)
+
+	return artifact
+}
+
+func signedEnvelope(t *testing.T, sv sigstoresig.SignerVerifier, digest string) []byte {
+	algorithm, hex, _ := strings.Cut(digest, ":")
+	statement, err := json.Marshal(in_toto.Statement{
+		StatementHeader: in_toto.StatementHeader{
+			Type:          in_toto.StatementInTotoV01,
+			PredicateType: "https://example.com/predicate/v1",
+			Subject: []in_toto.Subject{
+				{Name: "registry.io/repository/image", Digest: map[string]string{algorithm: hex}},
+			},
+		},
+		Predicate: map[string]any{"hello": "world"},
+	})
+	require.NoError(t, err)
+
+	envelope, err := sigstoredsse.WrapSigner(sv, "application/vnd.in-toto+json").SignMessage(bytes.NewReader(statement))
+	require.NoError(t, err)
+
+	return envelope
+}
+
+func envelopeArtifact(t *testing.T, envelope []byte) v1.Image {
+	artifact, err := mutate.Append(mutate.ConfigMediaType(empty.Image, "application/vnd.oci.empty.v1+json"), mutate.Addendum{
+		Layer: ggcrstatic.NewLayer(envelope, dsseArtifactType),
+	})
+	require.NoError(t, err)
+
+	return artifact
+}
+
+func bundleArtifact(t *testing.T, envelope []byte) v1.Image {
+	var env ssldsse.Envelope
+	require.NoError(t, json.Unmarshal(envelope, &env))
+
+	p, err := base64.StdEncoding.DecodeString(env.Payload)
+	require.NoError(t, err)
+
+	dsseEnvelope := &protodsse.Envelope{Payload: p, PayloadType: env.PayloadType}
+	for _, s := range env.Signatures {
+		sig, err := base64.StdEncoding.DecodeString(s.Sig)
+		require.NoError(t, err)
+		dsseEnvelope.Signatures = append(dsseEnvelope.Signatures, &protodsse.Signature{Sig: sig, Keyid: s.KeyID})
+	}
+
+	b, err := protojson.Marshal(&protobundle.Bundle{
+		MediaType: "application/vnd.dev.sigstore.bundle.v0.3+json",
+		VerificationMaterial: &protobundle.VerificationMaterial{
+			Content: &protobundle.VerificationMaterial_PublicKey{
+				PublicKey: &protocommon.PublicKeyIdentifier{Hint: "key"},
+			},
+		},
+		Content: &protobundle.Bundle_DsseEnvelope{DsseEnvelope: dsseEnvelope},
+	})
+	require.NoError(t, err)
+
+	artifact, err := mutate.Append(mutate.ConfigMediaType(empty.Image, "application/vnd.oci.empty.v1+json"), mutate.Addendum{
+		Layer: ggcrstatic.NewLayer(b, "application/vnd.dev.sigstore.bundle.v0.3+json"),
+	})
+	require.NoError(t, err)
+
+	return artifact
+}
+
+func messageBundleArtifact(t *testing.T, sv sigstoresig.Signer) v1.Image {
+	digest, err := hex.DecodeString(strings.TrimPrefix(referrersImageDigest, "sha256:"))
+	require.NoError(t, err)
+
+	sig, err := sv.SignMessage(bytes.NewReader(digest), options.WithDigest(digest))
+	require.NoError(t, err)
+
+	b, err := protojson.Marshal(&protobundle.Bundle{
+		MediaType: "application/vnd.dev.sigstore.bundle.v0.3+json",
+		VerificationMaterial: &protobundle.VerificationMaterial{
+			Content: &protobundle.VerificationMaterial_PublicKey{
+				PublicKey: &protocommon.PublicKeyIdentifier{Hint: "key"},
+			},
+		},
+		Content: &protobundle.Bundle_MessageSignature{
+			MessageSignature: &protocommon.MessageSignature{
+				MessageDigest: &protocommon.HashOutput{
+					Algorithm: protocommon.HashAlgorithm_SHA2_256,
+					Digest:    digest,
+				},
+				Signature: sig,
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	artifact, err := mutate.Append(mutate.ConfigMediaType(empty.Image, "application/vnd.oci.empty.v1+json"), mutate.Addendum{
+		Layer: ggcrstatic.NewLayer(b, "application/vnd.dev.sigstore.bundle.v0.3+json"),
+	})
+	require.NoError(t, err)
+
+	return artifact
+}
+
+func referrerClient(t *testing.T, ref name.Reference, artifacts map[string]v1.Image) *fake.FakeClient {
+	c := fake.FakeClient{}
+	c.On("VerifyImageSignatures", ref, mock.Anything).Return(nil, false, errors.New("no signatures found"))
+	c.On("VerifyImageAttestations", ref, mock.Anything).Return(nil, false, errors.New("no attestations found"))
+	c.On("ResolveDigest", ref).Return(referrersImageDigest, nil)
+
+	descriptors := make([]v1.Descriptor, 0, len(artifacts))
+	for artifactType, artifact := range artifacts {
+		digest, err := artifact.Digest()
+		require.NoError(t, err)
+		descriptors = append(descriptors, v1.Descriptor{
+			MediaType:    types.OCIManifestSchema1,
+			ArtifactType: artifactType,
+			Digest:       digest,
+		})
+		c.On("Image", ref.Context().Digest(digest.String())).Return(artifact, nil)
+	}
+	c.On("Referrers", ref.Context().Digest(referrersImageDigest)).Return(descriptors, nil)
+
+	return &c
+}
+
+func TestReferrerSignatures(t *testing.T) {
+	ref := name.MustParseReference("registry.io/repository/image:tag")
+	sv := newSignerVerifier(t)
+
+	c := referrerClient(t, ref, map[string]v1.Image{
+		cosignSignatureArtifactType: signatureArtifact(t, sv),
+	})
+	ctx := o.WithClient(context.Background(), c)
+
+	a := ApplicationSnapshotImage{
+		reference: ref,
+		checkOpts: cosign.CheckOpts{SigVerifier: sv, IgnoreTlog: true},
+	}
+
+	require.NoError(t, a.ValidateImageSignature(ctx))
+	require.Len(t, a.signatures, 1)
+}
+
+func TestReferrerBundlesWithTrustedRoot(t *testing.T) {
+	ref := name.MustParseReference("registry.io/repository/image:tag")
+	sv := newSignerVerifier(t)
+
+	c := referrerClient(t, ref, map[string]v1.Image{
+		"application/vnd.dev.sigstore.bundle.v0.3+json":        messageBundleArtifact(t, sv),
+		"application/vnd.dev.sigstore.bundle+json;version=0.3": bundleArtifact(t, signedEnvelope(t, sv, referrersImageDigest)),
+	})
+	ctx := o.WithClient(context.Background(), c)
+
+	a := ApplicationSnapshotImage{
+		reference:       ref,
+		checkOpts:       cosign.CheckOpts{SigVerifier: sv, IgnoreTlog: true},
+		trustedMaterial: root.TrustedMaterialCollection{},
+	}
+
+	require.NoError(t, a.ValidateImageSignature(ctx))
+	require.Len(t, a.signatures, 1)
+
+	require.NoError(t, a.ValidateAttestationSignature(ctx))
+	require.Len(t, a.attestations, 1)
+	assert.Equal(t, "https://example.com/predicate/v1", a.attestations[0].PredicateType())
+}
+
+func TestReferrerAttestations(t *testing.T) {
+	ref := name.MustParseReference("registry.io/repository/image:tag")
+	sv := newSignerVerifier(t)
+
+	cases := []struct {
+		name         string
+		artifactType string
+		artifact     func([]byte) v1.Image
+	}{
+		{
+			name:         "sigstore bundle",
+			artifactType: "application/vnd.dev.sigstore.bundle.v0.3+json",
+			artifact:     func(e []byte) v1.Image { return bundleArtifact(t, e) },
+		},
+		{
+			name:         "DSSE envelope",
+			artifactType: dsseArtifactType,
+			artifact:     func(e []byte) v1.Image { return envelopeArtifact(t, e) },
+		},
+		{
+			name:         "in-toto attestation",
+			artifactType: inTotoArtifactType,
+			artifact:     func(e []byte) v1.Image { return envelopeArtifact(t, e) },
+		},
+	}
+
+	for _, cc := range cases {
+		t.Run(cc.name, func(t *testing.T) {
+			c := referrerClient(t, ref, map[string]v1.Image{
+				cc.artifactType: cc.artifact(signedEnvelope(t, sv, referrersImageDigest)),
+			})
+			ctx := o.WithClient(context.Background(), c)
+
+			a := ApplicationSnapshotImage{
+				reference: ref,
+				checkOpts: cosign.CheckOpts{SigVerifier: sv, IgnoreTlog: true},
+			}
+
+			require.NoError(t, a.ValidateAttestationSignature(ctx))
+			require.Len(t, a.attestations, 1)
+			assert.Equal(t, "https://example.com/predicate/v1", a.attestations[0].PredicateType())
+		})
+	}
+}
+
+func TestReferrerAttestationsNotVerified(t *testing.T) {
+	ref := name.MustParseReference("registry.io/repository/image:tag")
+
+	cases := []struct {
+		name     string
+		envelope func(*testing.T, sigstoresig.SignerVerifier) []byte
+		err      string
+	}{
+		{
+			name: "signed by another key",
+			envelope: func(t *testing.T, _ sigstoresig.SignerVerifier) []byte {
+				return signedEnvelope(t, newSignerVerifier(t), referrersImageDigest)
+			},
+			err: "no matching attestations",
+		},
+		{
+			name: "different subject",
+			envelope: func(t *testing.T, sv sigstoresig.SignerVerifier) []byte {
+				return signedEnvelope(t, sv, "sha256:0000000000000000000000000000000000000000000000000000000000000000")
+			},
+			err: "no matching subject digest found",
+		},
+	}
+
+	for _, cc := range cases {
+		t.Run(cc.name, func(t *testing.T) {
+			sv := newSignerVerifier(t)
+			envelope := cc.envelope(t, sv)
+
+			c := referrerClient(t, ref, map[string]v1.Image{
+				"application/vnd.dev.sigstore.bundle.v0.3+json": bundleArtifact(t, envelope),
+			})
+			ctx := o.WithClient(context.Background(), c)
+
+			a := ApplicationSnapshotImage{
+				reference: ref,
+				checkOpts: cosign.CheckOpts{SigVerifier: sv, IgnoreTlog: true},
+			}
+
+			err := a.ValidateAttestationSignature(ctx)
+			require.Error(t, err)
+			assert.ErrorContains(t, err, "no attestations found")
+			assert.ErrorContains(t, err, cc.err)
+			assert.Empty(t, a.attestations)
+		})
+	}
+}
+
+func TestReferrersUnavailable(t *testing.T) {
+	ref := name.MustParseReference("registry.io/repository/image:tag")
+
+	c := fake.FakeClient{}
+	c.On("VerifyImageSignatures", ref, mock.Anything).Return(nil, false, errors.New("no signatures found"))
+	c.On("VerifyImageAttestations", ref, mock.Anything).Return(nil, false, errors.New("no attestations found"))
+	c.On("ResolveDigest", ref).Return(referrersImageDigest, nil)
+	c.On("Referrers", mock.Anything).Return(nil, errors.New("referrers not available"))
+	ctx := o.WithClient(context.Background(), &c)
+
+	a := ApplicationSnapshotImage{reference: ref}
+
+	assert.EqualError(t, a.ValidateImageSignature(ctx), "no signatures found")
+	assert.EqualError(t, a.ValidateAttestationSignature(ctx), "no attestations found")
+
+	// the referrers are fetched only once
+	c.AssertNumberOfCalls(t, "Referrers", 1)
+}
@@ -24,9 +24,11 @@ import (
 	"github.com/enterprise-contract/ec-cli/internal/evaluator"
 	"github.com/enterprise-contract/ec-cli/internal/policy"
 	"github.com/enterprise-contract/ec-cli/internal/policy/source"
+	"github.com/enterprise-contract/ec-cli/internal/utils"
 )
 
 var newConftestEvaluator = evaluator.NewConftestEvaluator
+var newOPAEvaluator = evaluator.NewOPAEvaluator
 
 // Input represents the structure needed to evaluate a generic file input
 type Input struct {
@@ -48,13 +50,19 @@ func NewInput(ctx context.Context, paths []string, p policy.Policy) (*Input, err
 			log.Debugf("policySource: %#v", policySource)
 		}
 
-		c, err := newConftestEvaluator(ctx, policySources, p, sourceGroup)
+		var c evaluator.Evaluator
+		var err error
+		if utils.IsOpaEnabled() {
+			c, err = newOPAEvaluator(ctx, policySources, p, sourceGroup)
+		} else {
+			c, err = newConftestEvaluator(ctx, policySources, p, sourceGroup)
+		}
 		if err != nil {
-			log.Debug("Failed to initialize the conftest evaluator!")
+			log.Debug("Failed to initialize the policy evaluator!")
 			return nil, err
 		}
 
-		log.Debug("Conftest evaluator initialized")
+		log.Debug("Policy evaluator initialized")
 		i.Evaluators = append(i.Evaluators, c)
 
 	}
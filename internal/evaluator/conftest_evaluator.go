@@ -19,6 +19,7 @@ package evaluator
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/url"
 	"os"
@@ -26,14 +27,14 @@ import (
 	"path/filepath"
 	"runtime/trace"
 	"strings"
+	"sync"
 	"time"
 
 	ecc "github.com/enterprise-contract/enterprise-contract-controller/api/v1alpha1"
 	"github.com/open-policy-agent/conftest/output"
+	"github.com/open-policy-agent/conftest/parser"
 	conftest "github.com/open-policy-agent/conftest/policy"
-	"github.com/open-policy-agent/conftest/runner"
 	"github.com/open-policy-agent/opa/ast"
-	"github.com/open-policy-agent/opa/storage"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/afero"
 	"k8s.io/apimachinery/pkg/util/sets"
@@ -163,9 +164,12 @@ func excludeDirectives(code string, rawTerm any) string {
 }
 
 type testRunner interface {
-	Run(context.Context, []string) ([]Outcome, error)
+	Run(context.Context, EvaluationTarget) ([]Outcome, error)
 }
 
+// inputName is the file name the in-memory input is evaluated as
+const inputName = "input.json"
+
 const (
 	effectiveOnFormat   = "2006-01-02T15:04:05Z"
 	effectiveOnTimeout  = -90 * 24 * time.Hour // keep effective_on metadata up to 90 days
@@ -196,7 +200,6 @@ type ConfigProvider interface {
 // ConftestEvaluator represents a structure which can be used to evaluate targets
 type conftestEvaluator struct {
 	policySources []source.PolicySource
-	outputFormat  string
 	workDir       string
 	dataDir       string
 	policyDir     string
@@ -205,21 +208,131 @@ type conftestEvaluator struct {
 	exclude       *Criteria
 	fs            afero.Fs
 	namespace     []string
+	prepared      *preparedPolicy
 }
 
-type conftestRunner struct {
-	runner.TestRunner
+// preparedPolicy holds the policy rules and the policy engine, with the
+// compiled policy, data and capabilities. Both are prepared by the first
+// successful evaluation and shared by all following evaluations, possibly
+// running concurrently, of the same evaluator. The opaEvaluator holds its
+// compiled policy and data store instead of the policy engine.
+type preparedPolicy struct {
+	rules    onceValue[policyRules]
+	engine   onceValue[*conftest.Engine]
+	compiled onceValue[compiledPolicy]
 }
 
-func (r conftestRunner) Run(ctx context.Context, fileList []string) (result []Outcome, err error) {
-	r.Trace = tracing.FromContext(ctx).Enabled(tracing.Opa)
+// onceValue holds the value produced by the first successful call to get. A
+// failed call isn't retained, so the following calls try again, e.g. after a
+// policy source failed to download.
+type onceValue[T any] struct {
+	mu    sync.Mutex
+	done  bool
+	value T
+}
 
-	var conftestResult []output.CheckResult
-	conftestResult, err = r.TestRunner.Run(ctx, fileList)
+func (o *onceValue[T]) get(f func() (T, error)) (T, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.done {
+		return o.value, nil
+	}
+
+	v, err := f()
+	if err != nil {
+		return v, err
+	}
+
+	o.value, o.done = v, true
+
+	return v, nil
+}
+
+// policyRules downloads the policy sources and collects the rule annotations,
+// this happens only once per evaluator
+func (c conftestEvaluator) policyRules(ctx context.Context) (policyRules, error) {
+	return c.prepared.rules.get(func() (policyRules, error) {
+		return collectPolicyRules(ctx, c.policySources, c.workDir)
+	})
+}
+
+// policyEngine loads and compiles the policy and loads the data documents
+// from the work directory, this happens only once per evaluator
+func (c conftestEvaluator) policyEngine(ctx context.Context) (*conftest.Engine, error) {
+	return c.prepared.engine.get(func() (*conftest.Engine, error) {
+		if trace.IsEnabled() {
+			region := trace.StartRegion(ctx, "ec:conftest-compile")
+			defer region.End()
+		}
+
+		return loadEngine(ctx, c.policyDir, c.dataDir, c.CapabilitiesPath())
+	})
+}
+
+func loadEngine(ctx context.Context, policyDir, dataDir, capabilities string) (*conftest.Engine, error) {
+	engine, err := conftest.LoadWithData([]string{policyDir}, []string{dataDir}, capabilities, false)
+	if err != nil {
+		return nil, fmt.Errorf("load: %w", err)
+	}
+
+	if tracing.FromContext(ctx).Enabled(tracing.Opa) {
+		engine.EnableTracing()
+	}
+
+	store := engine.Store()
+
+	txn, err := store.NewTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer store.Abort(ctx, txn)
+
+	ids := []string{} // everything
+
+	d, err := store.Read(ctx, txn, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := d.(map[string]any); !ok {
+		return nil, fmt.Errorf("could not retrieve data from the policy engine: Data is: %v", d)
+	}
+
+	return engine, nil
+}
+
+// conftestRunner evaluates the policy using an already loaded Conftest
+// policy engine, the same way the Conftest test runner would
+type conftestRunner struct {
+	engine    *conftest.Engine
+	namespace []string
+}
+
+func (r conftestRunner) Run(ctx context.Context, target EvaluationTarget) (result []Outcome, err error) {
+	var configurations map[string]any
+	configurations, err = targetConfigurations(target)
 	if err != nil {
 		return
 	}
 
+	namespaces := r.namespace
+	if len(namespaces) == 0 {
+		namespaces = r.engine.Namespaces()
+	}
+
+	var conftestResult []output.CheckResult
+	for _, namespace := range namespaces {
+		var checkResult []output.CheckResult
+		checkResult, err = r.engine.Check(ctx, configurations, namespace)
+		if err != nil {
+			err = fmt.Errorf("query rule: %w", err)
+			return
+		}
+
+		conftestResult = append(conftestResult, checkResult...)
+	}
+
 	for _, res := range conftestResult {
 		if log.IsLevelEnabled(log.TraceLevel) {
 			for _, q := range res.Queries {
@@ -249,37 +362,86 @@ func (r conftestRunner) Run(ctx context.Context, fileList []string) (result []Ou
 		})
 	}
 
-	// we can't reference the engine from the test runner or from the results so
-	// we need to recreate it, this needs to remain the same as in
-	// runner.TestRunner's Run function
-	var engine *conftest.Engine
-	engine, err = conftest.LoadWithData(r.Policy, r.Data, r.Capabilities, r.Strict)
-	if err != nil {
-		return
+	return
+}
+
+// targetConfigurations returns the parsed documents to evaluate keyed by the
+// name of the file they were read from. The in-memory input is converted to
+// the Rego value once, so it is not converted again for each query.
+func targetConfigurations(target EvaluationTarget) (map[string]any, error) {
+	if target.Input != nil {
+		var input any
+		if err := json.Unmarshal(target.Input, &input); err != nil {
+			return nil, fmt.Errorf("parse input: %w", err)
+		}
+
+		value, err := ast.InterfaceToValue(input)
+		if err != nil {
+			return nil, fmt.Errorf("convert input: %w", err)
+		}
+
+		return map[string]any{inputName: value}, nil
 	}
 
-	store := engine.Store()
+	files, err := configurationFiles(target.Inputs)
+	if err != nil {
+		return nil, fmt.Errorf("parse files: %w", err)
+	}
 
-	var txn storage.Transaction
-	txn, err = store.NewTransaction(ctx)
+	configurations, err := parser.ParseConfigurations(files)
 	if err != nil {
-		return
+		return nil, fmt.Errorf("parse configurations: %w", err)
 	}
 
-	ids := []string{} // everything
+	return configurations, nil
+}
 
-	var d any
-	d, err = store.Read(ctx, txn, ids)
-	if err != nil {
-		return
+// configurationFiles returns the list of files to evaluate, directories are
+// expanded to the files within them supported by Conftest. This needs to
+// remain the same as runner.TestRunner's handling of the file list.
+func configurationFiles(fileList []string) ([]string, error) {
+	var files []string
+	for _, file := range fileList {
+		if file == "" {
+			continue
+		}
+
+		if file == "-" {
+			files = append(files, "-")
+			continue
+		}
+
+		fileInfo, err := os.Stat(file)
+		if err != nil {
+			return nil, fmt.Errorf("get file info: %w", err)
+		}
+
+		if !fileInfo.IsDir() {
+			files = append(files, file)
+			continue
+		}
+
+		err = filepath.Walk(file, func(currentPath string, info os.FileInfo, err error) error {
+			if err != nil {
+				return fmt.Errorf("walk path: %w", err)
+			}
+
+			if !info.IsDir() && parser.FileSupported(currentPath) {
+				files = append(files, currentPath)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("get files from directory: %w", err)
+		}
 	}
 
-	var ok bool
-	if _, ok = d.(map[string]any); !ok {
-		err = fmt.Errorf("could not retrieve data from the policy engine: Data is: %v", d)
+	if len(files) == 0 {
+		return nil, errors.New("no files found")
 	}
 
-	return
+	return files, nil
 }
 
 // NewConftestEvaluator returns initialized conftestEvaluator implementing
@@ -298,10 +460,10 @@ func NewConftestEvaluatorWithNamespace(ctx context.Context, policySources []sour
 	fs := utils.FS(ctx)
 	c := conftestEvaluator{
 		policySources: policySources,
-		outputFormat:  "json",
 		policy:        p,
 		fs:            fs,
 		namespace:     namespace,
+		prepared:      &preparedPolicy{},
 	}
 
 	c.include, c.exclude = computeIncludeExclude(source, p)
@@ -314,13 +476,13 @@ func NewConftestEvaluatorWithNamespace(ctx context.Context, policySources []sour
 	c.policyDir = filepath.Join(c.workDir, "policy")
 	c.dataDir = filepath.Join(c.workDir, "data")
 
-	if err := c.createDataDirectory(ctx); err != nil {
+	if err := createDataDirectory(ctx, c.dataDir, c.policy); err != nil {
 		return nil, err
 	}
 
 	log.Debugf("Created work dir %s", dir)
 
-	if err := c.createCapabilitiesFile(ctx); err != nil {
+	if err := createCapabilitiesFile(ctx, c.CapabilitiesPath()); err != nil {
 		return nil, err
 	}
 
@@ -328,6 +490,17 @@ func NewConftestEvaluatorWithNamespace(ctx context.Context, policySources []sour
 	return c, nil
 }
 
+// Data returns the data documents available to the policy rules, the
+// documents from the data sources are present only after Evaluate
+func (c conftestEvaluator) Data(_ context.Context) (Data, error) {
+	documents, err := loadDocuments(c.dataDir)
+	if err != nil {
+		return nil, err
+	}
+
+	return documents.Documents, nil
+}
+
 // Destroy removes the working directory
 func (c conftestEvaluator) Destroy() {
 	if os.Getenv("EC_DEBUG") == "" {
@@ -365,21 +538,53 @@ func (r *policyRules) collect(a *ast.AnnotationsRef) error {
 }
 
 func (c conftestEvaluator) Evaluate(ctx context.Context, target EvaluationTarget) ([]Outcome, error) {
-	var results []Outcome
-
 	if trace.IsEnabled() {
 		region := trace.StartRegion(ctx, "ec:conftest-evaluate")
 		defer region.End()
 	}
 
+	rules, err := c.policyRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var r testRunner
+	var ok bool
+	if r, ok = ctx.Value(runnerKey).(testRunner); r == nil || !ok {
+		engine, err := c.policyEngine(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		r = &conftestRunner{
+			engine:    engine,
+			namespace: c.namespace,
+		}
+	}
+
+	log.Debugf("runner: %#v", r)
+	log.Debugf("inputs: %#v", target.Inputs)
+
+	runResults, err := r.Run(ctx, target)
+	if err != nil {
+		// TODO do we want to evaluate further policies instead of erroring out?
+		return nil, err
+	}
+
+	return processOutcomes(ctx, runResults, rules, target.Target, c.policy.EffectiveTime(), c.include, c.exclude)
+}
+
+// collectPolicyRules downloads all policy sources into the work directory and
+// collects the rule annotations found within the policy subdirectories.
+func collectPolicyRules(ctx context.Context, policySources []source.PolicySource, workDir string) (policyRules, error) {
 	// hold all rule annotations from all policy sources
 	// NOTE: emphasis on _all rules from all sources_; meaning that if two rules
 	// exist with the same code in two separate sources the collected rule
 	// information is not deterministic
 	rules := policyRules{}
 	// Download all sources
-	for _, s := range c.policySources {
-		dir, err := s.GetPolicy(ctx, c.workDir, false)
+	for _, s := range policySources {
+		dir, err := s.GetPolicy(ctx, workDir, false)
 		if err != nil {
 			log.Debugf("Unable to download source from %s!", s.PolicyUrl())
 			// TODO do we want to download other policies instead of erroring out?
@@ -424,39 +629,16 @@ func (c conftestEvaluator) Evaluate(ctx context.Context, target EvaluationTarget
 		}
 	}
 
-	var r testRunner
-	var ok bool
-	if r, ok = ctx.Value(runnerKey).(testRunner); r == nil || !ok {
-
-		// should there be a namespace defined or not
-		allNamespaces := true
-		if len(c.namespace) > 0 {
-			allNamespaces = false
-		}
-
-		r = &conftestRunner{
-			runner.TestRunner{
-				Data:          []string{c.dataDir},
-				Policy:        []string{c.policyDir},
-				Namespace:     c.namespace,
-				AllNamespaces: allNamespaces,
-				NoFail:        true,
-				Output:        c.outputFormat,
-				Capabilities:  c.CapabilitiesPath(),
-			},
-		}
-	}
-
-	log.Debugf("runner: %#v", r)
-	log.Debugf("inputs: %#v", target.Inputs)
+	return rules, nil
+}
 
-	runResults, err := r.Run(ctx, target.Inputs)
-	if err != nil {
-		// TODO do we want to evaluate further policies instead of erroring out?
-		return nil, err
-	}
+// processOutcomes applies the policy configuration to the raw outcomes of the
+// policy evaluation. Results are augmented with the rule metadata, filtered by
+// the include and exclude criteria, demoted to warnings when not yet
+// effective, and the successes are computed from the collected rules.
+func processOutcomes(ctx context.Context, runResults []Outcome, rules policyRules, target string, effectiveTime time.Time, include, exclude *Criteria) ([]Outcome, error) {
+	var results []Outcome
 
-	effectiveTime := c.policy.EffectiveTime()
 	ctx = context.WithValue(ctx, effectiveTimeKey, effectiveTime)
 
 	// Track how many rules have been processed. This is used later on to determine if anything
@@ -468,17 +650,17 @@ func (c conftestEvaluator) Evaluate(ctx context.Context, target EvaluationTarget
 	// Each include matching a result will be pruned from the list, so
 	// that in the end the list will contain all the unmatched includes.
 	missingIncludes := map[string]bool{}
-	for _, defaultItem := range c.include.defaultItems {
+	for _, defaultItem := range include.defaultItems {
 		missingIncludes[defaultItem] = true
 	}
-	for _, digestItems := range c.include.digestItems {
+	for _, digestItems := range include.digestItems {
 		for _, digestItem := range digestItems {
 			missingIncludes[digestItem] = true
 		}
 	}
 
 	// loop over each policy (namespace) evaluation
-	// effectively replacing the results returned from conftest
+	// effectively replacing the results returned from the runner
 	for i, result := range runResults {
 		log.Debugf("Evaluation result at %d: %#v", i, result)
 		warnings := []Result{}
@@ -490,7 +672,7 @@ func (c conftestEvaluator) Evaluate(ctx context.Context, target EvaluationTarget
 			warning := result.Warnings[i]
 			addRuleMetadata(ctx, &warning, rules)
 
-			if !c.isResultIncluded(warning, target.Target, missingIncludes) {
+			if !isResultIncluded(warning, target, missingIncludes, include, exclude) {
 				log.Debugf("Skipping result warning: %#v", warning)
 				continue
 			}
@@ -506,7 +688,7 @@ func (c conftestEvaluator) Evaluate(ctx context.Context, target EvaluationTarget
 			failure := result.Failures[i]
 			addRuleMetadata(ctx, &failure, rules)
 
-			if !c.isResultIncluded(failure, target.Target, missingIncludes) {
+			if !isResultIncluded(failure, target, missingIncludes, include, exclude) {
 				log.Debugf("Skipping result failure: %#v", failure)
 				continue
 			}
@@ -536,7 +718,7 @@ func (c conftestEvaluator) Evaluate(ctx context.Context, target EvaluationTarget
 		result.Skipped = skipped
 
 		// Replace the placeholder successes slice with the actual successes.
-		result.Successes = c.computeSuccesses(result, rules, target.Target, missingIncludes)
+		result.Successes = computeSuccesses(result, rules, target, missingIncludes, include, exclude)
 
 		totalRules += len(result.Warnings) + len(result.Failures) + len(result.Successes)
 
@@ -581,11 +763,12 @@ func toRules(results []output.Result) []Result {
 // computeSuccesses generates success results, these are not provided in the
 // Conftest results, so we reconstruct these from the parsed rules, any rule
 // that hasn't been touched by adding metadata must have succeeded
-func (c conftestEvaluator) computeSuccesses(
+func computeSuccesses(
 	result Outcome,
 	rules policyRules,
 	target string,
 	missingIncludes map[string]bool,
+	include, exclude *Criteria,
 ) []Result {
 	// what rules, by code, have we seen in the Conftest results, use map to
 	// take advantage of hashing for quicker lookup
@@ -638,7 +821,7 @@ func (c conftestEvaluator) computeSuccesses(
 			success.Metadata[metadataDependsOn] = rule.DependsOn
 		}
 
-		if !c.isResultIncluded(success, target, missingIncludes) {
+		if !isResultIncluded(success, target, missingIncludes, include, exclude) {
 			log.Debugf("Skipping result success: %#v", success)
 			continue
 		}
@@ -790,9 +973,8 @@ func createConfigJSON(ctx context.Context, dataDir string, p ConfigProvider) err
 }
 
 // createDataDirectory creates the base content in the data directory
-func (c *conftestEvaluator) createDataDirectory(ctx context.Context) error {
+func createDataDirectory(ctx context.Context, dataDir string, p ConfigProvider) error {
 	fs := utils.FS(ctx)
-	dataDir := c.dataDir
 	exists, err := afero.DirExists(fs, dataDir)
 	if err != nil {
 		return err
@@ -802,7 +984,7 @@ func (c *conftestEvaluator) createDataDirectory(ctx context.Context) error {
 		_ = fs.MkdirAll(dataDir, 0755)
 	}
 
-	if err := createConfigJSON(ctx, dataDir, c.policy); err != nil {
+	if err := createConfigJSON(ctx, dataDir, p); err != nil {
 		return err
 	}
 
@@ -810,9 +992,9 @@ func (c *conftestEvaluator) createDataDirectory(ctx context.Context) error {
 }
 
 // createCapabilitiesFile writes the default OPA capabilities a file.
-func (c *conftestEvaluator) createCapabilitiesFile(ctx context.Context) error {
+func createCapabilitiesFile(ctx context.Context, capabilitiesPath string) error {
 	fs := utils.FS(ctx)
-	f, err := fs.Create(c.CapabilitiesPath())
+	f, err := fs.Create(capabilitiesPath)
 	if err != nil {
 		return err
 	}
@@ -874,10 +1056,10 @@ func isResultEffective(failure Result, now time.Time) bool {
 // isResultIncluded returns whether or not the result should be included or
 // discarded based on the policy configuration.
 // 'missingIncludes' is a list of include directives that gets pruned if the result is matched
-func (c conftestEvaluator) isResultIncluded(result Result, target string, missingIncludes map[string]bool) bool {
+func isResultIncluded(result Result, target string, missingIncludes map[string]bool, include, exclude *Criteria) bool {
 	ruleMatchers := makeMatchers(result)
-	includeScore := scoreMatches(ruleMatchers, c.include.get(target), missingIncludes)
-	excludeScore := scoreMatches(ruleMatchers, c.exclude.get(target), map[string]bool{})
+	includeScore := scoreMatches(ruleMatchers, include.get(target), missingIncludes)
+	excludeScore := scoreMatches(ruleMatchers, exclude.get(target), map[string]bool{})
 	return includeScore > excludeScore
 }
 
@@ -954,7 +1136,7 @@ func score(name string) int {
 // makeMatchers returns the possible matching strings for the result.
 func makeMatchers(result Result) []string {
 	code := ExtractStringFromMetadata(result, metadataCode)
-	terms := extractStringsFromMetadata(result, metadataTerm)
+	terms := ExtractStringsFromMetadata(result, metadataTerm)
 	parts := strings.Split(code, ".")
 	var pkg string
 	if len(parts) >= 2 {
@@ -1005,17 +1187,29 @@ func extractCollections(result Result) []string {
 
 // ExtractStringFromMetadata returns the string value from the result metadata at the given key.
 func ExtractStringFromMetadata(result Result, key string) string {
-	values := extractStringsFromMetadata(result, key)
+	values := ExtractStringsFromMetadata(result, key)
 	if len(values) > 0 {
 		return values[0]
 	}
 	return ""
 }
 
-func extractStringsFromMetadata(result Result, key string) []string {
+// ExtractStringsFromMetadata returns the string values from the result metadata
+// at the given key, a single string is returned as a list of one value. Empty
+// and non-string values are omitted.
+func ExtractStringsFromMetadata(result Result, key string) []string {
 	if value, ok := result.Metadata[key].(string); ok && len(value) > 0 {
 		return []string{value}
 	}
+	if stringValues, ok := result.Metadata[key].([]string); ok {
+		var values []string
+		for _, value := range stringValues {
+			if len(value) > 0 {
+				values = append(values, value)
+			}
+		}
+		return values
+	}
 	if anyValues, ok := result.Metadata[key].([]any); ok {
 		var values []string
 		for _, anyValue := range anyValues {
@@ -23,6 +23,7 @@ import (
 	"context"
 	"embed"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
@@ -31,6 +32,7 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -55,8 +57,8 @@ type mockTestRunner struct {
 	mock.Mock
 }
 
-func (m *mockTestRunner) Run(ctx context.Context, inputs []string) ([]Outcome, error) {
-	args := m.Called(ctx, inputs)
+func (m *mockTestRunner) Run(ctx context.Context, target EvaluationTarget) ([]Outcome, error) {
+	args := m.Called(ctx, target)
 
 	return args.Get(0).([]Outcome), args.Error(2)
 }
@@ -291,7 +293,7 @@ func TestConftestEvaluatorEvaluateSeverity(t *testing.T) {
 
 	ctx := setupTestContext(&r, &dl)
 
-	r.On("Run", ctx, inputs.Inputs).Return(results, expectedData, nil)
+	r.On("Run", ctx, inputs).Return(results, expectedData, nil)
 
 	pol, err := policy.NewOfflinePolicy(ctx, policy.Now)
 	assert.NoError(t, err)
@@ -403,7 +405,7 @@ func TestConftestEvaluatorEvaluateNoSuccessWarningsOrFailures(t *testing.T) {
 			inputs := EvaluationTarget{Inputs: []string{"inputs"}}
 			ctx := setupTestContext(&r, &dl)
 
-			r.On("Run", ctx, inputs.Inputs).Return(tt.results, Data(nil), nil)
+			r.On("Run", ctx, inputs).Return(tt.results, Data(nil), nil)
 
 			p, err := policy.NewOfflinePolicy(ctx, policy.Now)
 			assert.NoError(t, err)
@@ -1317,7 +1319,7 @@ func TestConftestEvaluatorIncludeExclude(t *testing.T) {
 			dl := mockDownloader{}
 			inputs := EvaluationTarget{Inputs: []string{"inputs"}}
 			ctx := setupTestContext(&r, &dl)
-			r.On("Run", ctx, inputs.Inputs).Return(tt.results, Data(nil), nil)
+			r.On("Run", ctx, inputs).Return(tt.results, Data(nil), nil)
 
 			p, err := policy.NewOfflinePolicy(ctx, policy.Now)
 			assert.NoError(t, err)
@@ -1427,6 +1429,23 @@ func TestCollectAnnotationData(t *testing.T) {
 	}, rules)
 }
 
+func TestExtractStringsFromMetadata(t *testing.T) {
+	result := Result{Metadata: map[string]any{
+		"string":  "value",
+		"empty":   "",
+		"strings": []string{"a", "", "b"},
+		"any":     []any{"a", 1, "", "b", nil},
+		"number":  1,
+	}}
+
+	assert.Equal(t, []string{"value"}, ExtractStringsFromMetadata(result, "string"))
+	assert.Equal(t, []string{}, ExtractStringsFromMetadata(result, "empty"))
+	assert.Equal(t, []string{"a", "b"}, ExtractStringsFromMetadata(result, "strings"))
+	assert.Equal(t, []string{"a", "b"}, ExtractStringsFromMetadata(result, "any"))
+	assert.Equal(t, []string{}, ExtractStringsFromMetadata(result, "number"))
+	assert.Equal(t, []string{}, ExtractStringsFromMetadata(result, "missing"))
+}
+
 func TestRuleMetadata(t *testing.T) {
 	effectiveOnTest := time.Now().Format(effectiveOnFormat)
 
@@ -1888,6 +1907,80 @@ func TestConftestEvaluatorEvaluate(t *testing.T) {
 	snaps.MatchSnapshot(t, results)
 }
 
+func TestConftestEvaluatorEvaluateInput(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(path.Join(dir, "inputs"), 0755))
+	require.NoError(t, os.WriteFile(path.Join(dir, "inputs", "data.json"), []byte("{}"), 0600))
+
+	rego, err := fs.Sub(policies, "__testdir__/simple")
+	require.NoError(t, err)
+
+	rules, err := rulesArchive(t, rego)
+	require.NoError(t, err)
+
+	ctx := withCapabilities(context.Background(), testCapabilities)
+
+	p, err := policy.NewInertPolicy(ctx, "")
+	require.NoError(t, err)
+
+	evaluator, err := NewConftestEvaluator(ctx, []source.PolicySource{
+		&source.PolicyUrl{
+			Url:  rules,
+			Kind: source.PolicyKind,
+		},
+	}, p, ecc.Source{})
+	require.NoError(t, err)
+
+	fromFile, err := evaluator.Evaluate(ctx, EvaluationTarget{Inputs: []string{path.Join(dir, "inputs")}})
+	require.NoError(t, err)
+
+	engine := evaluator.(conftestEvaluator).prepared.engine.value
+	require.NotNil(t, engine)
+
+	for i := range fromFile {
+		fromFile[i].FileName = inputName
+	}
+
+	// evaluations with in-memory input, possibly concurrent, reuse the
+	// already compiled policy
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fromInput, err := evaluator.Evaluate(ctx, EvaluationTarget{Input: []byte("{}")})
+			assert.NoError(t, err)
+			assert.ElementsMatch(t, fromFile, fromInput)
+		}()
+	}
+	wg.Wait()
+
+	assert.Same(t, engine, evaluator.(conftestEvaluator).prepared.engine.value)
+}
+
+func TestOnceValue(t *testing.T) {
+	var o onceValue[int]
+	calls := 0
+
+	_, err := o.get(func() (int, error) {
+		calls++
+		return 0, errors.New("expected")
+	})
+	require.EqualError(t, err, "expected")
+
+	// the error isn't retained, the next call tries again
+	for range 2 {
+		v, err := o.get(func() (int, error) {
+			calls++
+			return 42, nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 42, v)
+	}
+
+	assert.Equal(t, 2, calls)
+}
+
 type mockConfigProvider struct {
 	mock.Mock
 }
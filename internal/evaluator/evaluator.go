@@ -22,6 +22,9 @@ import (
 
 type EvaluationTarget struct {
 	Inputs []string
+	// Input is the JSON encoded input document, when set it is evaluated in
+	// memory and Inputs are ignored
+	Input  []byte
 	Target string
 }
 
@@ -35,6 +38,12 @@ type Evaluator interface {
 	CapabilitiesPath() string
 }
 
+// DataProvider is implemented by evaluators that can provide the data
+// documents available to the policy rules
+type DataProvider interface {
+	Data(ctx context.Context) (Data, error)
+}
+
 type Data map[string]any
 
 type Outcome struct {
@@ -17,25 +17,140 @@
 package evaluator
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"os"
 	"path"
+	"path/filepath"
+	"regexp"
+	"runtime/trace"
+	"sort"
+	"strings"
 
+	ecc "github.com/enterprise-contract/enterprise-contract-controller/api/v1alpha1"
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/bundle"
+	"github.com/open-policy-agent/opa/loader"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/storage"
+	"github.com/open-policy-agent/opa/topdown/print"
+	log "github.com/sirupsen/logrus"
 	"github.com/spf13/afero"
+	"k8s.io/apimachinery/pkg/util/yaml"
+
+	"github.com/enterprise-contract/ec-cli/internal/policy/source"
+	"github.com/enterprise-contract/ec-cli/internal/tracing"
+	"github.com/enterprise-contract/ec-cli/internal/utils"
+)
+
+var (
+	// rules named like this are queried for warnings, e.g. warn or warn_something
+	warningRuleRegex = regexp.MustCompile("^warn(_[a-zA-Z0-9]+)*$")
+	// rules named like this are queried for failures, e.g. deny or violation_something
+	failureRuleRegex = regexp.MustCompile("^(deny|violation)(_[a-zA-Z0-9]+)*$")
 )
 
-// not sure what the properties will be yet, so setting the minimum.
+// opaEvaluator evaluates the policy rules using the OPA Go API directly,
+// rather than going through the Conftest test runner. Other than the way
+// the rules are evaluated it behaves the same as conftestEvaluator.
 type opaEvaluator struct {
-	workDir string
-	fs      afero.Fs
+	policySources []source.PolicySource
+	workDir       string
+	dataDir       string
+	policyDir     string
+	policy        ConfigProvider
+	include       *Criteria
+	exclude       *Criteria
+	fs            afero.Fs
+	namespace     []string
+	prepared      *preparedPolicy
 }
 
-func NewOPAEvaluator() (Evaluator, error) {
-	return opaEvaluator{}, nil
+// NewOPAEvaluator returns initialized opaEvaluator implementing Evaluator
+// interface
+func NewOPAEvaluator(ctx context.Context, policySources []source.PolicySource, p ConfigProvider, source ecc.Source) (Evaluator, error) {
+	return NewOPAEvaluatorWithNamespace(ctx, policySources, p, source, nil)
+}
+
+// NewOPAEvaluatorWithNamespace returns initialized opaEvaluator that
+// evaluates only the rules within the given namespaces
+func NewOPAEvaluatorWithNamespace(ctx context.Context, policySources []source.PolicySource, p ConfigProvider, source ecc.Source, namespace []string) (Evaluator, error) {
+	if trace.IsEnabled() {
+		r := trace.StartRegion(ctx, "ec:opa-create-evaluator")
+		defer r.End()
+	}
+
+	fs := utils.FS(ctx)
+	o := opaEvaluator{
+		policySources: policySources,
+		policy:        p,
+		fs:            fs,
+		namespace:     namespace,
+		prepared:      &preparedPolicy{},
+	}
+
+	o.include, o.exclude = computeIncludeExclude(source, p)
+	dir, err := utils.CreateWorkDir(fs)
+	if err != nil {
+		log.Debug("Failed to create work dir!")
+		return nil, err
+	}
+	o.workDir = dir
+	o.policyDir = filepath.Join(o.workDir, "policy")
+	o.dataDir = filepath.Join(o.workDir, "data")
+
+	if err := createDataDirectory(ctx, o.dataDir, o.policy); err != nil {
+		return nil, err
+	}
+
+	log.Debugf("Created work dir %s", dir)
+
+	if err := createCapabilitiesFile(ctx, o.CapabilitiesPath()); err != nil {
+		return nil, err
+	}
+
+	log.Debug("OPA evaluator created")
+	return o, nil
 }
 
 func (o opaEvaluator) Evaluate(ctx context.Context, target EvaluationTarget) ([]Outcome, error) {
-	return []Outcome{}, nil
+	if trace.IsEnabled() {
+		region := trace.StartRegion(ctx, "ec:opa-evaluate")
+		defer region.End()
+	}
+
+	rules, err := o.prepared.rules.get(func() (policyRules, error) {
+		return collectPolicyRules(ctx, o.policySources, o.workDir)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var r testRunner
+	var ok bool
+	if r, ok = ctx.Value(runnerKey).(testRunner); r == nil || !ok {
+		r = &opaRunner{
+			policyDir:    o.policyDir,
+			dataDir:      o.dataDir,
+			namespace:    o.namespace,
+			capabilities: o.CapabilitiesPath(),
+			prepared:     o.prepared,
+		}
+	}
+
+	log.Debugf("runner: %#v", r)
+	log.Debugf("inputs: %#v", target.Inputs)
+
+	runResults, err := r.Run(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+
+	return processOutcomes(ctx, runResults, rules, target.Target, o.policy.EffectiveTime(), o.include, o.exclude)
 }
 
 func (o opaEvaluator) Destroy() {
@@ -47,3 +162,459 @@ func (o opaEvaluator) Destroy() {
 func (o opaEvaluator) CapabilitiesPath() string {
 	return path.Join(o.workDir, "capabilities.json")
 }
+
+func (o opaEvaluator) Data(_ context.Context) (Data, error) {
+	documents, err := loadDocuments(o.dataDir)
+	if err != nil {
+		return nil, err
+	}
+
+	return documents.Documents, nil
+}
+
+// opaRunner evaluates the deny, violation and warn rules found in the policy
+// directory against each of the inputs, producing an Outcome per input and
+// namespace. The number of successes in each Outcome is a placeholder, the
+// same as with the Conftest runner, to be replaced with the actual successes
+// computed from the rule annotations. The policy is compiled and the data
+// documents are loaded only once per evaluator.
+type opaRunner struct {
+	policyDir    string
+	dataDir      string
+	namespace    []string
+	capabilities string
+	prepared     *preparedPolicy
+}
+
+// compiledPolicy holds the compiled Rego modules and the store holding the
+// data documents, shared by all evaluations of an opaEvaluator
+type compiledPolicy struct {
+	compiler *ast.Compiler
+	modules  map[string]*ast.Module
+	store    storage.Store
+}
+
+func (r opaRunner) Run(ctx context.Context, target EvaluationTarget) ([]Outcome, error) {
+	fs := utils.FS(ctx)
+
+	files, err := targetDocuments(fs, target)
+	if err != nil {
+		return nil, err
+	}
+
+	compiled, err := r.compiled(ctx, fs)
+	if err != nil {
+		return nil, err
+	}
+	compiler, modules, store := compiled.compiler, compiled.modules, compiled.store
+
+	namespaces := r.namespace
+	if len(namespaces) == 0 {
+		namespaces = moduleNamespaces(modules)
+	}
+
+	traceEnabled := tracing.FromContext(ctx).Enabled(tracing.Opa)
+
+	var results []Outcome
+	for _, f := range files {
+		file, inputs := f.name, f.inputs
+		for _, namespace := range namespaces {
+			outcome := Outcome{
+				FileName:  file,
+				Namespace: namespace,
+			}
+
+			rules, ruleCount := namespaceRules(modules, namespace)
+
+			successes := 0
+			for _, input := range inputs {
+				for _, rule := range rules {
+					query := fmt.Sprintf("data.%s.%s", namespace, rule)
+					found, err := evaluateQuery(ctx, compiler, store, input, query, traceEnabled)
+					if err != nil {
+						return nil, fmt.Errorf("query rule: %w", err)
+					}
+
+					if len(found) == 0 {
+						successes++
+						continue
+					}
+
+					if failureRuleRegex.MatchString(rule) {
+						outcome.Failures = append(outcome.Failures, found...)
+					} else {
+						outcome.Warnings = append(outcome.Warnings, found...)
+					}
+				}
+			}
+
+			// A rule reporting no result counts as a single success, even if
+			// there are multiple bodies for that rule. Same as Conftest, any
+			// difference between the number of rule bodies and the number of
+			// results is counted as successful.
+			if resultCount := len(outcome.Failures) + len(outcome.Warnings) + successes; resultCount < ruleCount*len(inputs) {
+				successes += ruleCount*len(inputs) - resultCount
+			}
+
+			// The successes are only a placeholder here, they get replaced by the
+			// successes computed from the rule annotations
+			outcome.Successes = make([]Result, successes)
+
+			results = append(results, outcome)
+		}
+	}
+
+	return results, nil
+}
+
+// compiled returns the compiled policy and the data store, compiling the
+// policy and loading the data documents on the first call
+func (r opaRunner) compiled(ctx context.Context, fs afero.Fs) (compiledPolicy, error) {
+	prepare := func() (compiledPolicy, error) {
+		if trace.IsEnabled() {
+			region := trace.StartRegion(ctx, "ec:opa-compile")
+			defer region.End()
+		}
+
+		compiler, modules, err := r.compile(fs)
+		if err != nil {
+			return compiledPolicy{}, err
+		}
+
+		store, err := r.store()
+		if err != nil {
+			return compiledPolicy{}, err
+		}
+
+		return compiledPolicy{compiler: compiler, modules: modules, store: store}, nil
+	}
+
+	if r.prepared == nil {
+		return prepare()
+	}
+
+	return r.prepared.compiled.get(prepare)
+}
+
+// compile loads and compiles all Rego modules from the policy directory using
+// the capabilities of the runner
+func (r opaRunner) compile(fs afero.Fs) (*ast.Compiler, map[string]*ast.Module, error) {
+	capabilities, err := loadCapabilities(fs, r.capabilities)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	policies, err := loader.NewFileLoader().WithProcessAnnotation(true).Filtered([]string{r.policyDir}, func(_ string, info os.FileInfo, _ int) bool {
+		return !info.IsDir() && !strings.HasSuffix(info.Name(), bundle.RegoExt)
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("load policies: %w", err)
+	}
+
+	modules := policies.ParsedModules()
+	if len(modules) == 0 {
+		return nil, nil, fmt.Errorf("no policies found in %s", r.policyDir)
+	}
+
+	compiler := ast.NewCompiler().WithEnablePrintStatements(true).WithCapabilities(capabilities)
+	if compiler.Compile(modules); compiler.Failed() {
+		return nil, nil, fmt.Errorf("compile policies: %w", compiler.Errors)
+	}
+
+	return compiler, modules, nil
+}
+
+// store loads all JSON and YAML data documents from the data directory into
+// an in-memory store
+func (r opaRunner) store() (storage.Store, error) {
+	documents, err := loadDocuments(r.dataDir)
+	if err != nil {
+		return nil, err
+	}
+
+	return documents.Store()
+}
+
+// loadDocuments loads the JSON and YAML data documents found in the data
+// directory
+func loadDocuments(dataDir string) (*loader.Result, error) {
+	paths, err := loader.FilteredPaths([]string{dataDir}, func(_ string, info os.FileInfo, _ int) bool {
+		if info.IsDir() {
+			return false
+		}
+		switch filepath.Ext(info.Name()) {
+		case ".json", ".yaml", ".yml":
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("filter data paths: %w", err)
+	}
+
+	documents, err := loader.NewFileLoader().All(paths)
+	if err != nil {
+		return nil, fmt.Errorf("load data: %w", err)
+	}
+
+	return documents, nil
+}
+
+func loadCapabilities(fs afero.Fs, capabilitiesPath string) (*ast.Capabilities, error) {
+	if capabilitiesPath == "" {
+		return ast.CapabilitiesForThisVersion(), nil
+	}
+
+	f, err := fs.Open(capabilitiesPath)
+	if err != nil {
+		return nil, fmt.Errorf("open capabilities: %w", err)
+	}
+	defer f.Close()
+
+	capabilities, err := ast.LoadCapabilitiesJSON(f)
+	if err != nil {
+		return nil, fmt.Errorf("load capabilities: %w", err)
+	}
+
+	return capabilities, nil
+}
+
+// moduleNamespaces returns the sorted, unique, package names of the modules
+func moduleNamespaces(modules map[string]*ast.Module) []string {
+	seen := map[string]bool{}
+	namespaces := make([]string, 0, len(modules))
+	for _, module := range modules {
+		namespace := strings.TrimPrefix(module.Package.Path.String(), "data.")
+		if seen[namespace] {
+			continue
+		}
+		seen[namespace] = true
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+
+	return namespaces
+}
+
+// namespaceRules returns the unique names of the deny, violation and warn
+// rules within the namespace, along with the number of rule bodies defined
+// for those rules
+func namespaceRules(modules map[string]*ast.Module, namespace string) ([]string, int) {
+	var rules []string
+	count := 0
+	seen := map[string]bool{}
+	for _, module := range modules {
+		if strings.TrimPrefix(module.Package.Path.String(), "data.") != namespace {
+			continue
+		}
+
+		for _, rule := range module.Rules {
+			name := rule.Head.Name.String()
+			if name == "" && len(rule.Head.Reference) > 0 {
+				name = rule.Head.Reference[0].Value.String()
+			}
+
+			if !failureRuleRegex.MatchString(name) && !warningRuleRegex.MatchString(name) {
+				continue
+			}
+
+			count++
+			if !seen[name] {
+				seen[name] = true
+				rules = append(rules, name)
+			}
+		}
+	}
+	sort.Strings(rules)
+
+	return rules, count
+}
+
+type printHook struct {
+	outputs *[]string
+}
+
+func (h printHook) Print(pctx print.Context, msg string) error {
+	*h.outputs = append(*h.outputs, fmt.Sprintf("%v: %s", pctx.Location, msg))
+	return nil
+}
+
+// evaluateQuery evaluates the query against the input and returns a Result
+// for each of the values the rule produced
+func evaluateQuery(ctx context.Context, compiler *ast.Compiler, store storage.Store, input any, query string, traceEnabled bool) ([]Result, error) {
+	outputs := []string{}
+	r := rego.New(
+		rego.Input(input),
+		rego.Query(query),
+		rego.Compiler(compiler),
+		rego.Store(store),
+		rego.Trace(traceEnabled),
+		rego.PrintHook(printHook{outputs: &outputs}),
+	)
+
+	resultSet, err := r.Eval(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("evaluating policy: %w", err)
+	}
+
+	if log.IsLevelEnabled(log.TraceLevel) {
+		buf := bytes.Buffer{}
+		rego.PrintTrace(&buf, r)
+		for _, t := range strings.Split(buf.String(), "\n") {
+			if t != "" {
+				log.Tracef("[%s] %s", query, t)
+			}
+		}
+	}
+	if log.IsLevelEnabled(log.DebugLevel) {
+		for _, o := range outputs {
+			log.Debugf("[%s] %s", query, o)
+		}
+	}
+
+	var results []Result
+	for _, result := range resultSet {
+		for _, expression := range result.Expressions {
+			values, ok := expression.Value.([]any)
+			if !ok {
+				continue
+			}
+
+			for _, v := range values {
+				switch val := v.(type) {
+				case string:
+					results = append(results, Result{Message: val})
+				case map[string]any:
+					msg, ok := val["msg"].(string)
+					if !ok {
+						return nil, fmt.Errorf("rule result must contain a string msg field: %v", val)
+					}
+
+					metadata := make(map[string]any, len(val))
+					for k, v := range val {
+						if k != "msg" {
+							metadata[k] = v
+						}
+					}
+
+					results = append(results, Result{Message: msg, Metadata: metadata})
+				}
+			}
+		}
+	}
+
+	return results, nil
+}
+
+// inputDocuments are the documents parsed from a single input
+type inputDocuments struct {
+	name   string
+	inputs []any
+}
+
+// targetDocuments returns the documents to evaluate, either the in-memory
+// input or the documents parsed from each of the input files
+func targetDocuments(fs afero.Fs, target EvaluationTarget) ([]inputDocuments, error) {
+	if target.Input != nil {
+		var input any
+		if err := json.Unmarshal(target.Input, &input); err != nil {
+			return nil, fmt.Errorf("parse input: %w", err)
+		}
+
+		return []inputDocuments{{name: inputName, inputs: []any{input}}}, nil
+	}
+
+	files, err := inputFiles(fs, target.Inputs)
+	if err != nil {
+		return nil, fmt.Errorf("input files: %w", err)
+	}
+
+	documents := make([]inputDocuments, 0, len(files))
+	for _, file := range files {
+		inputs, err := parseInput(fs, file)
+		if err != nil {
+			return nil, fmt.Errorf("parse input %s: %w", file, err)
+		}
+
+		documents = append(documents, inputDocuments{name: file, inputs: inputs})
+	}
+
+	return documents, nil
+}
+
+// inputFiles returns the list of files to evaluate, directories are expanded
+// to the JSON and YAML files found within them
+func inputFiles(fs afero.Fs, fileList []string) ([]string, error) {
+	var files []string
+	for _, file := range fileList {
+		if file == "" {
+			continue
+		}
+
+		isDir, err := afero.IsDir(fs, file)
+		if err != nil {
+			return nil, err
+		}
+
+		if !isDir {
+			files = append(files, file)
+			continue
+		}
+
+		err = afero.Walk(fs, file, func(p string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if !info.IsDir() && utils.HasJsonOrYamlExt(p) {
+				files = append(files, p)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(files) == 0 {
+		return nil, errors.New("no files found")
+	}
+
+	return files, nil
+}
+
+// parseInput parses the JSON or YAML file, returning a value for each of the
+// documents it contains
+func parseInput(fs afero.Fs, file string) ([]any, error) {
+	f, err := fs.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var inputs []any
+	decoder := yaml.NewYAMLOrJSONDecoder(f, 4096)
+	for {
+		var input any
+		if err := decoder.Decode(&input); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+
+		if input == nil {
+			// empty YAML documents
+			continue
+		}
+
+		inputs = append(inputs, input)
+	}
+
+	if len(inputs) == 0 {
+		return nil, errors.New("no input documents found")
+	}
+
+	return inputs, nil
+}
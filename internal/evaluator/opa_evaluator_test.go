@@ -14,30 +14,197 @@
 //
 // SPDX-License-Identifier: Apache-2.0
 
+//go:build unit
+
 package evaluator
 
 import (
 	"context"
+	"io/fs"
 	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
 	"testing"
+	"time"
 
+	ecc "github.com/enterprise-contract/enterprise-contract-controller/api/v1alpha1"
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/enterprise-contract/ec-cli/internal/policy"
+	"github.com/enterprise-contract/ec-cli/internal/policy/source"
 )
 
 // TestNewOPAEvaluator tests the constructor NewOPAEvaluator.
 func TestNewOPAEvaluator(t *testing.T) {
-	evaluator, err := NewOPAEvaluator()
+	ctx := withCapabilities(context.Background(), testCapabilities)
+
+	p, err := policy.NewInertPolicy(ctx, "")
+	require.NoError(t, err)
+
+	evaluator, err := NewOPAEvaluator(ctx, []source.PolicySource{testPolicySource{}}, p, ecc.Source{})
 	assert.NoError(t, err, "Expected no error from NewOPAEvaluator")
-	assert.Equal(t, evaluator, opaEvaluator{})
+	t.Cleanup(evaluator.Destroy)
+
+	o, ok := evaluator.(opaEvaluator)
+	require.True(t, ok)
+	assert.Equal(t, []source.PolicySource{testPolicySource{}}, o.policySources)
+	assert.Equal(t, filepath.Join(o.workDir, "policy"), o.policyDir)
+	assert.Equal(t, filepath.Join(o.workDir, "data"), o.dataDir)
+	assert.FileExists(t, filepath.Join(o.dataDir, "config.json"))
+
+	capabilities, err := os.ReadFile(evaluator.CapabilitiesPath())
+	require.NoError(t, err)
+	assert.Equal(t, testCapabilities, string(capabilities))
 }
 
-func TestEvaluate(t *testing.T) {
-	opaEval := opaEvaluator{}
+// opaTestEvaluators returns the OPA and the Conftest evaluators for the given
+// test policies, used to compare the results of both.
+func opaTestEvaluators(t *testing.T, ctx context.Context, dir string, src ecc.Source) (Evaluator, Evaluator) {
+	t.Helper()
+
+	rego, err := fs.Sub(policies, path.Join("__testdir__", dir))
+	require.NoError(t, err)
+
+	rules, err := rulesArchive(t, rego)
+	require.NoError(t, err)
+
+	eTime, err := time.Parse(policy.DateFormat, "2014-05-31")
+	require.NoError(t, err)
+	config := &mockConfigProvider{}
+	config.On("EffectiveTime").Return(eTime)
+	config.On("SigstoreOpts").Return(policy.SigstoreOpts{}, nil)
+	config.On("Spec").Return(ecc.EnterpriseContractPolicySpec{})
+
+	sources := []source.PolicySource{
+		&source.PolicyUrl{
+			Url:  rules,
+			Kind: source.PolicyKind,
+		},
+	}
+
+	opaEval, err := NewOPAEvaluator(ctx, sources, config, src)
+	require.NoError(t, err)
+	t.Cleanup(opaEval.Destroy)
+
+	conftestEval, err := NewConftestEvaluator(ctx, sources, config, src)
+	require.NoError(t, err)
+	t.Cleanup(conftestEval.Destroy)
+
+	return opaEval, conftestEval
+}
+
+func sortOutcomes(results []Outcome) {
+	sort.Slice(results, func(l, r int) bool {
+		return strings.Compare(results[l].Namespace, results[r].Namespace) < 0
+	})
+
+	for i := range results {
+		for _, r := range [][]Result{results[i].Successes, results[i].Warnings, results[i].Failures} {
+			sort.Slice(r, func(a, b int) bool {
+				return strings.Compare(ExtractStringFromMetadata(r[a], metadataCode), ExtractStringFromMetadata(r[b], metadataCode)) < 0
+			})
+		}
+	}
+}
+
+func TestOPAEvaluatorEvaluate(t *testing.T) {
+	cases := []struct {
+		name   string
+		input  string
+		source ecc.Source
+	}{
+		{
+			name:  "json input",
+			input: "{}",
+		},
+		{
+			name:  "multi document yaml input",
+			input: "a: 1\n---\nb: 2\n",
+		},
+		{
+			name:  "include and exclude",
+			input: "{}",
+			source: ecc.Source{
+				Config: &ecc.SourceConfig{
+					Include: []string{"a", "b.warning", "c"},
+					Exclude: []string{"a.warning"},
+				},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			dir := t.TempDir()
+			require.NoError(t, os.MkdirAll(path.Join(dir, "inputs"), 0755))
+			require.NoError(t, os.WriteFile(path.Join(dir, "inputs", "data.yaml"), []byte(c.input), 0600))
+
+			ctx := withCapabilities(context.Background(), testCapabilities)
+
+			opaEval, conftestEval := opaTestEvaluators(t, ctx, "simple", c.source)
+
+			target := EvaluationTarget{Inputs: []string{path.Join(dir, "inputs")}}
+			expected, err := conftestEval.Evaluate(ctx, target)
+			require.NoError(t, err)
+
+			got, err := opaEval.Evaluate(ctx, target)
+			require.NoError(t, err)
+
+			sortOutcomes(expected)
+			sortOutcomes(got)
+			assert.Equal(t, expected, got)
+		})
+	}
+}
+
+func TestOPAEvaluatorUnconformingRule(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(path.Join(dir, "inputs"), 0755))
+	require.NoError(t, os.WriteFile(path.Join(dir, "inputs", "data.json"), []byte("{}"), 0600))
+
+	ctx := withCapabilities(context.Background(), testCapabilities)
+
+	opaEval, _ := opaTestEvaluators(t, ctx, "unconforming", ecc.Source{})
+
+	_, err := opaEval.Evaluate(ctx, EvaluationTarget{Inputs: []string{path.Join(dir, "inputs")}})
+	assert.EqualError(t, err, `the rule "deny = true { true }" returns an unsupported value, at no_msg.rego:5`)
+}
+
+func TestOPAEvaluatorNoInputs(t *testing.T) {
+	ctx := withCapabilities(context.Background(), testCapabilities)
+
+	opaEval, _ := opaTestEvaluators(t, ctx, "simple", ecc.Source{})
+
+	_, err := opaEval.Evaluate(ctx, EvaluationTarget{Inputs: []string{t.TempDir()}})
+	assert.EqualError(t, err, "input files: no files found")
+}
+
+func TestOPAEvaluatorCompilesOnce(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(path.Join(dir, "inputs"), 0755))
+	require.NoError(t, os.WriteFile(path.Join(dir, "inputs", "data.json"), []byte("{}"), 0600))
+
+	ctx := withCapabilities(context.Background(), testCapabilities)
+
+	opaEval, _ := opaTestEvaluators(t, ctx, "simple", ecc.Source{})
+	target := EvaluationTarget{Inputs: []string{path.Join(dir, "inputs")}}
+
+	first, err := opaEval.Evaluate(ctx, target)
+	require.NoError(t, err)
+
+	prepared := opaEval.(opaEvaluator).prepared
+	compiler := prepared.compiled.value.compiler
+	require.NotNil(t, compiler)
+
+	second, err := opaEval.Evaluate(ctx, target)
+	require.NoError(t, err)
 
-	outcomes, err := opaEval.Evaluate(context.Background(), EvaluationTarget{})
-	assert.NoError(t, err, "Expected no error from Evaluate")
-	assert.Equal(t, []Outcome{}, outcomes)
+	assert.Equal(t, first, second)
+	assert.Same(t, compiler, prepared.compiled.value.compiler)
 }
 
 // Test Destroy method of opaEvaluator.
@@ -54,6 +54,8 @@ func FetchImageConfig(ctx context.Context, ref name.Reference) (json.RawMessage,
 }
 
 // FetchParentImage retrieves the reference to an image's parent image from its OCI registry.
+// The parent image is determined from the base image annotations of the image manifest or,
+// if those are not set, from the same labels in the image config.
 func FetchParentImage(ctx context.Context, ref name.Reference) (name.Reference, error) {
 	if trace.IsEnabled() {
 		region := trace.StartRegion(ctx, "ec:image-fetch-parent-image")
@@ -71,14 +73,23 @@ func FetchParentImage(ctx context.Context, ref name.Reference) (name.Reference,
 		return nil, err
 	}
 
-	parentName := manifest.Annotations[oci.BaseImageNameAnnotation]
+	annotations := manifest.Annotations
+	if annotations[oci.BaseImageNameAnnotation] == "" {
+		// Some builders record the base image as labels in the image config
+		// instead of as annotations in the image manifest
+		if configFile, err := image.ConfigFile(); err == nil && configFile != nil {
+			annotations = configFile.Config.Labels
+		}
+	}
+
+	parentName := annotations[oci.BaseImageNameAnnotation]
 	if parentName == "" {
 		return nil, fmt.Errorf(
 			"unable to determine parent image, make sure %s annotation is set", oci.BaseImageNameAnnotation)
 	}
 
 	if !strings.Contains(parentName, "@") {
-		parentDigest := manifest.Annotations[oci.BaseImageDigestAnnotation]
+		parentDigest := annotations[oci.BaseImageDigestAnnotation]
 		if parentDigest == "" {
 			return nil, fmt.Errorf(
 				"unable to determine parent image, make sure %s annotation is set", oci.BaseImageDigestAnnotation)
@@ -133,6 +133,38 @@ func TestFetchParentImage(t *testing.T) {
 			},
 			expected: parentURL,
 		},
+		{
+			name: "success with name and digest labels",
+			setup: func(client *fake.FakeClient) {
+				image, err := mutate.Config(empty.Image, v1.Config{
+					Labels: map[string]string{
+						oci.BaseImageNameAnnotation:   parentName,
+						oci.BaseImageDigestAnnotation: parentDigest,
+					},
+				})
+				require.NoError(t, err)
+
+				client.On("Image", ref).Return(image, nil)
+			},
+			expected: parentURL,
+		},
+		{
+			name: "annotations take precedence over labels",
+			setup: func(client *fake.FakeClient) {
+				image, err := mutate.Config(empty.Image, v1.Config{
+					Labels: map[string]string{
+						oci.BaseImageNameAnnotation: utils.WithDigest("registry.local/other-image"),
+					},
+				})
+				require.NoError(t, err)
+				image = mutate.Annotations(image, map[string]string{
+					oci.BaseImageNameAnnotation: parentURL,
+				}).(v1.Image)
+
+				client.On("Image", ref).Return(image, nil)
+			},
+			expected: parentURL,
+		},
 		{
 			name: "error fetching image",
 			setup: func(client *fake.FakeClient) {
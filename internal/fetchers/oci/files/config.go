@@ -0,0 +1,199 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package files
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	ecc "github.com/enterprise-contract/enterprise-contract-controller/api/v1alpha1"
+	"github.com/gobwas/glob"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// RuleDataKey is the key within the rule data of a policy source declaring
+// the files to extract from the image into input.image.files
+const RuleDataKey = "image_files"
+
+const (
+	// DefaultMaxFileSize is the default maximum size of an extracted file
+	DefaultMaxFileSize int64 = 1 << 20 // 1MiB
+	// DefaultMaxTotalSize is the default maximum size of all extracted files
+	DefaultMaxTotalSize int64 = 10 << 20 // 10MiB
+)
+
+// Config declares the files to extract from the image, for example within
+// the rule data of a policy source:
+//
+//	ruleData:
+//	  image_files:
+//	    max_file_size: 512Ki
+//	    max_total_size: 5Mi
+//	    files:
+//	      - glob: etc/containers/**/*.conf
+//	        format: toml
+//	      - glob: usr/share/buildinfo/*
+type Config struct {
+	Files        []Pattern `json:"files,omitempty"`
+	MaxFileSize  string    `json:"max_file_size,omitempty"`
+	MaxTotalSize string    `json:"max_total_size,omitempty"`
+}
+
+// Pattern matches the files to extract, ** in the glob matches across
+// directories. The format defaults to one derived from the file name.
+type Pattern struct {
+	Glob   string `json:"glob"`
+	Format Format `json:"format,omitempty"`
+}
+
+// Limits restricts the size of the extracted files, zero means no limit
+type Limits struct {
+	MaxFileSize  int64
+	MaxTotalSize int64
+}
+
+// FileExtractor is an Extractor that determines the format of the extracted
+// files and limits their size. Files matched by other Extractors are
+// extracted only if they're JSON or YAML documents.
+type FileExtractor interface {
+	Extractor
+	Format() Format
+	Limits() *Limits
+}
+
+// GlobExtractor extracts the files matching a glob
+type GlobExtractor struct {
+	glob    glob.Glob
+	pattern Pattern
+	limits  *Limits
+}
+
+// NewGlobExtractor creates a GlobExtractor for the pattern, the limits can be
+// shared between extractors to limit the total size of files they extract.
+func NewGlobExtractor(pattern Pattern, limits *Limits) (*GlobExtractor, error) {
+	if !slices.Contains(Formats, pattern.Format) {
+		return nil, fmt.Errorf("unsupported format %q of %q, use one of %q", pattern.Format, pattern.Glob, Formats[1:])
+	}
+
+	g, err := glob.Compile(normalize(pattern.Glob), '/')
+	if err != nil {
+		return nil, fmt.Errorf("invalid glob %q: %w", pattern.Glob, err)
+	}
+
+	return &GlobExtractor{glob: g, pattern: pattern, limits: limits}, nil
+}
+
+func (g *GlobExtractor) Matcher(img v1.Image) (Matcher, error) {
+	if img == nil {
+		return nil, nil
+	}
+
+	return func(header *tar.Header) bool {
+		return header != nil && header.Typeflag == tar.TypeReg && g.glob.Match(normalize(header.Name))
+	}, nil
+}
+
+func (g *GlobExtractor) Format() Format {
+	return g.pattern.Format
+}
+
+func (g *GlobExtractor) Limits() *Limits {
+	return g.limits
+}
+
+// normalize removes the leading slash or dot slash from the path
+func normalize(p string) string {
+	return strings.TrimPrefix(strings.TrimPrefix(p, "./"), "/")
+}
+
+// ExtractorsFromSources creates the extractors for the files declared in the
+// rule data of the policy sources, see Config. Files declared by any of the
+// sources are extracted, the smallest of the declared limits apply, so that no
+// source can raise the limits declared by another.
+func ExtractorsFromSources(sources []ecc.Source) ([]Extractor, error) {
+	limits := Limits{}
+	var patterns []Pattern
+	for _, s := range sources {
+		if s.RuleData == nil {
+			continue
+		}
+
+		var ruleData map[string]json.RawMessage
+		if err := json.Unmarshal(s.RuleData.Raw, &ruleData); err != nil {
+			// Not an object, nothing for us to use
+			continue
+		}
+
+		raw, ok := ruleData[RuleDataKey]
+		if !ok {
+			continue
+		}
+
+		var config Config
+		if err := json.Unmarshal(raw, &config); err != nil {
+			return nil, fmt.Errorf("invalid %s rule data of source %q: %w", RuleDataKey, s.Name, err)
+		}
+
+		for _, l := range []struct {
+			value  string
+			limit  *int64
+			option string
+		}{
+			{config.MaxFileSize, &limits.MaxFileSize, "max_file_size"},
+			{config.MaxTotalSize, &limits.MaxTotalSize, "max_total_size"},
+		} {
+			if l.value == "" {
+				continue
+			}
+			q, err := resource.ParseQuantity(l.value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s value %q of source %q: %w", l.option, l.value, s.Name, err)
+			}
+			if v := q.Value(); *l.limit == 0 || v < *l.limit {
+				*l.limit = v
+			}
+		}
+
+		patterns = append(patterns, config.Files...)
+	}
+
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+
+	if limits.MaxFileSize == 0 {
+		limits.MaxFileSize = DefaultMaxFileSize
+	}
+	if limits.MaxTotalSize == 0 {
+		limits.MaxTotalSize = DefaultMaxTotalSize
+	}
+
+	extractors := make([]Extractor, 0, len(patterns))
+	for _, p := range patterns {
+		e, err := NewGlobExtractor(p, &limits)
+		if err != nil {
+			return nil, err
+		}
+		extractors = append(extractors, e)
+	}
+
+	return extractors, nil
+}
@@ -0,0 +1,122 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package files
+
+import (
+	"testing"
+
+	ecc "github.com/enterprise-contract/enterprise-contract-controller/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+func TestExtractorsFromSources(t *testing.T) {
+	source := func(name, ruleData string) ecc.Source {
+		s := ecc.Source{Name: name}
+		if ruleData != "" {
+			s.RuleData = &extv1.JSON{Raw: []byte(ruleData)}
+		}
+		return s
+	}
+
+	cases := []struct {
+		name     string
+		sources  []ecc.Source
+		patterns []Pattern
+		limits   Limits
+		err      string
+	}{
+		{
+			name:    "no rule data",
+			sources: []ecc.Source{source("a", "")},
+		},
+		{
+			name:    "no image files",
+			sources: []ecc.Source{source("a", `{"allowed_registries": ["registry.io"]}`)},
+		},
+		{
+			name:    "rule data not an object",
+			sources: []ecc.Source{source("a", `[]`)},
+		},
+		{
+			name: "defaults",
+			sources: []ecc.Source{
+				source("a", `{"image_files": {"files": [{"glob": "etc/*.conf", "format": "ini"}]}}`),
+			},
+			patterns: []Pattern{{Glob: "etc/*.conf", Format: FormatINI}},
+			limits:   Limits{MaxFileSize: DefaultMaxFileSize, MaxTotalSize: DefaultMaxTotalSize},
+		},
+		{
+			name: "multiple sources",
+			sources: []ecc.Source{
+				source("a", `{"image_files": {"max_file_size": "1Ki", "files": [{"glob": "/etc/**"}]}}`),
+				source("b", ""),
+				source("c", `{"image_files": {"max_file_size": "2Ki", "max_total_size": "3Ki", "files": [{"glob": "root/buildinfo/*"}]}}`),
+			},
+			patterns: []Pattern{{Glob: "/etc/**"}, {Glob: "root/buildinfo/*"}},
+			limits:   Limits{MaxFileSize: 1024, MaxTotalSize: 3072},
+		},
+		{
+			name:    "invalid configuration",
+			sources: []ecc.Source{source("a", `{"image_files": {"files": "etc/*"}}`)},
+			err:     `invalid image_files rule data of source "a": json: cannot unmarshal string into Go struct field Config.files of type []files.Pattern`,
+		},
+		{
+			name:    "invalid size",
+			sources: []ecc.Source{source("a", `{"image_files": {"max_total_size": "lots", "files": [{"glob": "*"}]}}`)},
+			err:     `invalid max_total_size value "lots" of source "a": quantities must match the regular expression '^([+-]?[0-9.]+)([eEinumkKMGTP]*[-+]?[0-9]*)$'`,
+		},
+		{
+			name:    "invalid glob",
+			sources: []ecc.Source{source("a", `{"image_files": {"files": [{"glob": "etc/[a"}]}}`)},
+			err:     `invalid glob "etc/[a": unexpected end of input`,
+		},
+		{
+			name:    "invalid format",
+			sources: []ecc.Source{source("a", `{"image_files": {"files": [{"glob": "*.xml", "format": "xml"}]}}`)},
+			err:     `unsupported format "xml" of "*.xml", use one of ["json" "yaml" "toml" "ini" "properties" "dockerfile" "text" "binary"]`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			extractors, err := ExtractorsFromSources(c.sources)
+			if c.err != "" {
+				assert.EqualError(t, err, c.err)
+				return
+			}
+			require.NoError(t, err)
+
+			if c.patterns == nil {
+				assert.Nil(t, extractors)
+				return
+			}
+
+			require.Len(t, extractors, len(c.patterns))
+			for i, e := range extractors {
+				g, ok := e.(*GlobExtractor)
+				require.True(t, ok)
+				assert.Equal(t, c.patterns[i].Format, g.Format())
+				assert.Equal(t, c.patterns[i], g.pattern)
+				assert.Equal(t, c.limits, *g.Limits())
+			}
+		})
+	}
+}
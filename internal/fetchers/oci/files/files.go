@@ -54,13 +54,21 @@ func ImageFiles(ctx context.Context, ref name.Reference, extractors []Extractor)
 		return nil, err
 	}
 
-	matchers := make([]Matcher, 0, len(extractors))
+	matchers := make([]fileMatcher, 0, len(extractors))
 	for _, f := range extractors {
-		if m, err := f.Matcher(img); err != nil {
+		m, err := f.Matcher(img)
+		if err != nil {
 			return nil, err
-		} else if m != nil {
-			matchers = append(matchers, m)
 		}
+		if m == nil {
+			continue
+		}
+
+		fm := fileMatcher{match: m, structured: true}
+		if fe, ok := f.(FileExtractor); ok {
+			fm = fileMatcher{match: m, format: fe.Format(), limits: fe.Limits()}
+		}
+		matchers = append(matchers, fm)
 	}
 
 	if len(matchers) == 0 {
@@ -72,6 +80,7 @@ func ImageFiles(ctx context.Context, ref name.Reference, extractors []Extractor)
 	archive := tar.NewReader(content)
 
 	files := map[string]json.RawMessage{}
+	totals := map[*Limits]int64{}
 	for {
 		header, err := archive.Next()
 		if err != nil {
@@ -82,10 +91,21 @@ func ImageFiles(ctx context.Context, ref name.Reference, extractors []Extractor)
 		}
 
 		for _, matcher := range matchers {
-			if !matcher(header) {
+			if !matcher.match(header) {
 				continue
 			}
 
+			if l := matcher.limits; l != nil {
+				if l.MaxFileSize > 0 && header.Size > l.MaxFileSize {
+					log.Warnf("not extracting `%s` from the image, its size of %d bytes exceeds the limit of %d bytes", header.Name, header.Size, l.MaxFileSize)
+					break
+				}
+				if l.MaxTotalSize > 0 && totals[l]+header.Size > l.MaxTotalSize {
+					log.Warnf("not extracting `%s` from the image, the total size of extracted files would exceed the limit of %d bytes", header.Name, l.MaxTotalSize)
+					break
+				}
+			}
+
 			// TODO: large files could be an issue. We do need to read the archive
 			// in one pass making it difficult to not to buffer in memory.
 			// Offloading to disk and read at the time of JSON marshalling the input
@@ -96,11 +116,20 @@ func ImageFiles(ctx context.Context, ref name.Reference, extractors []Extractor)
 				return nil, err
 			}
 
-			// make sure we have JSON
-			data, err = yaml.YAMLToJSON(data)
-			if err != nil {
-				log.Debugf("unable to read the layer content of `%s` as JSON or YAML, ignoring (%v)", header.Name, err)
-				break
+			if matcher.structured {
+				// make sure we have JSON
+				data, err = yaml.YAMLToJSON(data)
+				if err != nil {
+					log.Debugf("unable to read the layer content of `%s` as JSON or YAML, ignoring (%v)", header.Name, err)
+					break
+				}
+			} else {
+				data, err = decode(header.Name, data, matcher.format)
+				if err != nil {
+					log.Debugf("unable to read the layer content of `%s` as %q, ignoring (%v)", header.Name, matcher.format, err)
+					break
+				}
+				totals[matcher.limits] += header.Size
 			}
 
 			files[header.Name] = data
@@ -111,6 +140,15 @@ func ImageFiles(ctx context.Context, ref name.Reference, extractors []Extractor)
 	return files, nil
 }
 
+// fileMatcher holds how the files matched by an Extractor are read
+type fileMatcher struct {
+	match Matcher
+	// structured files are JSON or YAML files, other files are ignored
+	structured bool
+	format     Format
+	limits     *Limits
+}
+
 type PathMatcher struct {
 	Path string
 }
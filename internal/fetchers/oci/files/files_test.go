@@ -124,6 +124,59 @@ func TestCustomExtractor(t *testing.T) {
 	}, files)
 }
 
+func TestGlobExtractor(t *testing.T) {
+	ref := name.MustParseReference("registry.io/repository/image:tag")
+
+	image, err := crane.Image(map[string][]byte{
+		"etc/containers/policy.json":      []byte(`{"default": [{"type": "reject"}]}`),
+		"etc/containers/registries.conf":  []byte("unqualified-search-registries = [\"registry.io\"]\n"),
+		"etc/yum.repos.d/ubi.repo":        []byte("[ubi-9-baseos]\nenabled = 1\n"),
+		"etc/yum.repos.d/z.repo":          []byte("[z]\nname = a longer name for z\nenabled = 0\n"),
+		"manifests/csv.yaml":              []byte(`kind: ClusterServiceVersion`),
+		"root/buildinfo/Dockerfile-ubi9":  []byte("FROM scratch\n"),
+		"root/buildinfo/content_manifest": []byte("\xff\xfe"),
+		"usr/bin/large":                   make([]byte, 100),
+	})
+	require.NoError(t, err)
+
+	limits := Limits{MaxFileSize: 64, MaxTotalSize: 128}
+	var extractors []Extractor
+	for _, p := range []Pattern{
+		{Glob: "/etc/containers/**"},
+		{Glob: "etc/containers/*.conf", Format: FormatTOML},
+		{Glob: "etc/yum.repos.d/*.repo", Format: FormatINI},
+		{Glob: "root/buildinfo/Dockerfile-*", Format: FormatDockerfile},
+		{Glob: "root/buildinfo/content_manifest"},
+		{Glob: "usr/bin/*"},
+	} {
+		e, err := NewGlobExtractor(p, &limits)
+		require.NoError(t, err)
+		extractors = append(extractors, e)
+	}
+
+	client := fake.FakeClient{}
+	client.On("Image", ref).Return(image, nil)
+
+	ctx := oci.WithClient(context.Background(), &client)
+
+	files, err := ImageFiles(ctx, ref, extractors)
+	require.NoError(t, err)
+
+	// The first matching extractor wins, usr/bin/large exceeds the file size
+	// limit and etc/yum.repos.d/z.repo the total size limit
+	expected := map[string]string{
+		"etc/containers/policy.json":      `{"default": [{"type": "reject"}]}`,
+		"etc/containers/registries.conf":  `"unqualified-search-registries = [\"registry.io\"]\n"`,
+		"etc/yum.repos.d/ubi.repo":        `{"ubi-9-baseos": {"enabled": "1"}}`,
+		"root/buildinfo/Dockerfile-ubi9":  `[{"instruction": "FROM", "flags": [], "value": ["scratch"], "original": "FROM scratch", "start_line": 1, "end_line": 1}]`,
+		"root/buildinfo/content_manifest": `"//4="`,
+	}
+	require.Len(t, files, len(expected))
+	for path, content := range expected {
+		assert.JSONEq(t, content, string(files[path]), path)
+	}
+}
+
 func TestShouldFilter(t *testing.T) {
 	cases := []struct {
 		name     string
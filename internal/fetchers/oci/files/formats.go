@@ -0,0 +1,209 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package files
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-ini/ini"
+	"github.com/magiconair/properties"
+	"github.com/moby/buildkit/frontend/dockerfile/parser"
+	"github.com/pelletier/go-toml/v2"
+	"sigs.k8s.io/yaml"
+)
+
+// Format determines how the content of an extracted file is represented
+type Format string
+
+const (
+	// FormatAuto picks the format based on the file name, falling back to
+	// text or binary
+	FormatAuto Format = ""
+	// FormatJSON and FormatYAML parse the file as a JSON or a YAML document
+	FormatJSON Format = "json"
+	FormatYAML Format = "yaml"
+	// FormatTOML parses the file as a TOML document
+	FormatTOML Format = "toml"
+	// FormatINI parses the file as an INI file, keys outside of any section
+	// are placed at the top level, sections are nested objects
+	FormatINI Format = "ini"
+	// FormatProperties parses the file as a Java properties file
+	FormatProperties Format = "properties"
+	// FormatDockerfile parses the file as a Dockerfile into a list of
+	// instructions
+	FormatDockerfile Format = "dockerfile"
+	// FormatText represents the file content as a string
+	FormatText Format = "text"
+	// FormatBinary represents the file content as a base64 encoded string
+	FormatBinary Format = "binary"
+)
+
+// Formats lists the supported formats
+var Formats = []Format{
+	FormatAuto, FormatJSON, FormatYAML, FormatTOML, FormatINI, FormatProperties, FormatDockerfile,
+	FormatText, FormatBinary,
+}
+
+// formatOf determines the format of the file from its name
+func formatOf(name string) Format {
+	base := path.Base(name)
+	switch strings.ToLower(path.Ext(base)) {
+	case ".json":
+		return FormatJSON
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".toml":
+		return FormatTOML
+	case ".ini":
+		return FormatINI
+	case ".properties":
+		return FormatProperties
+	case ".dockerfile", ".containerfile":
+		return FormatDockerfile
+	}
+
+	if base == "Dockerfile" || base == "Containerfile" || strings.HasPrefix(base, "Dockerfile.") || strings.HasPrefix(base, "Containerfile.") {
+		return FormatDockerfile
+	}
+
+	return FormatAuto
+}
+
+// decode converts the file content to JSON according to the format
+func decode(name string, data []byte, format Format) (json.RawMessage, error) {
+	if format == FormatAuto {
+		format = formatOf(name)
+	}
+
+	if format == FormatAuto {
+		format = FormatBinary
+		if utf8.Valid(data) {
+			format = FormatText
+		}
+	}
+
+	var value any
+	switch format {
+	case FormatJSON, FormatYAML:
+		// YAML is a superset of JSON
+		return yaml.YAMLToJSON(data)
+	case FormatTOML:
+		v := map[string]any{}
+		if err := toml.Unmarshal(data, &v); err != nil {
+			return nil, err
+		}
+		value = v
+	case FormatINI:
+		v, err := decodeINI(data)
+		if err != nil {
+			return nil, err
+		}
+		value = v
+	case FormatProperties:
+		p, err := properties.Load(data, properties.UTF8)
+		if err != nil {
+			return nil, err
+		}
+		value = p.Map()
+	case FormatDockerfile:
+		v, err := decodeDockerfile(data)
+		if err != nil {
+			return nil, err
+		}
+		value = v
+	case FormatText:
+		if !utf8.Valid(data) {
+			return nil, fmt.Errorf("%s is not a text file", name)
+		}
+		value = string(data)
+	case FormatBinary:
+		value = base64.StdEncoding.EncodeToString(data)
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+
+	return json.Marshal(value)
+}
+
+func decodeINI(data []byte) (map[string]any, error) {
+	f, err := ini.LoadSources(ini.LoadOptions{AllowBooleanKeys: true}, data)
+	if err != nil {
+		return nil, err
+	}
+
+	v := map[string]any{}
+	for _, section := range f.Sections() {
+		keys := section.KeysHash()
+		if section.Name() == ini.DefaultSection {
+			for k, val := range keys {
+				v[k] = val
+			}
+			continue
+		}
+
+		values := make(map[string]any, len(keys))
+		for k, val := range keys {
+			values[k] = val
+		}
+		v[section.Name()] = values
+	}
+
+	return v, nil
+}
+
+type dockerfileInstruction struct {
+	Instruction string   `json:"instruction"`
+	Flags       []string `json:"flags"`
+	Value       []string `json:"value"`
+	Original    string   `json:"original"`
+	StartLine   int      `json:"start_line"`
+	EndLine     int      `json:"end_line"`
+}
+
+func decodeDockerfile(data []byte) ([]dockerfileInstruction, error) {
+	result, err := parser.Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	instructions := make([]dockerfileInstruction, 0, len(result.AST.Children))
+	for _, node := range result.AST.Children {
+		i := dockerfileInstruction{
+			Instruction: strings.ToUpper(node.Value),
+			Flags:       node.Flags,
+			Value:       []string{},
+			Original:    node.Original,
+			StartLine:   node.StartLine,
+			EndLine:     node.EndLine,
+		}
+		if i.Flags == nil {
+			i.Flags = []string{}
+		}
+		for n := node.Next; n != nil; n = n.Next {
+			i.Value = append(i.Value, n.Value)
+		}
+		instructions = append(instructions, i)
+	}
+
+	return instructions, nil
+}
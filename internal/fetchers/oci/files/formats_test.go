@@ -0,0 +1,184 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package files
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecode(t *testing.T) {
+	cases := []struct {
+		name     string
+		file     string
+		data     string
+		format   Format
+		expected string
+		err      string
+	}{
+		{
+			name:     "json",
+			file:     "config.json",
+			data:     `{"a": [1, 2]}`,
+			expected: `{"a": [1, 2]}`,
+		},
+		{
+			name:     "yaml",
+			file:     "config.yml",
+			data:     "a:\n  - 1\n  - 2\n",
+			expected: `{"a": [1, 2]}`,
+		},
+		{
+			name:     "toml",
+			file:     "etc/containers/storage.conf",
+			format:   FormatTOML,
+			data:     "[storage]\ndriver = \"overlay\"\n\n[storage.options]\nsize = 10\n",
+			expected: `{"storage": {"driver": "overlay", "options": {"size": 10}}}`,
+		},
+		{
+			name:     "toml by extension",
+			file:     "pyproject.toml",
+			data:     "name = \"spam\"\n",
+			expected: `{"name": "spam"}`,
+		},
+		{
+			name:     "ini",
+			file:     "etc/yum.repos.d/ubi.repo",
+			format:   FormatINI,
+			data:     "top = level\n\n[ubi-9-baseos]\nname = UBI 9 BaseOS\nenabled = 1\ngpgcheck\n",
+			expected: `{"top": "level", "ubi-9-baseos": {"name": "UBI 9 BaseOS", "enabled": "1", "gpgcheck": "true"}}`,
+		},
+		{
+			name:     "properties",
+			file:     "app.properties",
+			data:     "# comment\nspam = ham\neggs: bacon\n",
+			expected: `{"spam": "ham", "eggs": "bacon"}`,
+		},
+		{
+			name: "dockerfile",
+			file: "root/buildinfo/Dockerfile-ubi9-9.4",
+			data: "FROM --platform=linux/amd64 registry.io/base:1 AS builder\n" +
+				"RUN dnf install -y \\\n    git\n" +
+				"CMD [\"run\"]\n",
+			format: FormatDockerfile,
+			expected: `[
+				{
+					"instruction": "FROM",
+					"flags": ["--platform=linux/amd64"],
+					"value": ["registry.io/base:1", "AS", "builder"],
+					"original": "FROM --platform=linux/amd64 registry.io/base:1 AS builder",
+					"start_line": 1,
+					"end_line": 1
+				},
+				{
+					"instruction": "RUN",
+					"flags": [],
+					"value": ["dnf install -y     git"],
+					"original": "RUN dnf install -y     git",
+					"start_line": 2,
+					"end_line": 3
+				},
+				{
+					"instruction": "CMD",
+					"flags": [],
+					"value": ["run"],
+					"original": "CMD [\"run\"]",
+					"start_line": 4,
+					"end_line": 4
+				}
+			]`,
+		},
+		{
+			name:     "dockerfile by name",
+			file:     "src/Containerfile",
+			data:     "FROM scratch\n",
+			expected: `[{"instruction": "FROM", "flags": [], "value": ["scratch"], "original": "FROM scratch", "start_line": 1, "end_line": 1}]`,
+		},
+		{
+			name:     "text",
+			file:     "etc/redhat-release",
+			data:     "Red Hat Enterprise Linux release 9.4 (Plow)\n",
+			expected: `"Red Hat Enterprise Linux release 9.4 (Plow)\n"`,
+		},
+		{
+			name:     "text format of a JSON file",
+			file:     "config.json",
+			format:   FormatText,
+			data:     `{"a": 1}`,
+			expected: `"{\"a\": 1}"`,
+		},
+		{
+			name:     "binary",
+			file:     "usr/bin/true",
+			data:     "\x7fELF\x02\x01\x01\xff",
+			expected: `"f0VMRgIBAf8="`,
+		},
+		{
+			name:     "binary format of a text file",
+			file:     "etc/hostname",
+			format:   FormatBinary,
+			data:     "spam",
+			expected: `"c3BhbQ=="`,
+		},
+		{
+			name:   "invalid json",
+			file:   "config.json",
+			data:   `{"a":`,
+			err:    "yaml: line 1: did not find expected node content",
+			format: FormatJSON,
+		},
+		{
+			name:   "invalid toml",
+			file:   "config.toml",
+			data:   `a = `,
+			err:    "toml: expected value, not eof",
+			format: FormatTOML,
+		},
+		{
+			name:   "not text",
+			file:   "usr/bin/true",
+			data:   "\x7fELF\xff",
+			err:    "usr/bin/true is not a text file",
+			format: FormatText,
+		},
+		{
+			name:   "unsupported format",
+			file:   "a.xml",
+			data:   "<a/>",
+			err:    `unsupported format "xml"`,
+			format: Format("xml"),
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			data, err := decode(c.file, []byte(c.data), c.format)
+			if c.err != "" {
+				assert.EqualError(t, err, c.err)
+				return
+			}
+			require.NoError(t, err)
+			require.True(t, json.Valid(data))
+			assert.JSONEq(t, c.expected, string(data))
+		})
+	}
+}
@@ -0,0 +1,244 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package forecast evaluates the policy rules at a future effective time to
+// find the rules that are going to start failing, and when.
+package forecast
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/enterprise-contract/ec-cli/internal/evaluator"
+)
+
+const (
+	// EffectiveOn is the reason of an upcoming failure of a rule that is not
+	// yet effective
+	EffectiveOn = "effective_on"
+	// ExpiresOn is the reason of an upcoming failure caused by a trusted task
+	// reference that is about to expire
+	ExpiresOn = "expires_on"
+)
+
+// the same format as used by the evaluator for the effective_on metadata
+const effectiveOnFormat = "2006-01-02T15:04:05Z"
+
+// Upcoming is a failure that is not present at the current effective time,
+// but is present at the effective time of the forecast
+type Upcoming struct {
+	Code    string `json:"code,omitempty"`
+	Term    string `json:"term,omitempty"`
+	Message string `json:"msg"`
+	// Date is the date the rule is going to start failing, if known
+	Date *time.Time `json:"date,omitempty"`
+	// Reason is either EffectiveOn or ExpiresOn depending on where the Date
+	// was taken from, empty if Date is not known
+	Reason string `json:"reason,omitempty"`
+}
+
+// Expiration is the time a trusted task reference stops being trusted
+type Expiration struct {
+	Ref       string
+	ExpiresOn time.Time
+}
+
+// policyAt provides the configuration of the policy with the effective time
+// replaced
+type policyAt struct {
+	evaluator.ConfigProvider
+	effectiveTime time.Time
+}
+
+func (p policyAt) EffectiveTime() time.Time {
+	return p.effectiveTime
+}
+
+// At returns the policy configuration with the effective time set to the
+// given time, used to create evaluators that evaluate the rules at that time
+func At(p evaluator.ConfigProvider, effectiveTime time.Time) evaluator.ConfigProvider {
+	return policyAt{ConfigProvider: p, effectiveTime: effectiveTime}
+}
+
+// Evaluate evaluates the policy input with the evaluators created for the
+// effective time of the forecast. It returns the failures and the expirations
+// of trusted task references found in the data of the evaluators between
+// from and until.
+func Evaluate(ctx context.Context, input []byte, target string, evaluators []evaluator.Evaluator, from, until time.Time) ([]evaluator.Result, []Expiration, error) {
+	var failures []evaluator.Result
+	var expirations []Expiration
+	for _, e := range evaluators {
+		outcomes, err := e.Evaluate(ctx, evaluator.EvaluationTarget{Input: input, Target: target})
+		if err != nil {
+			return nil, nil, fmt.Errorf("evaluating at %s: %w", until.Format(time.RFC3339), err)
+		}
+
+		for _, o := range outcomes {
+			failures = append(failures, o.Failures...)
+		}
+
+		if d, ok := e.(evaluator.DataProvider); ok {
+			data, err := d.Data(ctx)
+			if err != nil {
+				log.Debugf("Unable to read the policy data: %v", err)
+				continue
+			}
+			expirations = append(expirations, TrustedTaskExpirations(data, from, until)...)
+		}
+	}
+
+	return failures, expirations, nil
+}
+
+// TrustedTaskExpirations returns the trusted task references recorded in the
+// trusted_tasks data that expire after from and not after until
+func TrustedTaskExpirations(data evaluator.Data, from, until time.Time) []Expiration {
+	tasks, ok := data["trusted_tasks"].(map[string]any)
+	if !ok {
+		return nil
+	}
+
+	var expirations []Expiration
+	for _, records := range tasks {
+		list, ok := records.([]any)
+		if !ok {
+			continue
+		}
+
+		for _, r := range list {
+			record, ok := r.(map[string]any)
+			if !ok {
+				continue
+			}
+
+			ref, _ := record["ref"].(string)
+			expiresOn, _ := record["expires_on"].(string)
+			if ref == "" || expiresOn == "" {
+				continue
+			}
+
+			when, err := time.Parse(time.RFC3339, expiresOn)
+			if err != nil {
+				log.Debugf("Invalid expires_on value %q of trusted task %q", expiresOn, ref)
+				continue
+			}
+
+			if when.After(from) && !when.After(until) {
+				expirations = append(expirations, Expiration{Ref: ref, ExpiresOn: when})
+			}
+		}
+	}
+
+	return expirations
+}
+
+// Compare returns the future failures that are not among the current
+// failures. The date each one of those starts failing is taken from the
+// effective_on metadata of the rule, or from the expiration of the trusted
+// task reference mentioned in the failure message.
+func Compare(current, future []evaluator.Result, from, until time.Time, expirations []Expiration) []Upcoming {
+	seen := make(map[string]bool, len(current))
+	for _, r := range current {
+		seen[key(r)] = true
+	}
+
+	upcoming := []Upcoming{}
+	for _, r := range future {
+		k := key(r)
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+
+		u := Upcoming{
+			Code:    evaluator.ExtractStringFromMetadata(r, "code"),
+			Term:    evaluator.ExtractStringFromMetadata(r, "term"),
+			Message: r.Message,
+		}
+
+		if when, ok := effectiveOn(r); ok && when.After(from) && !when.After(until) {
+			u.Date = &when
+			u.Reason = EffectiveOn
+		} else if e, ok := expiration(r, expirations); ok {
+			u.Date = &e.ExpiresOn
+			u.Reason = ExpiresOn
+		}
+
+		upcoming = append(upcoming, u)
+	}
+
+	sort.SliceStable(upcoming, func(i, j int) bool {
+		a, b := upcoming[i], upcoming[j]
+		switch {
+		case a.Date == nil && b.Date == nil:
+			return a.Code < b.Code
+		case a.Date == nil:
+			return false
+		case b.Date == nil:
+			return true
+		case a.Date.Equal(*b.Date):
+			return a.Code < b.Code
+		default:
+			return a.Date.Before(*b.Date)
+		}
+	})
+
+	return upcoming
+}
+
+func key(r evaluator.Result) string {
+	return fmt.Sprintf("%s\x00%s\x00%s", evaluator.ExtractStringFromMetadata(r, "code"), evaluator.ExtractStringFromMetadata(r, "term"), r.Message)
+}
+
+func effectiveOn(r evaluator.Result) (time.Time, bool) {
+	s := evaluator.ExtractStringFromMetadata(r, "effective_on")
+	if s == "" {
+		return time.Time{}, false
+	}
+
+	when, err := time.Parse(effectiveOnFormat, s)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return when, true
+}
+
+// expiration returns the earliest expiration of a trusted task reference
+// mentioned in the message of the result
+func expiration(r evaluator.Result, expirations []Expiration) (Expiration, bool) {
+	var found *Expiration
+	for i := range expirations {
+		e := expirations[i]
+		if !strings.Contains(r.Message, e.Ref) {
+			continue
+		}
+		if found == nil || e.ExpiresOn.Before(found.ExpiresOn) {
+			found = &e
+		}
+	}
+
+	if found == nil {
+		return Expiration{}, false
+	}
+
+	return *found, true
+}
@@ -0,0 +1,182 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package forecast
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/enterprise-contract/ec-cli/internal/evaluator"
+	"github.com/enterprise-contract/ec-cli/internal/utils"
+)
+
+var (
+	from  = time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	until = time.Date(2024, 5, 31, 0, 0, 0, 0, time.UTC)
+)
+
+type fakeEvaluator struct {
+	input    []byte
+	target   string
+	outcomes []evaluator.Outcome
+	data     evaluator.Data
+}
+
+func (f *fakeEvaluator) Evaluate(ctx context.Context, target evaluator.EvaluationTarget) ([]evaluator.Outcome, error) {
+	f.input = target.Input
+	f.target = target.Target
+
+	return f.outcomes, nil
+}
+
+func (f *fakeEvaluator) Destroy() {}
+
+func (f *fakeEvaluator) CapabilitiesPath() string {
+	return ""
+}
+
+func (f *fakeEvaluator) Data(_ context.Context) (evaluator.Data, error) {
+	return f.data, nil
+}
+
+type fakeConfig struct {
+	evaluator.ConfigProvider
+}
+
+func (fakeConfig) EffectiveTime() time.Time {
+	return from
+}
+
+func TestAt(t *testing.T) {
+	p := At(fakeConfig{}, until)
+	assert.Equal(t, until, p.EffectiveTime())
+}
+
+func TestEvaluate(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	ctx := utils.WithFS(context.Background(), fs)
+
+	failure := evaluator.Result{Message: "Failure", Metadata: map[string]any{"code": "a.b"}}
+	e := &fakeEvaluator{
+		outcomes: []evaluator.Outcome{{Failures: []evaluator.Result{failure}}},
+		data: evaluator.Data{
+			"trusted_tasks": map[string]any{
+				"oci://registry.io/task:0.1": []any{
+					map[string]any{"ref": "sha256:new", "effective_on": "2024-05-10T00:00:00Z"},
+					map[string]any{"ref": "sha256:old", "effective_on": "2024-01-01T00:00:00Z", "expires_on": "2024-05-10T00:00:00Z"},
+				},
+			},
+		},
+	}
+
+	failures, expirations, err := Evaluate(ctx, []byte(`{"x":1}`), "registry.io/image@sha256:abc", []evaluator.Evaluator{e}, from, until)
+	require.NoError(t, err)
+
+	assert.Equal(t, []evaluator.Result{failure}, failures)
+	assert.Equal(t, []Expiration{{Ref: "sha256:old", ExpiresOn: time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC)}}, expirations)
+	assert.Equal(t, `{"x":1}`, string(e.input))
+	assert.Equal(t, "registry.io/image@sha256:abc", e.target)
+
+	// the temporary input is removed
+	files, err := afero.Glob(fs, "/tmp/ec-forecast-*")
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestTrustedTaskExpirations(t *testing.T) {
+	cases := []struct {
+		name     string
+		data     evaluator.Data
+		expected []Expiration
+	}{
+		{name: "no data"},
+		{name: "unexpected type", data: evaluator.Data{"trusted_tasks": []any{}}},
+		{
+			name: "within the forecast",
+			data: evaluator.Data{
+				"trusted_tasks": map[string]any{
+					"git+https://github.com/org/repo.git//task.yaml": []any{
+						map[string]any{"ref": "abc", "expires_on": "2024-05-20T00:00:00Z"},
+					},
+				},
+			},
+			expected: []Expiration{{Ref: "abc", ExpiresOn: time.Date(2024, 5, 20, 0, 0, 0, 0, time.UTC)}},
+		},
+		{
+			name: "outside of the forecast",
+			data: evaluator.Data{
+				"trusted_tasks": map[string]any{
+					"oci://registry.io/task:0.1": []any{
+						map[string]any{"ref": "sha256:expired", "expires_on": "2024-04-20T00:00:00Z"},
+						map[string]any{"ref": "sha256:later", "expires_on": "2024-06-20T00:00:00Z"},
+						map[string]any{"ref": "sha256:current"},
+						map[string]any{"ref": "sha256:invalid", "expires_on": "tomorrow"},
+					},
+				},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.expected, TrustedTaskExpirations(c.data, from, until))
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	date := func(s string) *time.Time {
+		d, err := time.Parse(time.RFC3339, s)
+		require.NoError(t, err)
+		return &d
+	}
+
+	current := []evaluator.Result{
+		{Message: "Already failing", Metadata: map[string]any{"code": "a.failing"}},
+	}
+
+	future := []evaluator.Result{
+		{Message: "Already failing", Metadata: map[string]any{"code": "a.failing"}},
+		{Message: "Unknown", Metadata: map[string]any{"code": "a.unknown"}},
+		{Message: "Task uses oci://registry.io/task:0.1@sha256:old", Metadata: map[string]any{"code": "trusted_task.trusted", "term": "task"}},
+		{Message: "Not yet effective", Metadata: map[string]any{"code": "a.effective", "effective_on": "2024-05-05T00:00:00Z"}},
+		{Message: "Not yet effective", Metadata: map[string]any{"code": "a.effective", "effective_on": "2024-05-05T00:00:00Z"}},
+		{Message: "Effective in the past", Metadata: map[string]any{"code": "a.past", "effective_on": "2024-01-01T00:00:00Z"}},
+	}
+
+	expirations := []Expiration{
+		{Ref: "sha256:other", ExpiresOn: *date("2024-05-07T00:00:00Z")},
+		{Ref: "sha256:old", ExpiresOn: *date("2024-05-20T00:00:00Z")},
+		{Ref: "sha256:old", ExpiresOn: *date("2024-05-10T00:00:00Z")},
+	}
+
+	assert.Equal(t, []Upcoming{
+		{Code: "a.effective", Message: "Not yet effective", Date: date("2024-05-05T00:00:00Z"), Reason: EffectiveOn},
+		{Code: "trusted_task.trusted", Term: "task", Message: "Task uses oci://registry.io/task:0.1@sha256:old", Date: date("2024-05-10T00:00:00Z"), Reason: ExpiresOn},
+		{Code: "a.past", Message: "Effective in the past"},
+		{Code: "a.unknown", Message: "Unknown"},
+	}, Compare(current, future, from, until, expirations))
+
+	assert.Equal(t, []Upcoming{}, Compare(current, current, from, until, nil))
+}
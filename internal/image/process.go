@@ -23,10 +23,11 @@ import (
 	"runtime/trace"
 	"strings"
 
-	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
+
+	"github.com/enterprise-contract/ec-cli/internal/utils/oci"
 )
 
 type key string
@@ -88,11 +89,13 @@ type ImageReference struct {
 // resolveDigest queries the image repository to determine the image digest and
 // returns a new instance of ImageReference with the updated digest value.
 func (i ImageReference) resolveDigest(ctx context.Context, opts ...name.Option) (*ImageReference, error) {
-	remoteHead := remote.Head
+	remoteHead := func(ref name.Reference, _ ...remote.Option) (*v1.Descriptor, error) {
+		return oci.NewClient(ctx).Head(ref)
+	}
 	if rh, ok := ctx.Value(RemoteHead).(func(name.Reference, ...remote.Option) (*v1.Descriptor, error)); ok {
 		remoteHead = rh
 	}
-	descriptor, err := remoteHead(i.ref, remote.WithAuthFromKeychain(authn.DefaultKeychain))
+	descriptor, err := remoteHead(i.ref)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,353 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package image
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"runtime/trace"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	app "github.com/konflux-ci/application-api/api/v1alpha1"
+	ociremote "github.com/sigstore/cosign/v2/pkg/oci/remote"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+
+	"github.com/enterprise-contract/ec-cli/internal/attestation"
+	"github.com/enterprise-contract/ec-cli/internal/cache"
+	"github.com/enterprise-contract/ec-cli/internal/output"
+	"github.com/enterprise-contract/ec-cli/internal/policy"
+	"github.com/enterprise-contract/ec-cli/internal/signature"
+	"github.com/enterprise-contract/ec-cli/internal/utils/oci"
+	"github.com/enterprise-contract/ec-cli/internal/version"
+)
+
+// ResultStoreName is the name of the cache store holding the results of image
+// validations
+const ResultStoreName = "results"
+
+const (
+	resultCacheKey = contextKey("ec.image.result_cache")
+	resultFile     = "result.json"
+)
+
+// ResultCache holds the results of image validations in the persistent cache,
+// so validating an unchanged image with the same policy again reuses the
+// result of the previous validation. The results are keyed by the image
+// digest, the digests of the signatures and attestations attached to the
+// image, the policy, the ec version and the day of the effective time.
+type ResultCache struct {
+	fs         afero.Fs
+	store      *cache.Store
+	policyHash string
+	snapshots  sync.Map
+}
+
+// cachedResult is the stored form of the output of a validation
+type cachedResult struct {
+	Output       output.Output       `json:"output"`
+	Attestations []cachedAttestation `json:"attestations,omitempty"`
+	PolicyInput  json.RawMessage     `json:"policyInput,omitempty"`
+	ExitCode     int                 `json:"exitCode"`
+}
+
+type cachedAttestation struct {
+	Statement  json.RawMessage             `json:"statement"`
+	Signatures []signature.EntitySignature `json:"signatures,omitempty"`
+}
+
+// NewResultCache returns the ResultCache for validations with the given
+// policy. The policy is hashed in its canonical JSON form, so it needs to be
+// resolved beforehand, i.e. with the policy sources pinned to their digests
+// by policy.PreProcessPolicy.
+func NewResultCache(fs afero.Fs, c cache.Config, p policy.Policy) (*ResultCache, error) {
+	if c.Dir == "" {
+		return nil, cache.ErrNoDir
+	}
+
+	opts, err := p.SigstoreOpts()
+	if err != nil {
+		return nil, err
+	}
+
+	spec, err := canonicalJSON(p.Spec())
+	if err != nil {
+		return nil, fmt.Errorf("policy spec: %w", err)
+	}
+
+	sigstore, err := canonicalJSON(opts)
+	if err != nil {
+		return nil, fmt.Errorf("sigstore options: %w", err)
+	}
+
+	return &ResultCache{
+		fs:         fs,
+		store:      cache.NewStore(fs, c, ResultStoreName),
+		policyHash: cache.Key(spec, sigstore),
+	}, nil
+}
+
+// WithResultCache returns a context with the ResultCache used by ValidateImage
+func WithResultCache(ctx context.Context, r *ResultCache) context.Context {
+	return context.WithValue(ctx, resultCacheKey, r)
+}
+
+func resultCache(ctx context.Context) *ResultCache {
+	if r, ok := ctx.Value(resultCacheKey).(*ResultCache); ok {
+		return r
+	}
+
+	return nil
+}
+
+// canonicalJSON returns the JSON of the value with the object keys sorted and
+// without insignificant whitespace, including in any embedded raw JSON
+func canonicalJSON(v any) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	var generic any
+	if err := json.Unmarshal(b, &generic); err != nil {
+		return "", err
+	}
+
+	b, err = json.Marshal(generic)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+// key returns the key of the result of validating the component, the image
+// reference needs to be resolved to the digest. If the signatures and
+// attestations attached to the image can't be determined an error is
+// returned, the result can't be cached then.
+func (r *ResultCache) key(ctx context.Context, ref string, comp app.SnapshotComponent, snap *app.SnapshotSpec, p policy.Policy, detailed bool) (string, error) {
+	digest, err := name.NewDigest(ref)
+	if err != nil {
+		return "", err
+	}
+
+	attached, err := attachments(ctx, digest)
+	if err != nil {
+		return "", err
+	}
+
+	source, err := canonicalJSON(comp.Source)
+	if err != nil {
+		return "", err
+	}
+
+	snapshot, err := r.snapshotHash(snap)
+	if err != nil {
+		return "", err
+	}
+
+	parts := []string{
+		version.Version,
+		r.policyHash,
+		p.EffectiveTime().UTC().Format("2006-01-02"),
+		digest.String(),
+		source,
+		snapshot,
+		strconv.FormatBool(detailed),
+		strconv.Itoa(baseImageDepth(ctx)),
+		strconv.FormatBool(verifyBaseImages(ctx)),
+	}
+
+	return cache.Key(append(parts, attached...)...), nil
+}
+
+// snapshotHash returns the hash of the snapshot, the same snapshot is shared
+// by all components so it is hashed only once
+func (r *ResultCache) snapshotHash(snap *app.SnapshotSpec) (string, error) {
+	if h, ok := r.snapshots.Load(snap); ok {
+		return h.(string), nil
+	}
+
+	j, err := canonicalJSON(snap)
+	if err != nil {
+		return "", err
+	}
+
+	h := cache.Key(j)
+	r.snapshots.Store(snap, h)
+
+	return h, nil
+}
+
+// attachments returns the digests of the signatures and attestations attached
+// to the image, using the cosign tag scheme and as OCI referrers. Any new
+// signature or attestation changes the digests.
+func attachments(ctx context.Context, digest name.Digest) ([]string, error) {
+	client := oci.NewClient(ctx)
+
+	var attached []string
+	for _, tagFn := range []func(name.Reference, ...ociremote.Option) (name.Tag, error){
+		ociremote.SignatureTag,
+		ociremote.AttestationTag,
+	} {
+		tag, err := tagFn(digest)
+		if err != nil {
+			return nil, err
+		}
+
+		desc, err := client.Head(tag)
+		if err != nil {
+			var terr *transport.Error
+			if errors.As(err, &terr) && terr.StatusCode == http.StatusNotFound {
+				attached = append(attached, tag.TagStr()+"=")
+				continue
+			}
+			return nil, err
+		}
+
+		attached = append(attached, tag.TagStr()+"="+desc.Digest.String())
+	}
+
+	referrers, err := client.Referrers(digest)
+	if err != nil {
+		return nil, err
+	}
+
+	digests := make([]string, 0, len(referrers))
+	for _, r := range referrers {
+		digests = append(digests, r.Digest.String())
+	}
+	sort.Strings(digests)
+
+	return append(attached, digests...), nil
+}
+
+// get returns the stored output of the validation with the given key
+func (r *ResultCache) get(key string) (*output.Output, bool, error) {
+	e, found, err := r.store.Get(key)
+	if err != nil || !found || !r.store.Fresh(e) {
+		return nil, false, err
+	}
+
+	b, err := afero.ReadFile(r.fs, filepath.Join(r.store.ContentPath(e), resultFile))
+	if err != nil {
+		return nil, false, err
+	}
+
+	var stored cachedResult
+	if err := json.Unmarshal(b, &stored); err != nil {
+		return nil, false, err
+	}
+
+	out := stored.Output
+	for _, a := range stored.Attestations {
+		att, err := attestation.FromStatement(a.Statement, a.Signatures)
+		if err != nil {
+			return nil, false, err
+		}
+		out.Attestations = append(out.Attestations, att)
+	}
+	out.PolicyInput = stored.PolicyInput
+	out.ExitCode = stored.ExitCode
+
+	return &out, true, nil
+}
+
+// put stores the output of the validation with the given key
+func (r *ResultCache) put(key string, out *output.Output) error {
+	stored := cachedResult{
+		Output:      *out,
+		PolicyInput: out.PolicyInput,
+		ExitCode:    out.ExitCode,
+	}
+	stored.Output.Attestations = nil
+	for _, a := range out.Attestations {
+		stored.Attestations = append(stored.Attestations, cachedAttestation{
+			Statement:  a.Statement(),
+			Signatures: a.Signatures(),
+		})
+	}
+
+	b, err := json.Marshal(stored)
+	if err != nil {
+		return err
+	}
+
+	dir, err := afero.TempDir(r.fs, "", "ec-result-")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = r.fs.RemoveAll(dir)
+	}()
+
+	if err := afero.WriteFile(r.fs, filepath.Join(dir, resultFile), b, 0600); err != nil {
+		return err
+	}
+
+	_, err = r.store.Put(key, []string{out.ImageURL}, nil, dir)
+
+	return err
+}
+
+// cachedValidation returns the output of a previous validation of the
+// component, if there is one, and a function storing the output of the
+// current validation otherwise. The function is nil when the result of the
+// validation can't be cached.
+func cachedValidation(ctx context.Context, ref string, comp app.SnapshotComponent, snap *app.SnapshotSpec, p policy.Policy, detailed bool) (*output.Output, func(*output.Output)) {
+	r := resultCache(ctx)
+	if r == nil {
+		return nil, nil
+	}
+
+	if trace.IsEnabled() {
+		region := trace.StartRegion(ctx, "ec:result-cache-lookup")
+		defer region.End()
+	}
+
+	key, err := r.key(ctx, ref, comp, snap, p, detailed)
+	if err != nil {
+		log.Debugf("Not caching the validation result of %s: %v", ref, err)
+		return nil, nil
+	}
+
+	out, found, err := r.get(key)
+	if err != nil {
+		log.Debugf("Unable to read the cached validation result of %s: %v", ref, err)
+	}
+	if found {
+		log.Debugf("Using the cached validation result of %s", ref)
+		out.ImageURL = ref
+		out.Detailed = detailed
+		out.Policy = p
+		return out, nil
+	}
+
+	return nil, func(out *output.Output) {
+		if err := r.put(key, out); err != nil {
+			log.Debugf("Unable to cache the validation result of %s: %v", ref, err)
+		}
+	}
+}
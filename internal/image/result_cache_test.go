@@ -0,0 +1,155 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package image
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	app "github.com/konflux-ci/application-api/api/v1alpha1"
+	"github.com/sigstore/cosign/v2/pkg/oci"
+	ociremote "github.com/sigstore/cosign/v2/pkg/oci/remote"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/enterprise-contract/ec-cli/internal/cache"
+	"github.com/enterprise-contract/ec-cli/internal/evaluator"
+	"github.com/enterprise-contract/ec-cli/internal/policy"
+	ecoci "github.com/enterprise-contract/ec-cli/internal/utils/oci"
+	"github.com/enterprise-contract/ec-cli/internal/utils/oci/fake"
+)
+
+func TestResultCache(t *testing.T) {
+	sigTag, err := ociremote.SignatureTag(refNoTag)
+	require.NoError(t, err)
+	attTag, err := ociremote.AttestationTag(refNoTag)
+	require.NoError(t, err)
+
+	attDescriptor := &v1.Descriptor{
+		MediaType: types.OCIManifestSchema1,
+		Digest:    v1.Hash{Algorithm: "sha256", Hex: "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"},
+	}
+
+	client := fake.FakeClient{}
+	client.On("Head", sigTag).Return(nil, &transport.Error{StatusCode: http.StatusNotFound})
+	client.On("Head", attTag).Return(attDescriptor, nil)
+	client.On("Head", mock.Anything).Return(&v1.Descriptor{MediaType: types.OCIManifestSchema1}, nil)
+	client.On("Image", refNoTag, mock.Anything).Return(empty.Image, nil)
+	client.On("VerifyImageSignatures", refNoTag, mock.Anything).Return([]oci.Signature{validSignature}, true, nil)
+	client.On("VerifyImageAttestations", refNoTag, mock.Anything).Return([]oci.Signature{validAttestation}, true, nil)
+	client.On("Referrers", refNoTag).Return([]v1.Descriptor{}, nil)
+	client.On("ResolveDigest", refNoTag).Return("@sha256:"+imageDigest, nil)
+	ctx := ecoci.WithClient(context.Background(), &client)
+
+	p, err := policy.NewOfflinePolicy(ctx, policy.Now)
+	require.NoError(t, err)
+
+	fs := afero.NewMemMapFs()
+	r, err := NewResultCache(fs, cache.Config{Dir: "/cache", TTL: time.Hour, MaxSize: cache.DefaultMaxSize}, p)
+	require.NoError(t, err)
+	ctx = WithResultCache(ctx, r)
+
+	e := &mockEvaluator{}
+	e.On("Evaluate", mock.Anything, mock.Anything).Return([]evaluator.Outcome{
+		{
+			Failures: []evaluator.Result{{Message: "failure", Metadata: map[string]any{"code": "test.failure"}}},
+		},
+	}, nil)
+	evaluators := []evaluator.Evaluator{e}
+
+	component := app.SnapshotComponent{ContainerImage: imageRef}
+	snap := app.SnapshotSpec{Components: []app.SnapshotComponent{component}}
+
+	first, err := ValidateImage(ctx, component, &snap, p, evaluators, false)
+	require.NoError(t, err)
+	e.AssertNumberOfCalls(t, "Evaluate", 1)
+
+	stores, err := cache.Stores(fs, cache.Config{Dir: "/cache"})
+	require.NoError(t, err)
+	require.Len(t, stores, 1)
+	assert.Equal(t, ResultStoreName, stores[0].Name())
+
+	// the same image validated with the same policy uses the stored result
+	second, err := ValidateImage(ctx, component, &snap, p, evaluators, false)
+	require.NoError(t, err)
+	e.AssertNumberOfCalls(t, "Evaluate", 1)
+
+	assert.Equal(t, first.ImageURL, second.ImageURL)
+	assert.Equal(t, first.Violations(), second.Violations())
+	assertSameJSON(t, first.Signatures, second.Signatures)
+	assert.JSONEq(t, string(first.PolicyInput), string(second.PolicyInput))
+	require.Len(t, second.Attestations, 1)
+	assert.Equal(t, first.Attestations[0].PredicateType(), second.Attestations[0].PredicateType())
+	assert.Equal(t, first.Attestations[0].Statement(), second.Attestations[0].Statement())
+	assertSameJSON(t, first.Attestations[0].Signatures(), second.Attestations[0].Signatures())
+
+	// the detailed output is not the same as the stored one
+	_, err = ValidateImage(ctx, component, &snap, p, evaluators, true)
+	require.NoError(t, err)
+	e.AssertNumberOfCalls(t, "Evaluate", 2)
+
+	// a new attestation invalidates the stored result
+	attDescriptor.Digest.Hex = "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+	_, err = ValidateImage(ctx, component, &snap, p, evaluators, false)
+	require.NoError(t, err)
+	e.AssertNumberOfCalls(t, "Evaluate", 3)
+
+	// without the result cache the image is always validated
+	_, err = ValidateImage(WithResultCache(ctx, nil), component, &snap, p, evaluators, false)
+	require.NoError(t, err)
+	e.AssertNumberOfCalls(t, "Evaluate", 4)
+}
+
+func assertSameJSON(t *testing.T, expected, actual any) {
+	t.Helper()
+
+	e, err := json.Marshal(expected)
+	require.NoError(t, err)
+	a, err := json.Marshal(actual)
+	require.NoError(t, err)
+
+	assert.JSONEq(t, string(e), string(a))
+}
+
+func TestResultCacheNotCachedOnError(t *testing.T) {
+	client := fake.FakeClient{}
+	client.On("Head", mock.Anything).Return(nil, &transport.Error{StatusCode: http.StatusInternalServerError})
+	ctx := ecoci.WithClient(context.Background(), &client)
+
+	p, err := policy.NewOfflinePolicy(ctx, policy.Now)
+	require.NoError(t, err)
+
+	r, err := NewResultCache(afero.NewMemMapFs(), cache.Config{Dir: "/cache", TTL: time.Hour}, p)
+	require.NoError(t, err)
+	ctx = WithResultCache(ctx, r)
+
+	snap := app.SnapshotSpec{}
+	out, store := cachedValidation(ctx, refNoTag.String(), app.SnapshotComponent{}, &snap, p, false)
+	assert.Nil(t, out)
+	assert.Nil(t, store)
+}
@@ -34,6 +34,46 @@ import (
 	"github.com/enterprise-contract/ec-cli/internal/policy"
 )
 
+type contextKey string
+
+const (
+	baseImageDepthKey   contextKey = "ec.image.base_image_depth"
+	verifyBaseImagesKey contextKey = "ec.image.verify_base_images"
+)
+
+// DefaultBaseImageDepth is the number of base images included in the policy
+// input when not configured otherwise, i.e. just the immediate base image
+const DefaultBaseImageDepth = 1
+
+// WithBaseImageDepth returns a context configuring up to how many base images
+// in the chain of base images of an image are included in the policy input,
+// zero excludes all base images
+func WithBaseImageDepth(ctx context.Context, depth int) context.Context {
+	return context.WithValue(ctx, baseImageDepthKey, depth)
+}
+
+func baseImageDepth(ctx context.Context) int {
+	if depth, ok := ctx.Value(baseImageDepthKey).(int); ok {
+		return depth
+	}
+
+	return DefaultBaseImageDepth
+}
+
+// WithVerifyBaseImages returns a context configuring whether the signatures
+// and attestations of the base images are verified, with the same keys or
+// identities as the image, and included in the policy input. By default only
+// the config of the base images is included.
+func WithVerifyBaseImages(ctx context.Context, verify bool) context.Context {
+	return context.WithValue(ctx, verifyBaseImagesKey, verify)
+}
+
+func verifyBaseImages(ctx context.Context) bool {
+	verify, _ := ctx.Value(verifyBaseImagesKey).(bool)
+
+	return verify
+}
+
 // ValidateImage executes the required method calls to evaluate a given policy
 // against a given image url.
 func ValidateImage(ctx context.Context, comp app.SnapshotComponent, snap *app.SnapshotSpec, p policy.Policy, evaluators []evaluator.Evaluator, detailed bool) (*output.Output, error) {
@@ -63,12 +103,14 @@ func ValidateImage(ctx context.Context, comp app.SnapshotComponent, snap *app.Sn
 		out.ImageURL = resolved
 	}
 
+	cached, store := cachedValidation(ctx, out.ImageURL, comp, snap, p, detailed)
+	if cached != nil {
+		return cached, nil
+	}
+
 	if err := a.FetchImageConfig(ctx); err != nil {
 		log.Debugf("Unable to fetch image config: %s", err)
 	}
-	if err := a.FetchParentImageConfig(ctx); err != nil {
-		log.Debugf("Unable to fetch parent's image config: %s", err)
-	}
 	if err := a.FetchImageFiles(ctx); err != nil {
 		log.Debugf("Unable to fetch image manifests: %s", err)
 	}
@@ -90,6 +132,10 @@ func ValidateImage(ctx context.Context, comp app.SnapshotComponent, snap *app.Sn
 		p.AttestationTime(*attestationTime)
 	}
 
+	if err := a.FetchBaseImages(ctx, baseImageDepth(ctx), verifyBaseImages(ctx)); err != nil {
+		log.Debugf("Unable to fetch base images: %s", err)
+	}
+
 	att := a.Attestations()
 	attCount := len(att)
 	out.Attestations = att
@@ -106,9 +152,9 @@ func ValidateImage(ctx context.Context, comp app.SnapshotComponent, snap *app.Sn
 		return out, nil
 	}
 
-	inputPath, inputJSON, err := a.WriteInputFile(ctx)
+	inputJSON, err := a.PolicyInput(ctx)
 	if err != nil {
-		log.Debug("Problem writing input files!")
+		log.Debug("Problem preparing the policy input!")
 		return nil, err
 	}
 
@@ -116,7 +162,7 @@ func ValidateImage(ctx context.Context, comp app.SnapshotComponent, snap *app.Sn
 
 	for _, e := range evaluators {
 		// Todo maybe: Handle each one concurrently
-		target := evaluator.EvaluationTarget{Inputs: []string{inputPath}}
+		target := evaluator.EvaluationTarget{Input: inputJSON}
 		if ref := a.ImageReference(ctx); ref == "" {
 			log.Debug("Problem getting image reference")
 		} else {
@@ -138,6 +184,10 @@ func ValidateImage(ctx context.Context, comp app.SnapshotComponent, snap *app.Sn
 	log.Debug("Conftest policy check complete")
 	out.SetPolicyCheck(allResults)
 
+	if store != nil {
+		store(out)
+	}
+
 	return out, nil
 }
 
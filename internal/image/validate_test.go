@@ -78,6 +78,7 @@ func TestBuiltinChecks(t *testing.T) {
 				c.On("Head", ref).Return(&v1.Descriptor{MediaType: types.OCIManifestSchema1}, nil)
 				c.On("VerifyImageSignatures", refNoTag, mock.Anything).Return([]oci.Signature{validSignature}, true, nil)
 				c.On("VerifyImageAttestations", refNoTag, mock.Anything).Return([]oci.Signature{validAttestation}, true, nil)
+				c.On("Referrers", refNoTag).Return([]v1.Descriptor{}, nil)
 			},
 			component:          app.SnapshotComponent{ContainerImage: imageRef},
 			expectedViolations: []evaluator.Result{},
@@ -104,6 +105,7 @@ func TestBuiltinChecks(t *testing.T) {
 				c.On("Head", ref).Return(&v1.Descriptor{MediaType: types.OCIManifestSchema1}, nil)
 				c.On("VerifyImageSignatures", refNoTag, mock.Anything).Return(nil, false, errors.New("no image signatures client error"))
 				c.On("VerifyImageAttestations", refNoTag, mock.Anything).Return([]oci.Signature{validAttestation}, true, nil)
+				c.On("Referrers", refNoTag).Return([]v1.Descriptor{}, nil)
 			},
 			component: app.SnapshotComponent{ContainerImage: imageRef},
 			expectedViolations: []evaluator.Result{
@@ -120,6 +122,7 @@ func TestBuiltinChecks(t *testing.T) {
 				c.On("Head", ref).Return(&v1.Descriptor{MediaType: types.OCIManifestSchema1}, nil)
 				c.On("VerifyImageSignatures", refNoTag, mock.Anything).Return(validSignature, true, nil)
 				c.On("VerifyImageAttestations", refNoTag, mock.Anything).Return(nil, false, errors.New("no image attestations client error"))
+				c.On("Referrers", refNoTag).Return([]v1.Descriptor{}, nil)
 			},
 			component: app.SnapshotComponent{ContainerImage: imageRef},
 			expectedViolations: []evaluator.Result{
@@ -313,6 +316,7 @@ func TestEvaluatorLifecycle(t *testing.T) {
 	client.On("Head", ref).Return(&v1.Descriptor{MediaType: types.OCIManifestSchema1}, nil)
 	client.On("VerifyImageSignatures", refNoTag, mock.Anything).Return([]oci.Signature{validSignature}, true, nil)
 	client.On("VerifyImageAttestations", refNoTag, mock.Anything).Return([]oci.Signature{validAttestation}, true, nil)
+	client.On("Referrers", refNoTag).Return([]v1.Descriptor{}, nil)
 	client.On("ResolveDigest", refNoTag).Return("@sha256:"+imageDigest, nil)
 	ctx = ecoci.WithClient(ctx, &client)
 
@@ -29,6 +29,7 @@ import (
 	"github.com/enterprise-contract/ec-cli/internal/evaluator"
 	"github.com/enterprise-contract/ec-cli/internal/format"
 	"github.com/enterprise-contract/ec-cli/internal/policy"
+	"github.com/enterprise-contract/ec-cli/internal/report/sarif"
 	"github.com/enterprise-contract/ec-cli/internal/version"
 )
 
@@ -88,8 +89,17 @@ const (
 	JSON    = "json"
 	YAML    = "yaml"
 	Summary = "summary"
+	SARIF   = "sarif"
 )
 
+// OutputFormats are the formats the report can be written as.
+var OutputFormats = []string{
+	JSON,
+	YAML,
+	Summary,
+	SARIF,
+}
+
 // WriteReport returns a new instance of Report representing the state of
 // the filepaths provided.
 func NewReport(inputs []Input, policy policy.Policy, policyInput [][]byte) (Report, error) {
@@ -154,12 +164,30 @@ func (r *Report) toFormat(format string) (data []byte, err error) {
 		data, err = yaml.Marshal(r)
 	case Summary:
 		data, err = json.Marshal(r.toSummary())
+	case SARIF:
+		data, err = json.Marshal(r.toSARIF())
 	default:
 		return nil, fmt.Errorf("%q is not a valid report format", format)
 	}
 	return
 }
 
+// toSARIF returns a version of the report in SARIF format, with the path of
+// each input file as the location of its results
+func (r *Report) toSARIF() sarif.Log {
+	targets := make([]sarif.Target, 0, len(r.FilePaths))
+	for _, f := range r.FilePaths {
+		targets = append(targets, sarif.Target{
+			Location:   f.FilePath,
+			Violations: f.Violations,
+			Warnings:   f.Warnings,
+			Successes:  f.Successes,
+		})
+	}
+
+	return sarif.NewLog(r.EcVersion, targets)
+}
+
 // toSummary returns a condensed version of the report.
 func (r *Report) toSummary() summary {
 	pr := summary{}
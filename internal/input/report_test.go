@@ -179,6 +179,46 @@ success: false
 	assert.False(t, report.Success)
 }
 
+func Test_ReportSARIF(t *testing.T) {
+	filePaths := []string{"/path/to/file1.yaml", "/path/to/file2.yaml", "/path/to/file3.yaml"}
+	inputs := testInputsFor(filePaths)
+	ctx := context.Background()
+	testPolicy := createTestPolicy(t, ctx)
+	report, err := NewReport(inputs, testPolicy, nil)
+	assert.NoError(t, err)
+
+	location := func(path string) string {
+		return fmt.Sprintf(`[{"physicalLocation": {"artifactLocation": {"uri": %q}}}]`, path)
+	}
+
+	expected := fmt.Sprintf(`{
+		"$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+		"version": "2.1.0",
+		"runs": [
+			{
+				"tool": {
+					"driver": {
+						"name": "ec",
+						"version": "development",
+						"informationUri": "https://conforma.dev"
+					}
+				},
+				"results": [
+					{"kind": "fail", "level": "error", "message": {"text": "violation1"}, "locations": %[1]s},
+					{"kind": "fail", "level": "warning", "message": {"text": "warning1"}, "locations": %[1]s},
+					{"kind": "pass", "level": "none", "message": {"text": "success1"}, "locations": %[1]s},
+					{"kind": "fail", "level": "error", "message": {"text": "violation2"}, "locations": %[2]s},
+					{"kind": "pass", "level": "none", "message": {"text": "success3"}, "locations": %[3]s}
+				]
+			}
+		]
+	}`, location(filePaths[0]), location(filePaths[1]), location(filePaths[2]))
+
+	reportSARIF, err := report.toFormat(SARIF)
+	assert.NoError(t, err)
+	assert.JSONEq(t, expected, string(reportSARIF))
+}
+
 func Test_ReportSummary(t *testing.T) {
 	tests := []struct {
 		name  string
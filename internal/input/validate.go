@@ -42,7 +42,7 @@ func ValidateInput(ctx context.Context, fpath string, policy policy.Policy, deta
 	}
 
 	log.Debugf("Current input filePath: %q", fpath)
-	inputFiles, err := detectInput(ctx, fpath)
+	inputFiles, err := DetectInput(ctx, fpath)
 	if err != nil {
 		return nil, err
 	}
@@ -70,9 +70,10 @@ func ValidateInput(ctx context.Context, fpath string, policy policy.Policy, deta
 	return &out, nil
 }
 
-// detect if a file or directory was passed. if a directory, gather all files in it
-// the order is file lookup, json lookup then yaml
-func detectInput(ctx context.Context, fpath string) ([]string, error) {
+// DetectInput detects if a JSON or YAML string, a file or a directory was
+// passed and returns the paths of the files to evaluate. A string is written
+// to a temporary file, and for a directory all the files in it are returned.
+func DetectInput(ctx context.Context, fpath string) ([]string, error) {
 	if utils.IsJson(fpath) {
 		log.Debug("valid JSON found for definition file")
 		return inputFromString(ctx, fpath)
@@ -0,0 +1,169 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package offline builds OCI image layouts holding everything needed to
+// validate images without network access. The layouts can be consumed with
+// the oci.NewLayoutTransport.
+package offline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	ociremote "github.com/sigstore/cosign/v2/pkg/oci/remote"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/enterprise-contract/ec-cli/internal/utils/oci"
+)
+
+// Exporter copies images from the remote registries into an OCI image layout.
+type Exporter struct {
+	path     layout.Path
+	client   oci.Client
+	exported map[string]*v1.Descriptor
+}
+
+// NewExporter returns an Exporter writing to the OCI image layout in the given
+// directory, the layout is created if it doesn't exist.
+func NewExporter(ctx context.Context, dir string) (*Exporter, error) {
+	p, err := layout.FromPath(dir)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("opening OCI layout %q: %w", dir, err)
+		}
+
+		if p, err = layout.Write(dir, empty.Index); err != nil {
+			return nil, fmt.Errorf("creating OCI layout %q: %w", dir, err)
+		}
+	}
+
+	return &Exporter{
+		path:     p,
+		client:   oci.NewClient(ctx),
+		exported: map[string]*v1.Descriptor{},
+	}, nil
+}
+
+// Image exports the image, or image index, and the signatures, attestations
+// and SBOMs stored alongside it using the cosign tag scheme. For image indexes
+// the same is exported for each of the images within the index.
+func (e *Exporter) Image(ref name.Reference) error {
+	desc, err := e.export(ref)
+	if err != nil {
+		return err
+	}
+
+	digests := []name.Digest{ref.Context().Digest(desc.Digest.String())}
+	if desc.MediaType.IsIndex() {
+		idx, err := e.client.Index(ref)
+		if err != nil {
+			return err
+		}
+
+		manifest, err := idx.IndexManifest()
+		if err != nil {
+			return err
+		}
+
+		for _, m := range manifest.Manifests {
+			digests = append(digests, ref.Context().Digest(m.Digest.String()))
+		}
+	}
+
+	for _, d := range digests {
+		for _, tagFn := range []func(name.Reference, ...ociremote.Option) (name.Tag, error){
+			ociremote.SignatureTag,
+			ociremote.AttestationTag,
+			ociremote.SBOMTag,
+		} {
+			tag, err := tagFn(d)
+			if err != nil {
+				return err
+			}
+
+			if _, err := e.client.Head(tag); err != nil {
+				// not every image has signatures, attestations or SBOMs
+				log.Debugf("Not exporting %s: %v", tag, err)
+				continue
+			}
+
+			if _, err := e.export(tag); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Artifact exports only the image, or image index, e.g. a policy bundle.
+func (e *Exporter) Artifact(ref name.Reference) error {
+	_, err := e.export(ref)
+	return err
+}
+
+// export writes the image, or image index, to the layout replacing any
+// manifest previously exported from the same reference
+func (e *Exporter) export(ref name.Reference) (*v1.Descriptor, error) {
+	if desc, ok := e.exported[ref.Name()]; ok {
+		return desc, nil
+	}
+
+	desc, err := e.client.Head(ref)
+	if err != nil {
+		return nil, fmt.Errorf("fetching %s: %w", ref, err)
+	}
+
+	annotations := layout.WithAnnotations(map[string]string{
+		oci.RefNameAnnotation: ref.Name(),
+	})
+
+	sameRef := func(d v1.Descriptor) bool {
+		return d.Annotations[oci.RefNameAnnotation] == ref.Name()
+	}
+
+	if desc.MediaType.IsIndex() {
+		idx, err := e.client.Index(ref)
+		if err != nil {
+			return nil, err
+		}
+
+		err = e.path.ReplaceIndex(idx, sameRef, annotations)
+		if err != nil {
+			return nil, fmt.Errorf("exporting %s: %w", ref, err)
+		}
+	} else {
+		img, err := e.client.Image(ref)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := e.path.ReplaceImage(img, sameRef, annotations); err != nil {
+			return nil, fmt.Errorf("exporting %s: %w", ref, err)
+		}
+	}
+
+	e.exported[ref.Name()] = desc
+	log.Debugf("Exported %s (%s)", ref, desc.Digest)
+
+	return desc, nil
+}
@@ -0,0 +1,192 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package offline
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	ociremote "github.com/sigstore/cosign/v2/pkg/oci/remote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/enterprise-contract/ec-cli/internal/utils/oci"
+)
+
+// source creates an OCI layout to export from, holding an image index with a
+// signature for one of its images, an image with a signature and an
+// attestation, and a policy bundle
+func source(t *testing.T) (string, v1.ImageIndex, v1.Image) {
+	dir := t.TempDir()
+	p, err := layout.Write(dir, empty.Index)
+	require.NoError(t, err)
+
+	appendImage := func(img v1.Image, ref string) {
+		require.NoError(t, p.AppendImage(img, layout.WithAnnotations(map[string]string{
+			oci.RefNameAnnotation: ref,
+		})))
+	}
+
+	randomImage := func() v1.Image {
+		img, err := random.Image(512, 1)
+		require.NoError(t, err)
+		return img
+	}
+
+	idx, err := random.Index(512, 1, 2)
+	require.NoError(t, err)
+	require.NoError(t, p.AppendIndex(idx, layout.WithAnnotations(map[string]string{
+		oci.RefNameAnnotation: "registry.io/app/index:v1",
+	})))
+
+	idxManifest, err := idx.IndexManifest()
+	require.NoError(t, err)
+	child, err := name.NewDigest("registry.io/app/index@" + idxManifest.Manifests[0].Digest.String())
+	require.NoError(t, err)
+	childSig, err := ociremote.SignatureTag(child)
+	require.NoError(t, err)
+	appendImage(randomImage(), childSig.Name())
+
+	img := randomImage()
+	appendImage(img, "registry.io/app/image:v1")
+
+	digest, err := img.Digest()
+	require.NoError(t, err)
+	ref, err := name.NewDigest("registry.io/app/image@" + digest.String())
+	require.NoError(t, err)
+
+	sig, err := ociremote.SignatureTag(ref)
+	require.NoError(t, err)
+	appendImage(randomImage(), sig.Name())
+
+	att, err := ociremote.AttestationTag(ref)
+	require.NoError(t, err)
+	appendImage(randomImage(), att.Name())
+
+	appendImage(randomImage(), "registry.io/policy/bundle:latest")
+
+	return dir, idx, img
+}
+
+func withSource(t *testing.T, dir string) context.Context {
+	ctx, err := WithLayout(context.Background(), dir)
+	require.NoError(t, err)
+
+	return ctx
+}
+
+func TestExport(t *testing.T) {
+	src, idx, img := source(t)
+	ctx := withSource(t, src)
+
+	dest := t.TempDir() + "/layout"
+	exporter, err := NewExporter(ctx, dest)
+	require.NoError(t, err)
+
+	require.NoError(t, exporter.Image(name.MustParseReference("registry.io/app/index:v1")))
+	require.NoError(t, exporter.Image(name.MustParseReference("registry.io/app/image:v1")))
+	require.NoError(t, exporter.Artifact(name.MustParseReference("registry.io/policy/bundle")))
+
+	p, err := layout.FromPath(dest)
+	require.NoError(t, err)
+	exported, err := p.ImageIndex()
+	require.NoError(t, err)
+	manifest, err := exported.IndexManifest()
+	require.NoError(t, err)
+
+	refs := make([]string, 0, len(manifest.Manifests))
+	for _, m := range manifest.Manifests {
+		refs = append(refs, m.Annotations[oci.RefNameAnnotation])
+	}
+
+	imgDigest, err := img.Digest()
+	require.NoError(t, err)
+	idxManifest, err := idx.IndexManifest()
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{
+		"registry.io/app/index:v1",
+		"registry.io/app/index:" + tag(idxManifest.Manifests[0].Digest, "sig"),
+		"registry.io/app/image:v1",
+		"registry.io/app/image:" + tag(imgDigest, "sig"),
+		"registry.io/app/image:" + tag(imgDigest, "att"),
+		"registry.io/policy/bundle:latest",
+	}, refs)
+
+	// the exported layout can be used in place of the source
+	client := oci.NewClient(withSource(t, dest))
+
+	got, err := client.ResolveDigest(name.MustParseReference("registry.io/app/image:v1"))
+	require.NoError(t, err)
+	assert.Equal(t, imgDigest.String(), got)
+
+	gotIdx, err := client.Index(name.MustParseReference("registry.io/app/index:v1"))
+	require.NoError(t, err)
+	gotIdxDigest, err := gotIdx.Digest()
+	require.NoError(t, err)
+	idxDigest, err := idx.Digest()
+	require.NoError(t, err)
+	assert.Equal(t, idxDigest, gotIdxDigest)
+
+	child, err := name.NewDigest("registry.io/app/index@" + idxManifest.Manifests[1].Digest.String())
+	require.NoError(t, err)
+	_, err = client.Image(child)
+	require.NoError(t, err)
+}
+
+func TestExportReplaces(t *testing.T) {
+	src, _, _ := source(t)
+	dest := t.TempDir()
+
+	for i := 0; i < 2; i++ {
+		exporter, err := NewExporter(withSource(t, src), dest)
+		require.NoError(t, err)
+
+		require.NoError(t, exporter.Artifact(name.MustParseReference("registry.io/policy/bundle")))
+		require.NoError(t, exporter.Artifact(name.MustParseReference("registry.io/policy/bundle")))
+	}
+
+	p, err := layout.FromPath(dest)
+	require.NoError(t, err)
+	exported, err := p.ImageIndex()
+	require.NoError(t, err)
+	manifest, err := exported.IndexManifest()
+	require.NoError(t, err)
+
+	assert.Len(t, manifest.Manifests, 1)
+}
+
+func TestExportMissing(t *testing.T) {
+	src, _, _ := source(t)
+
+	exporter, err := NewExporter(withSource(t, src), t.TempDir())
+	require.NoError(t, err)
+
+	err = exporter.Image(name.MustParseReference("registry.io/app/missing:v1"))
+	assert.ErrorContains(t, err, "fetching registry.io/app/missing:v1")
+}
+
+func tag(digest v1.Hash, suffix string) string {
+	return digest.Algorithm + "-" + digest.Hex + "." + suffix
+}
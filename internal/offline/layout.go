@@ -0,0 +1,43 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package offline
+
+import (
+	"context"
+
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+
+	"github.com/enterprise-contract/ec-cli/internal/downloader"
+	"github.com/enterprise-contract/ec-cli/internal/utils/oci"
+)
+
+// WithLayout returns a context in which images, their signatures and
+// attestations are resolved from the OCI image layout in the given directory.
+// Policy and data sources stored in OCI registries are downloaded from the
+// layout as well.
+func WithLayout(ctx context.Context, dir string) (context.Context, error) {
+	t, err := oci.NewLayoutTransport(dir)
+	if err != nil {
+		return ctx, err
+	}
+
+	downloader.UseOCITransport(t)
+
+	client := oci.NewClient(ctx, remote.WithContext(ctx), remote.WithTransport(t))
+
+	return oci.WithClient(ctx, client), nil
+}
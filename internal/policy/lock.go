@@ -0,0 +1,294 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package policy
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	ecc "github.com/enterprise-contract/enterprise-contract-controller/api/v1alpha1"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"sigs.k8s.io/yaml"
+
+	"github.com/enterprise-contract/ec-cli/internal/policy/source"
+	"github.com/enterprise-contract/ec-cli/internal/utils"
+)
+
+// Lockfile records the immutable references, i.e. git commit SHAs and OCI
+// image digests, the policy configuration and the policy and data sources of
+// a policy were resolved to, so the same policy can be used again. The digests
+// of the policy spec and of its source groups are recorded to detect changes
+// to the rule data, the configuration and other settings of the policy.
+type Lockfile struct {
+	// Configuration is the policy configuration, set only when it was
+	// fetched from a remote location
+	Configuration *LockedSource `json:"configuration,omitempty"`
+	// Digest is the digest of the policy spec without its source groups,
+	// e.g. of its include and exclude configuration, see specDigest
+	Digest string `json:"digest"`
+	// Sources holds the policy and data sources of each source group in the
+	// order of the source groups in the policy
+	Sources []LockedSourceGroup `json:"sources"`
+}
+
+// LockedSource is the URL of a source as given in the policy, and the URL
+// pinned to the immutable reference it was resolved to
+type LockedSource struct {
+	URL    string `json:"url"`
+	Pinned string `json:"pinned"`
+}
+
+// NewLockedSource returns the LockedSource of the given source URL and the URL
+// it was pinned to. An error is returned if the pinned URL doesn't reference
+// immutable content, e.g. for https or local file sources, as the content of
+// those could change without the lockfile noticing.
+func NewLockedSource(url, pinned string) (LockedSource, error) {
+	if !source.IsPinned(pinned) {
+		return LockedSource{}, fmt.Errorf("unable to lock the source %q: it resolved to %q which is not an immutable reference, only git and OCI sources can be locked", url, pinned)
+	}
+
+	return LockedSource{URL: url, Pinned: pinned}, nil
+}
+
+// LockedSourceGroup holds the locked policy and data sources of a source group
+// and the digest of the source group as given in the policy, see specDigest
+type LockedSourceGroup struct {
+	Name   string         `json:"name,omitempty"`
+	Digest string         `json:"digest"`
+	Policy []LockedSource `json:"policy,omitempty"`
+	Data   []LockedSource `json:"data,omitempty"`
+}
+
+// specDigest returns the SHA-256 digest of the canonical JSON encoding of the
+// given part of a policy spec, in the form sha256:<hex>. The JSON encoding is
+// canonical in that the keys of objects are sorted, so the digest of the rule
+// data doesn't depend on the formatting or the order of the keys.
+func specDigest(v any) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	var canonical any
+	if err := decoder.Decode(&canonical); err != nil {
+		return "", err
+	}
+
+	if b, err = json.Marshal(canonical); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(b)), nil
+}
+
+// policyDigest returns the digest of the policy spec without its source groups
+func policyDigest(spec ecc.EnterpriseContractPolicySpec) (string, error) {
+	spec.Sources = nil
+	return specDigest(spec)
+}
+
+// Lock fetches the policy and data sources of the policy spec and returns the
+// Lockfile with the sources pinned to the immutable references they were
+// resolved to. Sources that can't be pinned to an immutable reference are
+// rejected.
+func Lock(ctx context.Context, spec ecc.EnterpriseContractPolicySpec) (*Lockfile, error) {
+	fs := utils.FS(ctx)
+	dir, err := utils.CreateWorkDir(fs)
+	if err != nil {
+		return nil, err
+	}
+	defer utils.CleanupWorkDir(fs, dir)
+
+	digest, err := policyDigest(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	lock := Lockfile{
+		Digest:  digest,
+		Sources: make([]LockedSourceGroup, 0, len(spec.Sources)),
+	}
+	for _, sourceGroup := range spec.Sources {
+		digest, err := specDigest(sourceGroup)
+		if err != nil {
+			return nil, err
+		}
+		group := LockedSourceGroup{Name: sourceGroup.Name, Digest: digest}
+
+		for _, policySource := range PolicySourcesFrom(sourceGroup) {
+			url := policySource.PolicyUrl()
+			if strings.HasPrefix(url, "data:") {
+				continue
+			}
+
+			if _, err := policySource.GetPolicy(ctx, dir, false); err != nil {
+				return nil, fmt.Errorf("unable to lock the source %q: %w", url, err)
+			}
+
+			locked, err := NewLockedSource(url, policySource.PolicyUrl())
+			if err != nil {
+				return nil, err
+			}
+			log.Debugf("Locked %s to %s", locked.URL, locked.Pinned)
+
+			switch policySource.Type() {
+			case source.PolicyKind:
+				group.Policy = append(group.Policy, locked)
+			case source.DataKind:
+				group.Data = append(group.Data, locked)
+			}
+		}
+
+		lock.Sources = append(lock.Sources, group)
+	}
+
+	return &lock, nil
+}
+
+// ReadLockfile reads the Lockfile from the given YAML or JSON file
+func ReadLockfile(fs afero.Fs, path string) (*Lockfile, error) {
+	b, err := afero.ReadFile(fs, path)
+	if err != nil {
+		return nil, err
+	}
+
+	var lock Lockfile
+	if err := yaml.UnmarshalStrict(b, &lock); err != nil {
+		return nil, fmt.Errorf("unable to parse the lockfile %q: %w", path, err)
+	}
+
+	return &lock, nil
+}
+
+// PolicyConfiguration returns the pinned URL of the policy configuration to
+// fetch instead of the given policy configuration. An error is returned if the
+// policy configuration differs from the locked one. When the lockfile has no
+// locked policy configuration, the given policy configuration is returned.
+func (l *Lockfile) PolicyConfiguration(policyConfiguration string) (string, error) {
+	if l.Configuration == nil {
+		return policyConfiguration, nil
+	}
+
+	if policyConfiguration != l.Configuration.URL {
+		return "", fmt.Errorf("the policy configuration %q has drifted from the lockfile, expected %q", policyConfiguration, l.Configuration.URL)
+	}
+
+	if !source.IsPinned(l.Configuration.Pinned) {
+		return "", fmt.Errorf("the lockfile has the policy configuration pinned to %q which is not an immutable reference", l.Configuration.Pinned)
+	}
+
+	return l.Configuration.Pinned, nil
+}
+
+// Apply returns the policy spec with the URLs of the policy and data sources
+// replaced by the pinned URLs from the lockfile. An error is returned if the
+// source groups or their sources in the policy spec differ from the ones in
+// the lockfile, if the digests of the policy spec or of its source groups
+// differ from the ones in the lockfile, or if the lockfile has sources not
+// pinned to immutable references.
+func (l *Lockfile) Apply(spec ecc.EnterpriseContractPolicySpec) (ecc.EnterpriseContractPolicySpec, error) {
+	if len(spec.Sources) != len(l.Sources) {
+		return spec, fmt.Errorf("the policy has drifted from the lockfile: the policy has %d source groups, the lockfile %d", len(spec.Sources), len(l.Sources))
+	}
+
+	var errs error
+	digest, err := policyDigest(spec)
+	if err != nil {
+		return spec, err
+	}
+	if digest != l.Digest {
+		errs = errors.Join(errs, fmt.Errorf("the configuration or other settings of the policy have changed: digest %q, expected %q", digest, l.Digest))
+	}
+
+	sources := make([]ecc.Source, 0, len(spec.Sources))
+	for i, sourceGroup := range spec.Sources {
+		locked := l.Sources[i]
+		if sourceGroup.Name != locked.Name {
+			errs = errors.Join(errs, fmt.Errorf("the source group %q has drifted from the lockfile, expected %q", sourceGroup.Name, locked.Name))
+			continue
+		}
+
+		policyUrls, policyErr := pinned(sourceGroup.Name, source.PolicyKind, sourceGroup.Policy, locked.Policy)
+		dataUrls, dataErr := pinned(sourceGroup.Name, source.DataKind, sourceGroup.Data, locked.Data)
+		errs = errors.Join(errs, policyErr, dataErr)
+
+		// the URLs are part of the digest, the digest is compared only when the
+		// URLs are the same to report the drift of the other fields, e.g. of
+		// the rule data
+		if policyErr == nil && dataErr == nil {
+			digest, err := specDigest(sourceGroup)
+			if err != nil {
+				return spec, err
+			}
+			if digest != locked.Digest {
+				errs = errors.Join(errs, fmt.Errorf("the rule data, configuration or other settings of the source group %q have changed: digest %q, expected %q", sourceGroup.Name, digest, locked.Digest))
+			}
+		}
+
+		sourceGroup.Policy = policyUrls
+		sourceGroup.Data = dataUrls
+		sources = append(sources, sourceGroup)
+	}
+
+	if errs != nil {
+		return spec, fmt.Errorf("the policy has drifted from the lockfile: %w", errs)
+	}
+
+	spec.Sources = sources
+
+	return spec, nil
+}
+
+// pinned returns the pinned URLs of the given URLs, the URLs need to be the
+// same, and in the same order, as the URLs of the locked sources
+func pinned(group string, kind source.PolicyType, urls []string, locked []LockedSource) ([]string, error) {
+	lockedUrls := make([]string, 0, len(locked))
+	pinnedUrls := make([]string, 0, len(locked))
+	var errs error
+	for _, l := range locked {
+		lockedUrls = append(lockedUrls, l.URL)
+		pinnedUrls = append(pinnedUrls, l.Pinned)
+
+		if !source.IsPinned(l.Pinned) {
+			errs = errors.Join(errs, fmt.Errorf("the lockfile has the %s source %q of the source group %q pinned to %q which is not an immutable reference", kind, l.URL, group, l.Pinned))
+		}
+	}
+
+	if !slices.Equal(urls, lockedUrls) {
+		return nil, fmt.Errorf("the %s sources of the source group %q are %q, the lockfile has %q", kind, group, urls, lockedUrls)
+	}
+
+	if errs != nil {
+		return nil, errs
+	}
+
+	if len(urls) == 0 {
+		// keep nil and empty lists as they were in the policy
+		return urls, nil
+	}
+
+	return pinnedUrls, nil
+}
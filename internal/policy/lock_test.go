@@ -0,0 +1,346 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package policy
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	gitMetadata "github.com/conforma/go-gather/gather/git"
+	httpMetadata "github.com/conforma/go-gather/gather/http"
+	ociMetadata "github.com/conforma/go-gather/gather/oci"
+	"github.com/conforma/go-gather/metadata"
+	ecc "github.com/enterprise-contract/enterprise-contract-controller/api/v1alpha1"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+
+	"github.com/enterprise-contract/ec-cli/internal/policy/source"
+	"github.com/enterprise-contract/ec-cli/internal/utils"
+)
+
+type mockDownloader struct {
+	mock.Mock
+}
+
+func (m *mockDownloader) Download(_ context.Context, dest string, sourceUrl string, showMsg bool) (metadata.Metadata, error) {
+	args := m.Called(dest, sourceUrl, showMsg)
+
+	return args.Get(0).(metadata.Metadata), args.Error(1)
+}
+
+func TestLock(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	ctx := utils.WithFS(context.Background(), fs)
+
+	dl := mockDownloader{}
+	dl.On("Download", mock.Anything, "github.com/org/lock-policy//policy?ref=main", false).Return(&gitMetadata.GitMetadata{LatestCommit: "3b1e6ad9bd1e1f7e5cf0e6e1a8d6c4d0e2b9a7f1"}, nil)
+	dl.On("Download", mock.Anything, "registry.io/lock/data:latest", false).Return(&ociMetadata.OCIMetadata{Digest: "sha256:def456"}, nil)
+	ctx = context.WithValue(ctx, source.DownloaderFuncKey, &dl)
+
+	release := ecc.Source{
+		Name:     "release",
+		Policy:   []string{"github.com/org/lock-policy//policy?ref=main"},
+		Data:     []string{"registry.io/lock/data:latest"},
+		RuleData: &extv1.JSON{Raw: []byte(`{"key":"value"}`)},
+	}
+	spec := ecc.EnterpriseContractPolicySpec{
+		Configuration: &ecc.EnterpriseContractPolicyConfiguration{Include: []string{"@minimal"}},
+		Sources:       []ecc.Source{release, {Name: "empty"}},
+	}
+	lock, err := Lock(ctx, spec)
+	require.NoError(t, err)
+
+	assert.Equal(t, &Lockfile{
+		Digest: mustDigest(t, ecc.EnterpriseContractPolicySpec{Configuration: spec.Configuration}),
+		Sources: []LockedSourceGroup{
+			{
+				Name:   "release",
+				Digest: mustDigest(t, release),
+				Policy: []LockedSource{
+					{URL: "github.com/org/lock-policy//policy?ref=main", Pinned: "git::github.com/org/lock-policy//policy?ref=3b1e6ad9bd1e1f7e5cf0e6e1a8d6c4d0e2b9a7f1"},
+				},
+				Data: []LockedSource{
+					{URL: "registry.io/lock/data:latest", Pinned: "oci::registry.io/lock/data:latest@sha256:def456"},
+				},
+			},
+			{
+				Name:   "empty",
+				Digest: mustDigest(t, ecc.Source{Name: "empty"}),
+			},
+		},
+	}, lock)
+
+	// the lockfile is applicable to the policy it was created from
+	_, err = lock.Apply(spec)
+	require.NoError(t, err)
+}
+
+func mustDigest(t *testing.T, v any) string {
+	digest, err := specDigest(v)
+	require.NoError(t, err)
+	return digest
+}
+
+func TestSpecDigest(t *testing.T) {
+	a := mustDigest(t, ecc.Source{Name: "release", RuleData: &extv1.JSON{Raw: []byte(`{"a": 1, "b": [2, 3.50]}`)}})
+	b := mustDigest(t, ecc.Source{Name: "release", RuleData: &extv1.JSON{Raw: []byte(`{"b":[2,3.50],"a":1}`)}})
+	c := mustDigest(t, ecc.Source{Name: "release", RuleData: &extv1.JSON{Raw: []byte(`{"b":[2,3.5],"a":1}`)}})
+
+	assert.Regexp(t, `^sha256:[0-9a-f]{64}$`, a)
+	assert.Equal(t, a, b)
+	assert.NotEqual(t, a, c)
+}
+
+func TestLockRejectsUnpinnableSources(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	ctx := utils.WithFS(context.Background(), fs)
+
+	dl := mockDownloader{}
+	dl.On("Download", mock.Anything, "https://example.com/policy.tar.gz", false).Return(&httpMetadata.HTTPMetadata{}, nil)
+	ctx = context.WithValue(ctx, source.DownloaderFuncKey, &dl)
+
+	_, err := Lock(ctx, ecc.EnterpriseContractPolicySpec{
+		Sources: []ecc.Source{
+			{Name: "release", Policy: []string{"https://example.com/policy.tar.gz"}},
+		},
+	})
+	assert.ErrorContains(t, err, `unable to lock the source "https://example.com/policy.tar.gz": it resolved to "http::example.com/policy.tar.gz" which is not an immutable reference`)
+}
+
+func TestLockfileApplyRejectsUnpinnedSources(t *testing.T) {
+	release := ecc.Source{Name: "release", Policy: []string{"github.com/org/policy"}}
+	lock := Lockfile{
+		Digest: mustDigest(t, ecc.EnterpriseContractPolicySpec{}),
+		Sources: []LockedSourceGroup{
+			{
+				Name:   "release",
+				Digest: mustDigest(t, release),
+				Policy: []LockedSource{
+					{URL: "github.com/org/policy", Pinned: "git::github.com/org/policy?ref=main"},
+				},
+			},
+		},
+	}
+
+	_, err := lock.Apply(ecc.EnterpriseContractPolicySpec{
+		Sources: []ecc.Source{release},
+	})
+	assert.EqualError(t, err, `the policy has drifted from the lockfile: the lockfile has the policy source "github.com/org/policy" of the source group "release" pinned to "git::github.com/org/policy?ref=main" which is not an immutable reference`)
+}
+
+func TestLockfileApply(t *testing.T) {
+	release := ecc.Source{
+		Name:     "release",
+		Policy:   []string{"github.com/org/policy"},
+		Data:     []string{"registry.io/data:latest"},
+		RuleData: &extv1.JSON{Raw: []byte(`{"key":"value"}`)},
+	}
+
+	lock := Lockfile{
+		Digest: mustDigest(t, ecc.EnterpriseContractPolicySpec{}),
+		Sources: []LockedSourceGroup{
+			{
+				Name:   "release",
+				Digest: mustDigest(t, release),
+				Policy: []LockedSource{
+					{URL: "github.com/org/policy", Pinned: "git::github.com/org/policy?ref=3b1e6ad9bd1e1f7e5cf0e6e1a8d6c4d0e2b9a7f1"},
+				},
+				Data: []LockedSource{
+					{URL: "registry.io/data:latest", Pinned: "oci::registry.io/data:latest@sha256:def456"},
+				},
+			},
+		},
+	}
+
+	cases := []struct {
+		name          string
+		configuration *ecc.EnterpriseContractPolicyConfiguration
+		sources       []ecc.Source
+		want          []ecc.Source
+		err           string
+	}{
+		{
+			name: "pinned",
+			sources: []ecc.Source{
+				{
+					Name:     "release",
+					Policy:   []string{"github.com/org/policy"},
+					Data:     []string{"registry.io/data:latest"},
+					RuleData: &extv1.JSON{Raw: []byte(`{ "key": "value" }`)},
+				},
+			},
+			want: []ecc.Source{
+				{
+					Name:     "release",
+					Policy:   []string{"git::github.com/org/policy?ref=3b1e6ad9bd1e1f7e5cf0e6e1a8d6c4d0e2b9a7f1"},
+					Data:     []string{"oci::registry.io/data:latest@sha256:def456"},
+					RuleData: &extv1.JSON{Raw: []byte(`{ "key": "value" }`)},
+				},
+			},
+		},
+		{
+			name: "source group added",
+			sources: []ecc.Source{
+				{Name: "release", Policy: []string{"github.com/org/policy"}, Data: []string{"registry.io/data:latest"}},
+				{Name: "extra"},
+			},
+			err: "the policy has drifted from the lockfile: the policy has 2 source groups, the lockfile 1",
+		},
+		{
+			name: "source group renamed",
+			sources: []ecc.Source{
+				{Name: "other", Policy: []string{"github.com/org/policy"}, Data: []string{"registry.io/data:latest"}},
+			},
+			err: `the policy has drifted from the lockfile: the source group "other" has drifted from the lockfile, expected "release"`,
+		},
+		{
+			name: "policy source changed",
+			sources: []ecc.Source{
+				{Name: "release", Policy: []string{"github.com/org/policy?ref=v2"}, Data: []string{"registry.io/data:latest"}},
+			},
+			err: `the policy has drifted from the lockfile: the policy sources of the source group "release" are ["github.com/org/policy?ref=v2"], the lockfile has ["github.com/org/policy"]`,
+		},
+		{
+			name: "rule data changed",
+			sources: []ecc.Source{
+				{
+					Name:     "release",
+					Policy:   []string{"github.com/org/policy"},
+					Data:     []string{"registry.io/data:latest"},
+					RuleData: &extv1.JSON{Raw: []byte(`{"key":"other"}`)},
+				},
+			},
+			err: fmt.Sprintf(`the policy has drifted from the lockfile: the rule data, configuration or other settings of the source group "release" have changed: digest %q, expected %q`,
+				mustDigest(t, ecc.Source{
+					Name:     "release",
+					Policy:   []string{"github.com/org/policy"},
+					Data:     []string{"registry.io/data:latest"},
+					RuleData: &extv1.JSON{Raw: []byte(`{"key":"other"}`)},
+				}),
+				lock.Sources[0].Digest),
+		},
+		{
+			name: "source configuration changed",
+			sources: []ecc.Source{
+				{
+					Name:     "release",
+					Policy:   []string{"github.com/org/policy"},
+					Data:     []string{"registry.io/data:latest"},
+					RuleData: &extv1.JSON{Raw: []byte(`{"key":"value"}`)},
+					Config:   &ecc.SourceConfig{Exclude: []string{"test"}},
+				},
+			},
+			err: `the rule data, configuration or other settings of the source group "release" have changed`,
+		},
+		{
+			name:          "policy configuration changed",
+			configuration: &ecc.EnterpriseContractPolicyConfiguration{Exclude: []string{"test"}},
+			sources:       []ecc.Source{release},
+			err:           `the configuration or other settings of the policy have changed`,
+		},
+		{
+			name: "data source removed",
+			sources: []ecc.Source{
+				{Name: "release", Policy: []string{"github.com/org/policy"}},
+			},
+			err: `the policy has drifted from the lockfile: the data sources of the source group "release" are [], the lockfile has ["registry.io/data:latest"]`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			spec := ecc.EnterpriseContractPolicySpec{Configuration: c.configuration, Sources: c.sources}
+			got, err := lock.Apply(spec)
+			if c.err != "" {
+				assert.ErrorContains(t, err, c.err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, c.want, got.Sources)
+			// the given spec is not modified
+			assert.Equal(t, []string{"github.com/org/policy"}, c.sources[0].Policy)
+		})
+	}
+}
+
+func TestLockfilePolicyConfiguration(t *testing.T) {
+	unlocked := Lockfile{}
+	got, err := unlocked.PolicyConfiguration("policy.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, "policy.yaml", got)
+
+	locked := Lockfile{
+		Configuration: &LockedSource{URL: "github.com/org/config", Pinned: "git::github.com/org/config?ref=3b1e6ad9bd1e1f7e5cf0e6e1a8d6c4d0e2b9a7f1"},
+	}
+	got, err = locked.PolicyConfiguration("github.com/org/config")
+	require.NoError(t, err)
+	assert.Equal(t, "git::github.com/org/config?ref=3b1e6ad9bd1e1f7e5cf0e6e1a8d6c4d0e2b9a7f1", got)
+
+	_, err = locked.PolicyConfiguration("github.com/org/other")
+	assert.EqualError(t, err, `the policy configuration "github.com/org/other" has drifted from the lockfile, expected "github.com/org/config"`)
+
+	unpinned := Lockfile{
+		Configuration: &LockedSource{URL: "https://example.com/policy.yaml", Pinned: "http::https://example.com/policy.yaml"},
+	}
+	_, err = unpinned.PolicyConfiguration("https://example.com/policy.yaml")
+	assert.EqualError(t, err, `the lockfile has the policy configuration pinned to "http::https://example.com/policy.yaml" which is not an immutable reference`)
+}
+
+func TestReadLockfile(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "/policy.lock.yaml", []byte(`configuration:
+  url: github.com/org/config
+  pinned: git::github.com/org/config?ref=3b1e6ad9bd1e1f7e5cf0e6e1a8d6c4d0e2b9a7f1
+digest: sha256:44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a
+sources:
+- name: release
+  digest: sha256:8a9f2b5b9c44de4e7ba1c9f2a3d2f1c0e6f5d4c3b2a1908f7e6d5c4b3a291807
+  policy:
+  - url: github.com/org/policy
+    pinned: git::github.com/org/policy?ref=3b1e6ad9bd1e1f7e5cf0e6e1a8d6c4d0e2b9a7f1
+`), 0644))
+	require.NoError(t, afero.WriteFile(fs, "/invalid.yaml", []byte(`sources: [{unknown: field}]`), 0644))
+
+	lock, err := ReadLockfile(fs, "/policy.lock.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, &Lockfile{
+		Configuration: &LockedSource{URL: "github.com/org/config", Pinned: "git::github.com/org/config?ref=3b1e6ad9bd1e1f7e5cf0e6e1a8d6c4d0e2b9a7f1"},
+		Digest:        "sha256:44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a",
+		Sources: []LockedSourceGroup{
+			{
+				Name:   "release",
+				Digest: "sha256:8a9f2b5b9c44de4e7ba1c9f2a3d2f1c0e6f5d4c3b2a1908f7e6d5c4b3a291807",
+				Policy: []LockedSource{
+					{URL: "github.com/org/policy", Pinned: "git::github.com/org/policy?ref=3b1e6ad9bd1e1f7e5cf0e6e1a8d6c4d0e2b9a7f1"},
+				},
+			},
+		},
+	}, lock)
+
+	_, err = ReadLockfile(fs, "/invalid.yaml")
+	assert.ErrorContains(t, err, `unable to parse the lockfile "/invalid.yaml"`)
+
+	_, err = ReadLockfile(fs, "/missing.yaml")
+	assert.Error(t, err)
+}
@@ -19,6 +19,7 @@ package policy
 import (
 	"context"
 	"crypto"
+	"crypto/x509"
 	_ "embed"
 	"errors"
 	"fmt"
@@ -32,9 +33,12 @@ import (
 	"github.com/sigstore/cosign/v2/cmd/cosign/cli/rekor"
 	"github.com/sigstore/cosign/v2/pkg/cosign"
 	cosignSig "github.com/sigstore/cosign/v2/pkg/signature"
+	"github.com/sigstore/sigstore-go/pkg/root"
 	"github.com/sigstore/sigstore/pkg/cryptoutils"
 	sigstoreSig "github.com/sigstore/sigstore/pkg/signature"
+	"github.com/sigstore/sigstore/pkg/tuf"
 	log "github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
 	"sigs.k8s.io/yaml"
 
 	"github.com/enterprise-contract/ec-cli/internal/kubernetes"
@@ -78,6 +82,7 @@ type Policy interface {
 	Identity() cosign.Identity
 	Keyless() bool
 	SigstoreOpts() (SigstoreOpts, error)
+	TrustedMaterial() root.TrustedMaterial
 }
 
 type policy struct {
@@ -88,6 +93,7 @@ type policy struct {
 	attestationTime *time.Time
 	identity        cosign.Identity
 	ignoreRekor     bool
+	trustedMaterial root.TrustedMaterial
 }
 
 // PublicKeyPEM returns the PublicKey in PEM format.
@@ -126,6 +132,12 @@ func (p *policy) Keyless() bool {
 	return p.PublicKey == ""
 }
 
+// TrustedMaterial returns the Sigstore trusted material loaded from the trusted
+// root, or nil if no trusted root was provided.
+func (p *policy) TrustedMaterial() root.TrustedMaterial {
+	return p.trustedMaterial
+}
+
 func (p *policy) SigstoreOpts() (SigstoreOpts, error) {
 	pk, err := p.PublicKeyPEM()
 	if err != nil {
@@ -149,9 +161,13 @@ type Options struct {
 	EffectiveTime string
 	Identity      cosign.Identity
 	IgnoreRekor   bool
-	PolicyRef     string
-	PublicKey     string
-	RekorURL      string
+	// Lockfile, when set, replaces the policy and data source URLs with the
+	// pinned URLs from the lockfile
+	Lockfile    *Lockfile
+	PolicyRef   string
+	PublicKey   string
+	RekorURL    string
+	TrustedRoot string
 }
 
 // NewOfflinePolicy construct and return a new instance of Policy that is used
@@ -234,6 +250,14 @@ func NewPolicy(ctx context.Context, opts Options) (Policy, error) {
 		return nil, err
 	}
 
+	if opts.Lockfile != nil {
+		spec, err := opts.Lockfile.Apply(p.EnterpriseContractPolicySpec)
+		if err != nil {
+			return nil, err
+		}
+		p.EnterpriseContractPolicySpec = spec
+	}
+
 	if opts.RekorURL != "" && opts.RekorURL != p.RekorUrl {
 		p.RekorUrl = opts.RekorURL
 		log.Debugf("Updated rekor URL in policy to %q", opts.RekorURL)
@@ -270,6 +294,19 @@ func NewPolicy(ctx context.Context, opts Options) (Policy, error) {
 		p.effectiveTime = efn
 	}
 
+	if opts.TrustedRoot != "" {
+		data, err := afero.ReadFile(utils.FS(ctx), opts.TrustedRoot)
+		if err != nil {
+			return nil, fmt.Errorf("reading the trusted root from %q: %w", opts.TrustedRoot, err)
+		}
+		trustedRoot, err := root.NewTrustedRootFromJSON(data)
+		if err != nil {
+			return nil, fmt.Errorf("loading the trusted root from %q: %w", opts.TrustedRoot, err)
+		}
+		p.trustedMaterial = trustedRoot
+		log.Debugf("Using the trusted root from %q", opts.TrustedRoot)
+	}
+
 	if opts, err := checkOpts(ctx, &p); err != nil {
 		return nil, err
 	} else {
@@ -420,6 +457,11 @@ func checkOpts(ctx context.Context, p *policy) (*cosign.CheckOpts, error) {
 		if opts.SigVerifier, err = signatureVerifier(ctx, p); err != nil {
 			return nil, err
 		}
+	} else if p.trustedMaterial != nil {
+		log.Debug("Using keyless workflow with the trusted root")
+		opts.Identities = []cosign.Identity{p.identity}
+		opts.RootCerts, opts.IntermediateCerts = certificateAuthorities(p.trustedMaterial.FulcioCertificateAuthorities())
+		opts.CTLogPubKeys = transparencyLogKeys(p.trustedMaterial.CTLogs())
 	} else {
 		log.Debug("Using keyless workflow")
 		log.Debugf("TUF_ROOT=%s", os.Getenv("TUF_ROOT"))
@@ -462,15 +504,52 @@ func checkOpts(ctx context.Context, p *policy) (*cosign.CheckOpts, error) {
 			log.Debugf("Rekor client created, url %q", rekorURL)
 		}
 
-		if opts.RekorPubKeys, err = cosign.GetRekorPubs(ctx); err != nil {
+		if p.trustedMaterial != nil {
+			opts.RekorPubKeys = transparencyLogKeys(p.trustedMaterial.RekorLogs())
+		} else if opts.RekorPubKeys, err = cosign.GetRekorPubs(ctx); err != nil {
 			return nil, err
 		}
 		log.Debug("Retrieved Rekor public keys")
 	}
 
+	if p.trustedMaterial != nil {
+		for _, tsa := range p.trustedMaterial.TimestampingAuthorities() {
+			opts.TSARootCertificates = append(opts.TSARootCertificates, tsa.Root)
+			opts.TSAIntermediateCertificates = append(opts.TSAIntermediateCertificates, tsa.Intermediates...)
+		}
+	}
+
 	return &opts, nil
 }
 
+// certificateAuthorities returns the pools of root and intermediate
+// certificates of the certificate authorities
+func certificateAuthorities(authorities []root.CertificateAuthority) (*x509.CertPool, *x509.CertPool) {
+	roots := x509.NewCertPool()
+	intermediates := x509.NewCertPool()
+	for _, ca := range authorities {
+		if ca.Root != nil {
+			roots.AddCert(ca.Root)
+		}
+		for _, i := range ca.Intermediates {
+			intermediates.AddCert(i)
+		}
+	}
+
+	return roots, intermediates
+}
+
+// transparencyLogKeys returns the public keys of the transparency logs indexed
+// by the log ID, as expected by cosign
+func transparencyLogKeys(logs map[string]*root.TransparencyLog) *cosign.TrustedTransparencyLogPubKeys {
+	keys := cosign.NewTrustedTransparencyLogPubKeys()
+	for id, l := range logs {
+		keys.Keys[id] = cosign.TransparencyLogPubKey{PubKey: l.PublicKey, Status: tuf.Active}
+	}
+
+	return &keys
+}
+
 type signatureClient interface {
 	publicKeyFromKeyRef(context.Context, string) (sigstoreSig.Verifier, error)
 }
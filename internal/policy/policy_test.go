@@ -31,7 +31,10 @@ import (
 	ecc "github.com/enterprise-contract/enterprise-contract-controller/api/v1alpha1"
 	"github.com/sigstore/cosign/v2/pkg/cosign"
 	cosignSig "github.com/sigstore/cosign/v2/pkg/signature"
+	"github.com/sigstore/sigstore-go/pkg/root"
+	"github.com/sigstore/sigstore-go/pkg/testing/ca"
 	sigstoreSig "github.com/sigstore/sigstore/pkg/signature"
+	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -850,3 +853,62 @@ func TestUrls(t *testing.T) {
 		})
 	}
 }
+
+func TestCheckOptsWithTrustedRoot(t *testing.T) {
+	virtualSigstore, err := ca.NewVirtualSigstore()
+	require.NoError(t, err)
+
+	trustedRoot, err := root.NewTrustedRoot(root.TrustedRootMediaType01,
+		virtualSigstore.FulcioCertificateAuthorities(),
+		virtualSigstore.CTLogs(),
+		virtualSigstore.TimestampingAuthorities(),
+		virtualSigstore.RekorLogs())
+	require.NoError(t, err)
+
+	trustedRootJSON, err := trustedRoot.MarshalJSON()
+	require.NoError(t, err)
+
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "/trusted_root.json", trustedRootJSON, 0644))
+	require.NoError(t, afero.WriteFile(fs, "/bogus.json", []byte("bogus"), 0644))
+	ctx := utils.WithFS(context.Background(), fs)
+
+	identity := cosign.Identity{
+		Issuer:  "my-issuer",
+		Subject: "my-subject",
+	}
+
+	p, err := NewPolicy(ctx, Options{
+		EffectiveTime: Now,
+		Identity:      identity,
+		TrustedRoot:   "/trusted_root.json",
+	})
+	require.NoError(t, err)
+	require.NotNil(t, p.TrustedMaterial())
+
+	opts, err := p.CheckOpts()
+	require.NoError(t, err)
+
+	assert.Equal(t, []cosign.Identity{identity}, opts.Identities)
+	assert.NotEmpty(t, opts.RootCerts)
+	assert.NotEmpty(t, opts.CTLogPubKeys.Keys)
+	assert.NotEmpty(t, opts.RekorPubKeys.Keys)
+	assert.NotEmpty(t, opts.TSARootCertificates)
+	for id := range p.TrustedMaterial().RekorLogs() {
+		assert.Contains(t, opts.RekorPubKeys.Keys, id)
+	}
+
+	_, err = NewPolicy(ctx, Options{
+		EffectiveTime: Now,
+		Identity:      identity,
+		TrustedRoot:   "/missing.json",
+	})
+	assert.ErrorContains(t, err, `reading the trusted root from "/missing.json"`)
+
+	_, err = NewPolicy(ctx, Options{
+		EffectiveTime: Now,
+		Identity:      identity,
+		TrustedRoot:   "/bogus.json",
+	})
+	assert.ErrorContains(t, err, `loading the trusted root from "/bogus.json"`)
+}
@@ -47,6 +47,13 @@ func SourceIsHttp(src string) bool {
 }
 
 func GoGetterDownload(ctx context.Context, tmpDir, src string) (string, error) {
+	configFile, _, err := PinnedGoGetterDownload(ctx, tmpDir, src)
+	return configFile, err
+}
+
+// PinnedGoGetterDownload downloads the config like GoGetterDownload and also
+// returns the source url pinned to the immutable reference it was resolved to
+func PinnedGoGetterDownload(ctx context.Context, tmpDir, src string) (string, string, error) {
 	// Download the config from a url
 	c := PolicyUrl{
 		Url:  src,
@@ -55,7 +62,7 @@ func GoGetterDownload(ctx context.Context, tmpDir, src string) (string, error) {
 	configDir, err := c.GetPolicy(ctx, tmpDir, false)
 	if err != nil {
 		log.Debugf("Failed to download policy config from %s", c.Url)
-		return "", err
+		return "", "", err
 	}
 	log.Debugf("Downloaded policy config from %s to %s", c.Url, configDir)
 
@@ -63,8 +70,8 @@ func GoGetterDownload(ctx context.Context, tmpDir, src string) (string, error) {
 	configFile, err := choosePolicyFile(ctx, configDir)
 	if err != nil {
 		// A more useful error message:
-		return "", fmt.Errorf("no suitable config file found at %s", c.Url)
+		return "", "", fmt.Errorf("no suitable config file found at %s", c.Url)
 	}
 	log.Debugf("Chose file %s to use for the policy config", configFile)
-	return configFile, nil
+	return configFile, c.Url, nil
 }
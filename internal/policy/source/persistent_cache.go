@@ -0,0 +1,181 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package source
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	gitMetadata "github.com/conforma/go-gather/gather/git"
+	ociMetadata "github.com/conforma/go-gather/gather/oci"
+	"github.com/conforma/go-gather/metadata"
+	"github.com/go-git/go-git/v5/plumbing"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/enterprise-contract/ec-cli/internal/cache"
+)
+
+// PolicyStoreName is the name of the persistent cache store holding the
+// downloaded policy sources
+const PolicyStoreName = "policies"
+
+const (
+	infoType   = "type"
+	infoDigest = "digest"
+	infoPinned = "pinned"
+	typeGit    = "git"
+	typeOCI    = "oci"
+)
+
+type downloadFn func(source string, dest string) (metadata.Metadata, error)
+
+// persistentlyCached wraps the download function so that git and OCI sources
+// are stored in, and served from, the persistent cache. Sources pinned to a
+// commit or a digest are always served from the cache when present, other
+// sources only while the cached entry is fresh.
+func persistentlyCached(store *cache.Store, dl downloadFn) downloadFn {
+	return func(source string, dest string) (metadata.Metadata, error) {
+		if m, ok := fromPersistentCache(store, source, dest); ok {
+			return m, nil
+		}
+
+		m, err := dl(source, dest)
+		if err != nil {
+			return m, err
+		}
+
+		toPersistentCache(store, source, dest, m)
+
+		return m, nil
+	}
+}
+
+func fromPersistentCache(store *cache.Store, source string, dest string) (metadata.Metadata, bool) {
+	e, found, err := store.Lookup(source)
+	if err != nil {
+		log.Debugf("Unable to lookup %s in the persistent cache: %v", source, err)
+		return nil, false
+	}
+
+	if !found {
+		log.Debugf("Persistent cache miss: %s", source)
+		return nil, false
+	}
+
+	if !IsPinned(source) && !store.Fresh(e) {
+		log.Debugf("Persistent cache entry for %s is stale", source)
+		return nil, false
+	}
+
+	m := cachedMetadata(e, dest)
+	if m == nil {
+		return nil, false
+	}
+
+	if err := store.Restore(e, dest); err != nil {
+		log.Debugf("Unable to restore %s from the persistent cache: %v", source, err)
+		return nil, false
+	}
+
+	log.Debugf("Persistent cache hit: %s", source)
+	return m, true
+}
+
+func toPersistentCache(store *cache.Store, source string, dest string, m metadata.Metadata) {
+	kind, digest := describeMetadata(m)
+	if digest == "" {
+		// only git and OCI sources have content that can be addressed
+		return
+	}
+
+	pinned, err := m.GetPinnedURL(source)
+	if err != nil {
+		log.Debugf("Unable to determine the pinned URL of %s: %v", source, err)
+		return
+	}
+
+	info := map[string]string{
+		infoType:   kind,
+		infoDigest: digest,
+		infoPinned: pinned,
+	}
+
+	// make room for the new entry
+	if _, err := store.Prune(); err != nil {
+		log.Debugf("Unable to prune the persistent cache: %v", err)
+	}
+
+	if _, err := store.Put(cache.Key(pinned), []string{source, pinned}, info, dest); err != nil {
+		log.Debugf("Unable to store %s in the persistent cache: %v", source, err)
+	}
+}
+
+// describeMetadata returns the kind of the source and its commit or digest
+func describeMetadata(m metadata.Metadata) (string, string) {
+	switch v := m.(type) {
+	case gitMetadata.GitMetadata:
+		return typeGit, v.LatestCommit
+	case *gitMetadata.GitMetadata:
+		return typeGit, v.LatestCommit
+	case ociMetadata.OCIMetadata:
+		return typeOCI, v.Digest
+	case *ociMetadata.OCIMetadata:
+		return typeOCI, v.Digest
+	}
+
+	return "", ""
+}
+
+// cachedMetadata recreates the metadata of a download from a cache entry
+func cachedMetadata(e cache.Entry, dest string) metadata.Metadata {
+	switch e.Info[infoType] {
+	case typeGit:
+		return &gitMetadata.GitMetadata{
+			Path:         dest,
+			LatestCommit: e.Info[infoDigest],
+		}
+	case typeOCI:
+		return &ociMetadata.OCIMetadata{
+			Path:      dest,
+			Digest:    e.Info[infoDigest],
+			Timestamp: e.Created.Format(time.RFC3339),
+		}
+	}
+
+	return nil
+}
+
+// IsPinned returns true if the source URL references immutable content, i.e.
+// an OCI image by digest or a git commit by its hash
+func IsPinned(source string) bool {
+	if strings.Contains(source, "@sha256:") {
+		return true
+	}
+
+	_, query, found := strings.Cut(source, "?")
+	if !found {
+		return false
+	}
+
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return false
+	}
+
+	return plumbing.IsHash(values.Get("ref"))
+}
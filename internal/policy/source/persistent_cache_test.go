@@ -0,0 +1,192 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package source
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	fileMetadata "github.com/conforma/go-gather/gather/file"
+	gitMetadata "github.com/conforma/go-gather/gather/git"
+	ociMetadata "github.com/conforma/go-gather/gather/oci"
+	"github.com/conforma/go-gather/metadata"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/enterprise-contract/ec-cli/internal/cache"
+	"github.com/enterprise-contract/ec-cli/internal/utils"
+)
+
+func TestIsPinned(t *testing.T) {
+	cases := []struct {
+		source string
+		pinned bool
+	}{
+		{source: "quay.io/org/policy:latest", pinned: false},
+		{source: "oci::quay.io/org/policy@sha256:4d7b2a2b9d2f2a9a1d0c5c0b2e3f4a5b6c7d8e9f0a1b2c3d4e5f6a7b8c9d0e1f", pinned: true},
+		{source: "github.com/org/repo//policy", pinned: false},
+		{source: "git::github.com/org/repo//policy?ref=main", pinned: false},
+		{source: "git::github.com/org/repo//policy?ref=3b1e6ad9bd1e1f7e5cf0e6e1a8d6c4d0e2b9a7f1", pinned: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.source, func(t *testing.T) {
+			assert.Equal(t, c.pinned, IsPinned(c.source))
+		})
+	}
+}
+
+func TestPersistentlyCached(t *testing.T) {
+	const commit = "3b1e6ad9bd1e1f7e5cf0e6e1a8d6c4d0e2b9a7f1"
+	const digest = "sha256:4d7b2a2b9d2f2a9a1d0c5c0b2e3f4a5b6c7d8e9f0a1b2c3d4e5f6a7b8c9d0e1f"
+
+	cases := []struct {
+		name      string
+		source    string
+		metadata  metadata.Metadata
+		ttl       time.Duration
+		downloads int
+	}{
+		{
+			name:      "fresh git source",
+			source:    "git::github.com/org/repo//policy?ref=main",
+			metadata:  gitMetadata.GitMetadata{LatestCommit: commit},
+			ttl:       time.Hour,
+			downloads: 1,
+		},
+		{
+			name:      "stale git source",
+			source:    "git::github.com/org/repo//policy?ref=main",
+			metadata:  gitMetadata.GitMetadata{LatestCommit: commit},
+			ttl:       time.Nanosecond,
+			downloads: 2,
+		},
+		{
+			name:      "stale pinned git source",
+			source:    "git::github.com/org/repo//policy?ref=" + commit,
+			metadata:  gitMetadata.GitMetadata{LatestCommit: commit},
+			ttl:       time.Nanosecond,
+			downloads: 1,
+		},
+		{
+			name:      "stale pinned OCI source",
+			source:    "oci::quay.io/org/policy@" + digest,
+			metadata:  ociMetadata.OCIMetadata{Digest: digest},
+			ttl:       time.Nanosecond,
+			downloads: 1,
+		},
+		{
+			name:      "file source",
+			source:    "/some/path",
+			metadata:  &fileMetadata.FSMetadata{},
+			ttl:       time.Hour,
+			downloads: 2,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			store := cache.NewStore(fs, cache.Config{Dir: "/cache", TTL: c.ttl, MaxSize: cache.DefaultMaxSize}, PolicyStoreName)
+
+			downloads := 0
+			dl := persistentlyCached(store, func(source, dest string) (metadata.Metadata, error) {
+				downloads++
+				return c.metadata, afero.WriteFile(fs, filepath.Join(dest, "policy.rego"), []byte("package x"), 0644)
+			})
+
+			m1, err := dl(c.source, "/workdir1/policy/x")
+			require.NoError(t, err)
+
+			m2, err := dl(c.source, "/workdir2/policy/x")
+			require.NoError(t, err)
+
+			assert.Equal(t, c.downloads, downloads)
+
+			pinned1, err := m1.GetPinnedURL(c.source)
+			require.NoError(t, err)
+			pinned2, err := m2.GetPinnedURL(c.source)
+			require.NoError(t, err)
+			assert.Equal(t, pinned1, pinned2)
+
+			b, err := afero.ReadFile(fs, "/workdir2/policy/x/policy.rego")
+			require.NoError(t, err)
+			assert.Equal(t, "package x", string(b))
+		})
+	}
+}
+
+func TestPersistentlyCachedDownloadFailure(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	store := cache.NewStore(fs, cache.Config{Dir: "/cache", TTL: time.Hour, MaxSize: cache.DefaultMaxSize}, PolicyStoreName)
+
+	expected := errors.New("expected")
+	dl := persistentlyCached(store, func(source, dest string) (metadata.Metadata, error) {
+		return nil, expected
+	})
+
+	_, err := dl("github.com/org/repo", "/dest")
+	assert.ErrorIs(t, err, expected)
+
+	entries, err := store.Entries()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestGetPolicyPersistentCache(t *testing.T) {
+	t.Cleanup(func() {
+		downloadCache = sync.Map{}
+	})
+
+	t.Setenv("EC_PERSISTENT_CACHE", "1")
+	t.Setenv("EC_CACHE_DIR", "/cache")
+
+	fs := afero.NewMemMapFs()
+	ctx := utils.WithFS(context.Background(), fs)
+
+	const url = "git::github.com/org/repo//policy?ref=main"
+	const commit = "3b1e6ad9bd1e1f7e5cf0e6e1a8d6c4d0e2b9a7f1"
+
+	dl := mockDownloader{}
+	dl.On("Download", mock.Anything, url, false).Return(gitMetadata.GitMetadata{LatestCommit: commit}, nil).Run(func(args mock.Arguments) {
+		require.NoError(t, afero.WriteFile(fs, filepath.Join(args.String(0), "policy.rego"), []byte("package x"), 0644))
+	}).Once()
+	ctx = usingDownloader(ctx, &dl)
+
+	p := PolicyUrl{Url: url, Kind: PolicyKind}
+	_, err := p.GetPolicy(ctx, "/workdir1", false)
+	require.NoError(t, err)
+	assert.Equal(t, "git::github.com/org/repo//policy?ref="+commit, p.Url)
+
+	// simulate a new invocation
+	downloadCache = sync.Map{}
+
+	p = PolicyUrl{Url: url, Kind: PolicyKind}
+	_, err = p.GetPolicy(ctx, "/workdir2", false)
+	require.NoError(t, err)
+	assert.Equal(t, "git::github.com/org/repo//policy?ref="+commit, p.Url)
+
+	mock.AssertExpectationsForObjects(t, &dl)
+}
@@ -39,6 +39,7 @@ import (
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/afero"
 
+	"github.com/enterprise-contract/ec-cli/internal/cache"
 	"github.com/enterprise-contract/ec-cli/internal/downloader"
 	"github.com/enterprise-contract/ec-cli/internal/utils"
 )
@@ -73,6 +74,8 @@ type PolicyUrl struct {
 	// A string containing a go-getter style source url compatible with conftest pull
 	Url  string
 	Kind PolicyType
+	// Verification, when set, verifies the signature of the downloaded source
+	Verification *Verification
 }
 
 // downloadCache is a concurrent map used to cache downloaded files.
@@ -84,7 +87,7 @@ type cacheContent struct {
 	err       error
 }
 
-func getPolicyThroughCache(ctx context.Context, s PolicySource, workDir string, dl func(string, string) (metadata.Metadata, error)) (string, metadata.Metadata, error) {
+func getPolicyThroughCache(ctx context.Context, s PolicySource, workDir string, dl downloadFn) (string, metadata.Metadata, error) {
 	sourceUrl := s.PolicyUrl()
 	dest := uniqueDestination(workDir, s.Subdir(), sourceUrl)
 
@@ -147,7 +150,7 @@ func (p *PolicyUrl) GetPolicy(ctx context.Context, workDir string, showMsg bool)
 		trace.Logf(ctx, "", "policy=%q", p.Url)
 	}
 
-	dl := func(source string, dest string) (metadata.Metadata, error) {
+	var dl downloadFn = func(source string, dest string) (metadata.Metadata, error) {
 		x := ctx.Value(DownloaderFuncKey)
 		if dl, ok := x.(downloaderFunc); ok {
 			return dl.Download(ctx, dest, source, showMsg)
@@ -155,11 +158,23 @@ func (p *PolicyUrl) GetPolicy(ctx context.Context, workDir string, showMsg bool)
 		return downloader.Download(ctx, dest, source, showMsg)
 	}
 
+	c, err := cache.ConfigFromEnv()
+	if err != nil {
+		return "", err
+	}
+	if c.Enabled {
+		dl = persistentlyCached(cache.NewStore(utils.FS(ctx), c, PolicyStoreName), dl)
+	}
+
 	dest, metadata, err := getPolicyThroughCache(ctx, p, workDir, dl)
 	if err != nil {
 		return "", err
 	}
 
+	if err := p.Verification.verify(ctx, p.Url, metadata); err != nil {
+		return "", err
+	}
+
 	p.Url, err = metadata.GetPinnedURL(p.Url)
 	log.Debug("Pinned URL: ", p.Url)
 	if err != nil {
@@ -251,13 +266,19 @@ func (inlineData) Type() PolicyType {
 func PolicySourcesFrom(s ecc.Source) []PolicySource {
 	policySources := make([]PolicySource, 0, len(s.Policy)+len(s.Data))
 
+	verification, err := VerificationFrom(s)
+	if err != nil {
+		// reject the sources when they're fetched
+		verification = &Verification{err: err}
+	}
+
 	for _, policySourceUrl := range s.Policy {
-		url := PolicyUrl{Url: policySourceUrl, Kind: PolicyKind}
+		url := PolicyUrl{Url: policySourceUrl, Kind: PolicyKind, Verification: verification}
 		policySources = append(policySources, &url)
 	}
 
 	for _, dataSourceUrl := range s.Data {
-		url := PolicyUrl{Url: dataSourceUrl, Kind: DataKind}
+		url := PolicyUrl{Url: dataSourceUrl, Kind: DataKind, Verification: verification}
 		policySources = append(policySources, &url)
 	}
 
@@ -0,0 +1,270 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package source
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime/trace"
+	"strings"
+	"sync"
+
+	"github.com/conforma/go-gather/metadata"
+	ecc "github.com/enterprise-contract/enterprise-contract-controller/api/v1alpha1"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/sigstore/cosign/v2/cmd/cosign/cli/fulcio"
+	"github.com/sigstore/cosign/v2/cmd/cosign/cli/rekor"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	cosignSig "github.com/sigstore/cosign/v2/pkg/signature"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/enterprise-contract/ec-cli/internal/cache"
+	"github.com/enterprise-contract/ec-cli/internal/utils/oci"
+)
+
+// VerificationRuleDataKey is the key within the rule data of a policy source
+// declaring how the signatures of its policy and data sources are verified
+const VerificationRuleDataKey = "source_verification"
+
+// Verification declares how the signatures of the policy and data sources of
+// a policy source are verified before they're used, for example within the
+// rule data of a policy source:
+//
+//	ruleData:
+//	  source_verification:
+//	    oci:
+//	      public_key: k8s://tekton-chains/public-key
+//	    git:
+//	      gpg_keys:
+//	        - |
+//	          -----BEGIN PGP PUBLIC KEY BLOCK-----
+//	          ...
+//	      ssh_keys:
+//	        - ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAI...
+//
+// When verification is declared, each source needs to be of a kind with
+// verification settings, sources that can't be verified are rejected.
+type Verification struct {
+	OCI *OCIVerification `json:"oci,omitempty"`
+	Git *GitVerification `json:"git,omitempty"`
+	// err holds the error of parsing the verification settings, so that
+	// sources with invalid settings are rejected
+	err error
+}
+
+// OCIVerification verifies the cosign signature of OCI policy bundles, either
+// with the public key or keyless with the identity
+type OCIVerification struct {
+	PublicKey   string        `json:"public_key,omitempty"`
+	Identity    *ecc.Identity `json:"identity,omitempty"`
+	RekorURL    string        `json:"rekor_url,omitempty"`
+	IgnoreRekor bool          `json:"ignore_rekor,omitempty"`
+}
+
+// GitVerification verifies that the commit of git sources, or an annotated
+// tag pointing to the commit, is signed by one of the GPG or SSH keys
+type GitVerification struct {
+	// GPGKeys are ASCII armored GPG public keys
+	GPGKeys []string `json:"gpg_keys,omitempty"`
+	// SSHKeys are SSH public keys in the authorized_keys format
+	SSHKeys []string `json:"ssh_keys,omitempty"`
+}
+
+var (
+	// verified holds the keys of the sources verified at a commit or digest
+	// with the given settings, so each is verified only once. Failed
+	// verifications are not retained so that those are attempted again, e.g.
+	// after a transient network error.
+	verified sync.Map
+
+	// verifying makes concurrent verifications of the same source at the same
+	// commit or digest run only once
+	verifying singleflight.Group
+)
+
+// VerificationFrom returns the verification settings declared in the rule
+// data of the policy source, or nil if there are none
+func VerificationFrom(s ecc.Source) (*Verification, error) {
+	if s.RuleData == nil {
+		return nil, nil
+	}
+
+	var ruleData map[string]json.RawMessage
+	if err := json.Unmarshal(s.RuleData.Raw, &ruleData); err != nil {
+		// rule data that isn't an object can't declare verification
+		return nil, nil
+	}
+
+	raw, ok := ruleData[VerificationRuleDataKey]
+	if !ok {
+		return nil, nil
+	}
+
+	var v Verification
+	d := json.NewDecoder(bytes.NewReader(raw))
+	d.DisallowUnknownFields()
+	if err := d.Decode(&v); err != nil {
+		return nil, fmt.Errorf("invalid %s in the rule data of the source %q: %w", VerificationRuleDataKey, s.Name, err)
+	}
+
+	if v.OCI == nil && v.Git == nil {
+		return nil, fmt.Errorf("%s in the rule data of the source %q declares neither oci nor git verification", VerificationRuleDataKey, s.Name)
+	}
+
+	if v.OCI != nil && v.OCI.PublicKey == "" && v.OCI.Identity == nil {
+		return nil, fmt.Errorf("the oci %s of the source %q needs a public_key or an identity", VerificationRuleDataKey, s.Name)
+	}
+
+	if v.Git != nil && len(v.Git.GPGKeys) == 0 && len(v.Git.SSHKeys) == 0 {
+		return nil, fmt.Errorf("the git %s of the source %q needs gpg_keys or ssh_keys", VerificationRuleDataKey, s.Name)
+	}
+
+	return &v, nil
+}
+
+// verify checks the signature of the source downloaded from the url with the
+// given metadata, no verification is performed when v is nil
+func (v *Verification) verify(ctx context.Context, sourceUrl string, m metadata.Metadata) error {
+	if v == nil {
+		return nil
+	}
+
+	if v.err != nil {
+		return v.err
+	}
+
+	kind, digest := describeMetadata(m)
+	settings, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	key := cache.Key(kind, sourceUrl, digest, string(settings))
+	if _, ok := verified.Load(key); ok {
+		return nil
+	}
+
+	_, err, _ = verifying.Do(key, func() (any, error) {
+		if trace.IsEnabled() {
+			region := trace.StartRegion(ctx, "ec:verify-policy-source")
+			defer region.End()
+			trace.Logf(ctx, "", "source=%q", sourceUrl)
+		}
+
+		var err error
+		switch {
+		case kind == typeOCI && v.OCI != nil:
+			err = v.OCI.verify(ctx, sourceUrl, digest)
+		case kind == typeGit && v.Git != nil:
+			err = v.Git.verify(ctx, sourceUrl, digest)
+		case kind == "":
+			err = errors.New("only OCI and git sources can be verified")
+		default:
+			err = fmt.Errorf("no %s verification declared for the %s source", kind, kind)
+		}
+
+		if err == nil {
+			verified.Store(key, true)
+		}
+
+		return nil, err
+	})
+	if err != nil {
+		return fmt.Errorf("verification of %s failed: %w", sourceUrl, err)
+	}
+
+	log.Debugf("Verified the signature of %s", sourceUrl)
+
+	return nil
+}
+
+// verify checks that the OCI image with the digest, from the repository of
+// the source url, is signed
+func (o *OCIVerification) verify(ctx context.Context, sourceUrl, digest string) error {
+	u := sourceUrl
+	for _, scheme := range []string{"oci::", "oci://", "https://"} {
+		u = strings.TrimPrefix(u, scheme)
+	}
+
+	ref, err := name.ParseReference(u)
+	if err != nil {
+		return err
+	}
+
+	opts, err := o.checkOpts(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, _, err = oci.NewClient(ctx).VerifyImageSignatures(ref.Context().Digest(digest), opts)
+
+	return err
+}
+
+// checkOpts returns the cosign options verifying the signatures with the key
+// or keyless with the identity
+func (o *OCIVerification) checkOpts(ctx context.Context) (*cosign.CheckOpts, error) {
+	var err error
+	opts := cosign.CheckOpts{
+		IgnoreTlog: o.IgnoreRekor,
+	}
+
+	if o.PublicKey != "" {
+		if strings.Contains(o.PublicKey, "-----BEGIN PUBLIC KEY-----") {
+			opts.SigVerifier, err = cosignSig.LoadPublicKeyRaw([]byte(o.PublicKey), crypto.SHA256)
+		} else {
+			opts.SigVerifier, err = cosignSig.PublicKeyFromKeyRef(ctx, o.PublicKey)
+		}
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		opts.Identities = []cosign.Identity{{
+			Issuer:        o.Identity.Issuer,
+			IssuerRegExp:  o.Identity.IssuerRegExp,
+			Subject:       o.Identity.Subject,
+			SubjectRegExp: o.Identity.SubjectRegExp,
+		}}
+		if opts.RootCerts, err = fulcio.GetRoots(); err != nil {
+			return nil, err
+		}
+		if opts.IntermediateCerts, err = fulcio.GetIntermediates(); err != nil {
+			return nil, err
+		}
+		if opts.CTLogPubKeys, err = cosign.GetCTLogPubs(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	if !opts.IgnoreTlog {
+		if o.RekorURL != "" {
+			if opts.RekorClient, err = rekor.NewClient(o.RekorURL); err != nil {
+				return nil, err
+			}
+		}
+		if opts.RekorPubKeys, err = cosign.GetRekorPubs(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	return &opts, nil
+}
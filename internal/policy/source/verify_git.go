@@ -0,0 +1,340 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package source
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	sshSignatureMagic     = "SSHSIG"
+	sshSignatureNamespace = "git"
+	sshSignaturePEMType   = "SSH SIGNATURE"
+)
+
+// signedObject is a git commit or tag
+type signedObject interface {
+	EncodeWithoutSignature(plumbing.EncodedObject) error
+	Verify(armoredKeyRing string) (*openpgp.Entity, error)
+}
+
+// fetchRepository fetches the commit, and the tags pointing to it, from the
+// git repository at the url into memory, without a worktree. The commit is
+// fetched by the branches or tags pointing to it, by its hash otherwise, and
+// only when the server doesn't support fetching by hash are all the branches
+// fetched. The same as when downloading the source, SSH remotes authenticate
+// using the SSH agent, HTTPS remotes with the credentials within the url, and
+// GIT_SSL_NO_VERIFY turns off the TLS verification. Allows using local
+// repositories in tests.
+var fetchRepository = func(ctx context.Context, url, commit string) (*git.Repository, error) {
+	repo, err := git.Init(memory.NewStorage(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	remote, err := repo.CreateRemote(&config.RemoteConfig{Name: git.DefaultRemoteName, URLs: []string{url}})
+	if err != nil {
+		return nil, err
+	}
+
+	insecure := os.Getenv("GIT_SSL_NO_VERIFY") == "true"
+
+	refs, err := remote.ListContext(ctx, &git.ListOptions{PeelingOption: git.AppendPeeled, InsecureSkipTLS: insecure})
+	if err != nil {
+		return nil, err
+	}
+
+	var specs []config.RefSpec
+	for _, ref := range refs {
+		if ref.Hash().String() != commit {
+			continue
+		}
+
+		// annotated tags are also advertised with the commit they point to,
+		// i.e. peeled, under the name of the tag with the ^{} suffix
+		name, _ := strings.CutSuffix(ref.Name().String(), "^{}")
+		if strings.HasPrefix(name, "refs/heads/") || strings.HasPrefix(name, "refs/tags/") {
+			specs = append(specs, config.RefSpec(name+":"+name))
+		}
+	}
+
+	fetch := func(specs []config.RefSpec, depth int) error {
+		err := remote.FetchContext(ctx, &git.FetchOptions{
+			RefSpecs:        specs,
+			Depth:           depth,
+			Tags:            git.NoTags,
+			InsecureSkipTLS: insecure,
+		})
+		if errors.Is(err, git.NoErrAlreadyUpToDate) {
+			return nil
+		}
+		return err
+	}
+
+	if len(specs) > 0 {
+		return repo, fetch(specs, 1)
+	}
+
+	err = fetch([]config.RefSpec{config.RefSpec(commit + ":refs/heads/verify")}, 1)
+	if errors.Is(err, git.ErrExactSHA1NotSupported) {
+		err = fetch([]config.RefSpec{"+refs/heads/*:refs/remotes/origin/*"}, 0)
+	}
+
+	return repo, err
+}
+
+// verify checks that the commit, or an annotated tag pointing to the commit,
+// of the repository of the source url is signed by one of the keys
+func (g *GitVerification) verify(ctx context.Context, sourceUrl, commit string) error {
+	remote, err := gitRemote(sourceUrl)
+	if err != nil {
+		return err
+	}
+
+	repo, err := fetchRepository(ctx, remote, commit)
+	if err != nil {
+		return fmt.Errorf("fetching %s from %s: %w", commit, redacted(remote), err)
+	}
+
+	hash := plumbing.NewHash(commit)
+	c, err := repo.CommitObject(hash)
+	if err != nil {
+		return fmt.Errorf("commit %s: %w", commit, err)
+	}
+
+	if g.signedBy(c.PGPSignature, c) {
+		log.Debugf("Commit %s is signed by a trusted key", commit)
+		return nil
+	}
+
+	tags, err := repo.TagObjects()
+	if err != nil {
+		return err
+	}
+
+	var signedTag string
+	err = tags.ForEach(func(t *object.Tag) error {
+		if t.TargetType == plumbing.CommitObject && t.Target == hash && g.signedBy(t.PGPSignature, t) {
+			signedTag = t.Name
+			return io.EOF
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	if signedTag == "" {
+		return fmt.Errorf("neither the commit %s nor a tag pointing to it is signed by a trusted key", commit)
+	}
+
+	log.Debugf("Tag %s of the commit %s is signed by a trusted key", signedTag, commit)
+
+	return nil
+}
+
+// signedBy returns true if the signature of the commit or tag was made by one
+// of the GPG or SSH keys
+func (g *GitVerification) signedBy(signature string, o signedObject) bool {
+	if signature == "" {
+		return false
+	}
+
+	if strings.Contains(signature, "-----BEGIN "+sshSignaturePEMType+"-----") {
+		encoded := &plumbing.MemoryObject{}
+		if err := o.EncodeWithoutSignature(encoded); err != nil {
+			return false
+		}
+		r, err := encoded.Reader()
+		if err != nil {
+			return false
+		}
+		message, err := io.ReadAll(r)
+		if err != nil {
+			return false
+		}
+
+		for _, key := range g.SSHKeys {
+			if err := verifySSHSignature(message, []byte(signature), []byte(key)); err == nil {
+				return true
+			} else {
+				log.Debugf("SSH signature not made with %q: %v", key, err)
+			}
+		}
+
+		return false
+	}
+
+	for _, key := range g.GPGKeys {
+		if _, err := o.Verify(key); err == nil {
+			return true
+		} else {
+			log.Debugf("GPG signature not made with the key: %v", err)
+		}
+	}
+
+	return false
+}
+
+// gitRemote returns the url of the repository of the go-getter style source
+// url, without the subdirectory and the query. The same as when downloading
+// the source, SSH urls, i.e. ssh:// or the scp-like git@host:path, are kept,
+// as are the credentials within HTTPS urls, other urls are fetched via HTTPS.
+func gitRemote(sourceUrl string) (string, error) {
+	u := strings.TrimPrefix(sourceUrl, "git::")
+	u, _, _ = strings.Cut(u, "?")
+
+	if strings.HasPrefix(u, "/") || strings.HasPrefix(u, "file://") {
+		// local repository
+		path, _, _ := strings.Cut(strings.TrimPrefix(u, "file://"), "//")
+		return path, nil
+	}
+
+	scheme := "https"
+	switch {
+	case strings.HasPrefix(u, "ssh://"):
+		scheme, u = "ssh", strings.TrimPrefix(u, "ssh://")
+	case strings.HasPrefix(u, "git@"):
+		scheme = "ssh"
+		// scp-like syntax, git@host:path
+		if host, path, ok := strings.Cut(u, ":"); ok && !strings.HasPrefix(path, "/") {
+			u = host + "/" + path
+		}
+	default:
+		for _, prefix := range []string{"git://", "https://"} {
+			u = strings.TrimPrefix(u, prefix)
+		}
+	}
+
+	parsed, err := url.Parse(scheme + "://" + u)
+	if err != nil {
+		return "", err
+	}
+
+	parsed.Path, _, _ = strings.Cut(parsed.Path, "//")
+	if !strings.HasSuffix(parsed.Path, ".git") {
+		parsed.Path += ".git"
+	}
+
+	return parsed.String(), nil
+}
+
+// redacted returns the url without the password, if any, for use in errors
+func redacted(remote string) string {
+	u, err := url.Parse(remote)
+	if err != nil {
+		return remote
+	}
+
+	return u.Redacted()
+}
+
+// sshSignature is the SSH signature blob, see
+// https://github.com/openssh/openssh-portable/blob/master/PROTOCOL.sshsig
+type sshSignature struct {
+	MagicHeader   [6]byte
+	Version       uint32
+	PublicKey     string
+	Namespace     string
+	Reserved      string
+	HashAlgorithm string
+	Signature     string
+}
+
+// sshSignedData is the data signed by an SSH signature
+type sshSignedData struct {
+	Namespace     string
+	Reserved      string
+	HashAlgorithm string
+	Hash          string
+}
+
+// verifySSHSignature verifies the armored SSH signature of the message made
+// with the public key in the authorized_keys format for the git namespace
+func verifySSHSignature(message, armoredSignature, publicKey []byte) error {
+	block, _ := pem.Decode(armoredSignature)
+	if block == nil || block.Type != sshSignaturePEMType {
+		return errors.New("invalid SSH signature")
+	}
+
+	var sig sshSignature
+	if err := ssh.Unmarshal(block.Bytes, &sig); err != nil {
+		return err
+	}
+
+	if string(sig.MagicHeader[:]) != sshSignatureMagic || sig.Version != 1 {
+		return errors.New("unsupported SSH signature")
+	}
+
+	if sig.Namespace != sshSignatureNamespace {
+		return fmt.Errorf("SSH signature for the namespace %q, expected %q", sig.Namespace, sshSignatureNamespace)
+	}
+
+	key, _, _, _, err := ssh.ParseAuthorizedKey(publicKey)
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(key.Marshal(), []byte(sig.PublicKey)) {
+		return errors.New("SSH signature made with a different key")
+	}
+
+	var h hash.Hash
+	switch sig.HashAlgorithm {
+	case "sha256":
+		h = sha256.New()
+	case "sha512":
+		h = sha512.New()
+	default:
+		return fmt.Errorf("unsupported SSH signature hash algorithm %q", sig.HashAlgorithm)
+	}
+	h.Write(message)
+
+	var signature ssh.Signature
+	if err := ssh.Unmarshal([]byte(sig.Signature), &signature); err != nil {
+		return err
+	}
+
+	signed := append([]byte(sshSignatureMagic), ssh.Marshal(sshSignedData{
+		Namespace:     sig.Namespace,
+		Reserved:      sig.Reserved,
+		HashAlgorithm: sig.HashAlgorithm,
+		Hash:          string(h.Sum(nil)),
+	})...)
+
+	return key.Verify(signed, &signature)
+}
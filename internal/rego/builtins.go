@@ -19,5 +19,6 @@ package rego
 import (
 	_ "github.com/enterprise-contract/ec-cli/internal/rego/oci"
 	_ "github.com/enterprise-contract/ec-cli/internal/rego/purl"
+	_ "github.com/enterprise-contract/ec-cli/internal/rego/sbom"
 	_ "github.com/enterprise-contract/ec-cli/internal/rego/sigstore"
 )
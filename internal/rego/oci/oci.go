@@ -22,8 +22,10 @@ package oci
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
@@ -35,10 +37,13 @@ import (
 	"github.com/open-policy-agent/opa/topdown/builtins"
 	"github.com/open-policy-agent/opa/types"
 	log "github.com/sirupsen/logrus"
+	"golang.org/x/sync/singleflight"
 	"k8s.io/client-go/util/retry"
 
+	"github.com/enterprise-contract/ec-cli/internal/cache"
 	"github.com/enterprise-contract/ec-cli/internal/fetchers/oci/files"
 	"github.com/enterprise-contract/ec-cli/internal/image"
+	"github.com/enterprise-contract/ec-cli/internal/rpmdb"
 	"github.com/enterprise-contract/ec-cli/internal/utils/oci"
 )
 
@@ -48,6 +53,19 @@ const (
 	ociImageManifestName = "ec.oci.image_manifest"
 	ociImageFilesName    = "ec.oci.image_files"
 	ociImageIndexName    = "ec.oci.image_index"
+	ociImageRPMsName     = "ec.oci.image_rpms"
+)
+
+// imageRPMsCacheSize is the number of images the packages are kept for
+const imageRPMsCacheSize = 128
+
+// imageRPMsCache holds the packages read from the RPM database of the most
+// recently used images keyed by the image digest, so that the image layers are
+// read only once. Concurrent reads of the same image are deduplicated by
+// imageRPMsFlight. Failures are not cached, so they are retried on next use.
+var (
+	imageRPMsCache  = cache.NewMemory[string, []rpmdb.Package](imageRPMsCacheSize, nil)
+	imageRPMsFlight singleflight.Group
 )
 
 func registerOCIBlob() {
@@ -302,6 +320,54 @@ func registerOCIImageIndex() {
 	})
 }
 
+func registerOCIImageRPMs() {
+	rpm := types.NewObject(
+		[]*types.StaticProperty{
+			// Specifying the properties like this ensure the compiler catches typos when
+			// evaluating rego functions.
+			{Key: "name", Value: types.S},
+			{Key: "epoch", Value: types.N},
+			{Key: "version", Value: types.S},
+			{Key: "release", Value: types.S},
+			{Key: "arch", Value: types.S},
+			{Key: "nevra", Value: types.S},
+			{Key: "vendor", Value: types.S},
+			{Key: "signature_key_id", Value: types.S},
+			{Key: "source_rpm", Value: types.S},
+		},
+		nil,
+	)
+
+	decl := rego.Function{
+		Name: ociImageRPMsName,
+		Decl: types.NewFunction(
+			types.Args(
+				types.Named("ref", types.S).Description("OCI image reference"),
+			),
+			types.Named("rpms", types.NewArray(nil, rpm)).Description("the RPMs installed in the image"),
+		),
+		// As per the documentation, enable memoization to ensure function evaluation is
+		// deterministic. But also mark it as non-deterministic because it does rely on external
+		// entities, i.e. OCI registry. https://www.openpolicyagent.org/docs/latest/extensions/
+		Memoize:          true,
+		Nondeterministic: true,
+	}
+
+	rego.RegisterBuiltin1(&decl, ociImageRPMs)
+	// Due to https://github.com/open-policy-agent/opa/issues/6449, we cannot set a description for
+	// the custom function through the call above. As a workaround we re-register the function with
+	// a declaration that does include the description.
+	ast.RegisterBuiltin(&ast.Builtin{
+		Name: decl.Name,
+		Description: "List the RPMs installed in an image by reading the RPM database (sqlite, bdb " +
+			"or ndb) from the image layers. The epoch is omitted for packages without one, the " +
+			"signature_key_id is empty for unsigned packages. Images without an RPM database have " +
+			"no RPMs.",
+		Decl:             decl.Decl,
+		Nondeterministic: decl.Nondeterministic,
+	})
+}
+
 func ociBlob(bctx rego.BuiltinContext, a *ast.Term) (*ast.Term, error) {
 	logger := log.WithField("function", ociBlobName)
 
@@ -686,6 +752,96 @@ func newAnnotationsTerm(annotations map[string]string) *ast.Term {
 	return ast.ObjectTerm(annotationTerms...)
 }
 
+func ociImageRPMs(bctx rego.BuiltinContext, a *ast.Term) (*ast.Term, error) {
+	logger := log.WithField("function", ociImageRPMsName)
+
+	uriValue, ok := a.Value.(ast.String)
+	if !ok {
+		logger.Error("input is not a string")
+		return nil, nil
+	}
+	logger = logger.WithField("input_ref", string(uriValue))
+	logger.Debug("Starting image RPMs retrieval")
+
+	client := oci.NewClient(bctx.Context)
+
+	uri, err := resolveIfNeeded(client, string(uriValue))
+	if err != nil {
+		logger.WithField("action", "resolveIfNeeded").Error(err)
+		return nil, nil
+	}
+	logger = logger.WithField("ref", uri)
+
+	ref, err := name.NewDigest(uri)
+	if err != nil {
+		logger.WithFields(log.Fields{
+			"action": "new digest",
+			"error":  err,
+		}).Error("failed to create new digest")
+		return nil, nil
+	}
+
+	packages, err := imageRPMs(bctx.Context, ref)
+	if errors.Is(err, rpmdb.ErrNotFound) {
+		logger.Debug("Image does not contain an RPM database")
+		return ast.ArrayTerm(), nil
+	}
+	if err != nil {
+		logger.WithFields(log.Fields{
+			"action": "read rpms",
+			"error":  err,
+		}).Error("failed to read the RPM database")
+		return nil, nil
+	}
+
+	rpms := make([]*ast.Term, 0, len(packages))
+	for _, p := range packages {
+		items := [][2]*ast.Term{
+			ast.Item(ast.StringTerm("name"), ast.StringTerm(p.Name)),
+			ast.Item(ast.StringTerm("version"), ast.StringTerm(p.Version)),
+			ast.Item(ast.StringTerm("release"), ast.StringTerm(p.Release)),
+			ast.Item(ast.StringTerm("arch"), ast.StringTerm(p.Arch)),
+			ast.Item(ast.StringTerm("nevra"), ast.StringTerm(p.NEVRA())),
+			ast.Item(ast.StringTerm("vendor"), ast.StringTerm(p.Vendor)),
+			ast.Item(ast.StringTerm("signature_key_id"), ast.StringTerm(p.SignatureKeyID)),
+			ast.Item(ast.StringTerm("source_rpm"), ast.StringTerm(p.SourceRPM)),
+		}
+		if p.Epoch != nil {
+			items = append(items, ast.Item(ast.StringTerm("epoch"), ast.IntNumberTerm(*p.Epoch)))
+		}
+		rpms = append(rpms, ast.ObjectTerm(items...))
+	}
+
+	logger.Debugf("Successfully retrieved %d image RPMs", len(rpms))
+	return ast.ArrayTerm(rpms...), nil
+}
+
+// imageRPMs returns the packages of the image, from the cache when possible
+func imageRPMs(ctx context.Context, ref name.Digest) ([]rpmdb.Package, error) {
+	key := ref.DigestStr()
+	if packages, ok := imageRPMsCache.Get(key); ok {
+		return packages, nil
+	}
+
+	packages, err, _ := imageRPMsFlight.Do(key, func() (any, error) {
+		log.WithField("function", ociImageRPMsName).Debug("Image RPMs cache miss")
+		packages, err := rpmdb.ImagePackages(ctx, ref)
+		if err != nil && !errors.Is(err, rpmdb.ErrNotFound) {
+			return nil, err
+		}
+
+		// images without an RPM database are cached as such as well
+		imageRPMsCache.Add(key, packages)
+
+		return packages, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return packages.([]rpmdb.Package), nil
+}
+
 func resolveIfNeeded(client oci.Client, uri string) (string, error) {
 	if !strings.Contains(uri, "@") {
 		original := uri
@@ -711,4 +867,5 @@ func init() {
 	registerOCIImageFiles()
 	registerOCIImageManifest()
 	registerOCIImageIndex()
+	registerOCIImageRPMs()
 }
@@ -21,6 +21,8 @@ package oci
 import (
 	"context"
 	"errors"
+	"fmt"
+	"os"
 	"testing"
 
 	"github.com/gkampitakis/go-snaps/snaps"
@@ -728,6 +730,158 @@ func TestOCIImageIndex(t *testing.T) {
 	}
 }
 
+func TestOCIImageRPMs(t *testing.T) {
+	// The same RPM database as used by the rpmdb package tests
+	rpmdb, err := os.ReadFile("../../rpmdb/testdata/rpmdb.sqlite")
+	require.NoError(t, err)
+
+	withRPMs, err := crane.Image(map[string][]byte{
+		"usr/lib/sysimage/rpm/rpmdb.sqlite": rpmdb,
+	})
+	require.NoError(t, err)
+	withRPMsDigest, err := withRPMs.Digest()
+	require.NoError(t, err)
+
+	withoutRPMs, err := crane.Image(map[string][]byte{
+		"etc/os-release": []byte("ID=alpine"),
+	})
+	require.NoError(t, err)
+	withoutRPMsDigest, err := withoutRPMs.Digest()
+	require.NoError(t, err)
+
+	malformedRPMs, err := crane.Image(map[string][]byte{
+		"var/lib/rpm/rpmdb.sqlite": []byte("not sqlite"),
+	})
+	require.NoError(t, err)
+	malformedRPMsDigest, err := malformedRPMs.Digest()
+	require.NoError(t, err)
+
+	cases := []struct {
+		name      string
+		uri       *ast.Term
+		image     v1.Image
+		count     int
+		first     string
+		remoteErr error
+	}{
+		{
+			name:  "success",
+			uri:   ast.StringTerm("registry.local/spam@" + withRPMsDigest.String()),
+			image: withRPMs,
+			count: 41,
+			first: `{
+				"name": "zlib",
+				"version": "1.2.8",
+				"release": "10.fc24",
+				"arch": "i686",
+				"nevra": "zlib-1.2.8-10.fc24.i686",
+				"vendor": "Fedora Project",
+				"signature_key_id": "4089d8f2fdb19c98",
+				"source_rpm": "zlib-1.2.8-10.fc24.src.rpm"
+			}`,
+		},
+		{
+			name:  "no RPM database",
+			uri:   ast.StringTerm("registry.local/spam@" + withoutRPMsDigest.String()),
+			image: withoutRPMs,
+		},
+		{
+			name:  "malformed RPM database",
+			uri:   ast.StringTerm("registry.local/spam@" + malformedRPMsDigest.String()),
+			image: malformedRPMs,
+		},
+		{
+			name: "non string URI",
+			uri:  ast.BooleanTerm(true),
+		},
+		{
+			name: "invalid URI",
+			uri:  ast.StringTerm("registry.local/spam@sha256:123"),
+		},
+		{
+			name:      "remote error",
+			uri:       ast.StringTerm("registry.local/spam@sha256:4bbf56a3a9231f752d3b9c174637975f0f83ed2b15e65799837c571e4ef3374b"),
+			remoteErr: errors.New("kaboom!"),
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			imageRPMsCache.Clear()
+
+			client := fake.FakeClient{}
+			if c.remoteErr != nil {
+				client.On("Image", mock.Anything).Return(nil, c.remoteErr)
+			} else {
+				client.On("Image", mock.Anything).Return(c.image, nil)
+			}
+
+			ctx := oci.WithClient(context.Background(), &client)
+			bctx := rego.BuiltinContext{Context: ctx}
+
+			rpms, err := ociImageRPMs(bctx, c.uri)
+			require.NoError(t, err)
+			if c.image == nil || c.image == malformedRPMs {
+				require.Nil(t, rpms)
+				return
+			}
+			require.NotNil(t, rpms)
+
+			array, ok := rpms.Value.(*ast.Array)
+			require.True(t, ok)
+			require.Equal(t, c.count, array.Len())
+			if c.count > 0 {
+				require.JSONEq(t, c.first, array.Elem(0).String())
+			}
+
+			// The second call is served from the cache
+			cached, err := ociImageRPMs(bctx, c.uri)
+			require.NoError(t, err)
+			require.Equal(t, rpms.String(), cached.String())
+			client.AssertNumberOfCalls(t, "Image", 1)
+		})
+	}
+}
+
+func TestOCIImageRPMsErrorNotCached(t *testing.T) {
+	imageRPMsCache.Clear()
+	t.Cleanup(imageRPMsCache.Clear)
+
+	img, err := crane.Image(map[string][]byte{
+		"etc/os-release": []byte("ID=alpine"),
+	})
+	require.NoError(t, err)
+	digest, err := img.Digest()
+	require.NoError(t, err)
+	uri := ast.StringTerm("registry.local/spam@" + digest.String())
+
+	client := fake.FakeClient{}
+	client.On("Image", mock.Anything).Return(nil, context.Canceled).Once()
+	client.On("Image", mock.Anything).Return(img, nil).Once()
+	bctx := rego.BuiltinContext{Context: oci.WithClient(context.Background(), &client)}
+
+	rpms, err := ociImageRPMs(bctx, uri)
+	require.NoError(t, err)
+	require.Nil(t, rpms)
+
+	// the failure is retried
+	rpms, err = ociImageRPMs(bctx, uri)
+	require.NoError(t, err)
+	require.Equal(t, ast.ArrayTerm(), rpms)
+	client.AssertNumberOfCalls(t, "Image", 2)
+}
+
+func TestImageRPMsCacheBounded(t *testing.T) {
+	imageRPMsCache.Clear()
+	t.Cleanup(imageRPMsCache.Clear)
+
+	for i := range imageRPMsCacheSize + 10 {
+		imageRPMsCache.Add(fmt.Sprintf("sha256:%d", i), nil)
+	}
+
+	require.Equal(t, imageRPMsCacheSize, imageRPMsCache.Len())
+}
+
 func TestFunctionsRegistered(t *testing.T) {
 	names := []string{
 		ociBlobName,
@@ -735,6 +889,7 @@ func TestFunctionsRegistered(t *testing.T) {
 		ociImageFilesName,
 		ociImageManifestName,
 		ociImageIndexName,
+		ociImageRPMsName,
 	}
 	for _, name := range names {
 		t.Run(name, func(t *testing.T) {
@@ -21,6 +21,8 @@
 package rego
 
 import (
+	"fmt"
+
 	"github.com/open-policy-agent/opa/ast"
 	"github.com/open-policy-agent/opa/rego"
 	"github.com/open-policy-agent/opa/types"
@@ -31,6 +33,8 @@ import (
 const (
 	purlIsValidName = "ec.purl.is_valid"
 	purlParseName   = "ec.purl.parse"
+	purlCompareName = "ec.purl.compare"
+	purlInRangeName = "ec.purl.in_range"
 )
 
 func registerPURLIsValid() {
@@ -107,6 +111,66 @@ func registerPURLParse() {
 	})
 }
 
+func registerPURLCompare() {
+	decl := rego.Function{
+		Name: purlCompareName,
+		Decl: types.NewFunction(
+			types.Args(
+				types.Named("a", types.S).Description("the first PURL"),
+				types.Named("b", types.S).Description("the second PURL"),
+			),
+			types.Named("result", types.N).Description("-1, 0 or 1 if the version of a is lower, equal or greater than the version of b"),
+		),
+		// As per the documentation, enable memoization to ensure function evaluation is
+		// deterministic.
+		Memoize:          true,
+		Nondeterministic: false,
+	}
+
+	rego.RegisterBuiltin2(&decl, purlCompare)
+	// Due to https://github.com/open-policy-agent/opa/issues/6449, we cannot set a description for
+	// the custom function through the call above. As a workaround we re-register the function with
+	// a declaration that does include the description.
+	ast.RegisterBuiltin(&ast.Builtin{
+		Name: decl.Name,
+		Description: "Compare the versions of two PURLs of the same package using the ordering of " +
+			"the package ecosystem. Supported PURL types are rpm, npm, golang, pypi and maven.",
+		Decl:             decl.Decl,
+		Nondeterministic: decl.Nondeterministic,
+	})
+}
+
+func registerPURLInRange() {
+	decl := rego.Function{
+		Name: purlInRangeName,
+		Decl: types.NewFunction(
+			types.Args(
+				types.Named("purl", types.S).Description("the PURL"),
+				types.Named("vers", types.S).Description("the vers range, e.g. vers:rpm/>=1.2.3|<2.0.0"),
+			),
+			types.Named("result", types.B).Description("true if the version of the PURL is within the range"),
+		),
+		// As per the documentation, enable memoization to ensure function evaluation is
+		// deterministic.
+		Memoize:          true,
+		Nondeterministic: false,
+	}
+
+	rego.RegisterBuiltin2(&decl, purlInRange)
+	// Due to https://github.com/open-policy-agent/opa/issues/6449, we cannot set a description for
+	// the custom function through the call above. As a workaround we re-register the function with
+	// a declaration that does include the description.
+	ast.RegisterBuiltin(&ast.Builtin{
+		Name: decl.Name,
+		Description: "Determine whether the version of a PURL is within a package-url vers range. " +
+			"The versioning scheme of the range must match the PURL type, or be semver for the " +
+			"cargo, golang and npm PURL types. Supported versioning schemes are rpm, npm, golang, " +
+			"pypi, maven and semver.",
+		Decl:             decl.Decl,
+		Nondeterministic: decl.Nondeterministic,
+	})
+}
+
 func purlIsValid(bctx rego.BuiltinContext, a *ast.Term) (*ast.Term, error) {
 	uri, ok := a.Value.(ast.String)
 	if !ok {
@@ -149,7 +213,107 @@ func purlParse(bctx rego.BuiltinContext, a *ast.Term) (*ast.Term, error) {
 	), nil
 }
 
+func purlCompare(bctx rego.BuiltinContext, a, b *ast.Term) (*ast.Term, error) {
+	uriA, ok := a.Value.(ast.String)
+	if !ok {
+		return nil, nil
+	}
+	uriB, ok := b.Value.(ast.String)
+	if !ok {
+		return nil, nil
+	}
+
+	instanceA, err := packageurl.FromString(string(uriA))
+	if err != nil {
+		log.Errorf("Parsing PURL %s failed: %s", uriA, err)
+		return nil, nil
+	}
+	instanceB, err := packageurl.FromString(string(uriB))
+	if err != nil {
+		log.Errorf("Parsing PURL %s failed: %s", uriB, err)
+		return nil, nil
+	}
+
+	if instanceA.Type != instanceB.Type || instanceA.Namespace != instanceB.Namespace || instanceA.Name != instanceB.Name {
+		log.Errorf("Comparing PURLs %s and %s failed: not the same package", uriA, uriB)
+		return nil, nil
+	}
+
+	compare, ok := versionComparers[instanceA.Type]
+	if !ok {
+		log.Errorf("Comparing PURLs %s and %s failed: unsupported PURL type %q", uriA, uriB, instanceA.Type)
+		return nil, nil
+	}
+
+	c, err := compare(purlVersion(instanceA), purlVersion(instanceB))
+	if err != nil {
+		log.Errorf("Comparing PURLs %s and %s failed: %s", uriA, uriB, err)
+		return nil, nil
+	}
+
+	return ast.IntNumberTerm(c), nil
+}
+
+func purlInRange(bctx rego.BuiltinContext, a, b *ast.Term) (*ast.Term, error) {
+	uri, ok := a.Value.(ast.String)
+	if !ok {
+		return nil, nil
+	}
+	vers, ok := b.Value.(ast.String)
+	if !ok {
+		return nil, nil
+	}
+
+	instance, err := packageurl.FromString(string(uri))
+	if err != nil {
+		log.Errorf("Parsing PURL %s failed: %s", uri, err)
+		return nil, nil
+	}
+
+	r, err := parseVers(string(vers))
+	if err != nil {
+		log.Errorf("Parsing vers %s failed: %s", vers, err)
+		return nil, nil
+	}
+
+	if r.scheme != instance.Type && !(r.scheme == "semver" && semVerTypes[instance.Type]) {
+		log.Errorf("Matching PURL %s against %s failed: versioning scheme does not match the PURL type", uri, vers)
+		return nil, nil
+	}
+
+	compare, ok := versionComparers[r.scheme]
+	if !ok {
+		log.Errorf("Matching PURL %s against %s failed: unsupported versioning scheme %q", uri, vers, r.scheme)
+		return nil, nil
+	}
+
+	contained, err := r.contains(purlVersion(instance), compare)
+	if err != nil {
+		log.Errorf("Matching PURL %s against %s failed: %s", uri, vers, err)
+		return nil, nil
+	}
+
+	return ast.BooleanTerm(contained), nil
+}
+
+// purlVersion returns the version of the package, for RPMs the epoch held in the qualifiers is
+// prepended to it
+func purlVersion(instance packageurl.PackageURL) string {
+	version := instance.Version
+	if instance.Type != packageurl.TypeRPM {
+		return version
+	}
+
+	if epoch, ok := instance.Qualifiers.Map()["epoch"]; ok && epoch != "" && version != "" {
+		return fmt.Sprintf("%s:%s", epoch, version)
+	}
+
+	return version
+}
+
 func init() {
 	registerPURLIsValid()
 	registerPURLParse()
+	registerPURLCompare()
+	registerPURLInRange()
 }
@@ -103,10 +103,167 @@ func TestPURLParse(t *testing.T) {
 	}
 }
 
+func TestPURLCompare(t *testing.T) {
+	cases := []struct {
+		name     string
+		a        *ast.Term
+		b        *ast.Term
+		expected *ast.Term
+	}{
+		{
+			name:     "lower",
+			a:        ast.StringTerm("pkg:rpm/redhat/openssl@3.0.7-24.el9?arch=x86_64"),
+			b:        ast.StringTerm("pkg:rpm/redhat/openssl@3.0.7-25.el9?arch=x86_64"),
+			expected: ast.IntNumberTerm(-1),
+		},
+		{
+			name:     "equal",
+			a:        ast.StringTerm("pkg:npm/lodash@4.17.21"),
+			b:        ast.StringTerm("pkg:npm/lodash@4.17.21"),
+			expected: ast.IntNumberTerm(0),
+		},
+		{
+			name:     "greater",
+			a:        ast.StringTerm("pkg:pypi/django@4.2"),
+			b:        ast.StringTerm("pkg:pypi/django@4.2rc1"),
+			expected: ast.IntNumberTerm(1),
+		},
+		{
+			name:     "rpm epoch qualifier",
+			a:        ast.StringTerm("pkg:rpm/redhat/openssl@3.0.7-24.el9?epoch=1"),
+			b:        ast.StringTerm("pkg:rpm/redhat/openssl@3.1.0-1.el9"),
+			expected: ast.IntNumberTerm(1),
+		},
+		{
+			name: "different packages",
+			a:    ast.StringTerm("pkg:npm/lodash@4.17.21"),
+			b:    ast.StringTerm("pkg:npm/underscore@1.13.6"),
+		},
+		{
+			name: "different namespaces",
+			a:    ast.StringTerm("pkg:npm/%40a/foo@1.0.0"),
+			b:    ast.StringTerm("pkg:npm/%40b/foo@1.0.0"),
+		},
+		{
+			name:     "same namespace",
+			a:        ast.StringTerm("pkg:npm/%40a/foo@1.0.0"),
+			b:        ast.StringTerm("pkg:npm/%40a/foo@1.1.0"),
+			expected: ast.IntNumberTerm(-1),
+		},
+		{
+			name: "unsupported type",
+			a:    ast.StringTerm("pkg:gem/rails@7.0.0"),
+			b:    ast.StringTerm("pkg:gem/rails@7.1.0"),
+		},
+		{
+			name: "invalid version",
+			a:    ast.StringTerm("pkg:npm/lodash@latest"),
+			b:    ast.StringTerm("pkg:npm/lodash@4.17.21"),
+		},
+		{
+			name: "unexpected purl type",
+			a:    ast.IntNumberTerm(42),
+			b:    ast.StringTerm("pkg:npm/lodash@4.17.21"),
+		},
+		{
+			name: "malformed PURL string",
+			a:    ast.StringTerm("pkg:npm/lodash@4.17.21"),
+			b:    ast.StringTerm("pkg::rpm//fedora/curl7.50.3-1.fc25?arch=i386&distro=fedora-"),
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ctx := context.Background()
+			bctx := rego.BuiltinContext{Context: ctx}
+
+			result, err := purlCompare(bctx, c.a, c.b)
+			require.NoError(t, err)
+			require.Equal(t, c.expected, result)
+		})
+	}
+}
+
+func TestPURLInRange(t *testing.T) {
+	cases := []struct {
+		name     string
+		uri      *ast.Term
+		vers     *ast.Term
+		expected *ast.Term
+	}{
+		{
+			name:     "in range",
+			uri:      ast.StringTerm("pkg:rpm/redhat/openssl@3.0.7-24.el9?arch=x86_64"),
+			vers:     ast.StringTerm("vers:rpm/>=3.0.0|<3.0.7-25.el9"),
+			expected: ast.BooleanTerm(true),
+		},
+		{
+			name:     "not in range",
+			uri:      ast.StringTerm("pkg:maven/org.apache.logging.log4j/log4j-core@2.17.1"),
+			vers:     ast.StringTerm("vers:maven/>=2.0-beta9|<2.15.0"),
+			expected: ast.BooleanTerm(false),
+		},
+		{
+			name:     "semver scheme",
+			uri:      ast.StringTerm("pkg:golang/golang.org/x/net@v0.17.0"),
+			vers:     ast.StringTerm("vers:semver/<0.23.0"),
+			expected: ast.BooleanTerm(true),
+		},
+		{
+			name: "semver scheme for a type not following semver",
+			uri:  ast.StringTerm("pkg:rpm/redhat/openssl@3.0.7-24.el9"),
+			vers: ast.StringTerm("vers:semver/<4.0.0"),
+		},
+		{
+			name: "mismatched scheme",
+			uri:  ast.StringTerm("pkg:npm/lodash@4.17.21"),
+			vers: ast.StringTerm("vers:pypi/<5.0"),
+		},
+		{
+			name: "unsupported scheme",
+			uri:  ast.StringTerm("pkg:gem/rails@7.0.0"),
+			vers: ast.StringTerm("vers:gem/<7.1.0"),
+		},
+		{
+			name: "malformed vers",
+			uri:  ast.StringTerm("pkg:npm/lodash@4.17.21"),
+			vers: ast.StringTerm("npm/<5.0.0"),
+		},
+		{
+			name: "invalid version",
+			uri:  ast.StringTerm("pkg:npm/lodash@latest"),
+			vers: ast.StringTerm("vers:npm/<5.0.0"),
+		},
+		{
+			name: "unexpected vers type",
+			uri:  ast.StringTerm("pkg:npm/lodash@4.17.21"),
+			vers: ast.IntNumberTerm(42),
+		},
+		{
+			name: "malformed PURL string",
+			uri:  ast.StringTerm("pkg::rpm//fedora/curl7.50.3-1.fc25?arch=i386&distro=fedora-"),
+			vers: ast.StringTerm("vers:rpm/*"),
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ctx := context.Background()
+			bctx := rego.BuiltinContext{Context: ctx}
+
+			result, err := purlInRange(bctx, c.uri, c.vers)
+			require.NoError(t, err)
+			require.Equal(t, c.expected, result)
+		})
+	}
+}
+
 func TestFunctionsRegistered(t *testing.T) {
 	names := []string{
 		purlIsValidName,
 		purlParseName,
+		purlCompareName,
+		purlInRangeName,
 	}
 	for _, name := range names {
 		t.Run(name, func(t *testing.T) {
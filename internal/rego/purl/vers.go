@@ -0,0 +1,163 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package rego
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// versConstraint is a single constraint of a vers range, e.g. ">=1.2.3"
+type versConstraint struct {
+	comparator string
+	version    string
+}
+
+// versRange is a version range as defined by the package-url vers specification, see
+// https://github.com/package-url/purl-spec/blob/master/VERSION-RANGE-SPEC.rst
+type versRange struct {
+	scheme      string
+	constraints []versConstraint
+	any         bool
+}
+
+// versComparators lists the supported comparators, longer ones first so that they are matched
+// before their prefixes
+var versComparators = []string{">=", "<=", "!=", "<", ">", "="}
+
+func parseVers(vers string) (*versRange, error) {
+	vers = strings.Join(strings.Fields(vers), "")
+
+	rest, ok := strings.CutPrefix(vers, "vers:")
+	if !ok {
+		return nil, fmt.Errorf("vers range %q does not start with vers:", vers)
+	}
+
+	scheme, constraints, ok := strings.Cut(rest, "/")
+	if !ok || scheme == "" || constraints == "" {
+		return nil, fmt.Errorf("vers range %q is missing the versioning scheme or constraints", vers)
+	}
+
+	r := versRange{scheme: strings.ToLower(scheme)}
+	if constraints == "*" {
+		r.any = true
+		return &r, nil
+	}
+
+	for _, c := range strings.Split(constraints, "|") {
+		constraint := versConstraint{comparator: "="}
+		for _, comparator := range versComparators {
+			if v, ok := strings.CutPrefix(c, comparator); ok {
+				constraint.comparator = comparator
+				c = v
+				break
+			}
+		}
+
+		version, err := url.PathUnescape(c)
+		if err != nil {
+			return nil, fmt.Errorf("vers range %q has an invalid version %q: %w", vers, c, err)
+		}
+		if version == "" || version == "*" {
+			return nil, fmt.Errorf("vers range %q has an invalid constraint %q", vers, c)
+		}
+		constraint.version = version
+
+		r.constraints = append(r.constraints, constraint)
+	}
+
+	return &r, nil
+}
+
+// contains reports whether the version is within the range, using the algorithm from the vers
+// specification.
+func (r versRange) contains(version string, compare versionComparer) (bool, error) {
+	if r.any {
+		return true, nil
+	}
+
+	var errs []error
+	cmp := func(a, b string) int {
+		c, err := compare(a, b)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		return c
+	}
+
+	var ranges []versConstraint
+	for _, c := range r.constraints {
+		switch c.comparator {
+		case "=":
+			if cmp(version, c.version) == 0 {
+				return true, errors.Join(errs...)
+			}
+		case "!=":
+			if cmp(version, c.version) == 0 {
+				return false, errors.Join(errs...)
+			}
+		default:
+			ranges = append(ranges, c)
+		}
+	}
+
+	slices.SortStableFunc(ranges, func(a, b versConstraint) int {
+		return cmp(a.version, b.version)
+	})
+
+	satisfies := func(c versConstraint) bool {
+		x := cmp(version, c.version)
+		switch c.comparator {
+		case "<":
+			return x < 0
+		case "<=":
+			return x <= 0
+		case ">":
+			return x > 0
+		default:
+			return x >= 0
+		}
+	}
+	isLess := func(c versConstraint) bool {
+		return c.comparator == "<" || c.comparator == "<="
+	}
+
+	contained := false
+	for i, c := range ranges {
+		if i == 0 && isLess(c) && satisfies(c) {
+			contained = true
+			break
+		}
+		if i == len(ranges)-1 && !isLess(c) && satisfies(c) {
+			contained = true
+			break
+		}
+		if i < len(ranges)-1 && !isLess(c) && isLess(ranges[i+1]) && satisfies(c) && satisfies(ranges[i+1]) {
+			contained = true
+			break
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return false, err
+	}
+
+	return contained, nil
+}
@@ -0,0 +1,149 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package rego
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseVers(t *testing.T) {
+	cases := []struct {
+		name     string
+		vers     string
+		expected *versRange
+		err      string
+	}{
+		{
+			name:     "any",
+			vers:     "vers:npm/*",
+			expected: &versRange{scheme: "npm", any: true},
+		},
+		{
+			name: "constraints",
+			vers: "vers:RPM/>=1.0|<2.0|!=1.5|1.7",
+			expected: &versRange{scheme: "rpm", constraints: []versConstraint{
+				{comparator: ">=", version: "1.0"},
+				{comparator: "<", version: "2.0"},
+				{comparator: "!=", version: "1.5"},
+				{comparator: "=", version: "1.7"},
+			}},
+		},
+		{
+			name: "whitespace and escaping",
+			vers: "vers:pypi/ >= 1.0 | < 2.0%2Blocal ",
+			expected: &versRange{scheme: "pypi", constraints: []versConstraint{
+				{comparator: ">=", version: "1.0"},
+				{comparator: "<", version: "2.0+local"},
+			}},
+		},
+		{
+			name: "missing prefix",
+			vers: "npm/>=1.0",
+			err:  `vers range "npm/>=1.0" does not start with vers:`,
+		},
+		{
+			name: "missing scheme",
+			vers: "vers:>=1.0",
+			err:  `vers range "vers:>=1.0" is missing the versioning scheme or constraints`,
+		},
+		{
+			name: "missing constraints",
+			vers: "vers:npm/",
+			err:  `vers range "vers:npm/" is missing the versioning scheme or constraints`,
+		},
+		{
+			name: "missing version",
+			vers: "vers:npm/>=1.0|<",
+			err:  `vers range "vers:npm/>=1.0|<" has an invalid constraint ""`,
+		},
+		{
+			name: "star with constraints",
+			vers: "vers:npm/*|>=1.0",
+			err:  `vers range "vers:npm/*|>=1.0" has an invalid constraint "*"`,
+		},
+		{
+			name: "invalid escaping",
+			vers: "vers:npm/>=1.0%zz",
+			err:  `vers range "vers:npm/>=1.0%zz" has an invalid version "1.0%zz": invalid URL escape "%zz"`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r, err := parseVers(c.vers)
+			if c.err != "" {
+				assert.EqualError(t, err, c.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, c.expected, r)
+		})
+	}
+}
+
+func TestVersRangeContains(t *testing.T) {
+	cases := []struct {
+		name     string
+		vers     string
+		version  string
+		expected bool
+	}{
+		{name: "any", vers: "vers:npm/*", version: "1.0.0", expected: true},
+		{name: "equal", vers: "vers:npm/1.0.0|2.0.0", version: "2.0.0", expected: true},
+		{name: "not equal", vers: "vers:npm/1.0.0|2.0.0", version: "1.5.0", expected: false},
+		{name: "excluded", vers: "vers:npm/>=1.0.0|!=1.5.0", version: "1.5.0", expected: false},
+		{name: "not excluded", vers: "vers:npm/>=1.0.0|!=1.5.0", version: "1.6.0", expected: true},
+		{name: "below upper bound", vers: "vers:npm/<2.0.0", version: "1.0.0", expected: true},
+		{name: "at exclusive upper bound", vers: "vers:npm/<2.0.0", version: "2.0.0", expected: false},
+		{name: "at inclusive upper bound", vers: "vers:npm/<=2.0.0", version: "2.0.0", expected: true},
+		{name: "above lower bound", vers: "vers:npm/>1.0.0", version: "1.0.1", expected: true},
+		{name: "at exclusive lower bound", vers: "vers:npm/>1.0.0", version: "1.0.0", expected: false},
+		{name: "within interval", vers: "vers:npm/>=1.0.0|<2.0.0", version: "1.5.0", expected: true},
+		{name: "outside interval", vers: "vers:npm/>=1.0.0|<2.0.0", version: "2.5.0", expected: false},
+		{name: "unordered constraints", vers: "vers:npm/<2.0.0|>=1.0.0", version: "1.5.0", expected: true},
+		{name: "first of disjoint intervals", vers: "vers:npm/>=1.0.0|<1.2.0|>=2.0.0|<2.2.0", version: "1.1.0", expected: true},
+		{name: "between disjoint intervals", vers: "vers:npm/>=1.0.0|<1.2.0|>=2.0.0|<2.2.0", version: "1.5.0", expected: false},
+		{name: "second of disjoint intervals", vers: "vers:npm/>=1.0.0|<1.2.0|>=2.0.0|<2.2.0", version: "2.1.0", expected: true},
+		{name: "open ended after interval", vers: "vers:npm/<1.0.0|>=2.0.0", version: "3.0.0", expected: true},
+		{name: "gap before open end", vers: "vers:npm/<1.0.0|>=2.0.0", version: "1.5.0", expected: false},
+		{name: "rpm ordering", vers: "vers:rpm/<3.0.7-25.el9", version: "3.0.7-24.el9", expected: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r, err := parseVers(c.vers)
+			require.NoError(t, err)
+
+			contained, err := r.contains(c.version, versionComparers[r.scheme])
+			require.NoError(t, err)
+			assert.Equal(t, c.expected, contained)
+		})
+	}
+}
+
+func TestVersRangeContainsInvalidVersion(t *testing.T) {
+	r, err := parseVers("vers:npm/>=1.0.0|<2.0.0")
+	require.NoError(t, err)
+
+	_, err = r.contains("not a version", versionComparers["npm"])
+	assert.Error(t, err)
+}
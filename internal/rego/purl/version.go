@@ -0,0 +1,551 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package rego
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// versionComparer compares two versions of the same versioning scheme, returning -1, 0 or 1 if
+// the first version is respectively lower, equal or greater than the second one.
+type versionComparer func(a, b string) (int, error)
+
+// versionComparers holds the comparers for the supported versioning schemes keyed by the PURL
+// type, which is also the versioning scheme name used by vers ranges.
+var versionComparers = map[string]versionComparer{
+	"golang": compareSemVers,
+	"maven":  compareMavenVersions,
+	"npm":    compareSemVers,
+	"pypi":   comparePEP440Versions,
+	"rpm":    compareRPMVersions,
+	"semver": compareSemVers,
+}
+
+// semVerTypes holds the PURL types with versions following SemVer, these can also be matched
+// against vers ranges of the semver versioning scheme.
+var semVerTypes = map[string]bool{
+	"cargo":  true,
+	"golang": true,
+	"npm":    true,
+}
+
+// compareNumbers compares two strings of decimal digits numerically without being limited by the
+// size of any integer type
+func compareNumbers(a, b string) int {
+	a = strings.TrimLeft(a, "0")
+	b = strings.TrimLeft(b, "0")
+	if len(a) != len(b) {
+		if len(a) < len(b) {
+			return -1
+		}
+		return 1
+	}
+
+	return strings.Compare(a, b)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// compareSemVers compares semantic versions, the leading "v" used by Go modules is optional
+func compareSemVers(a, b string) (int, error) {
+	canonical := func(v string) (string, error) {
+		if !strings.HasPrefix(v, "v") {
+			v = "v" + v
+		}
+		if !semver.IsValid(v) {
+			return "", fmt.Errorf("invalid semantic version: %q", v)
+		}
+		return v, nil
+	}
+
+	va, err := canonical(a)
+	if err != nil {
+		return 0, err
+	}
+	vb, err := canonical(b)
+	if err != nil {
+		return 0, err
+	}
+
+	return semver.Compare(va, vb), nil
+}
+
+// compareRPMVersions compares RPM versions in the [epoch:]version[-release] form. A missing epoch
+// is the same as epoch 0, and the release is only compared when present in both versions, as rpm
+// itself does.
+func compareRPMVersions(a, b string) (int, error) {
+	type evr struct {
+		epoch   string
+		version string
+		release string
+	}
+
+	parse := func(v string) (evr, error) {
+		var e evr
+		if epoch, rest, ok := strings.Cut(v, ":"); ok {
+			if epoch == "" || strings.TrimFunc(epoch, func(r rune) bool { return r >= '0' && r <= '9' }) != "" {
+				return e, fmt.Errorf("invalid epoch in RPM version: %q", v)
+			}
+			e.epoch = epoch
+			v = rest
+		}
+		if i := strings.LastIndex(v, "-"); i != -1 {
+			e.version, e.release = v[:i], v[i+1:]
+		} else {
+			e.version = v
+		}
+		if e.version == "" {
+			return e, fmt.Errorf("invalid RPM version: %q", v)
+		}
+		return e, nil
+	}
+
+	ea, err := parse(a)
+	if err != nil {
+		return 0, err
+	}
+	eb, err := parse(b)
+	if err != nil {
+		return 0, err
+	}
+
+	if c := compareNumbers(ea.epoch, eb.epoch); c != 0 {
+		return c, nil
+	}
+	if c := rpmvercmp(ea.version, eb.version); c != 0 {
+		return c, nil
+	}
+	if ea.release == "" || eb.release == "" {
+		return 0, nil
+	}
+
+	return rpmvercmp(ea.release, eb.release), nil
+}
+
+// rpmvercmp is a port of the rpmvercmp function from rpm's lib/rpmvercmp.c, including the
+// handling of the tilde (sorts before anything) and caret (sorts after the base version).
+func rpmvercmp(a, b string) int {
+	if a == b {
+		return 0
+	}
+
+	skip := func(s string) string {
+		return strings.TrimLeftFunc(s, func(r rune) bool {
+			return r < 128 && !isDigit(byte(r)) && !isLetter(byte(r)) && r != '~' && r != '^'
+		})
+	}
+	span := func(s string, f func(byte) bool) string {
+		i := 0
+		for i < len(s) && f(s[i]) {
+			i++
+		}
+		return s[:i]
+	}
+
+	one, two := a, b
+	for one != "" || two != "" {
+		one, two = skip(one), skip(two)
+
+		if strings.HasPrefix(one, "~") || strings.HasPrefix(two, "~") {
+			if !strings.HasPrefix(one, "~") {
+				return 1
+			}
+			if !strings.HasPrefix(two, "~") {
+				return -1
+			}
+			one, two = one[1:], two[1:]
+			continue
+		}
+
+		if strings.HasPrefix(one, "^") || strings.HasPrefix(two, "^") {
+			if one == "" {
+				return -1
+			}
+			if two == "" {
+				return 1
+			}
+			if !strings.HasPrefix(one, "^") {
+				return 1
+			}
+			if !strings.HasPrefix(two, "^") {
+				return -1
+			}
+			one, two = one[1:], two[1:]
+			continue
+		}
+
+		if one == "" || two == "" {
+			break
+		}
+
+		isNumber := isDigit(one[0])
+		var seg1, seg2 string
+		if isNumber {
+			seg1, seg2 = span(one, isDigit), span(two, isDigit)
+		} else {
+			seg1, seg2 = span(one, isLetter), span(two, isLetter)
+		}
+		one, two = one[len(seg1):], two[len(seg2):]
+
+		// Segments of different types, numeric segments are considered newer
+		if seg2 == "" {
+			if isNumber {
+				return 1
+			}
+			return -1
+		}
+
+		var c int
+		if isNumber {
+			c = compareNumbers(seg1, seg2)
+		} else {
+			c = strings.Compare(seg1, seg2)
+		}
+		if c != 0 {
+			return c
+		}
+	}
+
+	switch {
+	case one == "" && two == "":
+		return 0
+	case one == "":
+		return -1
+	default:
+		return 1
+	}
+}
+
+// pep440Pattern is the version pattern from the PEP 440 specification
+var pep440Pattern = regexp.MustCompile(`(?i)^\s*v?` +
+	`(?:(?P<epoch>[0-9]+)!)?` +
+	`(?P<release>[0-9]+(?:\.[0-9]+)*)` +
+	`(?:[-_.]?(?P<pre_l>alpha|a|beta|b|preview|pre|c|rc)[-_.]?(?P<pre_n>[0-9]+)?)?` +
+	`(?:-(?P<post_n1>[0-9]+)|[-_.]?(?P<post_l>post|rev|r)[-_.]?(?P<post_n2>[0-9]+)?)?` +
+	`(?:[-_.]?(?P<dev_l>dev)[-_.]?(?P<dev_n>[0-9]+)?)?` +
+	`(?:\+(?P<local>[a-z0-9]+(?:[-_.][a-z0-9]+)*))?\s*$`)
+
+// pep440Version holds the comparison key of a PEP 440 version. Absent pre, post and dev segments
+// are represented with sentinel values so that the keys compare as mandated by the specification.
+type pep440Version struct {
+	epoch   int
+	release []int
+	pre     [3]int
+	post    [2]int
+	dev     [2]int
+	local   []string
+}
+
+func parsePEP440Version(v string) (*pep440Version, error) {
+	m := pep440Pattern.FindStringSubmatch(v)
+	if m == nil {
+		return nil, fmt.Errorf("invalid PEP 440 version: %q", v)
+	}
+	group := func(name string) string {
+		return m[pep440Pattern.SubexpIndex(name)]
+	}
+
+	var errs []error
+	number := func(s string) int {
+		if s == "" {
+			return 0
+		}
+		n, err := strconv.Atoi(s)
+		errs = append(errs, err)
+		return n
+	}
+
+	p := pep440Version{
+		epoch: number(group("epoch")),
+	}
+
+	for _, r := range strings.Split(group("release"), ".") {
+		p.release = append(p.release, number(r))
+	}
+	for len(p.release) > 1 && p.release[len(p.release)-1] == 0 {
+		p.release = p.release[:len(p.release)-1]
+	}
+
+	switch l := strings.ToLower(group("pre_l")); l {
+	case "":
+		if group("post_n1") == "" && group("post_l") == "" && group("dev_l") != "" {
+			// A dev release of a final release sorts before its pre-releases
+			p.pre = [3]int{0, 0, 0}
+		} else {
+			p.pre = [3]int{2, 0, 0}
+		}
+	case "a", "alpha":
+		p.pre = [3]int{1, 0, number(group("pre_n"))}
+	case "b", "beta":
+		p.pre = [3]int{1, 1, number(group("pre_n"))}
+	default:
+		p.pre = [3]int{1, 2, number(group("pre_n"))}
+	}
+
+	switch {
+	case group("post_n1") != "":
+		p.post = [2]int{1, number(group("post_n1"))}
+	case group("post_l") != "":
+		p.post = [2]int{1, number(group("post_n2"))}
+	}
+
+	if group("dev_l") != "" {
+		p.dev = [2]int{0, number(group("dev_n"))}
+	} else {
+		p.dev = [2]int{1, 0}
+	}
+
+	if local := group("local"); local != "" {
+		p.local = strings.FieldsFunc(strings.ToLower(local), func(r rune) bool {
+			return r == '-' || r == '_' || r == '.'
+		})
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("invalid PEP 440 version: %q: %w", v, err)
+	}
+
+	return &p, nil
+}
+
+func compareInts(a, b []int) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	return 0
+}
+
+// comparePEP440Versions compares Python package versions following the PEP 440 ordering
+func comparePEP440Versions(a, b string) (int, error) {
+	va, err := parsePEP440Version(a)
+	if err != nil {
+		return 0, err
+	}
+	vb, err := parsePEP440Version(b)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, c := range []int{
+		compareInts([]int{va.epoch}, []int{vb.epoch}),
+		compareInts(va.release, vb.release),
+		compareInts(va.pre[:], vb.pre[:]),
+		compareInts(va.post[:], vb.post[:]),
+		compareInts(va.dev[:], vb.dev[:]),
+	} {
+		if c != 0 {
+			return c, nil
+		}
+	}
+
+	// A version without a local segment sorts before the same version with one. Numeric local
+	// segments sort after alphanumeric ones.
+	for i := 0; i < len(va.local) || i < len(vb.local); i++ {
+		if i >= len(va.local) {
+			return -1, nil
+		}
+		if i >= len(vb.local) {
+			return 1, nil
+		}
+
+		x, y := va.local[i], vb.local[i]
+		_, xErr := strconv.Atoi(x)
+		_, yErr := strconv.Atoi(y)
+		var c int
+		switch {
+		case xErr == nil && yErr == nil:
+			c = compareNumbers(x, y)
+		case xErr == nil:
+			c = 1
+		case yErr == nil:
+			c = -1
+		default:
+			c = strings.Compare(x, y)
+		}
+		if c != 0 {
+			return c, nil
+		}
+	}
+
+	return 0, nil
+}
+
+// mavenItem is a single item of a Maven version, either a number or a qualifier
+type mavenItem struct {
+	number    string
+	qualifier string
+	isNumber  bool
+}
+
+func (i mavenItem) isNull() bool {
+	if i.isNumber {
+		return strings.TrimLeft(i.number, "0") == ""
+	}
+
+	return mavenQualifierKey(i.qualifier) == mavenQualifierKey("")
+}
+
+// mavenQualifiers lists the well known qualifiers in their order, qualifiers on the same line
+// are equivalent
+var mavenQualifiers = map[string]string{
+	"alpha":     "0",
+	"beta":      "1",
+	"milestone": "2",
+	"rc":        "3",
+	"cr":        "3",
+	"snapshot":  "4",
+	"":          "5",
+	"ga":        "5",
+	"final":     "5",
+	"release":   "5",
+	"sp":        "6",
+}
+
+// mavenQualifierKey returns the key used to order qualifiers, unknown qualifiers sort after the
+// well known ones in lexical order
+func mavenQualifierKey(q string) string {
+	if k, ok := mavenQualifiers[q]; ok {
+		return k
+	}
+
+	return "7-" + q
+}
+
+func parseMavenVersion(v string) ([]mavenItem, error) {
+	v = strings.ToLower(strings.TrimSpace(v))
+	if v == "" {
+		return nil, errors.New("empty Maven version")
+	}
+
+	var items []mavenItem
+	appendItem := func(token string, followedByDigit bool) {
+		if token == "" || isDigit(token[0]) {
+			if token == "" {
+				token = "0"
+			}
+			items = append(items, mavenItem{number: token, isNumber: true})
+			return
+		}
+		if followedByDigit && len(token) == 1 {
+			switch token {
+			case "a":
+				token = "alpha"
+			case "b":
+				token = "beta"
+			case "m":
+				token = "milestone"
+			}
+		}
+		items = append(items, mavenItem{qualifier: token})
+	}
+
+	start := 0
+	for i := 0; i < len(v); i++ {
+		c := v[i]
+		switch {
+		case c == '.' || c == '-':
+			appendItem(v[start:i], false)
+			start = i + 1
+		case i > start && isDigit(c) != isDigit(v[i-1]):
+			// The transition between digits and characters is an implicit separator
+			appendItem(v[start:i], isDigit(c))
+			start = i
+		}
+	}
+	appendItem(v[start:], false)
+
+	for len(items) > 0 && items[len(items)-1].isNull() {
+		items = items[:len(items)-1]
+	}
+
+	return items, nil
+}
+
+// compareMavenVersions compares Maven versions following the ordering of Maven's
+// ComparableVersion: numbers compare numerically and sort after qualifiers, well known qualifiers
+// follow alpha < beta < milestone < rc < snapshot < release < sp, and trailing zeros or release
+// qualifiers are ignored, e.g. 1.0 == 1.0.0 == 1-ga.
+func compareMavenVersions(a, b string) (int, error) {
+	va, err := parseMavenVersion(a)
+	if err != nil {
+		return 0, err
+	}
+	vb, err := parseMavenVersion(b)
+	if err != nil {
+		return 0, err
+	}
+
+	null := mavenItem{number: "0", isNumber: true}
+	for i := 0; i < len(va) || i < len(vb); i++ {
+		x, y := null, null
+		if i < len(va) {
+			x = va[i]
+		}
+		if i < len(vb) {
+			y = vb[i]
+		}
+		// Padding is neutral with respect to the other item's type
+		if i >= len(va) && !y.isNumber {
+			x = mavenItem{}
+		}
+		if i >= len(vb) && !x.isNumber {
+			y = mavenItem{}
+		}
+
+		var c int
+		switch {
+		case x.isNumber && y.isNumber:
+			c = compareNumbers(x.number, y.number)
+		case x.isNumber:
+			c = 1
+		case y.isNumber:
+			c = -1
+		default:
+			c = strings.Compare(mavenQualifierKey(x.qualifier), mavenQualifierKey(y.qualifier))
+		}
+		if c != 0 {
+			return c, nil
+		}
+	}
+
+	return 0, nil
+}
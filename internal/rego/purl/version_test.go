@@ -0,0 +1,134 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package rego
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersionComparers(t *testing.T) {
+	cases := []struct {
+		scheme   string
+		a        string
+		b        string
+		expected int
+	}{
+		// rpm
+		{"rpm", "1.0", "1.0", 0},
+		{"rpm", "1.0", "2.0", -1},
+		{"rpm", "2.0", "1.0", 1},
+		{"rpm", "1.10", "1.9", 1},
+		{"rpm", "1.010", "1.10", 0},
+		{"rpm", "1.0a", "1.0", 1},
+		{"rpm", "1.0", "1.0a", -1},
+		{"rpm", "1a", "1.0", -1},
+		{"rpm", "1.0~rc1", "1.0", -1},
+		{"rpm", "1.0~rc1", "1.0~rc2", -1},
+		{"rpm", "1.0^git1", "1.0", 1},
+		{"rpm", "1.0^git1", "1.0.1", -1},
+		{"rpm", "1.0_1", "1.0.1", 0},
+		{"rpm", "3.0.7-24.el9", "3.0.7-25.el9", -1},
+		{"rpm", "3.0.7-24.el9", "3.0.7", 0},
+		{"rpm", "1:1.0-1", "2.0-1", 1},
+		{"rpm", "0:1.0-1", "1.0-1", 0},
+		// semver
+		{"npm", "1.2.3", "1.2.3", 0},
+		{"npm", "1.2.3", "1.10.0", -1},
+		{"npm", "1.0.0-alpha", "1.0.0", -1},
+		{"npm", "1.0.0-alpha.1", "1.0.0-alpha.beta", -1},
+		{"npm", "1.0.0+build.1", "1.0.0+build.2", 0},
+		{"golang", "v0.22.0", "v0.3.0", 1},
+		{"golang", "v0.0.0-20240101000000-abcdefabcdef", "v0.0.1", -1},
+		// PEP 440
+		{"pypi", "1.0", "1.0.0", 0},
+		{"pypi", "1.0.dev0", "1.0a1", -1},
+		{"pypi", "1.0a1", "1.0b1", -1},
+		{"pypi", "1.0b1", "1.0rc1", -1},
+		{"pypi", "1.0rc1", "1.0", -1},
+		{"pypi", "1.0c1", "1.0rc1", 0},
+		{"pypi", "1.0.post1", "1.0", 1},
+		{"pypi", "1.0-1", "1.0.post1", 0},
+		{"pypi", "1.0.post1.dev1", "1.0.post1", -1},
+		{"pypi", "1.0a1.dev1", "1.0a1", -1},
+		{"pypi", "1.0+local", "1.0", 1},
+		{"pypi", "1.0+abc", "1.0+5", -1},
+		{"pypi", "1.0+abc.5", "1.0+abc.10", -1},
+		{"pypi", "1!1.0", "2.0", 1},
+		{"pypi", "V1.0-ALPHA1", "1.0a1", 0},
+		// maven
+		{"maven", "1.0", "1.0.0", 0},
+		{"maven", "1", "1-ga", 0},
+		{"maven", "1.0-final", "1.0", 0},
+		{"maven", "1.0-alpha-1", "1.0-beta-1", -1},
+		{"maven", "1.0-beta-1", "1.0-milestone-1", -1},
+		{"maven", "1.0-M1", "1.0-RC1", -1},
+		{"maven", "1.0-rc1", "1.0-cr1", 0},
+		{"maven", "1.0-RC1", "1.0-SNAPSHOT", -1},
+		{"maven", "1.0-SNAPSHOT", "1.0", -1},
+		{"maven", "1.0", "1.0-sp1", -1},
+		{"maven", "1.0-sp1", "1.0-foo", -1},
+		{"maven", "1.0a1", "1.0-alpha-1", 0},
+		{"maven", "1.0-alpha", "1.0", -1},
+		{"maven", "1.0.1", "1.0-foo", 1},
+		{"maven", "2.10", "2.9", 1},
+	}
+
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("%s %s %s", c.scheme, c.a, c.b), func(t *testing.T) {
+			compare := versionComparers[c.scheme]
+			require.NotNil(t, compare)
+
+			result, err := compare(c.a, c.b)
+			require.NoError(t, err)
+			assert.Equal(t, c.expected, result)
+
+			reverse, err := compare(c.b, c.a)
+			require.NoError(t, err)
+			assert.Equal(t, -c.expected, reverse)
+		})
+	}
+}
+
+func TestVersionComparersErrors(t *testing.T) {
+	cases := []struct {
+		scheme string
+		a      string
+		b      string
+	}{
+		{"rpm", "x:1.0", "1.0"},
+		{"rpm", "1.0", ":1.0"},
+		{"rpm", "-1", "1.0"},
+		{"npm", "1.2.3.4", "1.2.3"},
+		{"golang", "v1.0.0", "latest"},
+		{"pypi", "1.0-foo", "1.0"},
+		{"pypi", "1.0", "not a version"},
+		{"maven", "", "1.0"},
+	}
+
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("%s %s %s", c.scheme, c.a, c.b), func(t *testing.T) {
+			_, err := versionComparers[c.scheme](c.a, c.b)
+			assert.Error(t, err)
+		})
+	}
+}
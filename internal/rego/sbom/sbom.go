@@ -0,0 +1,294 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// IMPORTANT: The rego functions in this file never return an error. Instead, they return no value
+// when an error is encountered. If they did return an error, opa would exit abruptly and it would
+// not produce a report of which policy rules succeeded/failed.
+
+package sbom
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"strings"
+
+	"github.com/CycloneDX/cyclonedx-go"
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/types"
+	log "github.com/sirupsen/logrus"
+	"github.com/spdx/tools-golang/spdx"
+)
+
+const (
+	sbomPackagesName = "ec.sbom.packages"
+)
+
+// pkg is the format independent representation of a package listed in an SBOM
+type pkg struct {
+	Name     string   `json:"name"`
+	Version  string   `json:"version"`
+	PURL     string   `json:"purl"`
+	Licenses []string `json:"licenses"`
+	Hashes   []hash   `json:"hashes"`
+	Supplier string   `json:"supplier"`
+}
+
+type hash struct {
+	Algorithm string `json:"algorithm"`
+	Value     string `json:"value"`
+}
+
+func registerSBOMPackages() {
+	decl := rego.Function{
+		Name: sbomPackagesName,
+		Decl: types.NewFunction(
+			types.Args(
+				types.Named("sbom", types.NewAny(types.S, types.NewObject(nil, types.NewDynamicProperty(types.S, types.A)))).
+					Description("the SPDX or CycloneDX SBOM, either as an object or as a JSON string"),
+			),
+			types.Named("packages", types.NewArray(
+				nil, types.NewObject(
+					[]*types.StaticProperty{
+						// Specifying the properties like this ensure the compiler catches typos when
+						// evaluating rego functions.
+						{Key: "name", Value: types.S},
+						{Key: "version", Value: types.S},
+						{Key: "purl", Value: types.S},
+						{Key: "licenses", Value: types.NewArray(nil, types.S)},
+						{Key: "hashes", Value: types.NewArray(
+							nil, types.NewObject(
+								[]*types.StaticProperty{
+									{Key: "algorithm", Value: types.S},
+									{Key: "value", Value: types.S},
+								},
+								nil,
+							),
+						)},
+						{Key: "supplier", Value: types.S},
+					},
+					nil,
+				),
+			)).Description("the packages listed in the SBOM"),
+		),
+		// As per the documentation, enable memoization to ensure function evaluation is
+		// deterministic.
+		Memoize:          true,
+		Nondeterministic: false,
+	}
+
+	rego.RegisterBuiltin1(&decl, sbomPackages)
+	// Due to https://github.com/open-policy-agent/opa/issues/6449, we cannot set a description for
+	// the custom function through the call above. As a workaround we re-register the function with
+	// a declaration that does include the description.
+	ast.RegisterBuiltin(&ast.Builtin{
+		Name: decl.Name,
+		Description: "List the packages of an SPDX or a CycloneDX SBOM in a format independent way. " +
+			"Nested CycloneDX components are included in the list. Hash algorithms are normalized to " +
+			"lower case without a dash after \"sha\", e.g. sha256, sha3-256 or md5.",
+		Decl:             decl.Decl,
+		Nondeterministic: decl.Nondeterministic,
+	})
+}
+
+func sbomPackages(bctx rego.BuiltinContext, a *ast.Term) (*ast.Term, error) {
+	logger := log.WithField("function", sbomPackagesName)
+
+	var data []byte
+	switch v := a.Value.(type) {
+	case ast.String:
+		data = []byte(v)
+	case ast.Object:
+		obj, err := ast.JSON(v)
+		if err == nil {
+			data, err = json.Marshal(obj)
+		}
+		if err != nil {
+			logger.WithFields(log.Fields{
+				"action": "marshal sbom",
+				"error":  err,
+			}).Error("failed to marshal the SBOM object")
+			return nil, nil
+		}
+	default:
+		logger.Error("input sbom is neither an object nor a string")
+		return nil, nil
+	}
+
+	var format struct {
+		SPDXVersion string `json:"spdxVersion"`
+		BOMFormat   string `json:"bomFormat"`
+	}
+	if err := json.Unmarshal(data, &format); err != nil {
+		logger.WithFields(log.Fields{
+			"action": "unmarshal sbom",
+			"error":  err,
+		}).Error("failed to unmarshal the SBOM")
+		return nil, nil
+	}
+
+	var packages []pkg
+	var err error
+	switch {
+	case format.SPDXVersion != "":
+		packages, err = spdxPackages(data)
+	case format.BOMFormat == "CycloneDX":
+		packages, err = cyclonedxPackages(data)
+	default:
+		logger.Error("input sbom is neither an SPDX nor a CycloneDX SBOM")
+		return nil, nil
+	}
+	if err != nil {
+		logger.WithFields(log.Fields{
+			"action": "parse sbom",
+			"error":  err,
+		}).Error("failed to parse the SBOM")
+		return nil, nil
+	}
+
+	value, err := ast.InterfaceToValue(packages)
+	if err != nil {
+		logger.WithFields(log.Fields{
+			"action": "convert packages",
+			"error":  err,
+		}).Error("failed to convert packages to value")
+		return nil, nil
+	}
+
+	logger.Debugf("Listed %d packages of the SBOM", len(packages))
+	return ast.NewTerm(value), nil
+}
+
+func spdxPackages(data []byte) ([]pkg, error) {
+	var doc spdx.Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	packages := make([]pkg, 0, len(doc.Packages))
+	for _, p := range doc.Packages {
+		if p == nil {
+			continue
+		}
+
+		out := pkg{
+			Name:     p.PackageName,
+			Version:  p.PackageVersion,
+			Licenses: []string{},
+			Hashes:   []hash{},
+		}
+
+		for _, r := range p.PackageExternalReferences {
+			if r != nil && r.RefType == "purl" {
+				out.PURL = r.Locator
+				break
+			}
+		}
+
+		for _, l := range []string{p.PackageLicenseDeclared, p.PackageLicenseConcluded} {
+			if l != "" && l != "NOASSERTION" && l != "NONE" && !slices.Contains(out.Licenses, l) {
+				out.Licenses = append(out.Licenses, l)
+			}
+		}
+
+		for _, c := range p.PackageChecksums {
+			out.Hashes = append(out.Hashes, hash{Algorithm: hashAlgorithm(string(c.Algorithm)), Value: c.Value})
+		}
+
+		if s := p.PackageSupplier; s != nil && s.Supplier != "NOASSERTION" {
+			out.Supplier = s.Supplier
+		}
+
+		packages = append(packages, out)
+	}
+
+	return packages, nil
+}
+
+func cyclonedxPackages(data []byte) ([]pkg, error) {
+	var bom cyclonedx.BOM
+	if err := cyclonedx.NewBOMDecoder(bytes.NewReader(data), cyclonedx.BOMFileFormatJSON).Decode(&bom); err != nil {
+		return nil, err
+	}
+
+	packages := []pkg{}
+	var collect func(components *[]cyclonedx.Component)
+	collect = func(components *[]cyclonedx.Component) {
+		if components == nil {
+			return
+		}
+
+		for _, c := range *components {
+			out := pkg{
+				Name:     c.Name,
+				Version:  c.Version,
+				PURL:     c.PackageURL,
+				Licenses: []string{},
+				Hashes:   []hash{},
+			}
+
+			if c.Licenses != nil {
+				for _, l := range *c.Licenses {
+					var license string
+					switch {
+					case l.Expression != "":
+						license = l.Expression
+					case l.License != nil && l.License.ID != "":
+						license = l.License.ID
+					case l.License != nil:
+						license = l.License.Name
+					}
+					if license != "" && !slices.Contains(out.Licenses, license) {
+						out.Licenses = append(out.Licenses, license)
+					}
+				}
+			}
+
+			if c.Hashes != nil {
+				for _, h := range *c.Hashes {
+					out.Hashes = append(out.Hashes, hash{Algorithm: hashAlgorithm(string(h.Algorithm)), Value: h.Value})
+				}
+			}
+
+			if c.Supplier != nil {
+				out.Supplier = c.Supplier.Name
+			}
+
+			packages = append(packages, out)
+
+			collect(c.Components)
+		}
+	}
+	collect(bom.Components)
+
+	return packages, nil
+}
+
+// hashAlgorithm normalizes the names of the hash algorithms used by SPDX, e.g.
+// SHA256, and by CycloneDX, e.g. SHA-256, to the same name, e.g. sha256
+func hashAlgorithm(algorithm string) string {
+	algorithm = strings.ToLower(algorithm)
+	if rest, ok := strings.CutPrefix(algorithm, "sha-"); ok {
+		return "sha" + rest
+	}
+
+	return algorithm
+}
+
+func init() {
+	registerSBOMPackages()
+}
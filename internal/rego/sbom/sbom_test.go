@@ -0,0 +1,222 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package sbom
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const spdxSBOM = `{
+	"spdxVersion": "SPDX-2.3",
+	"dataLicense": "CC0-1.0",
+	"SPDXID": "SPDXRef-DOCUMENT",
+	"name": "image",
+	"creationInfo": {"created": "2024-05-21T12:16:52Z", "creators": ["Tool: syft"]},
+	"packages": [
+		{
+			"SPDXID": "SPDXRef-Package-openssl",
+			"name": "openssl",
+			"versionInfo": "3.0.7",
+			"downloadLocation": "NOASSERTION",
+			"supplier": "Organization: Red Hat",
+			"licenseDeclared": "Apache-2.0",
+			"licenseConcluded": "Apache-2.0",
+			"checksums": [{"algorithm": "SHA256", "checksumValue": "abc"}],
+			"externalRefs": [
+				{"referenceCategory": "SECURITY", "referenceType": "cpe23Type", "referenceLocator": "cpe:2.3:a:openssl:openssl:3.0.7:*:*:*:*:*:*:*"},
+				{"referenceCategory": "PACKAGE-MANAGER", "referenceType": "purl", "referenceLocator": "pkg:rpm/redhat/openssl@3.0.7"}
+			]
+		},
+		{
+			"SPDXID": "SPDXRef-Package-zlib",
+			"name": "zlib",
+			"downloadLocation": "NOASSERTION",
+			"supplier": "NOASSERTION",
+			"licenseDeclared": "NOASSERTION",
+			"licenseConcluded": "Zlib"
+		}
+	]
+}`
+
+const cyclonedxSBOM = `{
+	"bomFormat": "CycloneDX",
+	"specVersion": "1.5",
+	"version": 1,
+	"components": [
+		{
+			"type": "library",
+			"name": "openssl",
+			"version": "3.0.7",
+			"purl": "pkg:rpm/redhat/openssl@3.0.7",
+			"supplier": {"name": "Red Hat"},
+			"licenses": [{"license": {"id": "Apache-2.0"}}, {"license": {"name": "Custom"}}],
+			"hashes": [{"alg": "SHA-256", "content": "abc"}, {"alg": "SHA3-256", "content": "def"}],
+			"components": [
+				{"type": "library", "name": "libcrypto", "version": "3.0.7", "licenses": [{"expression": "Apache-2.0 OR MIT"}]}
+			]
+		},
+		{"type": "library", "name": "zlib"}
+	]
+}`
+
+func TestSBOMPackages(t *testing.T) {
+	cases := []struct {
+		name     string
+		sbom     *ast.Term
+		expected string
+	}{
+		{
+			name: "SPDX",
+			sbom: ast.MustParseTerm(spdxSBOM),
+			expected: `[
+				{
+					"name": "openssl",
+					"version": "3.0.7",
+					"purl": "pkg:rpm/redhat/openssl@3.0.7",
+					"licenses": ["Apache-2.0"],
+					"hashes": [{"algorithm": "sha256", "value": "abc"}],
+					"supplier": "Red Hat"
+				},
+				{
+					"name": "zlib",
+					"version": "",
+					"purl": "",
+					"licenses": ["Zlib"],
+					"hashes": [],
+					"supplier": ""
+				}
+			]`,
+		},
+		{
+			name: "CycloneDX",
+			sbom: ast.MustParseTerm(cyclonedxSBOM),
+			expected: `[
+				{
+					"name": "openssl",
+					"version": "3.0.7",
+					"purl": "pkg:rpm/redhat/openssl@3.0.7",
+					"licenses": ["Apache-2.0", "Custom"],
+					"hashes": [{"algorithm": "sha256", "value": "abc"}, {"algorithm": "sha3-256", "value": "def"}],
+					"supplier": "Red Hat"
+				},
+				{
+					"name": "libcrypto",
+					"version": "3.0.7",
+					"purl": "",
+					"licenses": ["Apache-2.0 OR MIT"],
+					"hashes": [],
+					"supplier": ""
+				},
+				{
+					"name": "zlib",
+					"version": "",
+					"purl": "",
+					"licenses": [],
+					"hashes": [],
+					"supplier": ""
+				}
+			]`,
+		},
+		{
+			name:     "JSON string",
+			sbom:     ast.StringTerm(`{"bomFormat": "CycloneDX", "specVersion": "1.6", "components": [{"type": "library", "name": "zlib"}]}`),
+			expected: `[{"name": "zlib", "version": "", "purl": "", "licenses": [], "hashes": [], "supplier": ""}]`,
+		},
+		{
+			name:     "no packages",
+			sbom:     ast.MustParseTerm(`{"bomFormat": "CycloneDX", "specVersion": "1.6"}`),
+			expected: `[]`,
+		},
+		{
+			name: "unexpected sbom type",
+			sbom: ast.IntNumberTerm(42),
+		},
+		{
+			name: "unknown format",
+			sbom: ast.MustParseTerm(`{"packages": []}`),
+		},
+		{
+			name: "malformed JSON string",
+			sbom: ast.StringTerm(`{"spdxVersion":`),
+		},
+		{
+			name: "malformed SBOM",
+			sbom: ast.MustParseTerm(`{"bomFormat": "CycloneDX", "components": {}}`),
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			bctx := rego.BuiltinContext{Context: context.Background()}
+
+			packages, err := sbomPackages(bctx, c.sbom)
+			require.NoError(t, err)
+			if c.expected == "" {
+				require.Nil(t, packages)
+				return
+			}
+			require.NotNil(t, packages)
+
+			value, err := ast.JSON(packages.Value)
+			require.NoError(t, err)
+			actual, err := json.Marshal(value)
+			require.NoError(t, err)
+			assert.JSONEq(t, c.expected, string(actual))
+		})
+	}
+}
+
+func TestHashAlgorithm(t *testing.T) {
+	cases := map[string]string{
+		"SHA1":        "sha1",
+		"SHA-1":       "sha1",
+		"SHA256":      "sha256",
+		"SHA-256":     "sha256",
+		"SHA3-256":    "sha3-256",
+		"BLAKE2b-256": "blake2b-256",
+		"MD5":         "md5",
+	}
+
+	for algorithm, expected := range cases {
+		assert.Equal(t, expected, hashAlgorithm(algorithm), algorithm)
+	}
+}
+
+func TestFunctionsRegistered(t *testing.T) {
+	names := []string{
+		sbomPackagesName,
+	}
+	for _, name := range names {
+		t.Run(name, func(t *testing.T) {
+			for _, builtin := range ast.Builtins {
+				if builtin.Name == name {
+					return
+				}
+			}
+			t.Fatalf("%s builtin not registered", name)
+		})
+	}
+}
@@ -0,0 +1,262 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package diff compares the reports of two validation runs, for example
+// before and after updating a policy or data source, to find the components
+// that newly fail.
+package diff
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/enterprise-contract/ec-cli/internal/applicationsnapshot"
+	"github.com/enterprise-contract/ec-cli/internal/evaluator"
+	"github.com/enterprise-contract/ec-cli/internal/format"
+	"github.com/enterprise-contract/ec-cli/internal/utils"
+)
+
+// Possible formats the diff can be written as.
+const (
+	Text     = "text"
+	JSON     = "json"
+	Markdown = "markdown"
+)
+
+var OutputFormats = []string{
+	Text,
+	JSON,
+	Markdown,
+}
+
+// Change is a result of a component that differs between the reports
+type Change struct {
+	Component      string `json:"component"`
+	ContainerImage string `json:"containerImage"`
+	Code           string `json:"code"`
+	Term           string `json:"term,omitempty"`
+	Message        string `json:"msg"`
+	EffectiveOn    string `json:"effective_on,omitempty"`
+}
+
+// Diff holds the differences between the violations of two reports.
+type Diff struct {
+	// Introduced are the violations present only in the new report
+	Introduced []Change `json:"introduced"`
+	// Resolved are the violations present only in the old report
+	Resolved []Change `json:"resolved"`
+	// Promoted are the warnings of the old report that are violations in the
+	// new report, usually because the effective_on date of the rule passed
+	Promoted []Change `json:"promoted"`
+}
+
+// Regressed returns true if the new report has violations the old report
+// didn't have.
+func (d Diff) Regressed() bool {
+	return len(d.Introduced) > 0 || len(d.Promoted) > 0
+}
+
+// ReadReport reads a report produced in the JSON or YAML format.
+func ReadReport(data []byte) (applicationsnapshot.Report, error) {
+	var r applicationsnapshot.Report
+	if err := yaml.Unmarshal(data, &r); err != nil {
+		return r, fmt.Errorf("unable to parse the report: %w", err)
+	}
+
+	return r, nil
+}
+
+// Compare returns the differences between the old and the new report. The
+// components are matched by their name and image reference, and the results
+// by their code and term. A component missing from one of the reports is
+// treated as having no results in that report.
+func Compare(old, new applicationsnapshot.Report) Diff {
+	type pair struct {
+		old, new *applicationsnapshot.Component
+	}
+
+	pairs := map[string]*pair{}
+	for i := range old.Components {
+		c := &old.Components[i]
+		pairs[componentKey(*c)] = &pair{old: c}
+	}
+	for i := range new.Components {
+		c := &new.Components[i]
+		if p, ok := pairs[componentKey(*c)]; ok {
+			p.new = c
+		} else {
+			pairs[componentKey(*c)] = &pair{new: c}
+		}
+	}
+
+	keys := make([]string, 0, len(pairs))
+	for k := range pairs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	d := Diff{
+		Introduced: []Change{},
+		Resolved:   []Change{},
+		Promoted:   []Change{},
+	}
+	for _, k := range keys {
+		p := pairs[k]
+
+		oldViolations := resultsByKey(p.old, violations)
+		oldWarnings := resultsByKey(p.old, warnings)
+		newViolations := resultsByKey(p.new, violations)
+
+		// the same result can be reported multiple times, include it only once
+		seen := map[string]bool{}
+		for _, r := range violations(p.new) {
+			key := resultKey(r)
+			if _, ok := oldViolations[key]; ok || seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			change := newChange(*p.new, r)
+			if w, ok := oldWarnings[key]; ok {
+				change.EffectiveOn = evaluator.ExtractStringFromMetadata(w, "effective_on")
+				d.Promoted = append(d.Promoted, change)
+			} else {
+				d.Introduced = append(d.Introduced, change)
+			}
+		}
+
+		seen = map[string]bool{}
+		for _, r := range violations(p.old) {
+			key := resultKey(r)
+			if _, ok := newViolations[key]; ok || seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			d.Resolved = append(d.Resolved, newChange(*p.old, r))
+		}
+	}
+
+	return d
+}
+
+func componentKey(c applicationsnapshot.Component) string {
+	return c.Name + "\x00" + c.ContainerImage
+}
+
+func resultKey(r evaluator.Result) string {
+	code := evaluator.ExtractStringFromMetadata(r, "code")
+	if code == "" {
+		// results without a code can only be told apart by their message
+		code = r.Message
+	}
+
+	return code + "\x00" + term(r)
+}
+
+func violations(c *applicationsnapshot.Component) []evaluator.Result {
+	if c == nil {
+		return nil
+	}
+
+	return c.Violations
+}
+
+func warnings(c *applicationsnapshot.Component) []evaluator.Result {
+	if c == nil {
+		return nil
+	}
+
+	return c.Warnings
+}
+
+func resultsByKey(c *applicationsnapshot.Component, results func(*applicationsnapshot.Component) []evaluator.Result) map[string]evaluator.Result {
+	m := map[string]evaluator.Result{}
+	for _, r := range results(c) {
+		m[resultKey(r)] = r
+	}
+
+	return m
+}
+
+func newChange(c applicationsnapshot.Component, r evaluator.Result) Change {
+	return Change{
+		Component:      c.Name,
+		ContainerImage: c.ContainerImage,
+		Code:           evaluator.ExtractStringFromMetadata(r, "code"),
+		Term:           term(r),
+		Message:        r.Message,
+	}
+}
+
+// WriteAll writes the diff to all the given targets.
+func (d Diff) WriteAll(targets []string, p format.TargetParser) (allErrors error) {
+	if len(targets) == 0 {
+		targets = append(targets, Text)
+	}
+	for _, targetName := range targets {
+		target, err := p.Parse(targetName)
+		if err != nil {
+			allErrors = errors.Join(allErrors, err)
+			continue
+		}
+
+		data, err := d.toFormat(target.Format)
+		if err != nil {
+			allErrors = errors.Join(allErrors, err)
+			continue
+		}
+
+		if !bytes.HasSuffix(data, []byte{'\n'}) {
+			data = append(data, "\n"...)
+		}
+
+		if _, err := target.Write(data); err != nil {
+			allErrors = errors.Join(allErrors, err)
+		}
+	}
+	return
+}
+
+//go:embed templates/*.tmpl
+var efs embed.FS
+
+// toFormat converts the diff into the given format.
+func (d *Diff) toFormat(format string) (data []byte, err error) {
+	switch format {
+	case JSON:
+		data, err = json.Marshal(d)
+	case Text:
+		data, err = utils.RenderFromTemplatesWithMain(d, "text.tmpl", efs)
+	case Markdown:
+		data, err = utils.RenderFromTemplatesWithMain(d, "markdown.tmpl", efs)
+	default:
+		return nil, fmt.Errorf("%q is not a valid diff format", format)
+	}
+	return
+}
+
+// term returns the term of the result, lists of terms are joined with a comma
+func term(r evaluator.Result) string {
+	return strings.Join(evaluator.ExtractStringsFromMetadata(r, "term"), ", ")
+}
@@ -0,0 +1,232 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package diff
+
+import (
+	"testing"
+
+	app "github.com/konflux-ci/application-api/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/enterprise-contract/ec-cli/internal/applicationsnapshot"
+	"github.com/enterprise-contract/ec-cli/internal/evaluator"
+)
+
+func result(code string, term any, msg string) evaluator.Result {
+	r := evaluator.Result{Message: msg, Metadata: map[string]any{"code": code}}
+	if term != nil {
+		r.Metadata["term"] = term
+	}
+	return r
+}
+
+func component(name, image string, violations, warnings []evaluator.Result) applicationsnapshot.Component {
+	return applicationsnapshot.Component{
+		SnapshotComponent: app.SnapshotComponent{Name: name, ContainerImage: image},
+		Violations:        violations,
+		Warnings:          warnings,
+		Success:           len(violations) == 0,
+	}
+}
+
+func TestCompare(t *testing.T) {
+	deprecated := result("tasks.deprecated", "buildah", "Task buildah is deprecated")
+	deprecated.Metadata["effective_on"] = "2026-01-01T00:00:00Z"
+
+	old := applicationsnapshot.Report{
+		Components: []applicationsnapshot.Component{
+			component("a", "registry.io/a@sha256:1", []evaluator.Result{
+				result("signature.missing", nil, "Missing signature"),
+				result("tasks.missing", "git-clone", "Task git-clone is missing"),
+			}, []evaluator.Result{
+				deprecated,
+			}),
+			component("b", "registry.io/b@sha256:2", nil, nil),
+			component("removed", "registry.io/removed@sha256:3", []evaluator.Result{
+				result("signature.missing", nil, "Missing signature"),
+			}, nil),
+		},
+	}
+
+	new := applicationsnapshot.Report{
+		Components: []applicationsnapshot.Component{
+			component("a", "registry.io/a@sha256:1", []evaluator.Result{
+				result("signature.missing", nil, "Missing signature, again"),
+				result("tasks.missing", "buildah", "Task buildah is missing"),
+				result("tasks.deprecated", "buildah", "Task buildah is deprecated"),
+			}, nil),
+			component("b", "registry.io/b@sha256:2", []evaluator.Result{
+				result("cve.critical", []any{"CVE-1", "CVE-2"}, "Critical CVEs found"),
+				result("cve.critical", []any{"CVE-1", "CVE-2"}, "Critical CVEs found"),
+			}, nil),
+		},
+	}
+
+	d := Compare(old, new)
+
+	assert.Equal(t, []Change{
+		{Component: "a", ContainerImage: "registry.io/a@sha256:1", Code: "tasks.missing", Term: "buildah", Message: "Task buildah is missing"},
+		{Component: "b", ContainerImage: "registry.io/b@sha256:2", Code: "cve.critical", Term: "CVE-1, CVE-2", Message: "Critical CVEs found"},
+	}, d.Introduced)
+	assert.Equal(t, []Change{
+		{Component: "a", ContainerImage: "registry.io/a@sha256:1", Code: "tasks.deprecated", Term: "buildah", Message: "Task buildah is deprecated", EffectiveOn: "2026-01-01T00:00:00Z"},
+	}, d.Promoted)
+	assert.Equal(t, []Change{
+		{Component: "a", ContainerImage: "registry.io/a@sha256:1", Code: "tasks.missing", Term: "git-clone", Message: "Task git-clone is missing"},
+		{Component: "removed", ContainerImage: "registry.io/removed@sha256:3", Code: "signature.missing", Message: "Missing signature"},
+	}, d.Resolved)
+	assert.True(t, d.Regressed())
+
+	// comparing the other way around
+	d = Compare(new, old)
+	assert.Len(t, d.Introduced, 2)
+	assert.Empty(t, d.Promoted)
+	assert.Len(t, d.Resolved, 3)
+	assert.True(t, d.Regressed())
+
+	d = Compare(old, old)
+	assert.Empty(t, d.Introduced)
+	assert.Empty(t, d.Promoted)
+	assert.Empty(t, d.Resolved)
+	assert.False(t, d.Regressed())
+}
+
+func TestCompareComponentsByImage(t *testing.T) {
+	old := applicationsnapshot.Report{
+		Components: []applicationsnapshot.Component{
+			component("a", "registry.io/a@sha256:1", []evaluator.Result{result("signature.missing", nil, "Missing signature")}, nil),
+		},
+	}
+	new := applicationsnapshot.Report{
+		Components: []applicationsnapshot.Component{
+			component("a", "registry.io/a@sha256:2", []evaluator.Result{result("signature.missing", nil, "Missing signature")}, nil),
+		},
+	}
+
+	d := Compare(old, new)
+	assert.Len(t, d.Introduced, 1)
+	assert.Len(t, d.Resolved, 1)
+}
+
+func TestReadReport(t *testing.T) {
+	r, err := ReadReport([]byte(`{
+		"success": false,
+		"components": [
+			{
+				"name": "a",
+				"containerImage": "registry.io/a@sha256:1",
+				"violations": [{"msg": "Missing signature", "metadata": {"code": "signature.missing", "term": ["x", "y"]}}],
+				"success": false
+			}
+		]
+	}`))
+	require.NoError(t, err)
+	require.Len(t, r.Components, 1)
+	assert.Equal(t, "registry.io/a@sha256:1", r.Components[0].ContainerImage)
+	assert.Equal(t, []any{"x", "y"}, r.Components[0].Violations[0].Metadata["term"])
+
+	r, err = ReadReport([]byte("components:\n- name: a\n  containerImage: registry.io/a@sha256:1\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "a", r.Components[0].Name)
+
+	_, err = ReadReport([]byte("{"))
+	assert.ErrorContains(t, err, "unable to parse the report")
+}
+
+func testDiff() Diff {
+	return Diff{
+		Introduced: []Change{{Component: "a", ContainerImage: "registry.io/a@sha256:1", Code: "tasks.missing", Term: "buildah", Message: "Task buildah is missing"}},
+		Promoted:   []Change{{Component: "a", ContainerImage: "registry.io/a@sha256:1", Code: "tasks.deprecated", Message: "Task is deprecated", EffectiveOn: "2026-01-01T00:00:00Z"}},
+		Resolved:   []Change{{Component: "b", ContainerImage: "registry.io/b@sha256:2", Code: "signature.missing", Message: "Missing signature"}},
+	}
+}
+
+func TestText(t *testing.T) {
+	d := testDiff()
+	data, err := d.toFormat(Text)
+	require.NoError(t, err)
+
+	assert.Equal(t, `Regression: true
+Introduced violations: 1, Resolved violations: 1, Warnings that became violations: 1
+
+Introduced violations:
+✕ [tasks.missing] a
+  ImageRef: registry.io/a@sha256:1
+  Reason: Task buildah is missing
+  Term: buildah
+
+Warnings that became violations:
+✕ [tasks.deprecated] a
+  ImageRef: registry.io/a@sha256:1
+  Reason: Task is deprecated
+  Effective on: 2026-01-01T00:00:00Z
+
+Resolved violations:
+✓ [signature.missing] b
+  ImageRef: registry.io/b@sha256:2
+  Reason: Missing signature
+`, string(data))
+
+	empty := Diff{}
+	data, err = empty.toFormat(Text)
+	require.NoError(t, err)
+	assert.Equal(t, "Regression: false\nIntroduced violations: 0, Resolved violations: 0, Warnings that became violations: 0\n", string(data))
+}
+
+func TestMarkdown(t *testing.T) {
+	d := testDiff()
+	data, err := d.toFormat(Markdown)
+	require.NoError(t, err)
+
+	assert.Equal(t, "| Result | Count |\n"+
+		"|--------|-------|\n"+
+		"| Introduced violations | 1 |\n"+
+		"| Warnings that became violations | 1 |\n"+
+		"| Resolved violations | 1 |\n"+
+		"| Regression | :x: |\n"+
+		"\n"+
+		"### Introduced violations\n"+
+		"\n"+
+		"| Component | Image | Code | Term | Message |\n"+
+		"|-----------|-------|------|------|---------|\n"+
+		"| a | `registry.io/a@sha256:1` | `tasks.missing` | buildah | Task buildah is missing |\n"+
+		"\n"+
+		"### Warnings that became violations\n"+
+		"\n"+
+		"| Component | Image | Code | Term | Message | Effective on |\n"+
+		"|-----------|-------|------|------|---------|--------------|\n"+
+		"| a | `registry.io/a@sha256:1` | `tasks.deprecated` |  | Task is deprecated | 2026-01-01T00:00:00Z |\n"+
+		"\n"+
+		"### Resolved violations\n"+
+		"\n"+
+		"| Component | Image | Code | Term | Message |\n"+
+		"|-----------|-------|------|------|---------|\n"+
+		"| b | `registry.io/b@sha256:2` | `signature.missing` |  | Missing signature |\n", string(data))
+}
+
+func TestJSON(t *testing.T) {
+	d := Compare(applicationsnapshot.Report{}, applicationsnapshot.Report{})
+	data, err := d.toFormat(JSON)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"introduced": [], "resolved": [], "promoted": []}`, string(data))
+
+	_, err = d.toFormat("spam")
+	assert.EqualError(t, err, `"spam" is not a valid diff format`)
+}
@@ -0,0 +1,220 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package sarif converts policy evaluation results into the Static Analysis
+// Results Interchange Format (SARIF) 2.1.0, understood by code scanning tools
+// such as the ones of GitHub and GitLab.
+package sarif
+
+import (
+	"github.com/enterprise-contract/ec-cli/internal/evaluator"
+)
+
+const (
+	schema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	version = "2.1.0"
+
+	toolName = "ec"
+	toolURI  = "https://conforma.dev"
+
+	levelError   = "error"
+	levelWarning = "warning"
+	levelNone    = "none"
+
+	kindFail = "fail"
+	kindPass = "pass"
+)
+
+// Log is the top-level SARIF document
+type Log struct {
+	Schema  string `json:"$schema"`
+	Version string `json:"version"`
+	Runs    []Run  `json:"runs"`
+}
+
+type Run struct {
+	Tool    Tool     `json:"tool"`
+	Results []Result `json:"results"`
+}
+
+type Tool struct {
+	Driver Driver `json:"driver"`
+}
+
+type Driver struct {
+	Name           string                `json:"name"`
+	Version        string                `json:"version,omitempty"`
+	InformationURI string                `json:"informationUri"`
+	Rules          []ReportingDescriptor `json:"rules,omitempty"`
+}
+
+// ReportingDescriptor describes a policy rule
+type ReportingDescriptor struct {
+	ID               string                    `json:"id"`
+	ShortDescription *MultiformatMessageString `json:"shortDescription,omitempty"`
+	FullDescription  *MultiformatMessageString `json:"fullDescription,omitempty"`
+	Help             *MultiformatMessageString `json:"help,omitempty"`
+}
+
+type MultiformatMessageString struct {
+	Text string `json:"text"`
+}
+
+type Result struct {
+	RuleID     string         `json:"ruleId,omitempty"`
+	RuleIndex  *int           `json:"ruleIndex,omitempty"`
+	Kind       string         `json:"kind"`
+	Level      string         `json:"level"`
+	Message    Message        `json:"message"`
+	Locations  []Location     `json:"locations"`
+	Properties map[string]any `json:"properties,omitempty"`
+}
+
+type Message struct {
+	Text string `json:"text"`
+}
+
+type Location struct {
+	PhysicalLocation PhysicalLocation `json:"physicalLocation"`
+}
+
+type PhysicalLocation struct {
+	ArtifactLocation ArtifactLocation `json:"artifactLocation"`
+}
+
+type ArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+// Target holds the results of evaluating the policy against a single
+// artifact, i.e. a component image or an input file
+type Target struct {
+	// Location is the reference to the artifact, e.g. the image reference or
+	// the path to the input file
+	Location   string
+	Violations []evaluator.Result
+	Warnings   []evaluator.Result
+	Successes  []evaluator.Result
+}
+
+// NewLog returns the SARIF document holding the results of all the targets
+// in a single run. Violations are reported with the error level, warnings
+// with the warning level, unless the severity of the rule states otherwise,
+// and successes as passing results.
+func NewLog(toolVersion string, targets []Target) Log {
+	b := builder{
+		rules: map[string]int{},
+		// an empty list of results states that nothing was found
+		results: []Result{},
+	}
+
+	for _, t := range targets {
+		for _, r := range t.Violations {
+			b.add(t.Location, r, kindFail, levelError)
+		}
+		for _, r := range t.Warnings {
+			b.add(t.Location, r, kindFail, levelWarning)
+		}
+		for _, r := range t.Successes {
+			b.add(t.Location, r, kindPass, levelNone)
+		}
+	}
+
+	return Log{
+		Schema:  schema,
+		Version: version,
+		Runs: []Run{
+			{
+				Tool: Tool{
+					Driver: Driver{
+						Name:           toolName,
+						Version:        toolVersion,
+						InformationURI: toolURI,
+						Rules:          b.descriptors,
+					},
+				},
+				Results: b.results,
+			},
+		},
+	}
+}
+
+type builder struct {
+	rules       map[string]int
+	descriptors []ReportingDescriptor
+	results     []Result
+}
+
+func (b *builder) add(location string, r evaluator.Result, kind, level string) {
+	result := Result{
+		Kind:    kind,
+		Level:   level,
+		Message: Message{Text: r.Message},
+		Locations: []Location{
+			{
+				PhysicalLocation: PhysicalLocation{
+					ArtifactLocation: ArtifactLocation{URI: location},
+				},
+			},
+		},
+	}
+
+	if kind == kindFail {
+		switch evaluator.ExtractStringFromMetadata(r, "severity") {
+		case "failure":
+			result.Level = levelError
+		case "warning":
+			result.Level = levelWarning
+		}
+	}
+
+	if code := evaluator.ExtractStringFromMetadata(r, "code"); code != "" {
+		i := b.rule(code, r)
+		result.RuleID = code
+		result.RuleIndex = &i
+	}
+
+	if term, ok := r.Metadata["term"]; ok {
+		result.Properties = map[string]any{"term": term}
+	}
+
+	b.results = append(b.results, result)
+}
+
+// rule returns the index of the descriptor of the rule with the given code,
+// adding the descriptor from the metadata of the result if not yet present
+func (b *builder) rule(code string, r evaluator.Result) int {
+	if i, ok := b.rules[code]; ok {
+		return i
+	}
+
+	d := ReportingDescriptor{ID: code}
+	if title := evaluator.ExtractStringFromMetadata(r, "title"); title != "" {
+		d.ShortDescription = &MultiformatMessageString{Text: title}
+	}
+	if description := evaluator.ExtractStringFromMetadata(r, "description"); description != "" {
+		d.FullDescription = &MultiformatMessageString{Text: description}
+	}
+	if solution := evaluator.ExtractStringFromMetadata(r, "solution"); solution != "" {
+		d.Help = &MultiformatMessageString{Text: solution}
+	}
+
+	i := len(b.descriptors)
+	b.rules[code] = i
+	b.descriptors = append(b.descriptors, d)
+
+	return i
+}
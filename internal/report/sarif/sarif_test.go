@@ -0,0 +1,193 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package sarif
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/enterprise-contract/ec-cli/internal/evaluator"
+)
+
+func TestNewLogEmpty(t *testing.T) {
+	data, err := json.Marshal(NewLog("v1.0.0", nil))
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{
+		"$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+		"version": "2.1.0",
+		"runs": [
+			{
+				"tool": {
+					"driver": {
+						"name": "ec",
+						"version": "v1.0.0",
+						"informationUri": "https://conforma.dev"
+					}
+				},
+				"results": []
+			}
+		]
+	}`, string(data))
+}
+
+func TestNewLog(t *testing.T) {
+	violation := evaluator.Result{
+		Message: "Missing signature",
+		Metadata: map[string]any{
+			"code":        "signature.missing",
+			"title":       "Signature is present",
+			"description": "The image needs to be signed.",
+			"solution":    "Sign the image.",
+			"term":        "image",
+		},
+	}
+
+	log := NewLog("development", []Target{
+		{
+			Location:   "registry.io/repository/image1@sha256:abc",
+			Violations: []evaluator.Result{violation},
+			Warnings: []evaluator.Result{
+				{
+					Message: "Deprecated task",
+					Metadata: map[string]any{
+						"code":  "tasks.deprecated",
+						"title": "No deprecated tasks",
+					},
+				},
+				{
+					Message: "No code",
+				},
+			},
+			Successes: []evaluator.Result{
+				{
+					Message: "Pass",
+					Metadata: map[string]any{
+						"code": "tasks.required",
+					},
+				},
+			},
+		},
+		{
+			Location: "registry.io/repository/image2@sha256:def",
+			Violations: []evaluator.Result{
+				violation,
+			},
+			Warnings: []evaluator.Result{
+				{
+					Message: "Not yet effective",
+					Metadata: map[string]any{
+						"code":     "signature.expired",
+						"severity": "failure",
+					},
+				},
+			},
+		},
+	})
+
+	data, err := json.Marshal(log)
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{
+		"$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+		"version": "2.1.0",
+		"runs": [
+			{
+				"tool": {
+					"driver": {
+						"name": "ec",
+						"version": "development",
+						"informationUri": "https://conforma.dev",
+						"rules": [
+							{
+								"id": "signature.missing",
+								"shortDescription": {"text": "Signature is present"},
+								"fullDescription": {"text": "The image needs to be signed."},
+								"help": {"text": "Sign the image."}
+							},
+							{
+								"id": "tasks.deprecated",
+								"shortDescription": {"text": "No deprecated tasks"}
+							},
+							{
+								"id": "tasks.required"
+							},
+							{
+								"id": "signature.expired"
+							}
+						]
+					}
+				},
+				"results": [
+					{
+						"ruleId": "signature.missing",
+						"ruleIndex": 0,
+						"kind": "fail",
+						"level": "error",
+						"message": {"text": "Missing signature"},
+						"locations": [{"physicalLocation": {"artifactLocation": {"uri": "registry.io/repository/image1@sha256:abc"}}}],
+						"properties": {"term": "image"}
+					},
+					{
+						"ruleId": "tasks.deprecated",
+						"ruleIndex": 1,
+						"kind": "fail",
+						"level": "warning",
+						"message": {"text": "Deprecated task"},
+						"locations": [{"physicalLocation": {"artifactLocation": {"uri": "registry.io/repository/image1@sha256:abc"}}}]
+					},
+					{
+						"kind": "fail",
+						"level": "warning",
+						"message": {"text": "No code"},
+						"locations": [{"physicalLocation": {"artifactLocation": {"uri": "registry.io/repository/image1@sha256:abc"}}}]
+					},
+					{
+						"ruleId": "tasks.required",
+						"ruleIndex": 2,
+						"kind": "pass",
+						"level": "none",
+						"message": {"text": "Pass"},
+						"locations": [{"physicalLocation": {"artifactLocation": {"uri": "registry.io/repository/image1@sha256:abc"}}}]
+					},
+					{
+						"ruleId": "signature.missing",
+						"ruleIndex": 0,
+						"kind": "fail",
+						"level": "error",
+						"message": {"text": "Missing signature"},
+						"locations": [{"physicalLocation": {"artifactLocation": {"uri": "registry.io/repository/image2@sha256:def"}}}],
+						"properties": {"term": "image"}
+					},
+					{
+						"ruleId": "signature.expired",
+						"ruleIndex": 3,
+						"kind": "fail",
+						"level": "error",
+						"message": {"text": "Not yet effective"},
+						"locations": [{"physicalLocation": {"artifactLocation": {"uri": "registry.io/repository/image2@sha256:def"}}}]
+					}
+				]
+			}
+		]
+	}`, string(data))
+}
@@ -0,0 +1,190 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package rpmdb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// The Berkeley DB hash database holds the header blobs as values stored on
+// overflow pages. Rather than following the hash buckets, every hash page is
+// visited and the values referring to overflow pages are read. See
+// https://github.com/berkeleydb/libdb/blob/master/src/dbinc/db_page.h for the
+// page layouts.
+
+const (
+	bdbHashMagic       = 0x061561
+	bdbPageHeaderSize  = 26
+	bdbHashUnsorted    = 2
+	bdbOverflowPage    = 7
+	bdbHashMetaPage    = 8
+	bdbHashPage        = 13
+	bdbOffPageItem     = 3
+	bdbOffPageItemSize = 12
+)
+
+type bdbPageHeader struct {
+	next     uint32
+	entries  uint16
+	hfOffset uint16
+	typ      byte
+}
+
+type bdbDB struct {
+	data     []byte
+	order    binary.ByteOrder
+	pageSize int
+	lastPage int
+}
+
+// bdbBlobs returns the header blobs held in the Berkeley DB hash database
+func bdbBlobs(data []byte) ([][]byte, error) {
+	db, err := newBDB(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var blobs [][]byte
+	for number := 1; number <= db.lastPage; number++ {
+		page, header, err := db.page(number)
+		if err != nil {
+			return nil, err
+		}
+
+		if header.typ != bdbHashPage && header.typ != bdbHashUnsorted {
+			continue
+		}
+
+		// Entries are pairs of keys and values, only the values are of
+		// interest
+		if bdbPageHeaderSize+int(header.entries)*2 > len(page) {
+			return nil, fmt.Errorf("too many entries in page %d", number)
+		}
+		for i := 1; i < int(header.entries); i += 2 {
+			offset := int(db.order.Uint16(page[bdbPageHeaderSize+i*2:]))
+			if offset+bdbOffPageItemSize > len(page) {
+				return nil, fmt.Errorf("entry %d of page %d out of bounds", i, number)
+			}
+
+			item := page[offset:]
+			if item[0] != bdbOffPageItem {
+				continue
+			}
+
+			blob, err := db.overflow(int(db.order.Uint32(item[4:])), int(db.order.Uint32(item[8:])))
+			if err != nil {
+				return nil, fmt.Errorf("entry %d of page %d: %w", i, number, err)
+			}
+			blobs = append(blobs, blob)
+		}
+	}
+
+	return blobs, nil
+}
+
+func newBDB(data []byte) (*bdbDB, error) {
+	if len(data) < 512 {
+		return nil, errors.New("not a Berkeley DB database")
+	}
+
+	// The byte order is the one of the host that created the database
+	var order binary.ByteOrder
+	switch {
+	case binary.LittleEndian.Uint32(data[12:]) == bdbHashMagic:
+		order = binary.LittleEndian
+	case binary.BigEndian.Uint32(data[12:]) == bdbHashMagic:
+		order = binary.BigEndian
+	default:
+		return nil, errors.New("not a Berkeley DB hash database")
+	}
+
+	if data[24] != 0 {
+		return nil, errors.New("encrypted Berkeley DB databases are not supported")
+	}
+
+	if data[25] != bdbHashMetaPage {
+		return nil, fmt.Errorf("unexpected metadata page type %d", data[25])
+	}
+
+	pageSize := int(order.Uint32(data[20:]))
+	if pageSize < 512 || pageSize&(pageSize-1) != 0 {
+		return nil, fmt.Errorf("invalid page size: %d", pageSize)
+	}
+
+	return &bdbDB{
+		data:     data,
+		order:    order,
+		pageSize: pageSize,
+		lastPage: int(order.Uint32(data[32:])),
+	}, nil
+}
+
+func (db *bdbDB) page(number int) ([]byte, bdbPageHeader, error) {
+	start := number * db.pageSize
+	if number < 0 || start+db.pageSize > len(db.data) {
+		return nil, bdbPageHeader{}, fmt.Errorf("page %d out of bounds", number)
+	}
+
+	page := db.data[start : start+db.pageSize]
+	return page, bdbPageHeader{
+		next:     db.order.Uint32(page[16:]),
+		entries:  db.order.Uint16(page[20:]),
+		hfOffset: db.order.Uint16(page[22:]),
+		typ:      page[25],
+	}, nil
+}
+
+// overflow reads a value of the given length from the chain of overflow pages
+// starting at the given page
+func (db *bdbDB) overflow(number, length int) ([]byte, error) {
+	if length > len(db.data) {
+		return nil, fmt.Errorf("value length %d out of bounds", length)
+	}
+
+	value := make([]byte, 0, length)
+	for visited := 0; number != 0; visited++ {
+		if visited > db.lastPage {
+			return nil, errors.New("overflow page chain loops")
+		}
+
+		page, header, err := db.page(number)
+		if err != nil {
+			return nil, err
+		}
+
+		if header.typ != bdbOverflowPage {
+			return nil, fmt.Errorf("unexpected page type %d of overflow page %d", header.typ, number)
+		}
+
+		// On overflow pages the free area offset holds the length of the
+		// data on the page
+		end := bdbPageHeaderSize + int(header.hfOffset)
+		if end > len(page) {
+			return nil, fmt.Errorf("overflow page %d data out of bounds", number)
+		}
+		value = append(value, page[bdbPageHeaderSize:end]...)
+		number = int(header.next)
+	}
+
+	if len(value) != length {
+		return nil, fmt.Errorf("read %d bytes of a %d bytes long value", len(value), length)
+	}
+
+	return value, nil
+}
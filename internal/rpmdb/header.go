@@ -0,0 +1,368 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package rpmdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// RPM header tags, see rpm's include/rpm/rpmtag.h
+const (
+	tagDSAHeader = 267
+	tagRSAHeader = 268
+	tagSigPGP    = 259
+	tagSigGPG    = 262
+	tagName      = 1000
+	tagVersion   = 1001
+	tagRelease   = 1002
+	tagEpoch     = 1003
+	tagVendor    = 1011
+	tagArch      = 1022
+	tagSourceRPM = 1044
+)
+
+// RPM header entry types
+const (
+	typeInt32       = 4
+	typeString      = 6
+	typeBin         = 7
+	typeStringArray = 8
+	typeI18NString  = 9
+)
+
+type headerEntry struct {
+	typ    uint32
+	offset int
+	count  int
+}
+
+// header is a parsed RPM header blob as stored in the RPM database: the
+// number of index entries and the size of the data store, followed by the
+// index entries and the data store.
+type header struct {
+	entries map[int32]headerEntry
+	data    []byte
+}
+
+func newHeader(blob []byte) (*header, error) {
+	if len(blob) < 8 {
+		return nil, errors.New("header blob too short")
+	}
+
+	il := binary.BigEndian.Uint32(blob[0:4])
+	dl := binary.BigEndian.Uint32(blob[4:8])
+	if uint64(8)+uint64(il)*16+uint64(dl) > uint64(len(blob)) {
+		return nil, fmt.Errorf("header blob of %d bytes too short for %d entries and %d bytes of data", len(blob), il, dl)
+	}
+
+	dataStart := 8 + int(il)*16
+	h := header{
+		entries: make(map[int32]headerEntry, il),
+		data:    blob[dataStart : dataStart+int(dl)],
+	}
+
+	// Entries appended after the immutable region replace the ones within
+	// it, so the last entry for a tag wins
+	for i := 0; i < int(il); i++ {
+		e := blob[8+i*16 : 8+(i+1)*16]
+		h.entries[int32(binary.BigEndian.Uint32(e[0:4]))] = headerEntry{
+			typ:    binary.BigEndian.Uint32(e[4:8]),
+			offset: int(int32(binary.BigEndian.Uint32(e[8:12]))),
+			count:  int(binary.BigEndian.Uint32(e[12:16])),
+		}
+	}
+
+	return &h, nil
+}
+
+func (h header) string(tag int32) (string, error) {
+	e, ok := h.entries[tag]
+	if !ok {
+		return "", nil
+	}
+
+	switch e.typ {
+	case typeString, typeStringArray, typeI18NString:
+	default:
+		return "", fmt.Errorf("unexpected type %d of tag %d", e.typ, tag)
+	}
+
+	if e.offset < 0 || e.offset >= len(h.data) {
+		return "", fmt.Errorf("offset %d of tag %d out of bounds", e.offset, tag)
+	}
+
+	s := h.data[e.offset:]
+	end := bytes.IndexByte(s, 0)
+	if end == -1 {
+		return "", fmt.Errorf("unterminated string of tag %d", tag)
+	}
+
+	return string(s[:end]), nil
+}
+
+func (h header) int32(tag int32) (*int, error) {
+	e, ok := h.entries[tag]
+	if !ok {
+		return nil, nil
+	}
+
+	if e.typ != typeInt32 {
+		return nil, fmt.Errorf("unexpected type %d of tag %d", e.typ, tag)
+	}
+
+	if e.offset < 0 || e.offset+4 > len(h.data) {
+		return nil, fmt.Errorf("offset %d of tag %d out of bounds", e.offset, tag)
+	}
+
+	v := int(int32(binary.BigEndian.Uint32(h.data[e.offset:])))
+	return &v, nil
+}
+
+func (h header) bin(tag int32) ([]byte, error) {
+	e, ok := h.entries[tag]
+	if !ok {
+		return nil, nil
+	}
+
+	if e.typ != typeBin {
+		return nil, fmt.Errorf("unexpected type %d of tag %d", e.typ, tag)
+	}
+
+	if e.offset < 0 || e.count < 0 || e.offset+e.count > len(h.data) {
+		return nil, fmt.Errorf("offset %d of tag %d out of bounds", e.offset, tag)
+	}
+
+	return h.data[e.offset : e.offset+e.count], nil
+}
+
+func parseHeader(blob []byte) (*Package, error) {
+	h, err := newHeader(blob)
+	if err != nil {
+		return nil, err
+	}
+
+	var p Package
+	for tag, field := range map[int32]*string{
+		tagName:      &p.Name,
+		tagVersion:   &p.Version,
+		tagRelease:   &p.Release,
+		tagArch:      &p.Arch,
+		tagVendor:    &p.Vendor,
+		tagSourceRPM: &p.SourceRPM,
+	} {
+		if *field, err = h.string(tag); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.Epoch, err = h.int32(tagEpoch); err != nil {
+		return nil, err
+	}
+
+	// Same order of preference as rpm uses when querying the signature
+	for _, tag := range []int32{tagDSAHeader, tagRSAHeader, tagSigGPG, tagSigPGP} {
+		sig, err := h.bin(tag)
+		if err != nil {
+			return nil, err
+		}
+		if sig == nil {
+			continue
+		}
+
+		if p.SignatureKeyID, err = signatureKeyID(sig); err != nil {
+			return nil, fmt.Errorf("signature of package %q: %w", p.Name, err)
+		}
+		break
+	}
+
+	return &p, nil
+}
+
+// OpenPGP packet and signature subpacket types, see RFC 4880
+const (
+	pgpSignaturePacket      = 2
+	pgpIssuerSubpacket      = 16
+	pgpIssuerFingerprintSub = 33
+)
+
+// signatureKeyID returns the hex encoded ID of the key that made the OpenPGP
+// signature packet
+func signatureKeyID(sig []byte) (string, error) {
+	body, err := pgpSignatureBody(sig)
+	if err != nil {
+		return "", err
+	}
+
+	if len(body) < 1 {
+		return "", errors.New("empty signature packet")
+	}
+
+	switch body[0] {
+	case 3:
+		// version, hashed length (5), type, creation time (4), key ID (8)
+		if len(body) < 15 {
+			return "", errors.New("truncated version 3 signature packet")
+		}
+		return hex.EncodeToString(body[7:15]), nil
+	case 4:
+		// version, type, public key algorithm, hash algorithm, followed by
+		// the hashed and the unhashed subpackets
+		if len(body) < 6 {
+			return "", errors.New("truncated version 4 signature packet")
+		}
+		rest := body[4:]
+		var fingerprint []byte
+		for i := 0; i < 2; i++ {
+			if len(rest) < 2 {
+				return "", errors.New("truncated version 4 signature packet")
+			}
+			n := int(binary.BigEndian.Uint16(rest))
+			if len(rest) < 2+n {
+				return "", errors.New("truncated version 4 signature subpackets")
+			}
+
+			id, fp, err := pgpIssuer(rest[2 : 2+n])
+			if err != nil {
+				return "", err
+			}
+			if id != nil {
+				return hex.EncodeToString(id), nil
+			}
+			if fp != nil {
+				fingerprint = fp
+			}
+
+			rest = rest[2+n:]
+		}
+
+		// The key ID of a version 4 key is the low 64 bits of its fingerprint,
+		// newer key versions use the high 64 bits
+		if len(fingerprint) >= 9 {
+			if fingerprint[0] == 4 {
+				return hex.EncodeToString(fingerprint[len(fingerprint)-8:]), nil
+			}
+			return hex.EncodeToString(fingerprint[1:9]), nil
+		}
+		return "", errors.New("signature packet without issuer")
+	default:
+		return "", fmt.Errorf("unsupported signature packet version %d", body[0])
+	}
+}
+
+// pgpSignatureBody returns the body of the OpenPGP signature packet
+func pgpSignatureBody(sig []byte) ([]byte, error) {
+	if len(sig) < 2 || sig[0]&0x80 == 0 {
+		return nil, errors.New("invalid OpenPGP packet")
+	}
+
+	var tag byte
+	var length, offset int
+	if sig[0]&0x40 == 0 {
+		// Old format packet
+		tag = (sig[0] >> 2) & 0x0f
+		switch sig[0] & 0x03 {
+		case 0:
+			length, offset = int(sig[1]), 2
+		case 1:
+			if len(sig) < 3 {
+				return nil, errors.New("truncated OpenPGP packet")
+			}
+			length, offset = int(binary.BigEndian.Uint16(sig[1:])), 3
+		case 2:
+			if len(sig) < 5 {
+				return nil, errors.New("truncated OpenPGP packet")
+			}
+			length, offset = int(binary.BigEndian.Uint32(sig[1:])), 5
+		default:
+			length, offset = len(sig)-1, 1
+		}
+	} else {
+		// New format packet
+		tag = sig[0] & 0x3f
+		switch o := sig[1]; {
+		case o < 192:
+			length, offset = int(o), 2
+		case o < 224:
+			if len(sig) < 3 {
+				return nil, errors.New("truncated OpenPGP packet")
+			}
+			length, offset = (int(o)-192)<<8+int(sig[2])+192, 3
+		case o == 255:
+			if len(sig) < 6 {
+				return nil, errors.New("truncated OpenPGP packet")
+			}
+			length, offset = int(binary.BigEndian.Uint32(sig[2:])), 6
+		default:
+			return nil, errors.New("partial length OpenPGP packets are not supported")
+		}
+	}
+
+	if tag != pgpSignaturePacket {
+		return nil, fmt.Errorf("unexpected OpenPGP packet type %d", tag)
+	}
+
+	if length < 0 || offset+length > len(sig) {
+		return nil, errors.New("truncated OpenPGP packet")
+	}
+
+	return sig[offset : offset+length], nil
+}
+
+// pgpIssuer looks for the issuer key ID and the issuer fingerprint within the
+// signature subpackets
+func pgpIssuer(subpackets []byte) (id []byte, fingerprint []byte, err error) {
+	for len(subpackets) > 0 {
+		var length, offset int
+		switch o := subpackets[0]; {
+		case o < 192:
+			length, offset = int(o), 1
+		case o < 255:
+			if len(subpackets) < 2 {
+				return nil, nil, errors.New("truncated signature subpacket")
+			}
+			length, offset = (int(o)-192)<<8+int(subpackets[1])+192, 2
+		default:
+			if len(subpackets) < 5 {
+				return nil, nil, errors.New("truncated signature subpacket")
+			}
+			length, offset = int(binary.BigEndian.Uint32(subpackets[1:])), 5
+		}
+
+		if length < 1 || offset+length > len(subpackets) {
+			return nil, nil, errors.New("truncated signature subpacket")
+		}
+
+		data := subpackets[offset+1 : offset+length]
+		switch subpackets[offset] & 0x7f {
+		case pgpIssuerSubpacket:
+			if len(data) == 8 {
+				id = data
+			}
+		case pgpIssuerFingerprintSub:
+			// Key version followed by the fingerprint
+			fingerprint = data
+		}
+
+		subpackets = subpackets[offset+length:]
+	}
+
+	return id, fingerprint, nil
+}
@@ -0,0 +1,95 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package rpmdb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// The ndb database is rpm's own format, see lib/backend/ndb/rpmpkg.c in rpm.
+// The file starts with a header followed by slots, each slot pointing to the
+// blob of a package. All values are little-endian.
+
+const (
+	ndbHeaderMagic    = 'R' | 'p'<<8 | 'm'<<16 | 'P'<<24
+	ndbSlotMagic      = 'S' | 'l'<<8 | 'o'<<16 | 't'<<24
+	ndbBlobMagic      = 'B' | 'l'<<8 | 'b'<<16 | 'S'<<24
+	ndbVersion        = 0
+	ndbPageSize       = 4096
+	ndbSlotSize       = 16
+	ndbBlockSize      = 16
+	ndbBlobHeaderSize = 16
+	// The first two slots are taken by the database header
+	ndbHeaderSlots = 2
+)
+
+// ndbBlobs returns the header blobs held in the ndb database
+func ndbBlobs(data []byte) ([][]byte, error) {
+	if len(data) < ndbSlotSize*ndbHeaderSlots {
+		return nil, errors.New("not an ndb database")
+	}
+
+	le := binary.LittleEndian
+	if le.Uint32(data) != ndbHeaderMagic {
+		return nil, errors.New("not an ndb database")
+	}
+	if v := le.Uint32(data[4:]); v != ndbVersion {
+		return nil, fmt.Errorf("unsupported ndb version %d", v)
+	}
+
+	slotPages := int(le.Uint32(data[12:]))
+	if slotPages < 1 || slotPages*ndbPageSize > len(data) {
+		return nil, fmt.Errorf("invalid number of slot pages: %d", slotPages)
+	}
+
+	var blobs [][]byte
+	for offset := ndbSlotSize * ndbHeaderSlots; offset < slotPages*ndbPageSize; offset += ndbSlotSize {
+		slot := data[offset : offset+ndbSlotSize]
+		if le.Uint32(slot) != ndbSlotMagic {
+			return nil, fmt.Errorf("invalid slot magic at offset %d", offset)
+		}
+
+		index := le.Uint32(slot[4:])
+		if index == 0 {
+			// Free slot
+			continue
+		}
+
+		start := int(le.Uint32(slot[8:])) * ndbBlockSize
+		if start+ndbBlobHeaderSize > len(data) {
+			return nil, fmt.Errorf("blob of package %d out of bounds", index)
+		}
+
+		blob := data[start:]
+		if le.Uint32(blob) != ndbBlobMagic {
+			return nil, fmt.Errorf("invalid blob magic of package %d", index)
+		}
+		if i := le.Uint32(blob[4:]); i != index {
+			return nil, fmt.Errorf("blob of package %d refers to package %d", index, i)
+		}
+
+		length := int(le.Uint32(blob[12:]))
+		if ndbBlobHeaderSize+length > len(blob) {
+			return nil, fmt.Errorf("blob of package %d out of bounds", index)
+		}
+		blobs = append(blobs, blob[ndbBlobHeaderSize:ndbBlobHeaderSize+length])
+	}
+
+	return blobs, nil
+}
@@ -0,0 +1,200 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package rpmdb reads the list of installed packages from an RPM database.
+// All three formats used by rpm are supported: sqlite (rpmdb.sqlite), the
+// Berkeley DB hash database (Packages) and ndb (Packages.db). The databases
+// are read directly without relying on rpm, Berkeley DB or sqlite libraries.
+package rpmdb
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"runtime/trace"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/enterprise-contract/ec-cli/internal/utils/oci"
+)
+
+// Format is the format of the RPM database
+type Format string
+
+const (
+	FormatSQLite Format = "sqlite"
+	FormatBDB    Format = "bdb"
+	FormatNDB    Format = "ndb"
+)
+
+// Paths holds the locations of the RPM database within an image in the order
+// of preference
+var Paths = []struct {
+	Path   string
+	Format Format
+}{
+	{"usr/lib/sysimage/rpm/rpmdb.sqlite", FormatSQLite},
+	{"var/lib/rpm/rpmdb.sqlite", FormatSQLite},
+	{"usr/lib/sysimage/rpm/Packages.db", FormatNDB},
+	{"var/lib/rpm/Packages.db", FormatNDB},
+	{"usr/lib/sysimage/rpm/Packages", FormatBDB},
+	{"var/lib/rpm/Packages", FormatBDB},
+}
+
+// maxDatabaseSize is the maximum size of the RPM database read from an image,
+// the databases of images with thousands of packages take up to a few hundred
+// megabytes
+const maxDatabaseSize = 512 << 20
+
+// ErrNotFound is returned when the image does not contain an RPM database
+var ErrNotFound = errors.New("no RPM database found")
+
+// Package is an installed package as recorded in the RPM database
+type Package struct {
+	Name    string
+	Epoch   *int
+	Version string
+	Release string
+	Arch    string
+	Vendor  string
+	// SourceRPM is the file name of the source RPM the package was built from
+	SourceRPM string
+	// SignatureKeyID is the hex encoded ID of the key that signed the
+	// package, empty if the package is not signed
+	SignatureKeyID string
+}
+
+// NEVRA returns the name-[epoch:]version-release.arch of the package
+func (p Package) NEVRA() string {
+	var b strings.Builder
+	b.WriteString(p.Name)
+	b.WriteString("-")
+	if p.Epoch != nil {
+		fmt.Fprintf(&b, "%d:", *p.Epoch)
+	}
+	b.WriteString(p.Version)
+	b.WriteString("-")
+	b.WriteString(p.Release)
+	if p.Arch != "" {
+		b.WriteString(".")
+		b.WriteString(p.Arch)
+	}
+
+	return b.String()
+}
+
+// Parse reads the packages from the RPM database in the given format. The
+// gpg-pubkey pseudo packages holding the imported signing keys are omitted.
+func Parse(format Format, data []byte) ([]Package, error) {
+	var blobs [][]byte
+	var err error
+	switch format {
+	case FormatSQLite:
+		blobs, err = sqliteBlobs(data)
+	case FormatBDB:
+		blobs, err = bdbBlobs(data)
+	case FormatNDB:
+		blobs, err = ndbBlobs(data)
+	default:
+		return nil, fmt.Errorf("unsupported RPM database format: %q", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %s RPM database: %w", format, err)
+	}
+
+	packages := make([]Package, 0, len(blobs))
+	for _, blob := range blobs {
+		pkg, err := parseHeader(blob)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s RPM database: %w", format, err)
+		}
+
+		if pkg.Name == "gpg-pubkey" {
+			continue
+		}
+
+		packages = append(packages, *pkg)
+	}
+
+	return packages, nil
+}
+
+// ImagePackages returns the packages installed in the image by locating the
+// RPM database within the image's file system. ErrNotFound is returned if the
+// image doesn't contain an RPM database.
+func ImagePackages(ctx context.Context, ref name.Reference) ([]Package, error) {
+	if trace.IsEnabled() {
+		region := trace.StartRegion(ctx, "ec:image-fetch-rpms")
+		defer region.End()
+		trace.Logf(ctx, "", "image=%q", ref)
+	}
+
+	img, err := oci.NewClient(ctx).Image(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	content := mutate.Extract(img)
+	defer content.Close()
+	archive := tar.NewReader(content)
+
+	databases := map[string][]byte{}
+	for {
+		header, err := archive.Next()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		name := path.Clean(strings.TrimPrefix(header.Name, "/"))
+		for _, p := range Paths {
+			if name != p.Path {
+				continue
+			}
+
+			data, err := io.ReadAll(io.LimitReader(archive, maxDatabaseSize+1))
+			if err != nil {
+				return nil, err
+			}
+			if len(data) > maxDatabaseSize {
+				return nil, fmt.Errorf("the RPM database %s exceeds the maximum size of %d bytes", name, maxDatabaseSize)
+			}
+			databases[name] = data
+			break
+		}
+	}
+
+	for _, p := range Paths {
+		if data, ok := databases[p.Path]; ok {
+			log.Debugf("Reading %s RPM database from %s", p.Format, p.Path)
+			return Parse(p.Format, data)
+		}
+	}
+
+	return nil, ErrNotFound
+}
@@ -0,0 +1,584 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package rpmdb
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/enterprise-contract/ec-cli/internal/utils/oci"
+	"github.com/enterprise-contract/ec-cli/internal/utils/oci/fake"
+)
+
+// testdata/zlib.hdr holds the header of zlib-1.2.8-10.fc24.i686.rpm with the
+// RSA header signature appended, as rpm does when installing the package.
+// testdata/rpmdb.sqlite was created using sqlite with the schema rpm uses,
+// holding the zlib header followed by 40 headers similar to testHeader's.
+
+var zlib = Package{
+	Name:           "zlib",
+	Version:        "1.2.8",
+	Release:        "10.fc24",
+	Arch:           "i686",
+	Vendor:         "Fedora Project",
+	SourceRPM:      "zlib-1.2.8-10.fc24.src.rpm",
+	SignatureKeyID: "4089d8f2fdb19c98",
+}
+
+func zlibHeader(t *testing.T) []byte {
+	return mustReadFile(t, "testdata/zlib.hdr")
+}
+
+func epoch(e int) *int {
+	return &e
+}
+
+// testHeader creates a header blob with the given tags, string values are
+// stored as strings, int values as int32 and byte slices as binary
+func testHeader(tags map[int32]any) []byte {
+	var index, data bytes.Buffer
+	for tag, value := range tags {
+		var typ, count uint32
+		offset := data.Len()
+		switch v := value.(type) {
+		case string:
+			typ, count = typeString, 1
+			data.WriteString(v)
+			data.WriteByte(0)
+		case int:
+			for data.Len()%4 != 0 {
+				data.WriteByte(0)
+			}
+			offset = data.Len()
+			typ, count = typeInt32, 1
+			_ = binary.Write(&data, binary.BigEndian, int32(v))
+		case []byte:
+			typ, count = typeBin, uint32(len(v))
+			data.Write(v)
+		}
+		_ = binary.Write(&index, binary.BigEndian, []uint32{uint32(tag), typ, uint32(offset), count})
+	}
+
+	var blob bytes.Buffer
+	_ = binary.Write(&blob, binary.BigEndian, []uint32{uint32(len(tags)), uint32(data.Len())})
+	blob.Write(index.Bytes())
+	blob.Write(data.Bytes())
+
+	return blob.Bytes()
+}
+
+func packageHeader(name string, e *int) []byte {
+	tags := map[int32]any{
+		tagName:      name,
+		tagVersion:   "1.0",
+		tagRelease:   "1.fc40",
+		tagArch:      "x86_64",
+		tagVendor:    "Example",
+		tagSourceRPM: name + "-1.0-1.fc40.src.rpm",
+	}
+	if e != nil {
+		tags[tagEpoch] = *e
+	}
+
+	return testHeader(tags)
+}
+
+func examplePackage(name string, e *int) Package {
+	return Package{
+		Name:      name,
+		Epoch:     e,
+		Version:   "1.0",
+		Release:   "1.fc40",
+		Arch:      "x86_64",
+		Vendor:    "Example",
+		SourceRPM: name + "-1.0-1.fc40.src.rpm",
+	}
+}
+
+// ndbDatabase creates an ndb database holding the given blobs
+func ndbDatabase(blobs ...[]byte) []byte {
+	le := binary.LittleEndian
+	db := make([]byte, ndbPageSize)
+	le.PutUint32(db, ndbHeaderMagic)
+	le.PutUint32(db[12:], 1)
+
+	for i := ndbHeaderSlots; i < ndbPageSize/ndbSlotSize; i++ {
+		le.PutUint32(db[i*ndbSlotSize:], ndbSlotMagic)
+	}
+
+	for i, blob := range blobs {
+		slot := db[(ndbHeaderSlots+i)*ndbSlotSize:]
+		le.PutUint32(slot[4:], uint32(i+1))
+		le.PutUint32(slot[8:], uint32(len(db)/ndbBlockSize))
+
+		header := make([]byte, ndbBlobHeaderSize)
+		le.PutUint32(header, ndbBlobMagic)
+		le.PutUint32(header[4:], uint32(i+1))
+		le.PutUint32(header[12:], uint32(len(blob)))
+		db = append(db, header...)
+		db = append(db, blob...)
+		for len(db)%ndbBlockSize != 0 {
+			db = append(db, 0)
+		}
+	}
+
+	return db
+}
+
+// bdbDatabase creates a Berkeley DB hash database holding the given blobs as
+// values on overflow pages
+func bdbDatabase(order binary.ByteOrder, blobs ...[]byte) []byte {
+	const pageSize = 512
+	pages := [][]byte{make([]byte, pageSize), make([]byte, pageSize)}
+
+	meta := pages[0]
+	order.PutUint32(meta[12:], bdbHashMagic)
+	order.PutUint32(meta[20:], pageSize)
+	meta[25] = bdbHashMetaPage
+
+	hash := pages[1]
+	hash[25] = bdbHashPage
+	order.PutUint16(hash[20:], uint16(len(blobs)*2))
+	end := pageSize
+	for i, blob := range blobs {
+		// Key item holding the package number
+		end -= 8
+		order.PutUint16(hash[bdbPageHeaderSize+i*4:], uint16(end))
+		hash[end] = 1
+		order.PutUint32(hash[end+1:], uint32(i+1))
+
+		// Off page value item
+		end -= bdbOffPageItemSize
+		order.PutUint16(hash[bdbPageHeaderSize+i*4+2:], uint16(end))
+		hash[end] = bdbOffPageItem
+		order.PutUint32(hash[end+4:], uint32(len(pages)))
+		order.PutUint32(hash[end+8:], uint32(len(blob)))
+
+		for len(blob) > 0 {
+			page := make([]byte, pageSize)
+			page[25] = bdbOverflowPage
+			n := copy(page[bdbPageHeaderSize:], blob)
+			order.PutUint16(page[22:], uint16(n))
+			blob = blob[n:]
+			if len(blob) > 0 {
+				order.PutUint32(page[16:], uint32(len(pages)+1))
+			}
+			pages = append(pages, page)
+		}
+	}
+	order.PutUint32(meta[32:], uint32(len(pages)-1))
+
+	return bytes.Join(pages, nil)
+}
+
+// sqlitePackages returns the packages held in testdata/rpmdb.sqlite
+func sqlitePackages() []Package {
+	packages := []Package{zlib}
+	for i := 0; i < 40; i++ {
+		p := Package{
+			Name:      fmt.Sprintf("pkg%02d", i),
+			Version:   fmt.Sprintf("1.%d", i),
+			Release:   "1.fc40",
+			Arch:      "x86_64",
+			Vendor:    "Example",
+			SourceRPM: fmt.Sprintf("pkg%02d-1.%d-1.fc40.src.rpm", i, i),
+		}
+		if i%2 == 1 {
+			p.Epoch = epoch(1)
+		}
+		packages = append(packages, p)
+	}
+
+	return packages
+}
+
+func TestParse(t *testing.T) {
+	zlibBlob := zlibHeader(t)
+	pubkey := testHeader(map[int32]any{tagName: "gpg-pubkey", tagVersion: "fd431d51", tagRelease: "4ae0493b"})
+
+	cases := []struct {
+		name     string
+		format   Format
+		data     []byte
+		expected []Package
+	}{
+		{
+			name:     "sqlite",
+			format:   FormatSQLite,
+			data:     mustReadFile(t, "testdata/rpmdb.sqlite"),
+			expected: sqlitePackages(),
+		},
+		{
+			name:     "ndb",
+			format:   FormatNDB,
+			data:     ndbDatabase(zlibBlob, packageHeader("bash", epoch(0)), pubkey),
+			expected: []Package{zlib, examplePackage("bash", epoch(0))},
+		},
+		{
+			name:     "bdb little-endian",
+			format:   FormatBDB,
+			data:     bdbDatabase(binary.LittleEndian, zlibBlob, packageHeader("bash", nil), pubkey),
+			expected: []Package{zlib, examplePackage("bash", nil)},
+		},
+		{
+			name:     "bdb big-endian",
+			format:   FormatBDB,
+			data:     bdbDatabase(binary.BigEndian, packageHeader("bash", epoch(2)), zlibBlob),
+			expected: []Package{examplePackage("bash", epoch(2)), zlib},
+		},
+		{
+			name:     "empty ndb",
+			format:   FormatNDB,
+			data:     ndbDatabase(),
+			expected: []Package{},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			packages, err := Parse(c.format, c.data)
+			require.NoError(t, err)
+			assert.Equal(t, c.expected, packages)
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	sqlite := mustReadFile(t, "testdata/rpmdb.sqlite")
+
+	truncatedNDB := ndbDatabase(packageHeader("bash", nil))
+	truncatedNDB = truncatedNDB[:len(truncatedNDB)-32]
+
+	truncatedBDB := bdbDatabase(binary.LittleEndian, zlibHeader(t))
+	truncatedBDB = truncatedBDB[:len(truncatedBDB)-512]
+
+	cases := []struct {
+		name   string
+		format Format
+		data   []byte
+		err    string
+	}{
+		{
+			name:   "unknown format",
+			format: Format("dbm"),
+			err:    `unsupported RPM database format: "dbm"`,
+		},
+		{
+			name:   "not sqlite",
+			format: FormatSQLite,
+			data:   ndbDatabase(),
+			err:    "reading sqlite RPM database: not an sqlite database",
+		},
+		{
+			name:   "truncated sqlite",
+			format: FormatSQLite,
+			data:   sqlite[:8192],
+			err:    "reading sqlite RPM database: page 8 out of bounds",
+		},
+		{
+			name:   "not ndb",
+			format: FormatNDB,
+			data:   sqlite,
+			err:    "reading ndb RPM database: not an ndb database",
+		},
+		{
+			name:   "truncated ndb",
+			format: FormatNDB,
+			data:   truncatedNDB,
+			err:    "reading ndb RPM database: blob of package 1 out of bounds",
+		},
+		{
+			name:   "not bdb",
+			format: FormatBDB,
+			data:   sqlite,
+			err:    "reading bdb RPM database: not a Berkeley DB hash database",
+		},
+		{
+			name:   "truncated bdb",
+			format: FormatBDB,
+			data:   truncatedBDB,
+			err:    "reading bdb RPM database: entry 1 of page 1: page 30 out of bounds",
+		},
+		{
+			name:   "malformed header",
+			format: FormatNDB,
+			data:   ndbDatabase([]byte{0, 0, 0, 1, 0, 0, 0, 1}),
+			err:    "reading ndb RPM database: header blob of 8 bytes too short for 1 entries and 1 bytes of data",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := Parse(c.format, c.data)
+			assert.EqualError(t, err, c.err)
+		})
+	}
+}
+
+// sqliteDAG returns an sqlite database whose schema table b-tree has the given
+// number of interior levels, with each cell and the right-most pointer of a
+// level pointing to the single page of the next level. Walking the b-tree
+// without tracking the visited pages takes cells^levels steps.
+func sqliteDAG(levels, cells int) []byte {
+	const pageSize = 512
+
+	data := make([]byte, (levels+1)*pageSize)
+	copy(data, sqliteMagic)
+	binary.BigEndian.PutUint16(data[16:], pageSize)
+
+	for l := 0; l <= levels; l++ {
+		page := data[l*pageSize : (l+1)*pageSize]
+		if l == 0 {
+			page = page[sqliteHeaderSize:]
+		}
+
+		if l == levels {
+			page[0] = sqliteLeafTable
+			break
+		}
+
+		child := uint32(l + 2)
+		page[0] = sqliteInteriorTable
+		binary.BigEndian.PutUint16(page[3:], uint16(cells))
+		binary.BigEndian.PutUint32(page[8:], child)
+		for i := 0; i < cells; i++ {
+			// cells hold the child page number followed by a single byte row
+			// ID, and are placed at the end of the page
+			cellOffset := pageSize - (i+1)*5
+			binary.BigEndian.PutUint16(page[12+i*2:], uint16(cellOffset))
+			cell := data[l*pageSize+cellOffset:]
+			binary.BigEndian.PutUint32(cell, child)
+			cell[4] = byte(i)
+		}
+	}
+
+	return data
+}
+
+func TestSQLiteSharedPages(t *testing.T) {
+	done := make(chan error)
+	go func() {
+		_, err := Parse(FormatSQLite, sqliteDAG(10, 50))
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		assert.EqualError(t, err, "reading sqlite RPM database: page 11 is referenced more than once")
+	case <-time.After(10 * time.Second):
+		t.Fatal("parsing a database with shared pages did not finish")
+	}
+}
+
+func TestSQLiteRecordHeader(t *testing.T) {
+	cases := [][]byte{
+		{},
+		// the header size is smaller than its own varint
+		{0x00},
+		// the header size is larger than the record
+		{0x05, 0x01},
+	}
+
+	for _, c := range cases {
+		_, err := sqliteRecord(c)
+		assert.EqualError(t, err, "truncated record header")
+	}
+}
+
+// FuzzParse checks that malformed databases, as found in untrusted images,
+// are rejected without panicking
+func FuzzParse(f *testing.F) {
+	zlibBlob, err := os.ReadFile("testdata/zlib.hdr")
+	require.NoError(f, err)
+	sqlite, err := os.ReadFile("testdata/rpmdb.sqlite")
+	require.NoError(f, err)
+
+	f.Add(uint8(0), sqlite)
+	f.Add(uint8(0), []byte{0x00})
+	f.Add(uint8(0), sqliteDAG(10, 50))
+	f.Add(uint8(1), bdbDatabase(binary.LittleEndian, zlibBlob, packageHeader("bash", nil)))
+	f.Add(uint8(1), bdbDatabase(binary.BigEndian, packageHeader("bash", epoch(2))))
+	f.Add(uint8(2), ndbDatabase(zlibBlob, packageHeader("bash", epoch(0))))
+
+	formats := []Format{FormatSQLite, FormatBDB, FormatNDB}
+	f.Fuzz(func(t *testing.T, format uint8, data []byte) {
+		_, _ = Parse(formats[int(format)%len(formats)], data)
+	})
+}
+
+func TestSignatureKeyID(t *testing.T) {
+	fingerprint := []byte{
+		0x4, 0x6a, 0x6a, 0xa7, 0xc6, 0x9b, 0x7a, 0xdb, 0x93, 0x9f, 0xad,
+		0x5c, 0x86, 0x19, 0x9e, 0x2f, 0x91, 0xfd, 0x43, 0x1d, 0x51,
+	}
+
+	// v4 RSA signature with SHA256, the signature MPI is omitted
+	v4 := func(hashed, unhashed []byte) []byte {
+		body := []byte{4, 0, 1, 8, 0, byte(len(hashed))}
+		body = append(body, hashed...)
+		body = append(body, 0, byte(len(unhashed)))
+		body = append(body, unhashed...)
+		body = append(body, 0xab, 0xcd)
+		return append([]byte{0xc2, byte(len(body))}, body...)
+	}
+	creationTime := []byte{5, 2, 0x65, 0x00, 0x00, 0x00}
+	issuerFingerprint := append([]byte{22, 33}, fingerprint...)
+	issuer := []byte{9, 16, 0x19, 0x9e, 0x2f, 0x91, 0xfd, 0x43, 0x1d, 0x51}
+
+	cases := []struct {
+		name     string
+		sig      []byte
+		expected string
+		err      string
+	}{
+		{
+			name:     "v3 old format",
+			sig:      []byte{0x88, 0x0f, 3, 5, 0, 0x57, 0x00, 0x00, 0x00, 0x40, 0x89, 0xd8, 0xf2, 0xfd, 0xb1, 0x9c, 0x98, 1, 8},
+			expected: "4089d8f2fdb19c98",
+		},
+		{
+			name:     "v4 issuer",
+			sig:      v4(creationTime, issuer),
+			expected: "199e2f91fd431d51",
+		},
+		{
+			name:     "v4 issuer fingerprint",
+			sig:      v4(append(creationTime, issuerFingerprint...), nil),
+			expected: "199e2f91fd431d51",
+		},
+		{
+			name: "v4 without issuer",
+			sig:  v4(creationTime, nil),
+			err:  "signature packet without issuer",
+		},
+		{
+			name: "not a signature",
+			sig:  []byte{0x99, 0x00, 0x01, 4},
+			err:  "unexpected OpenPGP packet type 6",
+		},
+		{
+			name: "truncated packet",
+			sig:  []byte{0xc2, 0x20, 4},
+			err:  "truncated OpenPGP packet",
+		},
+		{
+			name: "unsupported version",
+			sig:  []byte{0xc2, 0x01, 2},
+			err:  "unsupported signature packet version 2",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			id, err := signatureKeyID(c.sig)
+			if c.err != "" {
+				assert.EqualError(t, err, c.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, c.expected, id)
+		})
+	}
+}
+
+func TestNEVRA(t *testing.T) {
+	assert.Equal(t, "zlib-1.2.8-10.fc24.i686", zlib.NEVRA())
+	assert.Equal(t, "bash-0:1.0-1.fc40.x86_64", examplePackage("bash", epoch(0)).NEVRA())
+	assert.Equal(t, "bash-2:1.0-1.fc40.x86_64", examplePackage("bash", epoch(2)).NEVRA())
+	assert.Equal(t, "gpg-pubkey-fd431d51-4ae0493b", Package{Name: "gpg-pubkey", Version: "fd431d51", Release: "4ae0493b"}.NEVRA())
+}
+
+func TestImagePackages(t *testing.T) {
+	ref := name.MustParseReference("registry.io/repository/image:tag")
+
+	cases := []struct {
+		name     string
+		files    map[string][]byte
+		expected []Package
+		err      error
+	}{
+		{
+			name: "sqlite preferred",
+			files: map[string][]byte{
+				"var/lib/rpm/Packages":              bdbDatabase(binary.LittleEndian, packageHeader("bash", nil)),
+				"usr/lib/sysimage/rpm/rpmdb.sqlite": mustReadFile(t, "testdata/rpmdb.sqlite"),
+			},
+			expected: sqlitePackages(),
+		},
+		{
+			name: "ndb",
+			files: map[string][]byte{
+				"etc/os-release":          []byte("ID=fedora"),
+				"var/lib/rpm/Packages.db": ndbDatabase(packageHeader("bash", nil)),
+			},
+			expected: []Package{examplePackage("bash", nil)},
+		},
+		{
+			name: "bdb",
+			files: map[string][]byte{
+				"var/lib/rpm/Packages": bdbDatabase(binary.LittleEndian, packageHeader("bash", nil)),
+			},
+			expected: []Package{examplePackage("bash", nil)},
+		},
+		{
+			name: "no database",
+			files: map[string][]byte{
+				"etc/os-release": []byte("ID=alpine"),
+			},
+			err: ErrNotFound,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			image, err := crane.Image(c.files)
+			require.NoError(t, err)
+
+			client := fake.FakeClient{}
+			client.On("Image", ref).Return(image, nil)
+
+			ctx := oci.WithClient(context.Background(), &client)
+
+			packages, err := ImagePackages(ctx, ref)
+			if c.err != nil {
+				assert.ErrorIs(t, err, c.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, c.expected, packages)
+		})
+	}
+}
+
+func mustReadFile(t *testing.T, path string) []byte {
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	return data
+}
@@ -0,0 +1,383 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package rpmdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// The sqlite file format is documented at https://www.sqlite.org/fileformat.html,
+// only what is needed to walk the table b-trees is implemented here. Changes
+// held in a write-ahead log are not taken into account, rpm checkpoints the log
+// when it closes the database.
+
+const (
+	sqliteMagic          = "SQLite format 3\x00"
+	sqliteHeaderSize     = 100
+	sqliteInteriorTable  = 0x05
+	sqliteLeafTable      = 0x0d
+	sqliteMaxTreeDepth   = 64
+	sqliteMaxPages       = 1 << 20
+	sqliteMaxCells       = 1 << 20
+	sqlitePackagesTable  = "Packages"
+	sqliteSchemaRootPage = 1
+)
+
+// sqliteDB walks the pages of a database, each page is visited at most once
+// so that malformed databases with pages referenced from multiple places,
+// e.g. b-trees forming cycles or sharing subtrees, are rejected instead of
+// being walked over and over
+type sqliteDB struct {
+	data     []byte
+	pageSize int
+	usable   int
+	visited  map[int]bool
+	cells    int
+}
+
+// sqliteBlobs returns the header blobs held in the Packages table
+func sqliteBlobs(data []byte) ([][]byte, error) {
+	db, err := newSQLiteDB(data)
+	if err != nil {
+		return nil, err
+	}
+
+	root := 0
+	err = db.walk(sqliteSchemaRootPage, 0, func(record []byte) error {
+		values, err := sqliteRecord(record)
+		if err != nil {
+			return err
+		}
+
+		// The schema table columns are: type, name, tbl_name, rootpage and sql
+		if len(values) < 4 {
+			return errors.New("unexpected schema table record")
+		}
+		typ, _ := values[0].([]byte)
+		name, _ := values[1].([]byte)
+		if string(typ) == "table" && string(name) == sqlitePackagesTable {
+			if page, ok := values[3].(int64); ok {
+				root = int(page)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if root == 0 {
+		return nil, fmt.Errorf("table %s not found", sqlitePackagesTable)
+	}
+
+	var blobs [][]byte
+	err = db.walk(root, 0, func(record []byte) error {
+		values, err := sqliteRecord(record)
+		if err != nil {
+			return err
+		}
+
+		// The Packages table columns are: hnum, an alias of the row ID, and blob
+		if len(values) < 2 {
+			return errors.New("unexpected Packages table record")
+		}
+		blob, ok := values[1].([]byte)
+		if !ok {
+			return errors.New("unexpected Packages table blob")
+		}
+		blobs = append(blobs, blob)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return blobs, nil
+}
+
+func newSQLiteDB(data []byte) (*sqliteDB, error) {
+	if len(data) < sqliteHeaderSize || !bytes.HasPrefix(data, []byte(sqliteMagic)) {
+		return nil, errors.New("not an sqlite database")
+	}
+
+	pageSize := int(binary.BigEndian.Uint16(data[16:18]))
+	if pageSize == 1 {
+		pageSize = 65536
+	}
+	if pageSize < 512 || pageSize&(pageSize-1) != 0 {
+		return nil, fmt.Errorf("invalid page size: %d", pageSize)
+	}
+
+	usable := pageSize - int(data[20])
+	if usable < 480 {
+		return nil, fmt.Errorf("invalid usable page size: %d", usable)
+	}
+
+	return &sqliteDB{data: data, pageSize: pageSize, usable: usable, visited: map[int]bool{}}, nil
+}
+
+// visit returns the page with the given number, failing if the page has
+// already been visited or if too many pages have been visited
+func (db *sqliteDB) visit(number int) ([]byte, error) {
+	if db.visited[number] {
+		return nil, fmt.Errorf("page %d is referenced more than once", number)
+	}
+	if len(db.visited) >= sqliteMaxPages {
+		return nil, errors.New("too many pages")
+	}
+
+	page, err := db.page(number)
+	if err != nil {
+		return nil, err
+	}
+	db.visited[number] = true
+
+	return page, nil
+}
+
+func (db *sqliteDB) page(number int) ([]byte, error) {
+	start := (number - 1) * db.pageSize
+	if number < 1 || start+db.pageSize > len(db.data) {
+		return nil, fmt.Errorf("page %d out of bounds", number)
+	}
+
+	return db.data[start : start+db.pageSize], nil
+}
+
+// walk calls fn with the payload of every record of the table b-tree rooted
+// at the given page
+func (db *sqliteDB) walk(number, depth int, fn func([]byte) error) error {
+	if depth > sqliteMaxTreeDepth {
+		return errors.New("table b-tree too deep")
+	}
+
+	page, err := db.visit(number)
+	if err != nil {
+		return err
+	}
+
+	// The first page starts with the database header
+	offset := 0
+	if number == 1 {
+		offset = sqliteHeaderSize
+	}
+
+	if len(page) < offset+12 {
+		return fmt.Errorf("page %d too short", number)
+	}
+	typ := page[offset]
+	cells := int(binary.BigEndian.Uint16(page[offset+3:]))
+
+	headerSize := 8
+	if typ == sqliteInteriorTable {
+		headerSize = 12
+	} else if typ != sqliteLeafTable {
+		return fmt.Errorf("unexpected page type %#x of page %d", typ, number)
+	}
+
+	if offset+headerSize+cells*2 > len(page) {
+		return fmt.Errorf("too many cells in page %d", number)
+	}
+
+	if db.cells += cells; db.cells > sqliteMaxCells {
+		return errors.New("too many cells")
+	}
+
+	for i := 0; i < cells; i++ {
+		cellOffset := int(binary.BigEndian.Uint16(page[offset+headerSize+i*2:]))
+		if cellOffset >= len(page) {
+			return fmt.Errorf("cell %d of page %d out of bounds", i, number)
+		}
+		cell := page[cellOffset:]
+
+		if typ == sqliteInteriorTable {
+			if len(cell) < 4 {
+				return fmt.Errorf("truncated cell %d of page %d", i, number)
+			}
+			if err := db.walk(int(binary.BigEndian.Uint32(cell)), depth+1, fn); err != nil {
+				return err
+			}
+			continue
+		}
+
+		size, n := sqliteVarint(cell)
+		if n == 0 {
+			return fmt.Errorf("truncated cell %d of page %d", i, number)
+		}
+		cell = cell[n:]
+
+		// Row ID
+		if _, n = sqliteVarint(cell); n == 0 {
+			return fmt.Errorf("truncated cell %d of page %d", i, number)
+		}
+		cell = cell[n:]
+
+		payload, err := db.payload(cell, size)
+		if err != nil {
+			return fmt.Errorf("cell %d of page %d: %w", i, number, err)
+		}
+
+		if err := fn(payload); err != nil {
+			return err
+		}
+	}
+
+	if typ == sqliteInteriorTable {
+		return db.walk(int(binary.BigEndian.Uint32(page[offset+8:])), depth+1, fn)
+	}
+
+	return nil
+}
+
+// payload returns the payload of a table leaf cell, following the overflow
+// pages for payloads that don't fit into the cell
+func (db *sqliteDB) payload(cell []byte, size uint64) ([]byte, error) {
+	if size > uint64(len(db.data)) {
+		return nil, fmt.Errorf("payload size %d out of bounds", size)
+	}
+	remaining := int(size)
+
+	// The amount of payload stored within the cell
+	local := remaining
+	if maxLocal := db.usable - 35; remaining > maxLocal {
+		minLocal := (db.usable-12)*32/255 - 23
+		local = minLocal + (remaining-minLocal)%(db.usable-4)
+		if local > maxLocal {
+			local = minLocal
+		}
+	}
+
+	if len(cell) < local {
+		return nil, errors.New("truncated payload")
+	}
+
+	payload := make([]byte, 0, remaining)
+	payload = append(payload, cell[:local]...)
+	remaining -= local
+	if remaining == 0 {
+		return payload, nil
+	}
+
+	if len(cell) < local+4 {
+		return nil, errors.New("truncated payload")
+	}
+	next := int(binary.BigEndian.Uint32(cell[local:]))
+	for remaining > 0 {
+		if next == 0 {
+			return nil, errors.New("truncated overflow chain")
+		}
+
+		page, err := db.visit(next)
+		if err != nil {
+			return nil, err
+		}
+
+		n := min(remaining, db.usable-4)
+		payload = append(payload, page[4:4+n]...)
+		remaining -= n
+		next = int(binary.BigEndian.Uint32(page))
+	}
+
+	return payload, nil
+}
+
+// sqliteVarint decodes a variable length integer, returning the number of
+// bytes read or 0 if the integer is truncated
+func sqliteVarint(b []byte) (uint64, int) {
+	var v uint64
+	for i := 0; i < 9 && i < len(b); i++ {
+		if i == 8 {
+			return v<<8 | uint64(b[i]), 9
+		}
+		v = v<<7 | uint64(b[i]&0x7f)
+		if b[i]&0x80 == 0 {
+			return v, i + 1
+		}
+	}
+
+	return 0, 0
+}
+
+// sqliteRecord decodes a record into its values: nil, int64 or []byte for
+// text and blobs. Floating point values are not supported as rpm doesn't use
+// them.
+func sqliteRecord(record []byte) ([]any, error) {
+	headerSize, n := sqliteVarint(record)
+	if n == 0 || headerSize < uint64(n) || headerSize > uint64(len(record)) {
+		return nil, errors.New("truncated record header")
+	}
+
+	var types []uint64
+	for header := record[n:headerSize]; len(header) > 0; {
+		t, n := sqliteVarint(header)
+		if n == 0 {
+			return nil, errors.New("truncated record header")
+		}
+		types = append(types, t)
+		header = header[n:]
+	}
+
+	body := record[headerSize:]
+	values := make([]any, 0, len(types))
+	for _, t := range types {
+		var size uint64
+		switch {
+		case t == 0, t == 8, t == 9:
+		case t >= 1 && t <= 4:
+			size = t
+		case t == 5:
+			size = 6
+		case t == 6:
+			size = 8
+		case t >= 12:
+			size = (t - 12) / 2
+		default:
+			return nil, fmt.Errorf("unsupported record serial type %d", t)
+		}
+
+		if size > uint64(len(body)) {
+			return nil, errors.New("truncated record")
+		}
+		content := body[:size]
+		body = body[size:]
+
+		switch {
+		case t == 0:
+			values = append(values, nil)
+		case t == 8:
+			values = append(values, int64(0))
+		case t == 9:
+			values = append(values, int64(1))
+		case t >= 12:
+			values = append(values, content)
+		default:
+			// Big-endian two's complement integer
+			v := int64(int8(content[0]))
+			for _, b := range content[1:] {
+				v = v<<8 | int64(b)
+			}
+			values = append(values, v)
+		}
+	}
+
+	return values, nil
+}
@@ -0,0 +1,163 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package signature
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"time"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	"github.com/sigstore/cosign/v2/pkg/oci"
+	"github.com/sigstore/cosign/v2/pkg/oci/static"
+	cosigntypes "github.com/sigstore/cosign/v2/pkg/types"
+	"github.com/sigstore/sigstore-go/pkg/root"
+	"github.com/sigstore/sigstore-go/pkg/verify"
+	sigstoreSig "github.com/sigstore/sigstore/pkg/signature"
+)
+
+// BundleOptions hold the trusted material and the expectations a Sigstore
+// bundle is verified against
+type BundleOptions struct {
+	// TrustedMaterial holds the certificate authorities, the transparency
+	// logs and the timestamping authorities, typically from a trusted_root.json
+	TrustedMaterial root.TrustedMaterial
+	// PublicKey, when set, is the key the bundle needs to be signed with,
+	// otherwise a certificate matching one of the Identities is required
+	PublicKey sigstoreSig.Verifier
+	// Identities are the certificate identities accepted in the keyless
+	// workflow
+	Identities []cosign.Identity
+	// IgnoreTlog skips the transparency log inclusion verification
+	IgnoreTlog bool
+	// IgnoreSCT skips the signed certificate timestamp verification
+	IgnoreSCT bool
+}
+
+// VerifyBundle verifies a Sigstore bundle, or any other signed entity, signed
+// for the artifact with the given digest. The verification is performed
+// offline using only the provided trusted material: the certificate chain or
+// the public key, the transparency log inclusion proof or promise and the
+// RFC3161 timestamps. On success the bundle is returned in the same form as
+// cosign signatures: an attestation holding the DSSE envelope, or a signature
+// holding the message signature.
+func VerifyBundle(entity verify.SignedEntity, digest v1.Hash, opts BundleOptions) (oci.Signature, error) {
+	if opts.TrustedMaterial == nil {
+		return nil, errors.New("no trusted material provided to verify the bundle")
+	}
+
+	timestamps, err := entity.Timestamps()
+	if err != nil {
+		return nil, err
+	}
+
+	trusted := opts.TrustedMaterial
+	var config []verify.VerifierOption
+	var policy []verify.PolicyOption
+
+	if opts.PublicKey != nil {
+		key := root.NewExpiringKey(opts.PublicKey, time.Time{}, time.Time{})
+		trusted = root.TrustedMaterialCollection{
+			opts.TrustedMaterial,
+			root.NewTrustedPublicKeyMaterial(func(string) (root.TimeConstrainedVerifier, error) {
+				return key, nil
+			}),
+		}
+		policy = append(policy, verify.WithKey())
+	} else {
+		if len(opts.Identities) == 0 {
+			return nil, errors.New("no certificate identity provided to verify the bundle")
+		}
+		for _, i := range opts.Identities {
+			identity, err := verify.NewShortCertificateIdentity(i.Issuer, i.IssuerRegExp, i.Subject, i.SubjectRegExp)
+			if err != nil {
+				return nil, err
+			}
+			policy = append(policy, verify.WithCertificateIdentity(identity))
+		}
+		if !opts.IgnoreSCT {
+			config = append(config, verify.WithSignedCertificateTimestamps(1))
+		}
+	}
+
+	switch {
+	case !opts.IgnoreTlog:
+		config = append(config, verify.WithTransparencyLog(1), verify.WithObserverTimestamps(1))
+	case len(timestamps) > 0 || opts.PublicKey == nil:
+		// without the transparency log the certificate can only be verified
+		// using the signed timestamps
+		config = append(config, verify.WithSignedTimestamps(1))
+	default:
+		// a long-lived key does not need the time of signing to be observed
+		config = append(config, verify.WithoutAnyObserverTimestampsUnsafe())
+	}
+
+	verifier, err := verify.NewSignedEntityVerifier(trusted, config...)
+	if err != nil {
+		return nil, err
+	}
+
+	digestBytes, err := hex.DecodeString(digest.Hex)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := verifier.Verify(entity, verify.NewPolicy(verify.WithArtifactDigest(digest.Algorithm, digestBytes), policy...)); err != nil {
+		return nil, fmt.Errorf("bundle verification failed: %w", err)
+	}
+
+	return bundleSignature(entity)
+}
+
+// bundleSignature converts the content of the verified bundle into the form
+// of cosign signatures
+func bundleSignature(entity verify.SignedEntity) (oci.Signature, error) {
+	var opts []static.Option
+
+	verification, err := entity.VerificationContent()
+	if err != nil {
+		return nil, err
+	}
+	if cert := verification.GetCertificate(); cert != nil {
+		opts = append(opts, static.WithCertChain(pem.EncodeToMemory(&pem.Block{
+			Type:  "CERTIFICATE",
+			Bytes: cert.Raw,
+		}), nil))
+	}
+
+	content, err := entity.SignatureContent()
+	if err != nil {
+		return nil, err
+	}
+
+	if envelope := content.EnvelopeContent(); envelope != nil {
+		payload, err := json.Marshal(envelope.RawEnvelope())
+		if err != nil {
+			return nil, err
+		}
+
+		opts = append(opts, static.WithLayerMediaType(cosigntypes.DssePayloadType))
+		return static.NewAttestation(payload, opts...)
+	}
+
+	return static.NewSignature(nil, base64.StdEncoding.EncodeToString(content.Signature()), opts...)
+}
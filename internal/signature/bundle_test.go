@@ -0,0 +1,181 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package signature
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/in-toto/in-toto-golang/in_toto"
+	"github.com/secure-systems-lab/go-securesystemslib/dsse"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	"github.com/sigstore/sigstore-go/pkg/testing/ca"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	bundleIdentity = "signer@example.com"
+	bundleIssuer   = "https://issuer.example.com"
+)
+
+func statementFor(t *testing.T, digest v1.Hash) []byte {
+	statement, err := json.Marshal(in_toto.Statement{
+		StatementHeader: in_toto.StatementHeader{
+			Type:          in_toto.StatementInTotoV01,
+			PredicateType: "https://example.com/predicate/v1",
+			Subject: []in_toto.Subject{
+				{Name: "registry.io/repository/image", Digest: map[string]string{digest.Algorithm: digest.Hex}},
+			},
+		},
+		Predicate: map[string]any{},
+	})
+	require.NoError(t, err)
+
+	return statement
+}
+
+func TestVerifyBundleAttestation(t *testing.T) {
+	vs, err := ca.NewVirtualSigstore()
+	require.NoError(t, err)
+
+	digest := v1.Hash{Algorithm: "sha256", Hex: "4e388ab32b10dc8dbc7e28144f552830adc74787c1e2c0824032078a79f227fb"}
+
+	entity, err := vs.Attest(bundleIdentity, bundleIssuer, statementFor(t, digest))
+	require.NoError(t, err)
+
+	identity := cosign.Identity{Issuer: bundleIssuer, Subject: bundleIdentity}
+
+	cases := []struct {
+		name   string
+		digest v1.Hash
+		opts   BundleOptions
+		err    string
+	}{
+		{
+			name:   "verified",
+			digest: digest,
+			opts:   BundleOptions{TrustedMaterial: vs, Identities: []cosign.Identity{identity}, IgnoreSCT: true},
+		},
+		{
+			name:   "verified with identity regular expressions",
+			digest: digest,
+			opts: BundleOptions{TrustedMaterial: vs, Identities: []cosign.Identity{
+				{IssuerRegExp: `^https://issuer\.example\.com$`, SubjectRegExp: `@example\.com$`},
+			}, IgnoreSCT: true},
+		},
+		{
+			name:   "verified using the signed timestamps without the transparency log",
+			digest: digest,
+			opts:   BundleOptions{TrustedMaterial: vs, Identities: []cosign.Identity{identity}, IgnoreSCT: true, IgnoreTlog: true},
+		},
+		{
+			name:   "unexpected identity",
+			digest: digest,
+			opts:   BundleOptions{TrustedMaterial: vs, Identities: []cosign.Identity{{Issuer: bundleIssuer, Subject: "someone@example.com"}}, IgnoreSCT: true},
+			err:    "bundle verification failed: failed to verify certificate identity",
+		},
+		{
+			name:   "different subject",
+			digest: v1.Hash{Algorithm: "sha256", Hex: "0000000000000000000000000000000000000000000000000000000000000000"},
+			opts:   BundleOptions{TrustedMaterial: vs, Identities: []cosign.Identity{identity}, IgnoreSCT: true},
+			err:    "provided artifact digest does not match any digest in statement",
+		},
+		{
+			name:   "missing signed certificate timestamp",
+			digest: digest,
+			opts:   BundleOptions{TrustedMaterial: vs, Identities: []cosign.Identity{identity}},
+			err:    "bundle verification failed: failed to verify signed certificate timestamp",
+		},
+		{
+			name:   "no identity",
+			digest: digest,
+			opts:   BundleOptions{TrustedMaterial: vs, IgnoreSCT: true},
+			err:    "no certificate identity provided to verify the bundle",
+		},
+		{
+			name:   "no trusted material",
+			digest: digest,
+			opts:   BundleOptions{Identities: []cosign.Identity{identity}},
+			err:    "no trusted material provided to verify the bundle",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			sig, err := VerifyBundle(entity, c.digest, c.opts)
+			if c.err != "" {
+				require.Error(t, err)
+				assert.ErrorContains(t, err, c.err)
+				return
+			}
+			require.NoError(t, err)
+
+			payload, err := sig.Payload()
+			require.NoError(t, err)
+			var envelope dsse.Envelope
+			require.NoError(t, json.Unmarshal(payload, &envelope))
+			assert.Equal(t, "application/vnd.in-toto+json", envelope.PayloadType)
+			assert.Len(t, envelope.Signatures, 1)
+
+			es, err := NewEntitySignature(sig)
+			require.NoError(t, err)
+			assert.NotEmpty(t, es.Certificate)
+			assert.Contains(t, es.Metadata["Subject Alternative Name"], bundleIdentity)
+		})
+	}
+}
+
+func TestVerifyBundleMessageSignature(t *testing.T) {
+	vs, err := ca.NewVirtualSigstore()
+	require.NoError(t, err)
+
+	artifact := []byte(`{"schemaVersion": 2}`)
+	digest := v1.Hash{Algorithm: "sha256", Hex: fmt.Sprintf("%x", sha256.Sum256(artifact))}
+
+	entity, err := vs.Sign(bundleIdentity, bundleIssuer, artifact)
+	require.NoError(t, err)
+
+	opts := BundleOptions{
+		TrustedMaterial: vs,
+		Identities:      []cosign.Identity{{Issuer: bundleIssuer, Subject: bundleIdentity}},
+		IgnoreSCT:       true,
+	}
+
+	sig, err := VerifyBundle(entity, digest, opts)
+	require.NoError(t, err)
+
+	b64sig, err := sig.Base64Signature()
+	require.NoError(t, err)
+	content, err := entity.SignatureContent()
+	require.NoError(t, err)
+	assert.Equal(t, base64.StdEncoding.EncodeToString(content.Signature()), b64sig)
+
+	es, err := NewEntitySignature(sig)
+	require.NoError(t, err)
+	assert.Equal(t, b64sig, es.Signature)
+	assert.NotEmpty(t, es.Certificate)
+
+	_, err = VerifyBundle(entity, v1.Hash{Algorithm: "sha256", Hex: fmt.Sprintf("%x", sha256.Sum256([]byte("other")))}, opts)
+	assert.ErrorContains(t, err, "bundle verification failed")
+}
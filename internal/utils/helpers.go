@@ -98,6 +98,8 @@ func Experimental() bool {
 	return os.Getenv("EC_EXPERIMENTAL") == "1"
 }
 
+// detect if the EC_USE_OPA env var is set to evaluate policies using the
+// native OPA evaluator instead of the Conftest based one
 func IsOpaEnabled() bool {
 	return os.Getenv("EC_USE_OPA") == "1"
 }
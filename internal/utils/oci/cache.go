@@ -0,0 +1,654 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oci
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"path/filepath"
+	"runtime"
+	"runtime/trace"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/enterprise-contract/ec-cli/internal/cache"
+	"github.com/enterprise-contract/ec-cli/internal/utils"
+)
+
+const (
+	// memoryCacheSize is the maximum total size of the manifests and config
+	// blobs held in the in-memory tier
+	memoryCacheSize = 64 << 20
+
+	// descriptorCacheSize is the maximum number of descriptors held in the
+	// in-memory tier
+	descriptorCacheSize = 4096
+
+	// diskPruneFraction is the fraction of the maximum size of the on-disk
+	// tier written between prunes of the on-disk tier
+	diskPruneFraction = 16
+)
+
+// defaultPlatform is the platform of the image an index resolves to, the same
+// as the default of the registry client
+var defaultPlatform = v1.Platform{OS: "linux", Architecture: "amd64"}
+
+// CacheStats holds the number of lookups of manifests and blobs served from
+// each tier of the OCI cache, and the number of lookups that had to be served
+// from the registry
+type CacheStats struct {
+	MemoryHits int64
+	DiskHits   int64
+	Misses     int64
+}
+
+var (
+	// memoryCache holds the raw manifests and config blobs fetched by digest,
+	// shared by all clients within the process. Only the content is held, the
+	// images, indexes and layers are built from it for each client so that
+	// those use the context and the options of that client.
+	memoryCache = cache.NewMemory[string, []byte](memoryCacheSize, func(raw []byte) int64 {
+		return int64(len(raw))
+	})
+
+	// descriptorCache holds the descriptors of the manifests fetched by digest
+	descriptorCache = cache.NewMemory[string, v1.Descriptor](descriptorCacheSize, nil)
+
+	// contentFlight makes concurrent lookups of the same content fetch it
+	// only once
+	contentFlight singleflight.Group
+
+	memoryHits atomic.Int64
+	diskHits   atomic.Int64
+	misses     atomic.Int64
+
+	// diskPruned and diskWritten track when the on-disk tier was last
+	// pruned by the process, see prune
+	diskPruned  atomic.Bool
+	diskWritten atomic.Int64
+)
+
+// Stats returns the number of lookups served by each tier of the OCI cache
+// since the start of the process
+func Stats() CacheStats {
+	return CacheStats{
+		MemoryHits: memoryHits.Load(),
+		DiskHits:   diskHits.Load(),
+		Misses:     misses.Load(),
+	}
+}
+
+// cachingClient serves the images, indexes, layers and descriptors referenced
+// by digest from the cache, and from the wrapped client only when those are
+// not cached. As the content referenced by digest cannot change, the cached
+// content never needs to be refreshed. The in-memory tier holds the manifests
+// and config blobs, up to memoryCacheSize. The on-disk tier holds the
+// manifests and all blobs in the ImageStoreName area of the cache directory,
+// shared with the image cache of the wrapped client, so that the blobs shared
+// between images are fetched only once. The on-disk tier is kept within the
+// maximum size of the cache by removing the least recently accessed content. References by tag are always resolved
+// by the wrapped client.
+type cachingClient struct {
+	Client
+	ctx context.Context
+	fs  afero.Fs
+	dir string
+	// store is the area of the cache directory holding the on-disk tier, used
+	// to keep it within the maximum size of the cache
+	store *cache.Store
+	// pruneEvery is the number of bytes written to the on-disk tier after
+	// which it is pruned again, see prune
+	pruneEvery int64
+}
+
+// newCachingClient returns a client caching the content fetched by the given
+// client, with the on-disk tier in the ImageStoreName area of the cache
+// directory, or without the on-disk tier if the cache directory is empty
+func newCachingClient(ctx context.Context, client Client, c cache.Config) *cachingClient {
+	cc := &cachingClient{Client: client, ctx: ctx, fs: utils.FS(ctx)}
+	if c.Dir != "" {
+		cc.store = cache.NewStore(cc.fs, c, ImageStoreName)
+		cc.dir = filepath.Join(c.Dir, ImageStoreName)
+		cc.pruneEvery = c.MaxSize / diskPruneFraction
+	}
+
+	return cc
+}
+
+func (c *cachingClient) record(kind string, ref name.Digest, tier string, counter *atomic.Int64) {
+	counter.Add(1)
+
+	if trace.IsEnabled() {
+		trace.Logf(c.ctx, "ec:oci-cache", "%s %s: %s", kind, ref, tier)
+	}
+}
+
+// content returns the raw manifest or blob with the digest of the given
+// reference from the cache, or fetches it and stores it in the cache
+func (c *cachingClient) content(kind string, ref name.Digest, fetch func() ([]byte, error)) ([]byte, error) {
+	h, err := v1.NewHash(ref.DigestStr())
+	if err != nil {
+		return nil, err
+	}
+
+	if raw, ok := memoryCache.Get(h.String()); ok {
+		c.record(kind, ref, "memory", &memoryHits)
+		return raw, nil
+	}
+
+	load := func() (any, error) {
+		return c.load(kind, ref, h, fetch)
+	}
+
+	v, err, shared := contentFlight.Do(h.String(), load)
+	if err != nil && shared && c.ctx.Err() == nil && (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)) {
+		// the context of the client that fetched the content on behalf of
+		// this one ended, fetch it again with the context of this client
+		v, err = load()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return v.([]byte), nil
+}
+
+func (c *cachingClient) load(kind string, ref name.Digest, h v1.Hash, fetch func() ([]byte, error)) ([]byte, error) {
+	if raw, ok := c.fromDisk(h); ok {
+		c.record(kind, ref, "disk", &diskHits)
+		memoryCache.Add(h.String(), raw)
+		return raw, nil
+	}
+
+	c.record(kind, ref, "miss", &misses)
+	raw, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+
+	if digest, _, err := v1.SHA256(bytes.NewReader(raw)); err != nil || digest != h {
+		return nil, fmt.Errorf("content of %s doesn't match its digest", ref)
+	}
+
+	if c.dir != "" {
+		if err := c.toDisk(ref, h, bytes.NewReader(raw)); err != nil {
+			log.Debugf("Not storing %s in the on-disk OCI cache: %v", ref, err)
+		}
+	}
+	memoryCache.Add(h.String(), raw)
+
+	return raw, nil
+}
+
+// path returns the path of the content with the given digest in the on-disk
+// tier, the same as the path the image cache uses for the blobs it stores
+func (c *cachingClient) path(h v1.Hash) string {
+	if runtime.GOOS == "windows" {
+		return filepath.Join(c.dir, fmt.Sprintf("%s-%s", h.Algorithm, h.Hex))
+	}
+
+	return filepath.Join(c.dir, h.String())
+}
+
+// fromDisk returns the content with the given digest from the on-disk tier,
+// content not matching its digest, e.g. partially written, is removed
+func (c *cachingClient) fromDisk(h v1.Hash) ([]byte, bool) {
+	if c.dir == "" || h.Algorithm != "sha256" {
+		return nil, false
+	}
+
+	path := c.path(h)
+	raw, err := afero.ReadFile(c.fs, path)
+	if err != nil {
+		return nil, false
+	}
+
+	if sum := sha256.Sum256(raw); hex.EncodeToString(sum[:]) != h.Hex {
+		log.Debugf("Removing %s from the on-disk OCI cache, its content doesn't match its digest", h)
+		_ = c.fs.Remove(path)
+		return nil, false
+	}
+	c.touch(path)
+
+	return raw, true
+}
+
+// touch records the access to the content at the given path of the on-disk
+// tier, the least recently accessed content is pruned first
+func (c *cachingClient) touch(path string) {
+	now := time.Now()
+	if err := c.fs.Chtimes(path, now, now); err != nil {
+		log.Debugf("Unable to record the access to %s in the on-disk OCI cache: %v", path, err)
+	}
+}
+
+// prune removes the least recently accessed content from the on-disk tier to
+// keep it within the maximum size of the cache. As pruning needs to read the
+// whole on-disk tier, it is done before the first write of the process, and
+// then each time pruneEvery bytes were written.
+func (c *cachingClient) prune() {
+	if diskPruned.Load() && diskWritten.Load() < c.pruneEvery {
+		return
+	}
+	diskPruned.Store(true)
+	diskWritten.Store(0)
+
+	if _, err := c.store.Prune(); err != nil {
+		log.Debugf("Unable to prune the on-disk OCI cache: %v", err)
+	}
+}
+
+// toDisk stores the content with the given digest in the on-disk tier. The
+// content is stored only if it matches the digest.
+func (c *cachingClient) toDisk(ref name.Digest, h v1.Hash, r io.Reader) error {
+	c.prune()
+
+	if err := c.fs.MkdirAll(c.dir, 0700); err != nil {
+		return err
+	}
+
+	tmp, err := afero.TempFile(c.fs, c.dir, cache.TempPrefix)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = c.fs.Remove(tmp.Name())
+	}()
+
+	hasher := sha256.New()
+	n, err := io.Copy(io.MultiWriter(tmp, hasher), r)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	diskWritten.Add(n)
+
+	if sum := hex.EncodeToString(hasher.Sum(nil)); h.Algorithm != "sha256" || sum != h.Hex {
+		return fmt.Errorf("content of %s doesn't match its digest, got sha256:%s", ref, sum)
+	}
+
+	return c.fs.Rename(tmp.Name(), c.path(h))
+}
+
+func (c *cachingClient) Head(ref name.Reference) (*v1.Descriptor, error) {
+	digest, ok := ref.(name.Digest)
+	if !ok {
+		return c.Client.Head(ref)
+	}
+
+	h, err := v1.NewHash(digest.DigestStr())
+	if err != nil {
+		return nil, err
+	}
+
+	// return copies so that the cached descriptors can't be modified
+	if d, ok := descriptorCache.Get(h.String()); ok {
+		c.record("head", digest, "memory", &memoryHits)
+		return &d, nil
+	}
+
+	if raw, ok := memoryCache.Get(h.String()); ok && isManifest(manifestMediaType(raw)) {
+		c.record("head", digest, "memory", &memoryHits)
+		return &v1.Descriptor{MediaType: manifestMediaType(raw), Size: int64(len(raw)), Digest: h}, nil
+	}
+
+	c.record("head", digest, "miss", &misses)
+	d, err := c.Client.Head(ref)
+	if err != nil {
+		return nil, err
+	}
+	if d == nil {
+		return nil, fmt.Errorf("no descriptor received for %s", ref)
+	}
+	descriptorCache.Add(h.String(), *d)
+
+	cp := *d
+	return &cp, nil
+}
+
+func (c *cachingClient) Image(ref name.Reference) (v1.Image, error) {
+	digest, ok := ref.(name.Digest)
+	if !ok {
+		return c.Client.Image(ref)
+	}
+
+	raw, err := c.content("image", digest, func() ([]byte, error) {
+		img, err := c.Client.Image(ref)
+		if err != nil {
+			return nil, err
+		}
+
+		// the wrapped client resolved an index to one of its images, the
+		// index itself is cached under its digest instead
+		if d, err := img.Digest(); err == nil && d.String() != digest.DigestStr() {
+			idx, err := c.Client.Index(ref)
+			if err != nil {
+				return nil, err
+			}
+			return idx.RawManifest()
+		}
+
+		return img.RawManifest()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// the same as the wrapped client, an index resolves to the image for the
+	// default platform
+	if manifestMediaType(raw).IsIndex() {
+		child, err := platformChild(raw)
+		if err != nil {
+			return nil, fmt.Errorf("resolving %s: %w", ref, err)
+		}
+		return c.Image(digest.Context().Digest(child.String()))
+	}
+
+	return partial.CompressedToImage(&cachedImage{client: c, ref: digest, raw: raw})
+}
+
+func isManifest(mediaType types.MediaType) bool {
+	return mediaType.IsImage() || mediaType.IsIndex()
+}
+
+// platformChild returns the digest of the image within the index for the
+// default platform, linux/amd64, a child without a platform is assumed to be
+// for the default platform
+func platformChild(raw []byte) (v1.Hash, error) {
+	index, err := v1.ParseIndexManifest(bytes.NewReader(raw))
+	if err != nil {
+		return v1.Hash{}, err
+	}
+
+	for _, d := range index.Manifests {
+		p := defaultPlatform
+		if d.Platform != nil {
+			p = *d.Platform
+		}
+
+		if p.OS == defaultPlatform.OS && p.Architecture == defaultPlatform.Architecture {
+			return d.Digest, nil
+		}
+	}
+
+	return v1.Hash{}, fmt.Errorf("no image for the %s platform in the index", defaultPlatform)
+}
+
+func (c *cachingClient) Index(ref name.Reference) (v1.ImageIndex, error) {
+	digest, ok := ref.(name.Digest)
+	if !ok {
+		return c.Client.Index(ref)
+	}
+
+	raw, err := c.content("index", digest, func() ([]byte, error) {
+		idx, err := c.Client.Index(ref)
+		if err != nil {
+			return nil, err
+		}
+		return idx.RawManifest()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if mediaType := manifestMediaType(raw); !mediaType.IsIndex() {
+		return nil, fmt.Errorf("%s is not an index, got %s", ref, mediaType)
+	}
+
+	return &cachedIndex{client: c, ref: digest, raw: raw}, nil
+}
+
+func (c *cachingClient) Layer(ref name.Digest) (v1.Layer, error) {
+	return c.layer(ref, types.OCILayer)
+}
+
+// layer returns the layer, or any other blob, with the given digest from the
+// on-disk tier, or fetches it and stores it there. Layers are not held in the
+// in-memory tier.
+func (c *cachingClient) layer(ref name.Digest, mediaType types.MediaType) (v1.Layer, error) {
+	h, err := v1.NewHash(ref.DigestStr())
+	if err != nil {
+		return nil, err
+	}
+
+	if c.dir != "" && h.Algorithm == "sha256" {
+		if info, err := c.fs.Stat(c.path(h)); err == nil {
+			c.record("layer", ref, "disk", &diskHits)
+			c.touch(c.path(h))
+			return c.diskLayer(h, info.Size(), mediaType)
+		}
+	}
+
+	c.record("layer", ref, "miss", &misses)
+	layer, err := c.Client.Layer(ref)
+	if err != nil || c.dir == "" {
+		return layer, err
+	}
+
+	rc, err := layer.Compressed()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	if err := c.toDisk(ref, h, rc); err != nil {
+		log.Debugf("Not storing %s in the on-disk OCI cache: %v", ref, err)
+		return c.Client.Layer(ref)
+	}
+
+	info, err := c.fs.Stat(c.path(h))
+	if err != nil {
+		return nil, err
+	}
+
+	return c.diskLayer(h, info.Size(), mediaType)
+}
+
+// config returns the raw config blob with the given digest, the config blobs
+// are small and commonly shared between images so they're held in memory
+func (c *cachingClient) config(ref name.Digest) ([]byte, error) {
+	return c.content("config", ref, func() ([]byte, error) {
+		layer, err := c.Client.Layer(ref)
+		if err != nil {
+			return nil, err
+		}
+
+		rc, err := layer.Compressed()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+
+		return io.ReadAll(rc)
+	})
+}
+
+// cachedImage is an image with its manifest held in memory, and its config
+// and layers fetched through the cache
+type cachedImage struct {
+	client *cachingClient
+	ref    name.Digest
+	raw    []byte
+}
+
+var _ partial.CompressedImageCore = (*cachedImage)(nil)
+
+func (i *cachedImage) RawManifest() ([]byte, error) {
+	return i.raw, nil
+}
+
+func (i *cachedImage) MediaType() (types.MediaType, error) {
+	return manifestMediaType(i.raw), nil
+}
+
+func (i *cachedImage) RawConfigFile() ([]byte, error) {
+	m, err := v1.ParseManifest(bytes.NewReader(i.raw))
+	if err != nil {
+		return nil, err
+	}
+
+	if m.Config.Data != nil {
+		return m.Config.Data, nil
+	}
+
+	return i.client.config(i.ref.Context().Digest(m.Config.Digest.String()))
+}
+
+func (i *cachedImage) LayerByDigest(h v1.Hash) (partial.CompressedLayer, error) {
+	m, err := v1.ParseManifest(bytes.NewReader(i.raw))
+	if err != nil {
+		return nil, err
+	}
+
+	mediaType := types.OCILayer
+	for _, d := range append(m.Layers, m.Config) {
+		if d.Digest == h {
+			mediaType = d.MediaType
+			break
+		}
+	}
+
+	return i.client.layer(i.ref.Context().Digest(h.String()), mediaType)
+}
+
+// cachedIndex is an index with its manifest held in memory, and its child
+// images and indexes fetched through the cache
+type cachedIndex struct {
+	client *cachingClient
+	ref    name.Digest
+	raw    []byte
+}
+
+var _ v1.ImageIndex = (*cachedIndex)(nil)
+
+func (i *cachedIndex) MediaType() (types.MediaType, error) {
+	return manifestMediaType(i.raw), nil
+}
+
+func (i *cachedIndex) Digest() (v1.Hash, error) {
+	return v1.NewHash(i.ref.DigestStr())
+}
+
+func (i *cachedIndex) Size() (int64, error) {
+	return int64(len(i.raw)), nil
+}
+
+func (i *cachedIndex) IndexManifest() (*v1.IndexManifest, error) {
+	return v1.ParseIndexManifest(bytes.NewReader(i.raw))
+}
+
+func (i *cachedIndex) RawManifest() ([]byte, error) {
+	return i.raw, nil
+}
+
+func (i *cachedIndex) Image(h v1.Hash) (v1.Image, error) {
+	return i.client.Image(i.ref.Context().Digest(h.String()))
+}
+
+func (i *cachedIndex) ImageIndex(h v1.Hash) (v1.ImageIndex, error) {
+	return i.client.Index(i.ref.Context().Digest(h.String()))
+}
+
+// diskCompressedLayer is a layer, or any other blob, stored in the on-disk
+// tier of the cache
+type diskCompressedLayer struct {
+	client    *cachingClient
+	digest    v1.Hash
+	size      int64
+	mediaType types.MediaType
+}
+
+func (c *cachingClient) diskLayer(h v1.Hash, size int64, mediaType types.MediaType) (v1.Layer, error) {
+	return partial.CompressedToLayer(&diskCompressedLayer{
+		client:    c,
+		digest:    h,
+		size:      size,
+		mediaType: mediaType,
+	})
+}
+
+func (l *diskCompressedLayer) Digest() (v1.Hash, error) {
+	return l.digest, nil
+}
+
+// Compressed returns the content of the layer, failing at the end of the
+// content if it doesn't match the digest of the layer, as the blobs in the
+// on-disk tier may have been written only partially by the image cache
+func (l *diskCompressedLayer) Compressed() (io.ReadCloser, error) {
+	path := l.client.path(l.digest)
+	f, err := l.client.fs.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &verifyingReader{
+		ReadCloser: f,
+		hasher:     sha256.New(),
+		expected:   l.digest,
+		mismatch: func() {
+			log.Debugf("Removing %s from the on-disk OCI cache, its content doesn't match its digest", l.digest)
+			_ = l.client.fs.Remove(path)
+		},
+	}, nil
+}
+
+func (l *diskCompressedLayer) Size() (int64, error) {
+	return l.size, nil
+}
+
+func (l *diskCompressedLayer) MediaType() (types.MediaType, error) {
+	return l.mediaType, nil
+}
+
+// verifyingReader reads content and reports an error at its end if the
+// content doesn't match the expected sha256 digest
+type verifyingReader struct {
+	io.ReadCloser
+	hasher   hash.Hash
+	expected v1.Hash
+	mismatch func()
+}
+
+func (r *verifyingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.hasher.Write(p[:n])
+
+	if err == io.EOF {
+		if sum := hex.EncodeToString(r.hasher.Sum(nil)); sum != r.expected.Hex {
+			r.mismatch()
+			return n, fmt.Errorf("content of %s doesn't match its digest, got sha256:%s", r.expected, sum)
+		}
+	}
+
+	return n, err
+}
@@ -0,0 +1,324 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package oci
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/enterprise-contract/ec-cli/internal/cache"
+	"github.com/enterprise-contract/ec-cli/internal/utils"
+)
+
+// testRegistry returns a registry with a random image pushed to it, and the
+// log of the requests made to the registry
+func testRegistry(t *testing.T) (name.Digest, *bytes.Buffer) {
+	t.Helper()
+
+	memoryCache.Clear()
+	descriptorCache.Clear()
+	memoryHits.Store(0)
+	diskHits.Store(0)
+	misses.Store(0)
+	diskPruned.Store(false)
+	diskWritten.Store(0)
+
+	l := &bytes.Buffer{}
+	registry := httptest.NewServer(registry.New(registry.Logger(log.New(l, "", 0))))
+	t.Cleanup(registry.Close)
+
+	u, err := url.Parse(registry.URL)
+	require.NoError(t, err)
+
+	img, err := random.Image(4096, 2)
+	require.NoError(t, err)
+
+	ref, err := name.ParseReference(fmt.Sprintf("localhost:%s/repository/image:tag", u.Port()))
+	require.NoError(t, err)
+	require.NoError(t, remote.Push(ref, img))
+
+	digest, err := img.Digest()
+	require.NoError(t, err)
+
+	return ref.Context().Digest(digest.String()), l
+}
+
+// diskConfig returns the cache configuration with the on-disk tier within the
+// given directory
+func diskConfig(dir string) cache.Config {
+	return cache.Config{Dir: dir, TTL: cache.DefaultTTL, MaxSize: cache.DefaultMaxSize}
+}
+
+func fetchFully(t *testing.T, client Client, ref name.Digest) {
+	t.Helper()
+
+	img, err := client.Image(ref)
+	require.NoError(t, err)
+
+	_, err = img.ConfigFile()
+	require.NoError(t, err)
+
+	layers, err := img.Layers()
+	require.NoError(t, err)
+	for _, l := range layers {
+		r, err := l.Uncompressed()
+		require.NoError(t, err)
+		_, err = io.ReadAll(r)
+		require.NoError(t, err)
+		require.NoError(t, r.Close())
+	}
+}
+
+func TestCachingClientMemory(t *testing.T) {
+	ref, l := testRegistry(t)
+	ctx := context.Background()
+
+	for range 3 {
+		fetchFully(t, newCachingClient(ctx, &defaultClient{ctx: ctx}, cache.Config{}), ref)
+	}
+
+	assert.Equal(t, 1, strings.Count(l.String(), "GET /v2/repository/image/manifests/"+ref.DigestStr()))
+	// the manifest and the config are held in memory, the layers are not
+	assert.Equal(t, CacheStats{MemoryHits: 4, Misses: 8}, Stats())
+}
+
+func TestCachingClientContext(t *testing.T) {
+	ref, _ := testRegistry(t)
+
+	fetch := func(ctx context.Context) {
+		fetchFully(t, newCachingClient(ctx, &defaultClient{ctx, []remote.Option{remote.WithContext(ctx)}}, cache.Config{}), ref)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	fetch(ctx)
+	cancel()
+
+	// the content cached by the first client is used with the context of
+	// the second client
+	fetch(context.Background())
+	assert.Equal(t, int64(2), Stats().MemoryHits)
+}
+
+func TestCachingClientDisk(t *testing.T) {
+	dir := t.TempDir()
+	ref, l := testRegistry(t)
+	ctx := utils.WithFS(context.Background(), afero.NewOsFs())
+
+	fetchFully(t, newCachingClient(ctx, &defaultClient{ctx: ctx}, diskConfig(dir)), ref)
+	requests := l.Len()
+	// the manifest, the config and the two layers
+	assert.Equal(t, CacheStats{Misses: 4}, Stats())
+
+	// a new process starts with an empty in-memory tier
+	memoryCache.Clear()
+
+	client := newCachingClient(ctx, &defaultClient{ctx: ctx}, diskConfig(dir))
+	fetchFully(t, client, ref)
+	assert.Equal(t, requests, l.Len(), "no requests should be made to the registry")
+	assert.Equal(t, CacheStats{DiskHits: 4, Misses: 4}, Stats())
+
+	d, err := client.Head(ref)
+	require.NoError(t, err)
+	assert.Equal(t, ref.DigestStr(), d.Digest.String())
+	assert.True(t, d.MediaType.IsImage())
+	assert.Equal(t, requests, l.Len(), "no requests should be made to the registry")
+}
+
+func TestCachingClientDiskPrune(t *testing.T) {
+	dir := t.TempDir()
+	ref, _ := testRegistry(t)
+	fs := afero.NewOsFs()
+	ctx := utils.WithFS(context.Background(), fs)
+
+	stale := filepath.Join(dir, ImageStoreName, "sha256:stale")
+	require.NoError(t, fs.MkdirAll(filepath.Dir(stale), 0700))
+	require.NoError(t, afero.WriteFile(fs, stale, make([]byte, 10_000), 0600))
+	accessed := time.Now().Add(-time.Hour)
+	require.NoError(t, fs.Chtimes(stale, accessed, accessed))
+
+	// the stale content is pruned to make room for the fetched content
+	c := diskConfig(dir)
+	c.MaxSize = 10_000
+	client := newCachingClient(ctx, &defaultClient{ctx: ctx}, c)
+	fetchFully(t, client, ref)
+
+	exists, err := afero.Exists(fs, stale)
+	require.NoError(t, err)
+	assert.False(t, exists, "the least recently accessed content should be pruned")
+
+	h, err := v1.NewHash(ref.DigestStr())
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, ImageStoreName, h.String()), client.path(h))
+	_, found := client.fromDisk(h)
+	assert.True(t, found)
+}
+
+func TestCachingClientIndex(t *testing.T) {
+	dir := t.TempDir()
+	ref, l := testRegistry(t)
+	ctx := utils.WithFS(context.Background(), afero.NewOsFs())
+
+	img, err := remote.Image(ref)
+	require.NoError(t, err)
+	idx := mutate.AppendManifests(empty.Index, mutate.IndexAddendum{Add: img})
+	idxDigest, err := idx.Digest()
+	require.NoError(t, err)
+	require.NoError(t, remote.WriteIndex(ref.Context().Digest(idxDigest.String()), idx))
+	idxRef := ref.Context().Digest(idxDigest.String())
+
+	for range 2 {
+		memoryCache.Clear()
+		client := newCachingClient(ctx, &defaultClient{ctx: ctx}, diskConfig(dir))
+		i, err := client.Index(idxRef)
+		require.NoError(t, err)
+
+		manifest, err := i.IndexManifest()
+		require.NoError(t, err)
+		require.Len(t, manifest.Manifests, 1)
+
+		child, err := i.Image(manifest.Manifests[0].Digest)
+		require.NoError(t, err)
+		childDigest, err := child.Digest()
+		require.NoError(t, err)
+		assert.Equal(t, ref.DigestStr(), childDigest.String())
+
+		// an index resolves to the image for the default platform
+		resolved, err := client.Image(idxRef)
+		require.NoError(t, err)
+		resolvedDigest, err := resolved.Digest()
+		require.NoError(t, err)
+		assert.Equal(t, ref.DigestStr(), resolvedDigest.String())
+	}
+
+	assert.Equal(t, 1, strings.Count(l.String(), "GET /v2/repository/image/manifests/"+idxDigest.String()))
+	// once when setting up the index, once by the cache
+	assert.Equal(t, 2, strings.Count(l.String(), "GET /v2/repository/image/manifests/"+ref.DigestStr()))
+}
+
+func TestCachingClientTag(t *testing.T) {
+	ref, l := testRegistry(t)
+	ctx := context.Background()
+	tag := ref.Context().Tag("tag")
+
+	client := newCachingClient(ctx, &defaultClient{ctx: ctx}, cache.Config{})
+	for range 2 {
+		_, err := client.Image(tag)
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, 2, strings.Count(l.String(), "GET /v2/repository/image/manifests/tag"))
+	assert.Equal(t, CacheStats{}, Stats())
+}
+
+func TestCachingClientRetriesErrors(t *testing.T) {
+	ref, _ := testRegistry(t)
+	ctx := context.Background()
+
+	img, err := random.Image(1024, 1)
+	require.NoError(t, err)
+	digest, err := img.Digest()
+	require.NoError(t, err)
+	imgRef := ref.Context().Digest(digest.String())
+
+	client := newCachingClient(ctx, &defaultClient{ctx: ctx}, cache.Config{})
+	_, err = client.Head(imgRef)
+	require.Error(t, err)
+
+	require.NoError(t, remote.Write(imgRef, img))
+
+	d, err := client.Head(imgRef)
+	require.NoError(t, err)
+	assert.Equal(t, digest, d.Digest)
+}
+
+func TestCachingClientCorruptedContent(t *testing.T) {
+	ref, _ := testRegistry(t)
+	ctx := utils.WithFS(context.Background(), afero.NewOsFs())
+
+	client := newCachingClient(ctx, &defaultClient{ctx: ctx}, diskConfig(t.TempDir()))
+	h, err := v1.NewHash(ref.DigestStr())
+	require.NoError(t, err)
+
+	err = client.toDisk(ref, h, strings.NewReader("not the manifest"))
+	require.ErrorContains(t, err, "doesn't match its digest")
+
+	_, found := client.fromDisk(h)
+	assert.False(t, found)
+
+	// e.g. partially written by the image cache
+	require.NoError(t, afero.WriteFile(client.fs, client.path(h), []byte("not the manifest"), 0600))
+	_, found = client.fromDisk(h)
+	assert.False(t, found)
+	exists, err := afero.Exists(client.fs, client.path(h))
+	require.NoError(t, err)
+	assert.False(t, exists, "corrupted content should be removed")
+
+	// the content is fetched again
+	fetchFully(t, client, ref)
+	_, found = client.fromDisk(h)
+	assert.True(t, found)
+}
+
+func TestCachingClientCorruptedLayer(t *testing.T) {
+	ref, _ := testRegistry(t)
+	ctx := utils.WithFS(context.Background(), afero.NewOsFs())
+
+	img, err := remote.Image(ref)
+	require.NoError(t, err)
+	layers, err := img.Layers()
+	require.NoError(t, err)
+	h, err := layers[0].Digest()
+	require.NoError(t, err)
+
+	client := newCachingClient(ctx, &defaultClient{ctx: ctx}, diskConfig(t.TempDir()))
+	require.NoError(t, client.fs.MkdirAll(client.dir, 0700))
+	require.NoError(t, afero.WriteFile(client.fs, client.path(h), []byte("partial"), 0600))
+
+	layer, err := client.Layer(ref.Context().Digest(h.String()))
+	require.NoError(t, err)
+	rc, err := layer.Compressed()
+	require.NoError(t, err)
+	_, err = io.ReadAll(rc)
+	require.ErrorContains(t, err, "doesn't match its digest")
+	require.NoError(t, rc.Close())
+
+	exists, err := afero.Exists(client.fs, client.path(h))
+	require.NoError(t, err)
+	assert.False(t, exists, "corrupted content should be removed")
+}
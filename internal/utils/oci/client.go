@@ -20,7 +20,7 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"path"
+	"path/filepath"
 	"runtime/trace"
 	"strconv"
 	"sync"
@@ -35,6 +35,7 @@ import (
 	ociremote "github.com/sigstore/cosign/v2/pkg/oci/remote"
 	log "github.com/sirupsen/logrus"
 
+	eccache "github.com/enterprise-contract/ec-cli/internal/cache"
 	"github.com/enterprise-contract/ec-cli/internal/http"
 )
 
@@ -47,6 +48,15 @@ type contextKey string
 
 const clientContextKey contextKey = "ec.oci.client"
 
+// ImageStoreName is the name of the area of the cache directory holding the
+// blobs of images fetched from OCI registries, shared by the image cache and
+// the on-disk tier of the OCI cache
+const ImageStoreName = "images"
+
+// imgCacheConfig is the configuration of the image cache, with an empty
+// directory when the image cache is turned off
+var imgCacheConfig = sync.OnceValue(initCacheConfig)
+
 var imgCache = sync.OnceValue(initCache)
 
 func init() {
@@ -56,23 +66,41 @@ func init() {
 }
 
 func initCache() cache.Cache {
+	c := initCacheConfig()
+	if c.Dir == "" {
+		return nil
+	}
+
+	return cache.NewFilesystemCache(filepath.Join(c.Dir, ImageStoreName))
+}
+
+// initCacheConfig returns the cache configuration from the environment, see
+// eccache.ConfigFromEnv. Unlike the persistent cache, the image cache is on
+// unless EC_CACHE is set to a false value.
+func initCacheConfig() eccache.Config {
 	// if a value was set and it is parsed as false, turn the cache off
 	if v, err := strconv.ParseBool(os.Getenv("EC_CACHE")); err == nil && !v {
-		return nil
+		return eccache.Config{}
 	}
 
-	if userCache, err := os.UserCacheDir(); err != nil {
-		log.Debug("unable to find user cache directory")
-		return nil
-	} else {
-		imgCacheDir := path.Join(userCache, "ec", "images")
-		if err := os.MkdirAll(imgCacheDir, 0700); err != nil {
-			log.Debugf("unable to create temporary directory for image cache in %q: %v", imgCacheDir, err)
-			return nil
-		}
-		log.Debugf("using %q directory to store image cache", imgCacheDir)
-		return cache.NewFilesystemCache(imgCacheDir)
+	c, err := eccache.ConfigFromEnv()
+	if err != nil {
+		log.Debugf("image cache turned off: %v", err)
+		return eccache.Config{}
+	}
+	if c.Dir == "" {
+		log.Debug("unable to find the cache directory")
+		return eccache.Config{}
 	}
+
+	imgCacheDir := filepath.Join(c.Dir, ImageStoreName)
+	if err := os.MkdirAll(imgCacheDir, 0700); err != nil {
+		log.Debugf("unable to create directory for image cache in %q: %v", imgCacheDir, err)
+		return eccache.Config{}
+	}
+	log.Debugf("using %q directory to store image cache", imgCacheDir)
+
+	return c
 }
 
 func createRemoteOptions(ctx context.Context) []remote.Option {
@@ -99,6 +127,7 @@ type Client interface {
 	Image(name.Reference) (v1.Image, error)
 	Layer(name.Digest) (v1.Layer, error)
 	Index(name.Reference) (v1.ImageIndex, error)
+	Referrers(name.Digest) ([]v1.Descriptor, error)
 }
 
 func WithClient(ctx context.Context, client Client) context.Context {
@@ -117,7 +146,7 @@ func NewClient(ctx context.Context, opts ...remote.Option) Client {
 		o = createRemoteOptions(ctx)
 	}
 
-	return &defaultClient{ctx, o}
+	return newCachingClient(ctx, &defaultClient{ctx, o}, imgCacheConfig())
 }
 
 type defaultClient struct {
@@ -250,3 +279,26 @@ func (c *defaultClient) Index(ref name.Reference) (v1.ImageIndex, error) {
 
 	return index, nil
 }
+
+// Referrers returns the descriptors of the artifacts referring to the given
+// digest. The OCI referrers API is used when the registry supports it, with a
+// fallback to the referrers tag schema otherwise.
+func (c *defaultClient) Referrers(ref name.Digest) ([]v1.Descriptor, error) {
+	if trace.IsEnabled() {
+		region := trace.StartRegion(c.ctx, "ec:oci-fetch-referrers")
+		defer region.End()
+		trace.Logf(c.ctx, "", "image=%q", ref)
+	}
+
+	index, err := remote.Referrers(ref, c.opts...)
+	if err != nil {
+		return nil, fmt.Errorf("fetching referrers: %w", err)
+	}
+
+	manifest, err := index.IndexManifest()
+	if err != nil {
+		return nil, fmt.Errorf("fetching referrers: %w", err)
+	}
+
+	return manifest.Manifests, nil
+}
@@ -32,6 +32,10 @@ import (
 	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
 	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
 	"github.com/google/go-containerregistry/pkg/v1/random"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
 	"github.com/google/go-containerregistry/pkg/v1/types"
@@ -175,6 +179,78 @@ func TestLayer(t *testing.T) {
 	assert.Equal(t, fetchCount, blobDownloadCount)
 }
 
+func TestReferrers(t *testing.T) {
+	cases := []struct {
+		name      string
+		referrers bool
+	}{
+		{name: "referrers API", referrers: true},
+		{name: "referrers tag schema", referrers: false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			registry := httptest.NewServer(registry.New(registry.WithReferrersSupport(c.referrers), registry.Logger(log.New(io.Discard, "", 0))))
+			t.Cleanup(registry.Close)
+
+			u, err := url.Parse(registry.URL)
+			require.NoError(t, err)
+
+			img, err := random.Image(1024, 1)
+			require.NoError(t, err)
+
+			ref, err := name.ParseReference(fmt.Sprintf("localhost:%s/repository/image:tag", u.Port()))
+			require.NoError(t, err)
+			require.NoError(t, remote.Write(ref, img))
+
+			digest, err := img.Digest()
+			require.NoError(t, err)
+
+			subject, err := partial.Descriptor(img)
+			require.NoError(t, err)
+
+			artifact := mutate.ConfigMediaType(empty.Image, "application/vnd.example+json")
+			artifact = mutate.Subject(artifact, *subject).(v1.Image)
+			artifactDigest, err := artifact.Digest()
+			require.NoError(t, err)
+
+			require.NoError(t, remote.Write(ref.Context().Digest(artifactDigest.String()), artifact))
+
+			client := defaultClient{ctx: context.Background()}
+
+			referrers, err := client.Referrers(ref.Context().Digest(digest.String()))
+			require.NoError(t, err)
+			require.Len(t, referrers, 1)
+			assert.Equal(t, artifactDigest, referrers[0].Digest)
+			assert.Equal(t, "application/vnd.example+json", referrers[0].ArtifactType)
+		})
+	}
+}
+
+func TestReferrersNone(t *testing.T) {
+	registry := httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", 0))))
+	t.Cleanup(registry.Close)
+
+	u, err := url.Parse(registry.URL)
+	require.NoError(t, err)
+
+	img, err := random.Image(1024, 1)
+	require.NoError(t, err)
+
+	ref, err := name.ParseReference(fmt.Sprintf("localhost:%s/repository/image:tag", u.Port()))
+	require.NoError(t, err)
+	require.NoError(t, remote.Write(ref, img))
+
+	digest, err := img.Digest()
+	require.NoError(t, err)
+
+	client := defaultClient{ctx: context.Background()}
+
+	referrers, err := client.Referrers(ref.Context().Digest(digest.String()))
+	require.NoError(t, err)
+	assert.Empty(t, referrers)
+}
+
 func TestScopedAuth(t *testing.T) {
 	cases := []struct {
 		repository string
@@ -21,4 +21,5 @@ const (
 	// https://github.com/opencontainers/image-spec/blob/main/annotations.md#pre-defined-annotation-keys
 	BaseImageNameAnnotation   = "org.opencontainers.image.base.name"
 	BaseImageDigestAnnotation = "org.opencontainers.image.base.digest"
+	RefNameAnnotation         = "org.opencontainers.image.ref.name"
 )
@@ -22,6 +22,7 @@ package fake
 
 import (
 	"context"
+	"errors"
 
 	"github.com/google/go-containerregistry/pkg/name"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
@@ -73,6 +74,10 @@ func WithTestImageConfig(ctx context.Context, url string) context.Context {
 	client := &FakeClient{}
 	client.On("Image", ref, mock.Anything).Return(image, nil)
 	client.On("Image", parentRef, mock.Anything).Return(parentImage, nil)
+	// The parent image is neither signed nor attested
+	client.On("VerifyImageSignatures", parentRef, mock.Anything).Return(nil, false, errors.New("no signatures found"))
+	client.On("VerifyImageAttestations", parentRef, mock.Anything).Return(nil, false, errors.New("no attestations found"))
+	client.On("Referrers", parentRef).Return(nil, nil)
 
 	return oci.WithClient(ctx, client)
 }
@@ -143,3 +148,12 @@ func (m *FakeClient) Index(ref name.Reference) (v1.ImageIndex, error) {
 	}
 	return index, args.Error(1)
 }
+
+func (m *FakeClient) Referrers(ref name.Digest) ([]v1.Descriptor, error) {
+	args := m.Called(ref)
+	var descriptors []v1.Descriptor
+	if maybeDescriptors, ok := args.Get(0).([]v1.Descriptor); ok {
+		descriptors = maybeDescriptors
+	}
+	return descriptors, args.Error(1)
+}
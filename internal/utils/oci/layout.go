@@ -0,0 +1,237 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oci
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// layoutTransport serves the read-only part of the OCI distribution API from
+// an OCI image layout on disk. Using it as the transport of the remote
+// clients, e.g. cosign or ORAS, resolves manifests and blobs from the layout
+// without any network access. Manifests are resolved by tag using the
+// RefNameAnnotation of the descriptors in the layout's index, which holds the
+// full reference, e.g. "registry.io/repository:tag", and by digest using the
+// blobs within the layout.
+type layoutTransport struct {
+	path layout.Path
+	tags map[string]v1.Descriptor
+}
+
+type registryErrors struct {
+	Errors []registryError `json:"errors"`
+}
+
+type registryError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// NewLayoutTransport returns a http.RoundTripper serving the images held in
+// the OCI image layout in the given directory
+func NewLayoutTransport(dir string) (http.RoundTripper, error) {
+	p, err := layout.FromPath(dir)
+	if err != nil {
+		return nil, fmt.Errorf("opening OCI layout %q: %w", dir, err)
+	}
+
+	idx, err := p.ImageIndex()
+	if err != nil {
+		return nil, fmt.Errorf("reading OCI layout %q: %w", dir, err)
+	}
+
+	manifest, err := idx.IndexManifest()
+	if err != nil {
+		return nil, fmt.Errorf("reading OCI layout %q: %w", dir, err)
+	}
+
+	tags := map[string]v1.Descriptor{}
+	for _, d := range manifest.Manifests {
+		n, ok := d.Annotations[RefNameAnnotation]
+		if !ok {
+			continue
+		}
+
+		ref, err := name.NewTag(n)
+		if err != nil {
+			// references by digest are resolved from the blobs
+			continue
+		}
+
+		tags[ref.Name()] = d
+	}
+
+	log.Debugf("Using OCI layout %q with %d tagged manifests", dir, len(tags))
+
+	return &layoutTransport{path: p, tags: tags}, nil
+}
+
+func (t *layoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return errorResponse(req, http.StatusMethodNotAllowed, "UNSUPPORTED", "the OCI layout is read-only"), nil
+	}
+
+	path := strings.TrimSuffix(req.URL.Path, "/")
+	if path == "/v2" {
+		return response(req, http.StatusOK, http.Header{}, io.NopCloser(bytes.NewReader(nil)), 0), nil
+	}
+
+	path = strings.TrimPrefix(path, "/v2/")
+	if repo, ref, ok := cutLast(path, "/manifests/"); ok {
+		return t.manifest(req, repo, ref)
+	}
+
+	if _, digest, ok := cutLast(path, "/blobs/"); ok {
+		return t.blob(req, digest)
+	}
+
+	return errorResponse(req, http.StatusNotFound, "NAME_UNKNOWN", fmt.Sprintf("%s not found in the OCI layout", req.URL.Path)), nil
+}
+
+func (t *layoutTransport) manifest(req *http.Request, repo, ref string) (*http.Response, error) {
+	hash, err := v1.NewHash(ref)
+	if err != nil {
+		tag, err := name.NewTag(fmt.Sprintf("%s/%s:%s", req.URL.Host, repo, ref))
+		if err != nil {
+			return errorResponse(req, http.StatusBadRequest, "TAG_INVALID", err.Error()), nil
+		}
+
+		d, ok := t.tags[tag.Name()]
+		if !ok {
+			return errorResponse(req, http.StatusNotFound, "MANIFEST_UNKNOWN", fmt.Sprintf("%s not found in the OCI layout", tag)), nil
+		}
+		hash = d.Digest
+	}
+
+	raw, err := os.ReadFile(t.blobPath(hash))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return errorResponse(req, http.StatusNotFound, "MANIFEST_UNKNOWN", fmt.Sprintf("%s not found in the OCI layout", hash)), nil
+		}
+		return nil, err
+	}
+
+	header := http.Header{}
+	header.Set("Content-Type", string(manifestMediaType(raw)))
+	header.Set("Docker-Content-Digest", hash.String())
+
+	return response(req, http.StatusOK, header, io.NopCloser(bytes.NewReader(raw)), int64(len(raw))), nil
+}
+
+func (t *layoutTransport) blob(req *http.Request, digest string) (*http.Response, error) {
+	hash, err := v1.NewHash(digest)
+	if err != nil {
+		return errorResponse(req, http.StatusBadRequest, "DIGEST_INVALID", err.Error()), nil
+	}
+
+	f, err := os.Open(t.blobPath(hash))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return errorResponse(req, http.StatusNotFound, "BLOB_UNKNOWN", fmt.Sprintf("%s not found in the OCI layout", hash)), nil
+		}
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	header := http.Header{}
+	header.Set("Content-Type", "application/octet-stream")
+	header.Set("Docker-Content-Digest", hash.String())
+
+	return response(req, http.StatusOK, header, f, info.Size()), nil
+}
+
+func (t *layoutTransport) blobPath(hash v1.Hash) string {
+	return filepath.Join(string(t.path), "blobs", hash.Algorithm, hash.Hex)
+}
+
+// manifestMediaType returns the media type of the manifest, falling back to
+// the OCI media types when the manifest doesn't include it
+func manifestMediaType(raw []byte) types.MediaType {
+	var m struct {
+		MediaType types.MediaType `json:"mediaType"`
+		Manifests json.RawMessage `json:"manifests"`
+	}
+	if err := json.Unmarshal(raw, &m); err == nil && m.MediaType != "" {
+		return m.MediaType
+	}
+
+	if m.Manifests != nil {
+		return types.OCIImageIndex
+	}
+
+	return types.OCIManifestSchema1
+}
+
+func cutLast(s, sep string) (string, string, bool) {
+	i := strings.LastIndex(s, sep)
+	if i < 0 {
+		return "", "", false
+	}
+
+	return s[:i], s[i+len(sep):], true
+}
+
+func response(req *http.Request, status int, header http.Header, body io.ReadCloser, size int64) *http.Response {
+	header.Set("Content-Length", strconv.FormatInt(size, 10))
+
+	if req.Method == http.MethodHead {
+		_ = body.Close()
+		body = http.NoBody
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          body,
+		ContentLength: size,
+		Request:       req,
+	}
+}
+
+func errorResponse(req *http.Request, status int, code, message string) *http.Response {
+	// marshalling a struct of strings can't fail
+	body, _ := json.Marshal(registryErrors{Errors: []registryError{{Code: code, Message: message}}})
+
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+
+	return response(req, status, header, io.NopCloser(bytes.NewReader(body)), int64(len(body)))
+}
@@ -0,0 +1,233 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package oci
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	cosignempty "github.com/sigstore/cosign/v2/pkg/oci/empty"
+	"github.com/sigstore/cosign/v2/pkg/oci/mutate"
+	ociremote "github.com/sigstore/cosign/v2/pkg/oci/remote"
+	"github.com/sigstore/cosign/v2/pkg/oci/static"
+	"github.com/sigstore/sigstore/pkg/signature"
+	"github.com/sigstore/sigstore/pkg/signature/payload"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func layoutClient(t *testing.T, dir string) Client {
+	tr, err := NewLayoutTransport(dir)
+	require.NoError(t, err)
+
+	return NewClient(context.Background(), remote.WithTransport(tr))
+}
+
+func TestLayoutTransport(t *testing.T) {
+	dir := t.TempDir()
+	p, err := layout.Write(dir, empty.Index)
+	require.NoError(t, err)
+
+	img, err := random.Image(1024, 2)
+	require.NoError(t, err)
+	require.NoError(t, p.AppendImage(img, layout.WithAnnotations(map[string]string{
+		RefNameAnnotation: "registry.io/repository/image:tag",
+	})))
+
+	idx, err := random.Index(1024, 1, 2)
+	require.NoError(t, err)
+	require.NoError(t, p.AppendIndex(idx, layout.WithAnnotations(map[string]string{
+		RefNameAnnotation: "docker.io/library/index:latest",
+	})))
+
+	client := layoutClient(t, dir)
+
+	digest, err := img.Digest()
+	require.NoError(t, err)
+
+	t.Run("image by tag", func(t *testing.T) {
+		got, err := client.Image(name.MustParseReference("registry.io/repository/image:tag"))
+		require.NoError(t, err)
+
+		gotDigest, err := got.Digest()
+		require.NoError(t, err)
+		assert.Equal(t, digest, gotDigest)
+
+		layers, err := got.Layers()
+		require.NoError(t, err)
+		assert.Len(t, layers, 2)
+	})
+
+	t.Run("image by digest from any repository", func(t *testing.T) {
+		ref, err := name.NewDigest("other.io/repository@" + digest.String())
+		require.NoError(t, err)
+
+		got, err := client.Image(ref)
+		require.NoError(t, err)
+
+		gotDigest, err := got.Digest()
+		require.NoError(t, err)
+		assert.Equal(t, digest, gotDigest)
+	})
+
+	t.Run("resolve digest", func(t *testing.T) {
+		got, err := client.ResolveDigest(name.MustParseReference("registry.io/repository/image:tag"))
+		require.NoError(t, err)
+		assert.Equal(t, digest.String(), got)
+	})
+
+	t.Run("layer", func(t *testing.T) {
+		layers, err := img.Layers()
+		require.NoError(t, err)
+		layerDigest, err := layers[0].Digest()
+		require.NoError(t, err)
+
+		layerRef, err := name.NewDigest("registry.io/repository/image@" + layerDigest.String())
+		require.NoError(t, err)
+
+		got, err := client.Layer(layerRef)
+		require.NoError(t, err)
+
+		r, err := got.Compressed()
+		require.NoError(t, err)
+		buff := bytes.Buffer{}
+		_, err = buff.ReadFrom(r)
+		require.NoError(t, err)
+		assert.NotEmpty(t, buff.Bytes())
+	})
+
+	t.Run("index by normalized tag", func(t *testing.T) {
+		ref := name.MustParseReference("index")
+
+		desc, err := client.Head(ref)
+		require.NoError(t, err)
+		assert.True(t, desc.MediaType.IsIndex())
+
+		got, err := client.Index(ref)
+		require.NoError(t, err)
+		manifest, err := got.IndexManifest()
+		require.NoError(t, err)
+		assert.Len(t, manifest.Manifests, 2)
+	})
+
+	t.Run("unknown tag", func(t *testing.T) {
+		_, err := client.Head(name.MustParseReference("registry.io/repository/image:unknown"))
+
+		var terr *transport.Error
+		require.ErrorAs(t, err, &terr)
+		assert.Equal(t, http.StatusNotFound, terr.StatusCode)
+	})
+
+	t.Run("unknown digest", func(t *testing.T) {
+		_, err := client.Image(name.MustParseReference("registry.io/repository/image@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"))
+
+		var terr *transport.Error
+		require.ErrorAs(t, err, &terr)
+		assert.Equal(t, http.StatusNotFound, terr.StatusCode)
+	})
+}
+
+func TestLayoutTransportSignatures(t *testing.T) {
+	dir := t.TempDir()
+	p, err := layout.Write(dir, empty.Index)
+	require.NoError(t, err)
+
+	img, err := random.Image(1024, 1)
+	require.NoError(t, err)
+	require.NoError(t, p.AppendImage(img, layout.WithAnnotations(map[string]string{
+		RefNameAnnotation: "registry.io/repository/image:tag",
+	})))
+
+	digest, err := img.Digest()
+	require.NoError(t, err)
+	ref, err := name.NewDigest("registry.io/repository/image@" + digest.String())
+	require.NoError(t, err)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	signerVerifier, err := signature.LoadECDSASignerVerifier(key, crypto.SHA256)
+	require.NoError(t, err)
+
+	pl, err := payload.Cosign{Image: ref}.MarshalJSON()
+	require.NoError(t, err)
+	sig, err := signerVerifier.SignMessage(bytes.NewReader(pl))
+	require.NoError(t, err)
+
+	s, err := static.NewSignature(pl, base64.StdEncoding.EncodeToString(sig))
+	require.NoError(t, err)
+	sigs, err := mutate.AppendSignatures(cosignempty.Signatures(), false, s)
+	require.NoError(t, err)
+
+	sigTag, err := ociremote.SignatureTag(ref)
+	require.NoError(t, err)
+	require.NoError(t, p.AppendImage(sigs, layout.WithAnnotations(map[string]string{
+		RefNameAnnotation: sigTag.Name(),
+	})))
+
+	client := layoutClient(t, dir)
+
+	verified, _, err := client.VerifyImageSignatures(name.MustParseReference("registry.io/repository/image:tag"), &cosign.CheckOpts{
+		SigVerifier:   signerVerifier,
+		IgnoreTlog:    true,
+		ClaimVerifier: cosign.SimpleClaimVerifier,
+	})
+	require.NoError(t, err)
+	assert.Len(t, verified, 1)
+
+	_, _, err = client.VerifyImageAttestations(ref, &cosign.CheckOpts{
+		SigVerifier: signerVerifier,
+		IgnoreTlog:  true,
+	})
+	assert.Error(t, err)
+}
+
+func TestLayoutTransportReadOnly(t *testing.T) {
+	dir := t.TempDir()
+	_, err := layout.Write(dir, empty.Index)
+	require.NoError(t, err)
+
+	tr, err := NewLayoutTransport(dir)
+	require.NoError(t, err)
+
+	req, err := http.NewRequest(http.MethodPut, "https://registry.io/v2/repository/manifests/tag", nil)
+	require.NoError(t, err)
+
+	resp, err := tr.RoundTrip(req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
+
+func TestLayoutTransportNotALayout(t *testing.T) {
+	_, err := NewLayoutTransport(t.TempDir())
+	assert.ErrorContains(t, err, "opening OCI layout")
+}
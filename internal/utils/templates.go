@@ -24,6 +24,7 @@ import (
 	"bytes"
 	"embed"
 	"fmt"
+	htmltemplate "html/template"
 	"strings"
 	"text/template"
 
@@ -77,6 +78,23 @@ func RenderFromTemplatesWithGlob(input any, main string, glob []string, efs embe
 	return buf.Bytes(), nil
 }
 
+// Like RenderFromTemplatesWithGlob but for HTML output. The values are
+// escaped according to the context they're used in, see html/template.
+func RenderHTMLFromTemplatesWithGlob(input any, main string, glob []string, efs embed.FS) ([]byte, error) {
+	t, err := htmltemplate.New(defaultMainTemplate).Funcs(htmltemplate.FuncMap(templateHelpers)).ParseFS(efs, glob...)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	err = t.ExecuteTemplate(&buf, main, input)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
 // Helper funcs for use in templates
 
 func passWarnFailChooser(color string, choices []string) string {
@@ -175,3 +175,9 @@ func TestTemplateHelpers(t *testing.T) {
 		assert.Equal(t, tt.expected, buf.String())
 	}
 }
+
+func TestRenderHTMLFromTemplatesWithGlob(t *testing.T) {
+	out, err := RenderHTMLFromTemplatesWithGlob(map[string]string{"name": "<b>friend</b>"}, "main.tmpl", []string{"test_templates/*.tmpl"}, testTemplatesFS)
+	assert.NoError(t, err)
+	assert.Equal(t, "✓ Hello and greetings, &lt;b&gt;friend&lt;/b&gt;.\n\n", string(out))
+}
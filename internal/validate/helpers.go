@@ -23,45 +23,94 @@ import (
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/afero"
 
+	"github.com/enterprise-contract/ec-cli/internal/policy"
 	"github.com/enterprise-contract/ec-cli/internal/policy/source"
 	"github.com/enterprise-contract/ec-cli/internal/utils"
 )
 
 // Determine policyConfig
 func GetPolicyConfig(ctx context.Context, policyConfiguration string) (string, error) {
+	config, _, err := GetPinnedPolicyConfig(ctx, policyConfiguration)
+	return config, err
+}
+
+// GetLockedPolicyConfig determines the policyConfig like GetPolicyConfig, but
+// fetches a remote policy configuration from the immutable reference recorded
+// in the lockfile. A remote policy configuration not recorded in the lockfile
+// is rejected. The lockfile can be nil.
+func GetLockedPolicyConfig(ctx context.Context, policyConfiguration string, lock *policy.Lockfile) (string, error) {
+	if lock == nil {
+		return GetPolicyConfig(ctx, policyConfiguration)
+	}
+
+	if lock.Configuration == nil {
+		if isRemote(policyConfiguration) {
+			return "", fmt.Errorf("the policy configuration %q has drifted from the lockfile, the lockfile has no remote policy configuration", policyConfiguration)
+		}
+		return GetPolicyConfig(ctx, policyConfiguration)
+	}
+
+	pinned, err := lock.PolicyConfiguration(policyConfiguration)
+	if err != nil {
+		return "", err
+	}
+
+	config, _, err := fetchPolicyConfig(ctx, pinned)
+	return config, err
+}
+
+// GetPinnedPolicyConfig determines the policyConfig like GetPolicyConfig and
+// also returns the url of a remote policy configuration pinned to the
+// immutable reference it was resolved to. The pinned url is empty when the
+// policy configuration is not remote.
+func GetPinnedPolicyConfig(ctx context.Context, policyConfiguration string) (string, string, error) {
 	// If policyConfiguration is not detected as a file and is detected as a git URL,
 	// or if policyConfiguration is an https URL try to download a config file from
 	// the provided source. If successful we read its contents and return it.
-	if source.SourceIsGit(policyConfiguration) && !source.SourceIsFile(policyConfiguration) || source.SourceIsHttp(policyConfiguration) {
-		log.Debugf("Fetching policy config from url: %s", policyConfiguration)
-
-		// Create a temporary dir to download the config. This is separate from the workDir usd
-		// later for downloading policy sources, but it doesn't matter because this dir is not
-		// used again once the config file has been read.
-		fs := utils.FS(ctx)
-		tmpDir, err := utils.CreateWorkDir(fs)
-		if err != nil {
-			return "", err
-		}
-		defer utils.CleanupWorkDir(fs, tmpDir)
-
-		// Git download and find a suitable config file
-		configFile, err := source.GoGetterDownload(ctx, tmpDir, policyConfiguration)
-		if err != nil {
-			return "", err
-		}
-		log.Debugf("Loading %s as policy configuration", configFile)
-		return ReadFile(ctx, configFile)
+	if isRemote(policyConfiguration) {
+		return fetchPolicyConfig(ctx, policyConfiguration)
 	} else if source.SourceIsFile(policyConfiguration) && utils.HasJsonOrYamlExt(policyConfiguration) {
 		// If policyConfiguration is detected as a file and it has a json or yaml extension,
 		// we read its contents and return it.
 		log.Debugf("Loading %s as policy configuration", policyConfiguration)
-		return ReadFile(ctx, policyConfiguration)
+		config, err := ReadFile(ctx, policyConfiguration)
+		return config, "", err
 	}
 
 	// If policyConfiguration is not a file path, git url, or https url,
 	// we assume it's a string and return it as is.
-	return policyConfiguration, nil
+	return policyConfiguration, "", nil
+}
+
+// isRemote returns true if the policy configuration is fetched from a git
+// repository or an https URL
+func isRemote(policyConfiguration string) bool {
+	return source.SourceIsGit(policyConfiguration) && !source.SourceIsFile(policyConfiguration) || source.SourceIsHttp(policyConfiguration)
+}
+
+// fetchPolicyConfig downloads the policy configuration from the url and
+// returns its contents and the pinned url
+func fetchPolicyConfig(ctx context.Context, policyConfiguration string) (string, string, error) {
+	log.Debugf("Fetching policy config from url: %s", policyConfiguration)
+
+	// Create a temporary dir to download the config. This is separate from the workDir usd
+	// later for downloading policy sources, but it doesn't matter because this dir is not
+	// used again once the config file has been read.
+	fs := utils.FS(ctx)
+	tmpDir, err := utils.CreateWorkDir(fs)
+	if err != nil {
+		return "", "", err
+	}
+	defer utils.CleanupWorkDir(fs, tmpDir)
+
+	// Git download and find a suitable config file
+	configFile, pinned, err := source.PinnedGoGetterDownload(ctx, tmpDir, policyConfiguration)
+	if err != nil {
+		return "", "", err
+	}
+	log.Debugf("Loading %s as policy configuration", configFile)
+	config, err := ReadFile(ctx, configFile)
+	return config, pinned, err
 }
 
 // Read file from the workspace and return its contents.
@@ -0,0 +1,287 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package vsa
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	ecc "github.com/enterprise-contract/enterprise-contract-controller/api/v1alpha1"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	log "github.com/sirupsen/logrus"
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+
+	"github.com/enterprise-contract/ec-cli/internal/applicationsnapshot"
+	"github.com/enterprise-contract/ec-cli/internal/attestation"
+	"github.com/enterprise-contract/ec-cli/internal/policy/source"
+	"github.com/enterprise-contract/ec-cli/internal/utils/oci"
+)
+
+// maxClockSkew is how far in the future the effective time of a VSA can be
+// when checking its age, to allow for clocks that are slightly off
+const maxClockSkew = 5 * time.Minute
+
+// Expectations are the conditions a VSA needs to meet in addition to being
+// signed by the expected signer and describing the verified image.
+type Expectations struct {
+	// Policy, when set, needs to match the policy the image was validated
+	// with, see PolicyMatches
+	Policy *ecc.EnterpriseContractPolicySpec
+	// PolicyDigest, when set, needs to match the digest of the policy the
+	// image was validated with, see PolicyDigest
+	PolicyDigest string
+	// MaxAge is the maximum age of the effective time of the validation, the
+	// age is not checked when zero
+	MaxAge time.Duration
+	// Now is the time the age of the validation is computed against, the
+	// current time is used when not set
+	Now time.Time
+}
+
+// Verify fetches the VSAs attached to the image, verifies their signatures
+// with the given options and returns the first VSA that describes the image
+// and meets the expectations. An error describing why each of the VSAs was
+// rejected is returned if none is acceptable.
+func Verify(ctx context.Context, ref name.Reference, opts *cosign.CheckOpts, expect Expectations) (*applicationsnapshot.ProvenanceStatementVSA, error) {
+	client := oci.NewClient(ctx)
+
+	digest, err := client.ResolveDigest(ref)
+	if err != nil {
+		return nil, fmt.Errorf("resolving the digest of %s: %w", ref, err)
+	}
+
+	pinned, err := name.NewDigest(fmt.Sprintf("%s@%s", ref.Context().Name(), digest))
+	if err != nil {
+		return nil, err
+	}
+
+	sigs, _, err := client.VerifyImageAttestations(pinned, opts)
+	if err != nil {
+		return nil, fmt.Errorf("verifying the attestations of %s: %w", pinned, err)
+	}
+
+	var rejected error
+	found := false
+	for _, sig := range sigs {
+		att, err := attestation.ProvenanceFromSignature(sig)
+		if err != nil {
+			// other attestations of the image are of no concern here
+			log.Debugf("Skipping a malformed attestation of %s: %v", pinned, err)
+			continue
+		}
+
+		if att.PredicateType() != applicationsnapshot.PredicateVSAProvenance {
+			continue
+		}
+		found = true
+
+		var vsa applicationsnapshot.ProvenanceStatementVSA
+		if err := json.Unmarshal(att.Statement(), &vsa); err != nil {
+			log.Debugf("Skipping a malformed VSA of %s: %v", pinned, err)
+			rejected = errors.Join(rejected, fmt.Errorf("malformed VSA: %w", err))
+			continue
+		}
+
+		if err := check(vsa, digest, expect); err != nil {
+			log.Debugf("Rejected the VSA of %s: %v", pinned, err)
+			rejected = errors.Join(rejected, err)
+			continue
+		}
+
+		return &vsa, nil
+	}
+
+	if !found {
+		return nil, fmt.Errorf("no VSA found for %s", pinned)
+	}
+
+	return nil, rejected
+}
+
+// check verifies that the VSA describes the image with the given digest and
+// meets the expectations
+func check(vsa applicationsnapshot.ProvenanceStatementVSA, digest string, expect Expectations) error {
+	algorithm, hex, _ := strings.Cut(digest, ":")
+
+	subject := false
+	for _, s := range vsa.Subject {
+		if s.Digest[algorithm] == hex {
+			subject = true
+			break
+		}
+	}
+	if !subject {
+		return fmt.Errorf("the VSA does not describe the image with digest %s", digest)
+	}
+
+	if !vsa.Predicate.Success {
+		return errors.New("the VSA records a failed validation")
+	}
+
+	if expect.Policy != nil && !PolicyMatches(vsa.Predicate.Policy, *expect.Policy) {
+		return errors.New("the VSA was produced with a different policy than expected")
+	}
+
+	if expect.PolicyDigest != "" {
+		d, err := PolicyDigest(vsa.Predicate.Policy)
+		if err != nil {
+			return err
+		}
+		if d != expect.PolicyDigest {
+			return fmt.Errorf("the VSA was produced with a policy with digest %s, expected %s", d, expect.PolicyDigest)
+		}
+	}
+
+	if expect.MaxAge > 0 {
+		now := expect.Now
+		if now.IsZero() {
+			now = time.Now()
+		}
+
+		effective := vsa.Predicate.EffectiveTime
+		if effective.After(now.Add(maxClockSkew)) {
+			return fmt.Errorf("the VSA effective time %s is in the future", effective.Format(time.RFC3339))
+		}
+		if effective.Add(expect.MaxAge).Before(now) {
+			return fmt.Errorf("the VSA effective time %s is older than %s", effective.Format(time.RFC3339), expect.MaxAge)
+		}
+	}
+
+	return nil
+}
+
+// PolicyDigest returns the SHA-256 digest of the JSON encoded policy, in the
+// form sha256:<hex>
+func PolicyDigest(spec ecc.EnterpriseContractPolicySpec) (string, error) {
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(data)), nil
+}
+
+// PolicyMatches returns true if the recorded policy has the same sources,
+// source configuration and rule data as the expected policy. The sources
+// recorded in the VSA are pinned to the digest or the commit they were
+// fetched at, the pinning is disregarded unless the expected source is pinned
+// too, see sameURL. The keys and identities are not compared, the signer of
+// the VSA vouches for those.
+func PolicyMatches(recorded, expected ecc.EnterpriseContractPolicySpec) bool {
+	if len(recorded.Sources) != len(expected.Sources) {
+		return false
+	}
+
+	for i := range recorded.Sources {
+		r, e := recorded.Sources[i], expected.Sources[i]
+		if !sameJSON(r.RuleData, e.RuleData) || !sameURLs(r.Policy, e.Policy) || !sameURLs(r.Data, e.Data) {
+			return false
+		}
+
+		r.RuleData, e.RuleData = nil, nil
+		r.Policy, e.Policy = nil, nil
+		r.Data, e.Data = nil, nil
+		if !reflect.DeepEqual(r, e) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// sameJSON returns true if both hold the same JSON value regardless of the
+// formatting and the order of the keys
+func sameJSON(a, b *extv1.JSON) bool {
+	value := func(j *extv1.JSON) any {
+		if j == nil || len(j.Raw) == 0 {
+			return nil
+		}
+		var v any
+		if err := json.Unmarshal(j.Raw, &v); err != nil {
+			return string(j.Raw)
+		}
+		return v
+	}
+
+	return reflect.DeepEqual(value(a), value(b))
+}
+
+func sameURLs(recorded, expected []string) bool {
+	if len(recorded) != len(expected) {
+		return false
+	}
+
+	for i := range recorded {
+		if !sameURL(recorded[i], expected[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// sameURL returns true if the recorded policy or data source URL references
+// the same source as the expected URL. The digest or the commit the recorded
+// source was pinned to when it was fetched is disregarded when the expected
+// URL is not pinned. Pinning replaces the git reference of the source, so a
+// recorded source never matches an expected source referencing a branch or a
+// tag.
+func sameURL(recorded, expected string) bool {
+	if source.IsPinned(expected) {
+		return normalizedURL(recorded) == normalizedURL(expected)
+	}
+
+	return unpinnedURL(recorded) == normalizedURL(expected)
+}
+
+// normalizedURL strips the scheme from a policy or data source URL
+func normalizedURL(u string) string {
+	for _, scheme := range []string{"oci::", "oci://", "git::", "git://", "http::", "http://", "file::", "file://", "https://"} {
+		u = strings.TrimPrefix(u, scheme)
+	}
+
+	if strings.HasPrefix(u, "git@") {
+		u = strings.Replace(strings.TrimPrefix(u, "git@"), ":", "/", 1)
+	}
+
+	return u
+}
+
+// unpinnedURL strips the scheme and the image digest or the git commit the
+// source was pinned to from a policy or data source URL, git references to a
+// branch or a tag are kept
+func unpinnedURL(u string) string {
+	u = normalizedURL(u)
+
+	if base, ref, ok := strings.Cut(u, "?ref="); ok && plumbing.IsHash(ref) {
+		u = base
+	}
+
+	if repository, digest, ok := strings.Cut(u, "@"); ok && strings.Contains(digest, ":") {
+		u = repository
+	}
+
+	return u
+}
@@ -0,0 +1,256 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package vsa
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	ecc "github.com/enterprise-contract/enterprise-contract-controller/api/v1alpha1"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/in-toto/in-toto-golang/in_toto"
+	app "github.com/konflux-ci/application-api/api/v1alpha1"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+
+	"github.com/enterprise-contract/ec-cli/internal/applicationsnapshot"
+)
+
+var effectiveTime = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+var testPolicy = ecc.EnterpriseContractPolicySpec{
+	Sources: []ecc.Source{
+		{
+			Name:     "default",
+			Policy:   []string{"oci::quay.io/policy/release:latest@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"},
+			Data:     []string{"git::github.com/org/data?ref=0123456789abcdef0123456789abcdef01234567"},
+			RuleData: &extv1.JSON{Raw: []byte(`{"a": 1, "b": [2, 3]}`)},
+		},
+	},
+}
+
+func TestVerify(t *testing.T) {
+	ctx := context.Background()
+	keyPath, verifier := keyPair(t)
+
+	r := httptest.NewServer(registry.New())
+	t.Cleanup(r.Close)
+	u, err := url.Parse(r.URL)
+	require.NoError(t, err)
+
+	push := func(repository string) (name.Reference, name.Digest) {
+		img, err := random.Image(512, 1)
+		require.NoError(t, err)
+		digest, err := img.Digest()
+		require.NoError(t, err)
+
+		tag, err := name.NewTag(fmt.Sprintf("%s/%s:latest", u.Host, repository))
+		require.NoError(t, err)
+		require.NoError(t, remote.Write(tag, img))
+
+		return tag, tag.Context().Digest(digest.String())
+	}
+
+	signer, err := NewSigner(ctx, Options{KeyRef: keyPath})
+	require.NoError(t, err)
+	t.Cleanup(signer.Close)
+
+	vsaFor := func(ref name.Digest, success bool) applicationsnapshot.ProvenanceStatementVSA {
+		vsa, err := applicationsnapshot.NewComponentVSA(applicationsnapshot.Report{
+			Policy:        testPolicy,
+			EffectiveTime: effectiveTime,
+		}, applicationsnapshot.Component{
+			SnapshotComponent: app.SnapshotComponent{Name: "component", ContainerImage: ref.String()},
+			Success:           success,
+		})
+		require.NoError(t, err)
+		return vsa
+	}
+
+	// the VSAs are signed with a key and no Rekor URL, so they are not recorded in
+	// the transparency log, as with the defaults of "ec verify vsa --public-key"
+	opts := &cosign.CheckOpts{SigVerifier: verifier, IgnoreTlog: true}
+
+	digest, err := PolicyDigest(testPolicy)
+	require.NoError(t, err)
+
+	expected := ecc.EnterpriseContractPolicySpec{
+		Sources: []ecc.Source{
+			{
+				Name:     "default",
+				Policy:   []string{"quay.io/policy/release:latest"},
+				Data:     []string{"github.com/org/data"},
+				RuleData: &extv1.JSON{Raw: []byte(`{"b":[2,3],"a":1}`)},
+			},
+		},
+	}
+
+	passing, passingDigest := push("passing")
+	require.NoError(t, signer.Attest(ctx, passingDigest, vsaFor(passingDigest, true)))
+
+	failing, failingDigest := push("failing")
+	require.NoError(t, signer.Attest(ctx, failingDigest, vsaFor(failingDigest, false)))
+
+	other, otherDigest := push("other")
+	require.NoError(t, signer.Attest(ctx, otherDigest, vsaFor(passingDigest, true)))
+
+	unattested, _ := push("unattested")
+
+	cases := []struct {
+		name   string
+		ref    name.Reference
+		expect Expectations
+		err    string
+	}{
+		{
+			name: "no expectations",
+			ref:  passing,
+		},
+		{
+			name: "all expectations met",
+			ref:  passing,
+			expect: Expectations{
+				Policy:       &expected,
+				PolicyDigest: digest,
+				MaxAge:       time.Hour,
+				Now:          effectiveTime.Add(time.Minute),
+			},
+		},
+		{
+			name: "failed validation",
+			ref:  failing,
+			err:  "the VSA records a failed validation",
+		},
+		{
+			name: "different subject",
+			ref:  other,
+			err:  "the VSA does not describe the image with digest " + otherDigest.DigestStr(),
+		},
+		{
+			name:   "different policy",
+			ref:    passing,
+			expect: Expectations{Policy: &ecc.EnterpriseContractPolicySpec{}},
+			err:    "the VSA was produced with a different policy than expected",
+		},
+		{
+			name:   "different policy digest",
+			ref:    passing,
+			expect: Expectations{PolicyDigest: "sha256:abc"},
+			err:    fmt.Sprintf("the VSA was produced with a policy with digest %s, expected sha256:abc", digest),
+		},
+		{
+			name:   "stale",
+			ref:    passing,
+			expect: Expectations{MaxAge: time.Hour, Now: effectiveTime.Add(2 * time.Hour)},
+			err:    "the VSA effective time 2026-01-02T03:04:05Z is older than 1h0m0s",
+		},
+		{
+			name:   "future effective time",
+			ref:    passing,
+			expect: Expectations{MaxAge: time.Hour, Now: effectiveTime.Add(-time.Hour)},
+			err:    "the VSA effective time 2026-01-02T03:04:05Z is in the future",
+		},
+		{
+			name:   "future effective time within the clock skew",
+			ref:    passing,
+			expect: Expectations{MaxAge: time.Hour, Now: effectiveTime.Add(-time.Minute)},
+		},
+		{
+			name: "no VSA",
+			ref:  unattested,
+			err:  "verifying the attestations of",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			vsa, err := Verify(ctx, c.ref, opts, c.expect)
+			if c.err != "" {
+				assert.ErrorContains(t, err, c.err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, []in_toto.Subject{{
+				Name:   c.ref.Context().Name(),
+				Digest: map[string]string{"sha256": passingDigest.DigestStr()[len("sha256:"):]},
+			}}, vsa.Subject)
+		})
+	}
+}
+
+func TestUnpinnedURL(t *testing.T) {
+	cases := map[string]string{
+		"oci::quay.io/org/policy:tag@sha256:abc": "quay.io/org/policy:tag",
+		"oci://quay.io/org/policy@sha256:abc":    "quay.io/org/policy",
+		"quay.io/org/policy:tag":                 "quay.io/org/policy:tag",
+		"git::github.com/org/repo//policy?ref=0123456789abcdef0123456789abcdef01234567": "github.com/org/repo//policy",
+		"git::github.com/org/repo//policy?ref=v1.0":                                     "github.com/org/repo//policy?ref=v1.0",
+		"git::https://github.com/org/repo.git//policy":                                  "github.com/org/repo.git//policy",
+		"git@github.com:org/repo.git//policy?ref=main":                                  "github.com/org/repo.git//policy?ref=main",
+		"https://example.com/policy.tar.gz":                                             "example.com/policy.tar.gz",
+		"http::example.com/policy.tar.gz":                                               "example.com/policy.tar.gz",
+		"file::/tmp/policy":                                                             "/tmp/policy",
+		"oci::localhost:5000/org/policy:v1@sha256:abcdef0":                              "localhost:5000/org/policy:v1",
+	}
+
+	for u, expected := range cases {
+		t.Run(u, func(t *testing.T) {
+			assert.Equal(t, expected, unpinnedURL(u))
+		})
+	}
+}
+
+func TestSameURL(t *testing.T) {
+	const commit = "0123456789abcdef0123456789abcdef01234567"
+	const digest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+	cases := []struct {
+		recorded string
+		expected string
+		same     bool
+	}{
+		{"git::github.com/org/repo//policy?ref=" + commit, "github.com/org/repo//policy", true},
+		{"git::github.com/org/repo//policy?ref=" + commit, "git::github.com/org/repo//policy?ref=" + commit, true},
+		{"git::github.com/org/repo//policy?ref=" + commit, "github.com/org/repo//policy?ref=fedcba9876543210fedcba9876543210fedcba98", false},
+		{"git::github.com/org/repo//policy?ref=" + commit, "github.com/org/repo//policy?ref=main", false},
+		{"git::github.com/org/repo//policy?ref=main", "github.com/org/repo//policy?ref=main", true},
+		{"git::github.com/org/repo//policy?ref=main", "github.com/org/repo//policy?ref=devel", false},
+		{"git::github.com/org/repo//policy?ref=main", "github.com/org/repo//policy", false},
+		{"oci::quay.io/org/policy:v1@" + digest, "quay.io/org/policy:v1", true},
+		{"oci::quay.io/org/policy:v1@" + digest, "oci::quay.io/org/policy:v1@" + digest, true},
+		{"oci::quay.io/org/policy:v1@" + digest, "quay.io/org/policy:v1@sha256:fedcba9876543210fedcba9876543210fedcba9876543210fedcba9876543210", false},
+		{"oci::quay.io/org/policy:v1@" + digest, "quay.io/org/policy:v2", false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.recorded+" "+c.expected, func(t *testing.T) {
+			assert.Equal(t, c.same, sameURL(c.recorded, c.expected))
+		})
+	}
+}
@@ -0,0 +1,237 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package vsa signs, publishes and verifies the Verification Summary
+// Attestations (VSA) produced by validating images, see
+// applicationsnapshot.NewComponentVSA. A VSA can be attached to the image as a
+// cosign attestation or written to a file as a DSSE envelope.
+package vsa
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/sigstore/cosign/v2/cmd/cosign/cli/generate"
+	"github.com/sigstore/cosign/v2/cmd/cosign/cli/options"
+	"github.com/sigstore/cosign/v2/cmd/cosign/cli/rekor"
+	"github.com/sigstore/cosign/v2/cmd/cosign/cli/sign"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	cbundle "github.com/sigstore/cosign/v2/pkg/cosign/bundle"
+	cremote "github.com/sigstore/cosign/v2/pkg/cosign/remote"
+	"github.com/sigstore/cosign/v2/pkg/oci/mutate"
+	ociremote "github.com/sigstore/cosign/v2/pkg/oci/remote"
+	"github.com/sigstore/cosign/v2/pkg/oci/static"
+	"github.com/sigstore/cosign/v2/pkg/types"
+	"github.com/sigstore/sigstore/pkg/signature/dsse"
+	signatureoptions "github.com/sigstore/sigstore/pkg/signature/options"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+
+	"github.com/enterprise-contract/ec-cli/internal/applicationsnapshot"
+)
+
+// Options configure how the VSA is signed.
+type Options struct {
+	// KeyRef is the reference to the private key, e.g. a file path or a KMS
+	// URI, the password of the key is read from COSIGN_PASSWORD
+	KeyRef string
+	// Keyless requests a short-lived certificate from Fulcio instead of using
+	// a private key
+	Keyless bool
+	// FulcioURL is the URL of the Fulcio instance used when signing keyless
+	FulcioURL string
+	// IDToken is the OIDC identity token presented to Fulcio, when not set an
+	// ambient token provider or the interactive flow is used
+	IDToken string
+	// RekorURL is the URL of the Rekor instance to record the signature in.
+	// When not set, keyless signatures are recorded in the public Rekor
+	// instance and signatures made with a key are not recorded.
+	RekorURL string
+}
+
+// Enabled returns true if the options request the VSA to be signed
+func (o Options) Enabled() bool {
+	return o.KeyRef != "" || o.Keyless
+}
+
+// rekorURL returns the URL of the Rekor instance to record the signature in.
+// Keyless signatures are always recorded, without the transparency log entry
+// they can't be verified once the short-lived certificate expires.
+func (o Options) rekorURL() string {
+	if o.RekorURL == "" && o.Keyless {
+		return options.DefaultRekorURL
+	}
+
+	return o.RekorURL
+}
+
+// Signer signs and publishes VSAs.
+type Signer struct {
+	sv       *sign.SignerVerifier
+	rekorURL string
+}
+
+// NewSigner returns a Signer for the given options, the Signer should be
+// closed once no longer needed.
+func NewSigner(ctx context.Context, opts Options) (*Signer, error) {
+	if opts.KeyRef != "" && opts.Keyless {
+		return nil, errors.New("a signing key and keyless signing cannot be used together")
+	}
+
+	if !opts.Enabled() {
+		return nil, errors.New("either a signing key or keyless signing is required")
+	}
+
+	fulcioURL := opts.FulcioURL
+	if fulcioURL == "" {
+		fulcioURL = options.DefaultFulcioURL
+	}
+
+	sv, err := sign.SignerFromKeyOpts(ctx, "", "", options.KeyOpts{
+		KeyRef:           opts.KeyRef,
+		PassFunc:         generate.GetPass,
+		FulcioURL:        fulcioURL,
+		IDToken:          opts.IDToken,
+		OIDCIssuer:       options.DefaultOIDCIssuerURL,
+		OIDCClientID:     "sigstore",
+		SkipConfirmation: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("getting the VSA signer: %w", err)
+	}
+
+	return &Signer{sv: sv, rekorURL: opts.rekorURL()}, nil
+}
+
+// Close releases the resources held by the signer
+func (s *Signer) Close() {
+	s.sv.Close()
+}
+
+// Sign returns the DSSE envelope holding the signed VSA
+func (s *Signer) Sign(ctx context.Context, vsa applicationsnapshot.ProvenanceStatementVSA) ([]byte, error) {
+	payload, err := json.Marshal(vsa)
+	if err != nil {
+		return nil, err
+	}
+
+	envelope, err := dsse.WrapSigner(s.sv, types.IntotoPayloadType).SignMessage(bytes.NewReader(payload), signatureoptions.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("signing the VSA: %w", err)
+	}
+
+	return envelope, nil
+}
+
+// WriteFile signs the VSA and writes the DSSE envelope to the file at path
+func (s *Signer) WriteFile(ctx context.Context, fs afero.Fs, path string, vsa applicationsnapshot.ProvenanceStatementVSA) error {
+	envelope, err := s.Sign(ctx, vsa)
+	if err != nil {
+		return err
+	}
+
+	if err := afero.WriteFile(fs, path, envelope, 0644); err != nil {
+		return fmt.Errorf("writing the VSA: %w", err)
+	}
+
+	log.Debugf("Wrote the VSA to %s", path)
+
+	return nil
+}
+
+// Attest signs the VSA and attaches it to the image as a cosign attestation,
+// replacing any VSA previously attached to the image
+func (s *Signer) Attest(ctx context.Context, ref name.Digest, vsa applicationsnapshot.ProvenanceStatementVSA) error {
+	envelope, err := s.Sign(ctx, vsa)
+	if err != nil {
+		return err
+	}
+
+	opts := []static.Option{
+		static.WithLayerMediaType(types.DssePayloadType),
+		static.WithAnnotations(map[string]string{
+			"predicateType": applicationsnapshot.PredicateVSAProvenance,
+		}),
+	}
+
+	if s.sv.Cert != nil {
+		opts = append(opts, static.WithCertChain(s.sv.Cert, s.sv.Chain))
+	}
+
+	if s.rekorURL != "" {
+		bundle, err := s.upload(ctx, envelope)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, static.WithBundle(bundle))
+	}
+
+	att, err := static.NewAttestation(envelope, opts...)
+	if err != nil {
+		return err
+	}
+
+	remoteOpts := ociremote.WithRemoteOptions(
+		remote.WithContext(ctx),
+		remote.WithAuthFromKeychain(authn.DefaultKeychain),
+	)
+
+	// the image itself is not needed to attach the attestation to it
+	se := ociremote.SignedUnknown(ref, remoteOpts)
+
+	se, err = mutate.AttachAttestationToEntity(se, att,
+		mutate.WithDupeDetector(cremote.NewDupeDetector(s.sv)),
+		mutate.WithReplaceOp(cremote.NewReplaceOp(applicationsnapshot.PredicateVSAProvenance)),
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := ociremote.WriteAttestations(ref.Repository, se, remoteOpts); err != nil {
+		return fmt.Errorf("attaching the VSA to %s: %w", ref, err)
+	}
+
+	log.Debugf("Attached the VSA to %s", ref)
+
+	return nil
+}
+
+// upload records the signed VSA in the transparency log
+func (s *Signer) upload(ctx context.Context, envelope []byte) (*cbundle.RekorBundle, error) {
+	pem, err := s.sv.Bytes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := rekor.NewClient(s.rekorURL)
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := cosign.TLogUploadDSSEEnvelope(ctx, client, envelope, pem)
+	if err != nil {
+		return nil, fmt.Errorf("recording the VSA in the transparency log: %w", err)
+	}
+	log.Debugf("Recorded the VSA in the transparency log with index %d", *entry.LogIndex)
+
+	return cbundle.EntryToBundle(entry), nil
+}
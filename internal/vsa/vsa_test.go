@@ -0,0 +1,180 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package vsa
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/in-toto/in-toto-golang/in_toto"
+	"github.com/secure-systems-lab/go-securesystemslib/dsse"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	cosignSig "github.com/sigstore/cosign/v2/pkg/signature"
+	"github.com/sigstore/sigstore/pkg/signature"
+	sigsdsse "github.com/sigstore/sigstore/pkg/signature/dsse"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/enterprise-contract/ec-cli/internal/applicationsnapshot"
+)
+
+// keyPair writes an encrypted private key to a temporary file and returns the
+// path to it and the verifier for its public key
+func keyPair(t *testing.T) (string, signature.Verifier) {
+	password := []byte("s3cr3t")
+	t.Setenv("COSIGN_PASSWORD", string(password))
+
+	keys, err := cosign.GenerateKeyPair(func(bool) ([]byte, error) {
+		return password, nil
+	})
+	require.NoError(t, err)
+
+	keyPath := path.Join(t.TempDir(), "cosign.key")
+	require.NoError(t, afero.WriteFile(afero.NewOsFs(), keyPath, keys.PrivateBytes, 0600))
+
+	verifier, err := cosignSig.LoadPublicKeyRaw(keys.PublicBytes, crypto.SHA256)
+	require.NoError(t, err)
+
+	return keyPath, verifier
+}
+
+func testVSA(image string) applicationsnapshot.ProvenanceStatementVSA {
+	return applicationsnapshot.ProvenanceStatementVSA{
+		StatementHeader: in_toto.StatementHeader{
+			Type:          applicationsnapshot.StatmentVSA,
+			PredicateType: applicationsnapshot.PredicateVSAProvenance,
+			Subject:       []in_toto.Subject{{Name: image}},
+		},
+		Predicate: applicationsnapshot.Report{Success: true},
+	}
+}
+
+func TestNewSigner(t *testing.T) {
+	ctx := context.Background()
+
+	_, err := NewSigner(ctx, Options{})
+	assert.EqualError(t, err, "either a signing key or keyless signing is required")
+
+	_, err = NewSigner(ctx, Options{KeyRef: "cosign.key", Keyless: true})
+	assert.EqualError(t, err, "a signing key and keyless signing cannot be used together")
+
+	_, err = NewSigner(ctx, Options{KeyRef: path.Join(t.TempDir(), "missing.key")})
+	assert.ErrorContains(t, err, "getting the VSA signer")
+}
+
+func TestRekorURL(t *testing.T) {
+	assert.Equal(t, "", Options{KeyRef: "cosign.key"}.rekorURL())
+	assert.Equal(t, "https://rekor.local", Options{KeyRef: "cosign.key", RekorURL: "https://rekor.local"}.rekorURL())
+	assert.Equal(t, "https://rekor.sigstore.dev", Options{Keyless: true}.rekorURL())
+	assert.Equal(t, "https://rekor.local", Options{Keyless: true, RekorURL: "https://rekor.local"}.rekorURL())
+}
+
+func TestSign(t *testing.T) {
+	ctx := context.Background()
+	keyPath, verifier := keyPair(t)
+
+	signer, err := NewSigner(ctx, Options{KeyRef: keyPath})
+	require.NoError(t, err)
+	t.Cleanup(signer.Close)
+
+	vsa := testVSA("registry.io/repository/image")
+	envelope, err := signer.Sign(ctx, vsa)
+	require.NoError(t, err)
+
+	require.NoError(t, sigsdsse.WrapVerifier(verifier).VerifySignature(bytes.NewReader(envelope), nil))
+
+	var e dsse.Envelope
+	require.NoError(t, json.Unmarshal(envelope, &e))
+	assert.Equal(t, "application/vnd.in-toto+json", e.PayloadType)
+
+	payload, err := base64.StdEncoding.DecodeString(e.Payload)
+	require.NoError(t, err)
+
+	var got applicationsnapshot.ProvenanceStatementVSA
+	require.NoError(t, json.Unmarshal(payload, &got))
+	assert.Equal(t, vsa.StatementHeader, got.StatementHeader)
+	assert.True(t, got.Predicate.Success)
+}
+
+func TestWriteFile(t *testing.T) {
+	ctx := context.Background()
+	keyPath, verifier := keyPair(t)
+
+	signer, err := NewSigner(ctx, Options{KeyRef: keyPath})
+	require.NoError(t, err)
+	t.Cleanup(signer.Close)
+
+	fs := afero.NewMemMapFs()
+	require.NoError(t, signer.WriteFile(ctx, fs, "/vsa.json", testVSA("registry.io/repository/image")))
+
+	envelope, err := afero.ReadFile(fs, "/vsa.json")
+	require.NoError(t, err)
+	require.NoError(t, sigsdsse.WrapVerifier(verifier).VerifySignature(bytes.NewReader(envelope), nil))
+}
+
+func TestAttest(t *testing.T) {
+	ctx := context.Background()
+	keyPath, verifier := keyPair(t)
+
+	r := httptest.NewServer(registry.New())
+	t.Cleanup(r.Close)
+	u, err := url.Parse(r.URL)
+	require.NoError(t, err)
+
+	img, err := random.Image(512, 1)
+	require.NoError(t, err)
+	digest, err := img.Digest()
+	require.NoError(t, err)
+
+	ref, err := name.NewDigest(fmt.Sprintf("%s/repository/image@%s", u.Host, digest))
+	require.NoError(t, err)
+	require.NoError(t, remote.Write(ref, img))
+
+	signer, err := NewSigner(ctx, Options{KeyRef: keyPath})
+	require.NoError(t, err)
+	t.Cleanup(signer.Close)
+
+	// attaching twice replaces the previously attached VSA
+	require.NoError(t, signer.Attest(ctx, ref, testVSA(ref.Context().Name())))
+	require.NoError(t, signer.Attest(ctx, ref, testVSA(ref.Context().Name())))
+
+	attestations, _, err := cosign.VerifyImageAttestations(ctx, ref, &cosign.CheckOpts{
+		SigVerifier: verifier,
+		IgnoreTlog:  true,
+	})
+	require.NoError(t, err)
+	require.Len(t, attestations, 1)
+
+	annotations, err := attestations[0].Annotations()
+	require.NoError(t, err)
+	assert.Equal(t, applicationsnapshot.PredicateVSAProvenance, annotations["predicateType"])
+}
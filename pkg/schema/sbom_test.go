@@ -0,0 +1,118 @@
+// Copyright The Conforma Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/gkampitakis/go-snaps/snaps"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+var validSPDX = []byte(`{
+  "spdxVersion": "SPDX-2.3",
+  "dataLicense": "CC0-1.0",
+  "SPDXID": "SPDXRef-DOCUMENT",
+  "name": "registry.io/repository/image",
+  "documentNamespace": "https://example.com/spdx/image",
+  "creationInfo": {
+    "created": "2024-05-21T12:16:52Z",
+    "creators": ["Tool: syft-1.4.1"]
+  },
+  "packages": [
+    {
+      "SPDXID": "SPDXRef-Package-1",
+      "name": "openssl",
+      "versionInfo": "3.0.7",
+      "downloadLocation": "NOASSERTION",
+      "externalRefs": [
+        {
+          "referenceCategory": "PACKAGE-MANAGER",
+          "referenceType": "purl",
+          "referenceLocator": "pkg:rpm/redhat/openssl@3.0.7"
+        }
+      ]
+    }
+  ]
+}`)
+
+var validCycloneDX = []byte(`{
+  "bomFormat": "CycloneDX",
+  "specVersion": "1.5",
+  "version": 1,
+  "components": [
+    {
+      "type": "library",
+      "name": "openssl",
+      "version": "3.0.7",
+      "purl": "pkg:rpm/redhat/openssl@3.0.7"
+    }
+  ]
+}`)
+
+func checkSBOM(t *testing.T, schema *jsonschema.Schema, valid []byte, patches ...string) {
+	for i, patch := range patches {
+		t.Run(fmt.Sprintf("case_%d", i), func(t *testing.T) {
+			j, err := jsonpatch.MergePatch(valid, []byte(patch))
+			assert.NoError(t, err)
+
+			var v any
+			err = json.Unmarshal(j, &v)
+			assert.NoError(t, err)
+
+			err = schema.Validate(v)
+			snaps.MatchSnapshot(t, err)
+		})
+	}
+}
+
+func TestSPDX(t *testing.T) {
+	checkSBOM(t, SPDX_v2_3, validSPDX,
+		`{}`,
+		`{"spdxVersion": null}`,
+		`{"spdxVersion": "2.3"}`,
+		`{"creationInfo": {"creators": []}}`,
+		`{"packages": {}}`,
+		`{"packages": [{"SPDXID": "SPDXRef-Package-1"}]}`,
+		`{"packages": [{"SPDXID": "SPDXRef-Package-1", "name": "a", "downloadLocation": "NONE", "checksums": [{"algorithm": "CRC32", "checksumValue": "abc"}]}]}`,
+		`{"packages": [{"SPDXID": "SPDXRef-Package-1", "name": "a", "downloadLocation": "NONE", "externalRefs": [{"referenceCategory": "UNKNOWN"}]}]}`,
+		`{"relationships": [{"spdxElementId": "SPDXRef-DOCUMENT", "relationshipType": "DESCRIBES", "relatedSpdxElement": "SPDXRef-Package-1"}]}`,
+		`{"relationships": [{"spdxElementId": "SPDXRef-DOCUMENT"}]}`,
+	)
+}
+
+func TestCycloneDX(t *testing.T) {
+	for version, schema := range map[string]*jsonschema.Schema{"v1.5": CycloneDX_v1_5, "v1.6": CycloneDX_v1_6} {
+		t.Run(version, func(t *testing.T) {
+			checkSBOM(t, schema, validCycloneDX,
+				`{}`,
+				`{"bomFormat": "SPDX"}`,
+				`{"specVersion": null}`,
+				`{"components": {}}`,
+				`{"components": [{"name": "openssl"}]}`,
+				`{"components": [{"type": "unknown", "name": "openssl"}]}`,
+				`{"components": [{"type": "library", "name": "openssl", "components": [{"type": "library", "name": "libcrypto"}]}]}`,
+			)
+		})
+	}
+}
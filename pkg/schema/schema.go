@@ -30,6 +30,43 @@ var SLSA_Provenance_v0_2 *jsonschema.Schema
 
 var SLSA_Provenance_v0_2_URI = "https://slsa.dev/provenance/v0.2"
 
+//go:embed slsa_provenance_v1.json
+var slsa_provenance_v1_json string
+
+var SLSA_Provenance_v1 *jsonschema.Schema
+
+var SLSA_Provenance_v1_URI = "https://slsa.dev/provenance/v1"
+
+//go:embed spdx_v2.3.json
+var spdx_v2_3_json string
+
+var SPDX_v2_3 *jsonschema.Schema
+
+var SPDX_v2_3_URI = "http://spdx.org/rdf/terms/2.3"
+
+//go:embed cyclonedx_v1.5.json
+var cyclonedx_v1_5_json string
+
+var CycloneDX_v1_5 *jsonschema.Schema
+
+var CycloneDX_v1_5_URI = "http://cyclonedx.org/schema/bom-1.5.schema.json"
+
+//go:embed cyclonedx_v1.6.json
+var cyclonedx_v1_6_json string
+
+var CycloneDX_v1_6 *jsonschema.Schema
+
+var CycloneDX_v1_6_URI = "http://cyclonedx.org/schema/bom-1.6.schema.json"
+
+// The CycloneDX schemas refer to the SPDX license identifiers and the JSON
+// Signature Format schemas
+//
+//go:embed cyclonedx_spdx.json
+var cyclonedx_spdx_json string
+
+//go:embed cyclonedx_jsf_v0.82.json
+var cyclonedx_jsf_v0_82_json string
+
 func init() {
 	compiler := jsonschema.NewCompiler()
 	compiler.AssertFormat = true
@@ -38,4 +75,27 @@ func init() {
 		panic(err)
 	}
 	SLSA_Provenance_v0_2 = compiler.MustCompile(SLSA_Provenance_v0_2_URI)
+
+	if err := compiler.AddResource(SLSA_Provenance_v1_URI, strings.NewReader(slsa_provenance_v1_json)); err != nil {
+		panic(err)
+	}
+	SLSA_Provenance_v1 = compiler.MustCompile(SLSA_Provenance_v1_URI)
+
+	if err := compiler.AddResource(SPDX_v2_3_URI, strings.NewReader(spdx_v2_3_json)); err != nil {
+		panic(err)
+	}
+	SPDX_v2_3 = compiler.MustCompile(SPDX_v2_3_URI)
+
+	for uri, resource := range map[string]string{
+		"http://cyclonedx.org/schema/spdx.schema.json":     cyclonedx_spdx_json,
+		"http://cyclonedx.org/schema/jsf-0.82.schema.json": cyclonedx_jsf_v0_82_json,
+		CycloneDX_v1_5_URI: cyclonedx_v1_5_json,
+		CycloneDX_v1_6_URI: cyclonedx_v1_6_json,
+	} {
+		if err := compiler.AddResource(uri, strings.NewReader(resource)); err != nil {
+			panic(err)
+		}
+	}
+	CycloneDX_v1_5 = compiler.MustCompile(CycloneDX_v1_5_URI)
+	CycloneDX_v1_6 = compiler.MustCompile(CycloneDX_v1_6_URI)
 }